@@ -0,0 +1,52 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// RunAC3 makes rows and cols, the possible lines of each row and column of a grid, arc consistent:
+// it filters every row by the letters the columns crossing it allow, and every column by the rows,
+// until neither changes. Every grid that fits rows and cols still fits the lines returned, which
+// are often far fewer. rows and cols are not modified.
+//
+// It returns an error wrapping ErrNoGridsPossible if some line has no possibilities left.
+//
+// A Generator does this to the lines of every row and column before it starts searching.
+func RunAC3(rows, cols []primitives.PossibleLines) ([]primitives.PossibleLines, []primitives.PossibleLines, error) {
+	gs := &gridState{across: slices.Clone(rows), down: slices.Clone(cols)}
+	if err := runAC3(context.Background(), gs, 1); err != nil {
+		return nil, nil, err
+	}
+	return gs.across, gs.down, nil
+}
+
+// runAC3 is like RunAC3, but filters the lines of gs in place, filtering each line on up to
+// workers goroutines. It returns ctx's error if ctx is done first.
+func runAC3(ctx context.Context, gs *gridState, workers int) error {
+	direction, unchanged := DirectionHorizontal, 0
+	for unchanged < 2 {
+		next, changed := prefilter(ctx, *gs, direction, workers)
+		*gs = next
+		if changed {
+			unchanged = 0
+		} else {
+			unchanged++
+		}
+		direction = 1 - direction
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if y := slices.IndexFunc(gs.across, impossible); y >= 0 {
+		return fmt.Errorf("%w: no words fit row %d", ErrNoGridsPossible, y)
+	}
+	if x := slices.IndexFunc(gs.down, impossible); x >= 0 {
+		return fmt.Errorf("%w: no words fit column %d", ErrNoGridsPossible, x)
+	}
+	return nil
+}
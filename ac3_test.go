@@ -0,0 +1,66 @@
+package xwgen
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestRunAC3(t *testing.T) {
+	gen, err := CreateGeneratorE(5, WithPreferredWords(loadTrimmedWords(t)), WithMaxBlocks(0), WithSeed(42, 1024))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	lines, err := gen.allPossibleLines(t.Context(), 5)
+	if err != nil {
+		t.Fatalf("allPossibleLines() error: %v", err)
+	}
+	lines = lettersOnly(lines)
+	rows := slices.Repeat([]primitives.PossibleLines{lines}, 5)
+	cols := slices.Clone(rows)
+
+	total := func(lines []primitives.PossibleLines) int64 {
+		var n int64
+		for _, line := range lines {
+			n += line.MaxPossibilities()
+		}
+		return n
+	}
+	gotRows, gotCols, err := RunAC3(rows, cols)
+	if err != nil {
+		t.Fatalf("RunAC3() error: %v", err)
+	}
+	before, after := total(rows)+total(cols), total(gotRows)+total(gotCols)
+	t.Logf("RunAC3() reduced the possible lines of a 5x5 word square from %d to %d", before, after)
+	if after >= before {
+		t.Errorf("RunAC3() left %d possible lines, want fewer than %d", after, before)
+	}
+	if rows[0] != lines || cols[0] != lines {
+		t.Error("RunAC3() modified its arguments")
+	}
+
+	// Every grid still fits.
+	count := 0
+	for grid := range gen.PossibleGrids(t.Context()) {
+		for y := range 5 {
+			row := gotRows[y]
+			for x := range 5 {
+				row = row.Filter(grid.Get(x, y), x)
+			}
+			if row.MaxPossibilities() == 0 {
+				t.Errorf("row %d of grid does not fit the lines from RunAC3():\n%s", y, grid.Repr())
+			}
+		}
+		if count++; count >= 20 {
+			break
+		}
+	}
+
+	across := primitives.MakeWords([]string{"aaaaa", "abbbb"}, 2, 5)
+	down := primitives.MakeWords([]string{"bbbbb", "bcccc"}, 2, 5)
+	if _, _, err := RunAC3(slices.Repeat([]primitives.PossibleLines{across}, 5), slices.Repeat([]primitives.PossibleLines{down}, 5)); !errors.Is(err, ErrNoGridsPossible) {
+		t.Errorf("RunAC3() with no way to fill the grid = %v, want ErrNoGridsPossible", err)
+	}
+}
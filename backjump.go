@@ -0,0 +1,158 @@
+package xwgen
+
+import (
+	"slices"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// WithoutBackjumping makes the search backtrack chronologically, i.e. always retry the most recent
+// choice when a branch fails.
+//
+// By default, the search records which choices narrowed each line, and when a branch fails
+// because of lines that an earlier choice alone determined, it jumps straight back to that choice
+// instead of retrying every choice made since. Both find the same grids, though the order can
+// differ for the same random source; this option exists to compare the two.
+func WithoutBackjumping() GeneratorOption {
+	return func(g *Generator) error {
+		g.noBackjumping = true
+		return nil
+	}
+}
+
+// conflictSet is an immutable set of choice levels, where level n is the nth choice on the path
+// from the root of the search. It records which choices a line's possibilities, or a failure,
+// depend on.
+type conflictSet struct {
+	// all means the set depends on every choice made so far, e.g. because it is not known which.
+	all  bool
+	bits []uint64
+}
+
+// allLevels is the conflict set of a failure that may depend on any choice.
+var allLevels = conflictSet{all: true}
+
+func (c conflictSet) has(level int) bool {
+	if c.all {
+		return true
+	}
+	i := level / 64
+	return i < len(c.bits) && c.bits[i]&(1<<(level%64)) != 0
+}
+
+func (c conflictSet) isEmpty() bool {
+	return !c.all && !slices.ContainsFunc(c.bits, func(b uint64) bool { return b != 0 })
+}
+
+// with returns c with level added.
+func (c conflictSet) with(level int) conflictSet {
+	if c.has(level) {
+		return c
+	}
+	b := make([]uint64, max(len(c.bits), level/64+1))
+	copy(b, c.bits)
+	b[level/64] |= 1 << (level % 64)
+	return conflictSet{bits: b}
+}
+
+// without returns c with level removed. If c is all levels, so is the result.
+func (c conflictSet) without(level int) conflictSet {
+	if !c.has(level) || c.all {
+		return c
+	}
+	b := slices.Clone(c.bits)
+	b[level/64] &^= 1 << (level % 64)
+	return conflictSet{bits: b}
+}
+
+func (c conflictSet) union(other conflictSet) conflictSet {
+	switch {
+	case c.all || other.isEmpty():
+		return c
+	case other.all || c.isEmpty():
+		return other
+	}
+	long, short := c.bits, other.bits
+	if len(short) > len(long) {
+		long, short = short, long
+	}
+	b := slices.Clone(long)
+	for i, w := range short {
+		b[i] |= w
+	}
+	return conflictSet{bits: b}
+}
+
+// whyOf returns the conflict set of the dir line at index of state.
+func (s *gridState) whyOf(dir Direction, index int) conflictSet {
+	if dir == DirectionHorizontal {
+		return s.acrossWhy[index]
+	}
+	return s.downWhy[index]
+}
+
+// impossibleWhy returns the union of the conflict sets of every impossible line of state, or all
+// levels if backjumping is disabled.
+func (s *gridState) impossibleWhy() conflictSet {
+	if s.acrossWhy == nil {
+		return allLevels
+	}
+	var why conflictSet
+	for i, line := range s.across {
+		if impossible(line) {
+			why = why.union(s.acrossWhy[i])
+		}
+	}
+	for i, line := range s.down {
+		if impossible(line) {
+			why = why.union(s.downWhy[i])
+		}
+	}
+	return why
+}
+
+// enforceSymmetriesTracked is enforceSymmetries, but also records the choices each line narrowed
+// by a symmetry depends on. Since a symmetry can mirror any cell into any line, those lines depend
+// on every choice that narrowed the grid.
+func enforceSymmetriesTracked(state *gridState, symmetries []symmetry) {
+	if state.acrossWhy == nil {
+		enforceSymmetries(state, symmetries)
+		return
+	}
+
+	across, down := slices.Clone(state.across), slices.Clone(state.down)
+	enforceSymmetries(state, symmetries)
+
+	var why conflictSet
+	for _, w := range state.acrossWhy {
+		why = why.union(w)
+	}
+	for _, w := range state.downWhy {
+		why = why.union(w)
+	}
+	for i := range across {
+		if across[i] != state.across[i] {
+			state.acrossWhy[i] = why
+		}
+	}
+	for i := range down {
+		if down[i] != state.down[i] {
+			state.downWhy[i] = why
+		}
+	}
+}
+
+// narrowedWhys returns the conflict sets of lines narrowed from before to after by the choice at
+// level, or nil if whys is nil.
+func narrowedWhys(whys []conflictSet, before, after []primitives.PossibleLines, level int) []conflictSet {
+	if whys == nil {
+		return nil
+	}
+	result := slices.Clone(whys)
+	for i := range result {
+		if before[i] != after[i] {
+			result[i] = result[i].with(level)
+		}
+	}
+	return result
+}
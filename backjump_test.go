@@ -0,0 +1,318 @@
+package xwgen
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestConflictSet(t *testing.T) {
+	var empty conflictSet
+	if !empty.isEmpty() || empty.has(1) {
+		t.Errorf("zero conflictSet = %+v, want empty", empty)
+	}
+
+	s := empty.with(3).with(70)
+	for level, want := range map[int]bool{1: false, 3: true, 64: false, 70: true, 200: false} {
+		if got := s.has(level); got != want {
+			t.Errorf("has(%d) = %v, want %v", level, got, want)
+		}
+	}
+	if empty.has(3) {
+		t.Error("with modified its receiver")
+	}
+
+	u := s.without(70).union(empty.with(5))
+	if !u.has(3) || !u.has(5) || u.has(70) {
+		t.Errorf("union = %+v, want {3, 5}", u)
+	}
+	if !s.has(70) {
+		t.Error("without modified its receiver")
+	}
+
+	all := s.union(allLevels).without(3)
+	if !all.has(3) || !all.has(1000) {
+		t.Errorf("union with allLevels = %+v, want all levels", all)
+	}
+}
+
+// deepBacktrackingState returns a 6x6 grid state where the search needlessly backtracks through
+// several irrelevant choices when it follows deepBacktrackingOrder.
+//
+// The top-left 2x2 corner has no solution once column 2 puts a 'u' in row 0, but that only becomes
+// apparent once one of its lines is chosen. Rows 2-5 each have three independent choices in between.
+func deepBacktrackingState(g *Generator) *gridState {
+	base := [][]rune{
+		[]rune("abcdef"),
+		[]rune("ghijkl"),
+		[]rune("mnopqr"),
+		[]rune("stuvwx"),
+		[]rune("yzabcd"),
+		[]rune("efghij"),
+	}
+	// cells maps each varying cell to the letters it can hold.
+	type cell struct{ x, y int }
+	cells := map[cell]string{
+		{0, 0}: "xyz", {1, 0}: "xyz", {0, 1}: "xy", {1, 1}: "xyz",
+		{2, 0}: "uv",
+		{3, 2}: "klm", {4, 3}: "klm", {5, 4}: "klm", {3, 5}: "klm",
+	}
+
+	// lineWords returns every way to fill the cells of a line that ok accepts.
+	lineWords := func(at func(i int) cell, ok func(get func(x, y int) rune) bool) primitives.PossibleLines {
+		var words []string
+		var fill func(i int, line []rune)
+		fill = func(i int, line []rune) {
+			if i == len(line) {
+				get := func(x, y int) rune {
+					for i := range line {
+						if at(i) == (cell{x, y}) {
+							return line[i]
+						}
+					}
+					return 0
+				}
+				if ok(get) {
+					words = append(words, string(line))
+				}
+				return
+			}
+			c := at(i)
+			letters, varies := cells[c]
+			if !varies {
+				letters = string(base[c.y][c.x])
+			}
+			for _, r := range letters {
+				line[i] = r
+				fill(i+1, line)
+			}
+		}
+		fill(0, make([]rune, 6))
+		return primitives.MakeWords(words, len(words), 6)
+	}
+	always := func(func(x, y int) rune) bool { return true }
+
+	state := &gridState{
+		across: make([]primitives.PossibleLines, 6),
+		down:   make([]primitives.PossibleLines, 6),
+		rand:   g.rand,
+	}
+	for y := range 6 {
+		state.across[y] = lineWords(func(i int) cell { return cell{i, y} }, always)
+	}
+	for x := range 6 {
+		state.down[x] = lineWords(func(i int) cell { return cell{x, i} }, always)
+	}
+
+	// Row 0 needs different letters in its first two cells, which must be 'x' or 'y' if column
+	// 2 puts a 'u' in it. Row 1 and column 0 need different letters too, and column 1 the same.
+	state.across[0] = lineWords(func(i int) cell { return cell{i, 0} }, func(get func(x, y int) rune) bool {
+		a, b := get(0, 0), get(1, 0)
+		return a != b && (get(2, 0) == 'v' || a != 'z' && b != 'z')
+	})
+	state.across[1] = lineWords(func(i int) cell { return cell{i, 1} }, func(get func(x, y int) rune) bool {
+		return get(0, 1) != get(1, 1)
+	})
+	state.down[0] = lineWords(func(i int) cell { return cell{0, i} }, func(get func(x, y int) rune) bool {
+		return get(0, 0) != get(0, 1)
+	})
+	state.down[1] = lineWords(func(i int) cell { return cell{1, i} }, func(get func(x, y int) rune) bool {
+		return get(1, 0) == get(1, 1)
+	})
+
+	if !g.noBackjumping {
+		state.acrossWhy = make([]conflictSet, 6)
+		state.downWhy = make([]conflictSet, 6)
+	}
+	return state
+}
+
+// deepBacktrackingOrder chooses column 2, then rows 2-5, then the top-left corner.
+var deepBacktrackingOrder = LineSelectorFunc(func(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool) {
+	if down[2].MaxPossibilities() > 1 {
+		return DirectionVertical, 2, true
+	}
+	for _, y := range []int{2, 3, 4, 5, 0} {
+		if across[y].MaxPossibilities() > 1 {
+			return DirectionHorizontal, y, true
+		}
+	}
+	return MostConstrained.SelectLine(across, down, r)
+})
+
+func TestBackjumping(t *testing.T) {
+	search := func(opts ...GeneratorOption) (map[string]bool, SearchStats) {
+		gen, err := CreateGeneratorE(6, append(opts,
+			WithPreferredWords([]string{"abcdef"}),
+			WithRand(rand.New(rand.NewPCG(42, 1024))),
+			WithLineSelector(deepBacktrackingOrder),
+		)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+		grids := make(map[string]bool)
+		var total SearchStats
+		for grid, stats := range gen.search(ctx, deepBacktrackingState(gen), nil) {
+			if grid.Cell(0, 2) == 'u' {
+				t.Errorf("found grid with a 'u' in column 2:\n%s", grid.Repr())
+			}
+			grids[grid.Repr()] = true
+			total.Add(stats)
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		return grids, total
+	}
+
+	backjumping, withStats := search()
+	chronological, withoutStats := search(WithoutBackjumping())
+
+	if len(backjumping) == 0 {
+		t.Fatal("found no grids")
+	}
+	if !maps.Equal(backjumping, chronological) {
+		t.Errorf("backjumping found %d grids, and chronological backtracking %d, want the same grids",
+			len(backjumping), len(chronological))
+	}
+
+	// Under the first choice for column 2, the corner's two alternatives fail once, rather than
+	// once for each of the 3^4 ways to fill rows 2-5.
+	if withStats.Backjumps == 0 {
+		t.Errorf("got no backjumps: %v", withStats)
+	}
+	if withoutStats.Backjumps != 0 {
+		t.Errorf("got backjumps with WithoutBackjumping: %v", withoutStats)
+	}
+	if saved := withoutStats.Backtracks - withStats.Backtracks; saved < 2*(81-1) {
+		t.Errorf("backjumping made %d backtracks, and chronological backtracking %d, want at least %d fewer",
+			withStats.Backtracks, withoutStats.Backtracks, 2*(81-1))
+	}
+}
+
+func TestBackjumping_SameGrids(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var subset []string
+	for i, word := range words {
+		if i%3 == 0 {
+			subset = append(subset, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(4, append(opts,
+			WithPreferredWords(subset),
+			WithRand(rand.New(rand.NewPCG(42, 1024))),
+		)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		var grids []string
+		for grid := range gen.PossibleGrids(ctx) {
+			grids = append(grids, grid.Repr())
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		slices.Sort(grids)
+		return grids
+	}
+
+	backjumping := search()
+	chronological := search(WithoutBackjumping())
+	if len(backjumping) == 0 {
+		t.Fatal("found no grids")
+	}
+	if !slices.Equal(backjumping, chronological) {
+		t.Errorf("backjumping found %d grids, and chronological backtracking %d, want the same grids",
+			len(backjumping), len(chronological))
+	}
+}
+
+// TestBackjumping_Hard5x5 exhausts a 5x5 search with too few words for any grid, where every
+// branch fails and the search cannot stop early.
+func TestBackjumping_Hard5x5(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var subset []string
+	for i, word := range words {
+		if i%4 == 0 {
+			subset = append(subset, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) (int, Stats) {
+		var stats Stats
+		gen, err := CreateGeneratorE(5, append(opts,
+			WithPreferredWords(subset),
+			WithMaxBlocks(3),
+			WithSeed(42, 1024),
+			WithStats(&stats),
+		)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		count := 0
+		for range gen.PossibleGrids(ctx) {
+			count++
+		}
+		if err := gen.Err(); err != nil && !errors.Is(err, ErrNoGridsPossible) {
+			t.Fatalf("search error: %v", err)
+		}
+		return count, stats
+	}
+
+	backjumping, withStats := search()
+	chronological, withoutStats := search(WithoutBackjumping())
+	t.Logf("nodes explored with backjumping: %d, with chronological backtracking: %d", withStats.NodesExplored, withoutStats.NodesExplored)
+	if backjumping != chronological {
+		t.Errorf("backjumping found %d grids, and chronological backtracking %d, want the same", backjumping, chronological)
+	}
+	if withStats.NodesExplored > withoutStats.NodesExplored {
+		t.Errorf("backjumping explored %d nodes, want at most the %d of chronological backtracking",
+			withStats.NodesExplored, withoutStats.NodesExplored)
+	}
+}
+
+func BenchmarkBackjumping(b *testing.B) {
+	words := loadTrimmedWords(b)
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+	}{
+		{name: "Backjumping"},
+		{name: "Chronological", opts: []GeneratorOption{WithoutBackjumping()}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			for b.Loop() {
+				gen, err := CreateGeneratorE(5, append(tc.opts,
+					WithPreferredWords(words),
+					WithRand(rand.New(rand.NewPCG(42, 1024))),
+				)...)
+				if err != nil {
+					b.Fatalf("CreateGeneratorE() error: %v", err)
+				}
+				count := 0
+				for range gen.PossibleGrids(b.Context()) {
+					if count++; count >= 50 {
+						break
+					}
+				}
+			}
+		})
+	}
+}
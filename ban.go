@@ -0,0 +1,96 @@
+package xwgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// QWithoutU is the rule for WithBannedSequences that bans a "q" not followed by a "u" in the same
+// word, e.g. in "qat" or "iraq".
+const QWithoutU = "q!u"
+
+// WithBannedSequences excludes grids in which any of rules appears in a row or column. A rule is
+// either QWithoutU, or a sequence of two or more letters, e.g. "qq" or "eee", which may not appear
+// within a word, nor across the blocked cells between two words of a row or column, e.g. "qq" in
+// "iraq`qat".
+//
+// Words that break a rule are left out of the word lists, so that the search never tries them.
+// Sequences across blocked cells are checked with a line validator: see WithLineValidator. It can
+// be given more than once, and CreateGeneratorE returns an error if a required word breaks a rule.
+func WithBannedSequences(rules ...string) GeneratorOption {
+	return func(g *Generator) error {
+		for _, rule := range rules {
+			rule = strings.ToLower(rule)
+			if rule == QWithoutU {
+				g.banQWithoutU = true
+				continue
+			}
+			if len(rule) < 2 || strings.ContainsFunc(rule, func(r rune) bool { return r < 'a' || r > 'z' }) {
+				return fmt.Errorf("banned sequence %q must be %q or at least two letters", rule, QWithoutU)
+			}
+			if slices.Contains(g.bannedSequences, rule) {
+				continue
+			}
+			if len(g.bannedSequences) == 0 {
+				g.lineValidators = append(g.lineValidators, g.noBannedSequenceAcrossBlocks)
+			}
+			g.bannedSequences = append(g.bannedSequences, rule)
+		}
+		return nil
+	}
+}
+
+// isBanned returns true if word breaks a rule of WithBannedSequences.
+func (g *Generator) isBanned(word string) bool {
+	if g.banQWithoutU {
+		for i := range len(word) {
+			if word[i] == 'q' && (i+1 == len(word) || word[i+1] != 'u') {
+				return true
+			}
+		}
+	}
+	return slices.ContainsFunc(g.bannedSequences, func(seq string) bool {
+		return strings.Contains(word, seq)
+	})
+}
+
+// validateBannedSequences returns an error if a required word breaks a rule of
+// WithBannedSequences.
+func (g *Generator) validateBannedSequences() error {
+	for _, word := range g.requiredWords {
+		if g.isBanned(word) {
+			return fmt.Errorf("required word %q contains a banned sequence", word)
+		}
+	}
+	return nil
+}
+
+// unbannedWords returns the preferred and obscure words that break no rule of
+// WithBannedSequences.
+func (g *Generator) unbannedWords() (preferred, obscure []string) {
+	if !g.banQWithoutU && len(g.bannedSequences) == 0 {
+		return g.PreferredWords, g.ObscureWords
+	}
+	if g.lazyUnbannedWords == nil {
+		g.lazyUnbannedWords = &[2][]string{
+			slices.DeleteFunc(slices.Clone(g.PreferredWords), g.isBanned),
+			slices.DeleteFunc(slices.Clone(g.ObscureWords), g.isBanned),
+		}
+	}
+	return g.lazyUnbannedWords[0], g.lazyUnbannedWords[1]
+}
+
+// noBannedSequenceAcrossBlocks is a line validator that rejects lines in which a banned sequence
+// spans the blocked cells between two words. The words themselves were already checked.
+func (g *Generator) noBannedSequenceAcrossBlocks(line primitives.ConcreteLine, _ LineContext) bool {
+	if len(line.Words) < 2 {
+		return true
+	}
+	letters := strings.ReplaceAll(string(line.Line), string(primitives.Blocked), "")
+	return !slices.ContainsFunc(g.bannedSequences, func(seq string) bool {
+		return strings.Contains(letters, seq)
+	})
+}
@@ -0,0 +1,132 @@
+package xwgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestWithBannedSequences(t *testing.T) {
+	words := loadTrimmedWords(t)
+	generate := func(size, n int, opts ...GeneratorOption) []Grid {
+		gen, err := CreateGeneratorE(size, append([]GeneratorOption{WithPreferredWords(words), WithSeed(42, 1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var grids []Grid
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if grids = append(grids, grid); len(grids) >= n {
+				break
+			}
+		}
+		return grids
+	}
+	containing := func(grids []Grid, seq string) int {
+		n := 0
+		for _, grid := range grids {
+			for _, line := range gridLines(grid) {
+				// Strip blocked cells, to find sequences spanning them too.
+				letters := strings.ReplaceAll(string(line), string(primitives.Blocked), "")
+				if strings.Contains(letters, seq) {
+					n++
+					break
+				}
+			}
+		}
+		return n
+	}
+
+	for _, tc := range []struct {
+		size int
+		seq  string
+		opts []GeneratorOption
+	}{
+		{4, "es", nil},
+		{5, "er", nil},
+		// Single letter words leave rows and columns like "a``i", whose letters meet across blocks.
+		{4, "ai", []GeneratorOption{WithPreferredWords(append(slices.Clone(words), "a", "i", "o")), WithMinWordLength(1)}},
+	} {
+		if containing(generate(tc.size, 40, tc.opts...), tc.seq) == 0 {
+			t.Fatalf("no %dx%d grid contains %q without the ban, so the test proves nothing", tc.size, tc.size, tc.seq)
+		}
+		grids := generate(tc.size, 40, append(tc.opts, WithBannedSequences(tc.seq))...)
+		if len(grids) == 0 {
+			t.Fatalf("got no %dx%d grids banning %q", tc.size, tc.size, tc.seq)
+		}
+		for _, grid := range grids {
+			if containing([]Grid{grid}, tc.seq) > 0 {
+				t.Errorf("grid contains banned %q:\n%s", tc.seq, grid.Repr())
+			}
+		}
+	}
+
+	t.Run("q without u", func(t *testing.T) {
+		// Preferred words are tried first, so that grids with them come first.
+		qWords := []string{"qat", "qis", "suq", "quo"}
+		qWithoutU := func(grid Grid) bool {
+			return slices.ContainsFunc(grid.AllWords(), func(word string) bool {
+				i := strings.IndexRune(word, 'q')
+				return i >= 0 && !strings.HasPrefix(word[i:], "qu")
+			})
+		}
+		for _, ban := range []bool{false, true} {
+			opts := []GeneratorOption{WithPreferredWords(qWords), WithObscureWords(words), WithSeed(42, 1024)}
+			if ban {
+				opts = append(opts, WithBannedSequences(QWithoutU))
+			}
+			gen, err := CreateGeneratorE(3, opts...)
+			if err != nil {
+				t.Fatalf("CreateGeneratorE() error: %v", err)
+			}
+			grids, withQ := 0, 0
+			for grid := range gen.PossibleGrids(t.Context()) {
+				if qWithoutU(grid) {
+					withQ++
+				}
+				if grids++; grids >= 1000 {
+					break
+				}
+			}
+			if grids == 0 {
+				t.Errorf("got no grids (ban %v)", ban)
+			}
+			if want := !ban; (withQ > 0) != want {
+				t.Errorf("got %d of %d grids with a q without u (ban %v)", withQ, grids, ban)
+			}
+		}
+	})
+
+	t.Run("across blocks", func(t *testing.T) {
+		g, err := CreateGeneratorE(4, WithBannedSequences("qq"))
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		for _, tc := range []struct {
+			line  string
+			words []string
+			want  bool
+		}{
+			{"iraq`qat", []string{"iraq", "qat"}, false},
+			{"iraq`tea", []string{"iraq", "tea"}, true},
+			{"qq", []string{"qq"}, true},
+		} {
+			line := primitives.ConcreteLine{Line: []rune(tc.line), Words: tc.words}
+			if got := g.noBannedSequenceAcrossBlocks(line, LineContext{}); got != tc.want {
+				t.Errorf("noBannedSequenceAcrossBlocks(%q) = %v, want %v", tc.line, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, rule := range []string{"", "q", "q u", "q!x", "é€"} {
+			if _, err := CreateGeneratorE(4, WithBannedSequences(rule)); err == nil {
+				t.Errorf("CreateGeneratorE() banning %q succeeded, want an error", rule)
+			}
+		}
+		if _, err := CreateGeneratorE(4, WithRequiredWords([]string{"quiz", "pizza"}), WithBannedSequences("zz")); err == nil {
+			t.Error("CreateGeneratorE() with a banned required word succeeded, want an error")
+		}
+	})
+}
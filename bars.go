@@ -0,0 +1,224 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen/internal"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// Characters of each cell in the text form of Bars.
+const (
+	barNone       = '.'
+	barRight      = '|'
+	barBelow      = '_'
+	barRightBelow = '+'
+)
+
+// Bars are the bars of a barred grid, as in British-style puzzles, which separate its entries in
+// place of blocked cells. Both are indexed by row and then column.
+type Bars struct {
+	// Right holds whether there is a bar between each cell and the next cell of its row.
+	Right [][]bool
+	// Below holds whether there is a bar between each cell and the next cell of its column.
+	Below [][]bool
+}
+
+// ParseBars parses bars from text with a line per row and a character per cell: '.' for a cell
+// without bars, '|' for one with a bar to its right, '_' for one with a bar below it, and '+' for
+// one with both, e.g.
+//
+//	..|..
+//	_____
+//	.....
+//
+// for a 5x3 grid with a bar in the middle of the top row, and bars between the middle row and the
+// bottom one.
+func ParseBars(text string) (*Bars, error) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	bars := &Bars{}
+	for y, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if len(line) != len(lines[0]) {
+			return nil, fmt.Errorf("row %d of the bars has %d cells, but row 0 has %d", y, len(line), len(lines[0]))
+		}
+		right, below := make([]bool, len(line)), make([]bool, len(line))
+		for x, c := range line {
+			switch c {
+			case barNone:
+			case barRight:
+				right[x] = true
+			case barBelow:
+				below[x] = true
+			case barRightBelow:
+				right[x], below[x] = true, true
+			default:
+				return nil, fmt.Errorf("cell (%d, %d) of the bars is %q, expected one of %q", y, x, c, string([]rune{barNone, barRight, barBelow, barRightBelow}))
+			}
+		}
+		bars.Right = append(bars.Right, right)
+		bars.Below = append(bars.Below, below)
+	}
+	if len(lines[0]) == 0 {
+		return nil, fmt.Errorf("bars are empty")
+	}
+	return bars, nil
+}
+
+// String returns the bars in the form parsed by ParseBars.
+func (b *Bars) String() string {
+	var s strings.Builder
+	for y, right := range b.Right {
+		for x := range right {
+			switch {
+			case b.Right[y][x] && b.Below[y][x]:
+				s.WriteRune(barRightBelow)
+			case b.Right[y][x]:
+				s.WriteRune(barRight)
+			case b.Below[y][x]:
+				s.WriteRune(barBelow)
+			default:
+				s.WriteRune(barNone)
+			}
+		}
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+// size returns the width and height of the grid of b.
+func (b *Bars) size() (width, height int) {
+	if len(b.Right) == 0 {
+		return 0, 0
+	}
+	return len(b.Right[0]), len(b.Right)
+}
+
+// segments returns the lengths of the runs of cells between the bars of the given row or column,
+// in order.
+func (b *Bars) segments(dir Direction, line int) []int {
+	width, height := b.size()
+	length, barAfter := width, func(i int) bool { return b.Right[line][i] }
+	if dir == DirectionVertical {
+		length, barAfter = height, func(i int) bool { return b.Below[i][line] }
+	}
+	var segments []int
+	start := 0
+	for i := range length {
+		if i == length-1 || barAfter(i) {
+			segments = append(segments, i+1-start)
+			start = i + 1
+		}
+	}
+	return segments
+}
+
+// WithBars makes every grid a barred grid, where bars separate the entries of each row and column
+// rather than blocked cells, so no cell is blocked. Every run of cells between bars and the edges
+// of the grid is an entry, so it must have between the minimum and maximum word lengths, and every
+// cell is part of an across entry and a down entry.
+//
+// CreateGeneratorE returns an error if bars do not have the size of the grid, or have a bar at its
+// edge, if a run of cells cannot hold a word, or if other options block cells or place rebus
+// cells, which barred grids do not support.
+func WithBars(bars *Bars) GeneratorOption {
+	return func(g *Generator) error {
+		if bars == nil {
+			return fmt.Errorf("bars must not be nil")
+		}
+		g.bars = bars
+		return nil
+	}
+}
+
+// validateBars returns an error if g's bars do not fit its grids, or conflict with its other
+// options.
+func (g *Generator) validateBars() error {
+	if g.bars == nil {
+		return nil
+	}
+	if width, height := g.bars.size(); width != g.LineLength || height != g.Height || len(g.bars.Below) != height ||
+		slices.ContainsFunc(g.bars.Right, func(row []bool) bool { return len(row) != width }) ||
+		slices.ContainsFunc(g.bars.Below, func(row []bool) bool { return len(row) != width }) {
+		return fmt.Errorf("bars are for a %dx%d grid, but the grid is %dx%d", width, height, g.LineLength, g.Height)
+	}
+	for y := range g.Height {
+		if g.bars.Right[y][g.LineLength-1] {
+			return fmt.Errorf("the bar to the right of cell (%d, %d) is at the edge of the grid", y, g.LineLength-1)
+		}
+	}
+	for x := range g.LineLength {
+		if g.bars.Below[g.Height-1][x] {
+			return fmt.Errorf("the bar below cell (%d, %d) is at the edge of the grid", g.Height-1, x)
+		}
+	}
+
+	minLength, maxLength := g.minWordLength(), g.maxWordLength()
+	for _, dir := range []Direction{DirectionHorizontal, DirectionVertical} {
+		name, lines := "row", g.Height
+		if dir == DirectionVertical {
+			name, lines = "column", g.LineLength
+		}
+		for line := range lines {
+			for _, n := range g.bars.segments(dir, line) {
+				if n < minLength || n > maxLength {
+					return fmt.Errorf("%s %d has an entry of %d cells between bars, but words have between %d and %d letters", name, line, n, minLength, maxLength)
+				}
+			}
+		}
+	}
+
+	if len(g.rebusCells) > 0 {
+		return fmt.Errorf("rebus cells are not supported in barred grids")
+	}
+	for _, p := range g.pinnedCells {
+		if p.r == primitives.Blocked {
+			return fmt.Errorf("cell (%d, %d) cannot be blocked in a barred grid", p.row, p.col)
+		}
+	}
+	for y, row := range g.partial {
+		if x := slices.Index(row, CellBlocked); x >= 0 {
+			return fmt.Errorf("cell (%d, %d) of the partial grid cannot be blocked in a barred grid", y, x)
+		}
+	}
+	return nil
+}
+
+// barLines returns the lines of every row and column of a barred grid, with their words in a new
+// random order if shuffle is true, or nothing if g's grids are not barred.
+func (g *Generator) barLines(ctx context.Context, shuffle bool) ([]lineOverride, error) {
+	if g.bars == nil {
+		return nil, nil
+	}
+	var overrides []lineOverride
+	// Lines with the same segments, e.g. rows without bars, share their lines.
+	bySegments := make(map[string]primitives.PossibleLines)
+	for _, dir := range []Direction{DirectionHorizontal, DirectionVertical} {
+		lineLength, lines := g.LineLength, g.Height
+		if dir == DirectionVertical {
+			lineLength, lines = g.Height, g.LineLength
+		}
+		params := g.allPossibleLinesParams(lineLength)
+		params.ShuffleWords = shuffle
+		for line := range lines {
+			segments := g.bars.segments(dir, line)
+			key := fmt.Sprint(segments)
+			l, ok := bySegments[key]
+			if !ok {
+				var err error
+				if l, err = internal.SegmentLines(ctx, params, segments); err != nil {
+					return nil, err
+				}
+				if g.memoizeFilters {
+					l = primitives.MemoizedFilter(l)
+				}
+				bySegments[key] = l
+			}
+			overrides = append(overrides, lineOverride{dir: dir, index: line, lines: l})
+		}
+	}
+	return overrides, nil
+}
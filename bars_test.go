@@ -0,0 +1,112 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBars(t *testing.T) {
+	text := "..|..\n_+___\n.....\n"
+	bars, err := ParseBars(text)
+	if err != nil {
+		t.Fatalf("ParseBars() error: %v", err)
+	}
+	if !bars.Right[0][2] || !bars.Right[1][1] || bars.Right[1][0] || !bars.Below[1][0] || !bars.Below[1][1] || bars.Below[0][0] {
+		t.Errorf("ParseBars() = %+v, want bars after (0, 2), (1, 1) and below row 1", bars)
+	}
+	if got := bars.String(); got != text {
+		t.Errorf("String() = %q, want %q", got, text)
+	}
+	if got, want := bars.segments(DirectionHorizontal, 0), []int{3, 2}; !slices.Equal(got, want) {
+		t.Errorf("segments(row 0) = %v, want %v", got, want)
+	}
+	if got, want := bars.segments(DirectionVertical, 1), []int{2, 1}; !slices.Equal(got, want) {
+		t.Errorf("segments(column 1) = %v, want %v", got, want)
+	}
+
+	for _, text := range []string{"", "..\n.", "..\n.x"} {
+		if _, err := ParseBars(text); err == nil {
+			t.Errorf("ParseBars(%q) succeeded, want error", text)
+		}
+	}
+}
+
+func TestWithBars(t *testing.T) {
+	words := loadTrimmedWords(t)
+	bars, err := ParseBars("..|...\n......\n______\n......\n......\n......\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := CreateGeneratorE(6,
+		WithPreferredWords(words),
+		WithSeed(42, 1024),
+		WithBars(bars),
+	)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		if grid.Bars() != bars {
+			t.Errorf("grid #%d Bars() = %v, want %v", count, grid.Bars(), bars)
+		}
+		if strings.ContainsRune(grid.Repr(), CellBlocked) {
+			t.Errorf("grid #%d has blocked cells:\n%s", count, grid.ReprBarred())
+		}
+		var answers []string
+		for _, e := range grid.Entries() {
+			answers = append(answers, e.Answer)
+			if !slices.Contains(words, e.Answer) {
+				t.Errorf("grid #%d has entry %q, which is not in the word list:\n%s", count, e.Answer, grid.ReprBarred())
+			}
+		}
+		// Row 0 has two entries, and columns have two entries each, split by the bars below row 2.
+		if got, want := len(answers), 2+5+2*6; got != want {
+			t.Errorf("grid #%d has %d entries, want %d:\n%s", count, got, want, grid.ReprBarred())
+		}
+		if got, want := slices.Sorted(slices.Values(answers)), slices.Sorted(slices.Values(grid.AllWords())); !slices.Equal(got, want) {
+			t.Errorf("grid #%d Entries() answers = %q, want the words %q", count, got, want)
+		}
+		if count >= 5 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Errorf("no grids found: %v", gen.Err())
+	}
+}
+
+func TestWithBars_Invalid(t *testing.T) {
+	words := []string{"abc", "def", "ghi", "adg", "beh", "cfi"}
+	for _, tc := range []struct {
+		name string
+		bars string
+		opts []GeneratorOption
+		want string
+	}{
+		{name: "WrongSize", bars: "..\n..\n", want: "bars are for a 2x2 grid, but the grid is 3x3"},
+		{name: "Edge", bars: "..|\n...\n...\n", want: "the bar to the right of cell (0, 2) is at the edge of the grid"},
+		{name: "ShortEntry", bars: ".|.\n...\n...\n", want: "row 0 has an entry of 2 cells between bars, but words have between 3 and 3 letters"},
+		{name: "Rebus", bars: "...\n...\n...\n", opts: []GeneratorOption{WithRebusCell(0, 0, "ab")}, want: "rebus cells are not supported in barred grids"},
+		{name: "Blocked", bars: "...\n...\n...\n", opts: []GeneratorOption{WithBlockedCell(1, 1)}, want: "cell (1, 1) cannot be blocked in a barred grid"},
+		{name: "NoWordFits", bars: "...\n...\n...\n", opts: []GeneratorOption{WithCell(0, 0, 'x')}, want: "pinned cells and bars are self-contradictory"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bars, err := ParseBars(tc.bars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = CreateGeneratorE(3, append([]GeneratorOption{WithPreferredWords(words), WithBars(bars)}, tc.opts...)...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("CreateGeneratorE() error = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
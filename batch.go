@@ -0,0 +1,61 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/Eyas/xwgen/internal"
+)
+
+// GenerateBatch generates grids of each of sizes from the same words, e.g. a 4x4, a 5x5, and a 7x7
+// grid for the same day, and returns the grids of each size by size. Each size is the width of its
+// grids, and their height unless opts set one. Each size has its own generator, configured by
+// opts, as CreateGeneratorE(size, opts...) would, and an error is returned if any of them is
+// invalid.
+//
+// The words are ordered and partitioned by length once, and shared by every size, rather than for
+// each size. Options that hold state, e.g. WithRand, are shared too, so the grids of the sizes must
+// then not be iterated concurrently; WithSeed gives each size its own source.
+func GenerateBatch(ctx context.Context, sizes []int, opts ...GeneratorOption) (map[int]iter.Seq[Grid], error) {
+	generators := make(map[int]*Generator, len(sizes))
+	maxWordLength := 0
+	for _, size := range sizes {
+		if _, ok := generators[size]; ok {
+			return nil, fmt.Errorf("size %d is repeated", size)
+		}
+		g, err := CreateGeneratorE(size, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("size %d: %w", size, err)
+		}
+		generators[size] = g
+		maxWordLength = max(maxWordLength, g.maxWordLength())
+	}
+	if len(generators) == 0 {
+		return map[int]iter.Seq[Grid]{}, nil
+	}
+
+	// Every generator has the same words, so any of them can build the dictionary, for the
+	// longest words of any of them.
+	g := generators[slices.Min(sizes)]
+	params := g.allPossibleLinesParams(maxWordLength)
+	params.MaxWordLength = &maxWordLength
+	dictionary := internal.NewDictionary(params)
+
+	grids := make(map[int]iter.Seq[Grid], len(generators))
+	for size, g := range generators {
+		g.dictionary = dictionary
+		grids[size] = g.PossibleGrids(ctx)
+	}
+	return grids, nil
+}
+
+// maxWordLength returns the length of the longest word that can be placed in a grid.
+func (g *Generator) maxWordLength() int {
+	n := max(g.LineLength, g.Height)
+	if g.MaxWordLength != nil {
+		n = min(n, *g.MaxWordLength)
+	}
+	return n
+}
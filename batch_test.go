@@ -0,0 +1,61 @@
+package xwgen
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGenerateBatch(t *testing.T) {
+	words := loadTrimmedWords(t)
+	opts := []GeneratorOption{WithPreferredWords(words[:len(words)/2]), WithObscureWords(words[len(words)/2:]), WithSeed(42, 1024)}
+	firstGrids := func(grids iter.Seq[Grid]) []string {
+		var reprs []string
+		for grid := range grids {
+			if reprs = append(reprs, grid.Repr()); len(reprs) == 5 {
+				break
+			}
+		}
+		return reprs
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	batch, err := GenerateBatch(ctx, []int{5, 3, 4}, opts...)
+	if err != nil {
+		t.Fatalf("GenerateBatch() error: %v", err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("GenerateBatch() returned %d sizes, want 3", len(batch))
+	}
+	// Each size finds the grids its own generator would.
+	for _, size := range []int{3, 4, 5} {
+		gen, err := CreateGeneratorE(size, opts...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE(%d) error: %v", size, err)
+		}
+		want := firstGrids(gen.PossibleGrids(ctx))
+		got := firstGrids(batch[size])
+		if len(got) == 0 || !slices.Equal(got, want) {
+			t.Errorf("size %d found grids %q, want %q", size, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		sizes []int
+		opts  []GeneratorOption
+	}{
+		{name: "RepeatedSize", sizes: []int{3, 4, 3}, opts: opts},
+		{name: "InvalidSize", sizes: []int{3, 0}, opts: opts},
+		{name: "InvalidOption", sizes: []int{3}, opts: []GeneratorOption{WithMaxBlocks(-1)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := GenerateBatch(ctx, tc.sizes, tc.opts...); err == nil {
+				t.Error("GenerateBatch() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package xwgen
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// WithBeamSearch searches the grid one level of choices at a time, keeping only the width most
+// promising points of the search at each level rather than every one. See the package
+// documentation for how it compares to the default search.
+//
+// Beam search cannot be combined with WithWorkers, WithRestarts, or WithIterativeDeepening.
+func WithBeamSearch(width int) GeneratorOption {
+	return func(g *Generator) error {
+		if width < 1 {
+			return fmt.Errorf("beam width must be at least 1, got %d", width)
+		}
+		g.beamWidth = width
+		return nil
+	}
+}
+
+// beamState is a point of a beam search, ranked by the number of grids it could still lead to.
+type beamState struct {
+	state *gridState
+	// remaining is the log2 of the number of ways to fill in every line of state, ignoring how
+	// they cross. Lower is more promising.
+	remaining float64
+	// order is the order states were added to the beam in, which breaks ties.
+	order int
+}
+
+// beamRemaining returns the beamState.remaining of state, or false if some line of state is
+// impossible.
+func beamRemaining(state *gridState) (float64, bool) {
+	var remaining float64
+	for _, lines := range [][]primitives.PossibleLines{state.across, state.down} {
+		for _, line := range lines {
+			n := line.MaxPossibilities()
+			if n == 0 {
+				return 0, false
+			}
+			remaining += math.Log2(float64(n))
+		}
+	}
+	return remaining, true
+}
+
+func compareBeamStates(a, b beamState) int {
+	return cmp.Or(cmp.Compare(a.remaining, b.remaining), cmp.Compare(a.order, b.order))
+}
+
+// beamHeap is a max-heap of states, so the least promising state is always at the root.
+type beamHeap []beamState
+
+func (h beamHeap) Len() int           { return len(h) }
+func (h beamHeap) Less(i, j int) bool { return compareBeamStates(h[i], h[j]) > 0 }
+func (h beamHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *beamHeap) Push(x any)        { *h = append(*h, x.(beamState)) }
+func (h *beamHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// beam keeps the width most promising states added to it, without holding on to any others.
+type beam struct {
+	width int
+	heap  beamHeap
+	added int
+}
+
+func (b *beam) Add(state *gridState) {
+	remaining, ok := beamRemaining(state)
+	if !ok {
+		return
+	}
+	bs := beamState{state: state, remaining: remaining, order: b.added}
+	b.added++
+	if len(b.heap) < b.width {
+		heap.Push(&b.heap, bs)
+		return
+	}
+	if compareBeamStates(bs, b.heap[0]) < 0 {
+		b.heap[0] = bs
+		heap.Fix(&b.heap, 0)
+	}
+}
+
+// Sorted returns the states kept, most promising first.
+func (b *beam) Sorted() []*gridState {
+	sorted := slices.SortedFunc(slices.Values(b.heap), compareBeamStates)
+	states := make([]*gridState, len(sorted))
+	for i, bs := range sorted {
+		states[i] = bs.state
+	}
+	return states
+}
+
+// searchBeam is like searcher.grids, but only explores the g.beamWidth most promising points of
+// the search at each level. It sets dropped if it skips any of the others.
+func (g *Generator) searchBeam(ctx context.Context, root *gridState, dropped *bool) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		// carried accumulates the statistics of the search since the last grid.
+		var carried SearchStats
+		last := time.Now()
+		for level := []*gridState{root}; len(level) > 0; {
+			next := &beam{width: g.beamWidth}
+			for _, state := range level {
+				// Make one more choice, and collect the states it leads to rather than search them.
+				sr := &searcher{
+					g:   g,
+					ctx: ctx,
+					spawn: func(state *gridState) bool {
+						next.Add(state)
+						return true
+					},
+					splitDepth: 1,
+				}
+				for grid, stats := range sr.grids(state) {
+					stats.Add(carried)
+					carried = SearchStats{}
+					now := time.Now()
+					stats.Elapsed = now.Sub(last)
+					last = now
+					if !yield(grid, stats) {
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				carried.Add(sr.stats)
+			}
+			*dropped = *dropped || next.added > len(next.heap)
+			level = next.Sorted()
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package xwgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithBeamSearch(t *testing.T) {
+	var words []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			words = append(words, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) (map[string]bool, error) {
+		gen, err := CreateGeneratorE(3, append(opts, WithPreferredWords(words), WithSeed(42, 1024))...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		grids := make(map[string]bool)
+		for grid := range gen.PossibleGrids(ctx) {
+			if grids[grid.Repr()] {
+				t.Errorf("grid yielded twice:\n%s", grid.Repr())
+			}
+			grids[grid.Repr()] = true
+		}
+		return grids, gen.Err()
+	}
+
+	all, err := search()
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	prev := 0
+	for _, width := range []int{1, 10, 100, 1 << 20} {
+		got, err := search(WithBeamSearch(width))
+		t.Logf("%d of %d grids with a beam width of %d", len(got), len(all), width)
+		if err != nil && !(len(got) == 0 && errors.Is(err, ErrSearchIncomplete)) {
+			t.Errorf("beam search of width %d error: %v", width, err)
+		}
+		// The full search itself misses a few grids, depending on the order it explores them in.
+		extra := 0
+		for grid := range got {
+			if !all[grid] {
+				extra++
+			}
+		}
+		if extra > 10 {
+			t.Errorf("beam search of width %d yielded %d grids the full search did not", width, extra)
+		}
+		if len(got) < prev {
+			t.Errorf("beam search of width %d found %d grids, fewer than the %d of a narrower beam", width, len(got), prev)
+		}
+		prev = len(got)
+	}
+	// A beam wide enough to never drop anything searches everything.
+	if diff := len(all) - prev; diff < -10 || diff > 10 {
+		t.Errorf("beam search of width %d found %d grids, want about %d", 1<<20, prev, len(all))
+	}
+
+	if _, err := CreateGeneratorE(3, WithBeamSearch(0)); err == nil {
+		t.Error("CreateGeneratorE(WithBeamSearch(0)) succeeded, want an error")
+	}
+	if _, err := CreateGeneratorE(3, WithBeamSearch(10), WithWorkers(2)); err == nil {
+		t.Error("CreateGeneratorE(WithBeamSearch(10), WithWorkers(2)) succeeded, want an error")
+	}
+}
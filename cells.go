@@ -0,0 +1,103 @@
+package xwgen
+
+import (
+	"fmt"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// pinnedCell is a cell of every grid fixed by WithCell or WithBlockedCell.
+type pinnedCell struct {
+	row, col int
+	// r is a letter, or primitives.Blocked.
+	r rune
+}
+
+// describeCell returns how r, a letter or primitives.Blocked, reads in an error message.
+func describeCell(r rune) string {
+	if r == primitives.Blocked {
+		return "blocked"
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+// WithCell makes the cell at the given row and column hold the lowercase letter r in every grid,
+// e.g. to anchor a theme letter. It composes with other pinned cells and with WithPartialGrid.
+//
+// CreateGeneratorE returns an error naming the cell if it is outside the grid, if another option
+// pins it to something else or blocks it, including through the symmetry of the grid, or if no
+// grid can hold the letter there at all.
+func WithCell(row, col int, r rune) GeneratorOption {
+	return func(g *Generator) error {
+		if r < 'a' || r > 'z' {
+			return fmt.Errorf("cell (%d, %d) must be pinned to a lowercase letter, got %q", row, col, r)
+		}
+		g.pinnedCells = append(g.pinnedCells, pinnedCell{row: row, col: col, r: r})
+		return nil
+	}
+}
+
+// WithBlockedCell makes the cell at the given row and column blocked in every grid. It composes
+// like WithCell, and CreateGeneratorE returns errors like those for WithCell.
+func WithBlockedCell(row, col int) GeneratorOption {
+	return func(g *Generator) error {
+		g.pinnedCells = append(g.pinnedCells, pinnedCell{row: row, col: col, r: primitives.Blocked})
+		return nil
+	}
+}
+
+// validatePinnedCells returns an error if a pinned cell is outside g's grids, or conflicts with
+// another pinned cell, the partial grid, or the symmetry of the grid.
+func (g *Generator) validatePinnedCells() error {
+	if len(g.pinnedCells) == 0 {
+		return nil
+	}
+	if g.partial != nil {
+		if err := g.validatePartialSize(g.partial); err != nil {
+			return err
+		}
+	}
+
+	// cells holds what each cell is fixed to so far, by the partial grid and the pinned cells.
+	cells := make(map[[2]int]rune)
+	for y, row := range g.partial {
+		for x, r := range row {
+			if c, ok := cellConstraint(r); ok {
+				cells[[2]int{y, x}] = c
+			}
+		}
+	}
+	for _, p := range g.pinnedCells {
+		if p.row < 0 || p.col < 0 || p.row >= g.Height || p.col >= g.LineLength {
+			return fmt.Errorf("cell (%d, %d) is outside the %dx%d grid", p.row, p.col, g.LineLength, g.Height)
+		}
+		if r, ok := cells[[2]int{p.row, p.col}]; ok && r != p.r {
+			return fmt.Errorf("cell (%d, %d) cannot be both %s and %s", p.row, p.col, describeCell(r), describeCell(p.r))
+		}
+		cells[[2]int{p.row, p.col}] = p.r
+	}
+
+	// Each blocked cell blocks the cells it maps to under the symmetries of the grid.
+	for _, p := range g.pinnedCells {
+		for _, sym := range g.symmetries {
+			x, y := sym(p.col, p.row, g.LineLength, g.Height)
+			r, ok := cells[[2]int{y, x}]
+			if !ok || (r == primitives.Blocked) == (p.r == primitives.Blocked) {
+				continue
+			}
+			if p.r == primitives.Blocked {
+				return fmt.Errorf("cell (%d, %d) cannot be %s: the symmetry of the grid blocks it, like cell (%d, %d)", y, x, describeCell(r), p.row, p.col)
+			}
+			return fmt.Errorf("cell (%d, %d) cannot be %s: the symmetry of the grid blocks it, like cell (%d, %d)", p.row, p.col, describeCell(p.r), y, x)
+		}
+	}
+	return nil
+}
+
+// applyPinnedCells filters the lines of gs to those matching cells.
+func applyPinnedCells(gs *gridState, cells []pinnedCell) {
+	for _, p := range cells {
+		gs.across[p.row] = gs.across[p.row].Filter(p.r, p.col)
+		gs.down[p.col] = gs.down[p.col].Filter(p.r, p.row)
+	}
+}
@@ -0,0 +1,85 @@
+package xwgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithCell(t *testing.T) {
+	words := loadTrimmedWords(t)
+	gen, err := CreateGeneratorE(4,
+		WithPreferredWords(words),
+		WithSeed(42, 1024),
+		WithCell(0, 1, 'a'),
+		WithCell(2, 3, 'e'),
+		WithBlockedCell(3, 0),
+	)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		if grid.Cell(0, 1) != 'a' || grid.Cell(2, 3) != 'e' || !grid.IsBlocked(3, 0) {
+			t.Errorf("grid #%d does not have its pinned cells:\n%s", count, grid.Repr())
+		}
+		if count >= 20 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Errorf("no grids found: %v", gen.Err())
+	}
+}
+
+func TestWithCell_Invalid(t *testing.T) {
+	words := []string{"abc", "def", "ghi", "adg", "beh", "cfi"}
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+		want string
+	}{
+		{name: "NotALetter", opts: []GeneratorOption{WithCell(0, 0, '#')}, want: "cell (0, 0) must be pinned to a lowercase letter"},
+		{name: "OutsideGrid", opts: []GeneratorOption{WithCell(0, 3, 'c')}, want: "cell (0, 3) is outside the 3x3 grid"},
+		{name: "NegativeRow", opts: []GeneratorOption{WithBlockedCell(-1, 0)}, want: "cell (-1, 0) is outside the 3x3 grid"},
+		{name: "TwoLetters", opts: []GeneratorOption{WithCell(1, 1, 'e'), WithCell(1, 1, 'a')}, want: "cell (1, 1) cannot be both 'e' and 'a'"},
+		{name: "LetterAndBlocked", opts: []GeneratorOption{WithCell(1, 1, 'e'), WithBlockedCell(1, 1)}, want: "cell (1, 1) cannot be both 'e' and blocked"},
+		{
+			name: "BlockedByPartialGrid",
+			opts: []GeneratorOption{WithPartialGrid(partialFromRows("...", ".#.", "...")), WithCell(1, 1, 'e')},
+			want: "cell (1, 1) cannot be both blocked and 'e'",
+		},
+		{
+			name: "BlockedBySymmetry",
+			opts: []GeneratorOption{WithRotationalSymmetry(), WithBlockedCell(0, 0), WithCell(2, 2, 'i')},
+			want: "cell (2, 2) cannot be 'i': the symmetry of the grid blocks it, like cell (0, 0)",
+		},
+		{
+			name: "SymmetryOfPartialGrid",
+			opts: []GeneratorOption{WithReflectiveSymmetry("vertical"), WithPartialGrid(partialFromRows("#..", "...", "...")), WithCell(0, 2, 'c')},
+			want: "cell (0, 2) cannot be 'c': the symmetry of the grid blocks it, like cell (0, 0)",
+		},
+		{name: "NoWordFits", opts: []GeneratorOption{WithCell(0, 0, 'z')}, want: "pinned cells are self-contradictory: no words fit row 0"},
+		{
+			name: "NoWordFitsWithPartialGrid",
+			opts: []GeneratorOption{WithPartialGrid(partialFromRows("a..", "...", "...")), WithCell(0, 1, 'e')},
+			want: "partial grid and pinned cells are self-contradictory: no words fit row 0",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := CreateGeneratorE(3, append([]GeneratorOption{WithPreferredWords(words)}, tc.opts...)...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("CreateGeneratorE() error = %v, want one containing %q", err, tc.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("CreateGeneratorE() took %v to fail", elapsed)
+			}
+		})
+	}
+}
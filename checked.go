@@ -0,0 +1,59 @@
+package xwgen
+
+// WithFullyChecked requires every letter of each grid to be part of both an across word and a down
+// word, as is standard in crosswords. A letter is unchecked if it is the only letter between
+// blocked cells (or the edge of the grid) in either direction.
+//
+// This only matters when single letter words are allowed, e.g. with WithMinWordLength(1): single
+// letter words are then left out of the lines the search starts from, so that every run of letters
+// is at least two long in both directions.
+func WithFullyChecked() GeneratorOption {
+	return func(g *Generator) error {
+		g.noUncheckedSquares = true
+		return nil
+	}
+}
+
+// WithNoUncheckedSquares is the same as WithFullyChecked.
+func WithNoUncheckedSquares() GeneratorOption {
+	return WithFullyChecked()
+}
+
+// minWordLength returns the length of the shortest word that can be placed in a grid.
+func (g *Generator) minWordLength() int {
+	n := 3
+	if g.MinWordLength != nil {
+		n = *g.MinWordLength
+	}
+	if g.noUncheckedSquares {
+		// A single letter word is an unchecked square.
+		n = max(n, 2)
+	}
+	return n
+}
+
+// hasUncheckedSquare returns true if some cell of state is definitely a letter, and is definitely
+// isolated from other letters in its row or column.
+func hasUncheckedSquare(state *gridState) bool {
+	width, height := len(state.down), len(state.across)
+	blockedOrEdge := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return true
+		}
+		return definitelyBlocked(state, x, y)
+	}
+
+	for y := range height {
+		for x := range width {
+			isolatedAcross := blockedOrEdge(x-1, y) && blockedOrEdge(x+1, y)
+			isolatedDown := blockedOrEdge(x, y-1) && blockedOrEdge(x, y+1)
+			if !isolatedAcross && !isolatedDown {
+				continue
+			}
+			if definitelyOpen(state, x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
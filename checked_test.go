@@ -0,0 +1,112 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// stateFromRows returns a grid state where every line is decided, from rows using '#' for blocked
+// cells.
+func stateFromRows(rows ...string) *gridState {
+	state := &gridState{
+		across: make([]primitives.PossibleLines, len(rows)),
+		down:   make([]primitives.PossibleLines, len(rows[0])),
+	}
+	cols := make([][]rune, len(rows[0]))
+	for y, row := range rows {
+		line := []rune(strings.ReplaceAll(row, "#", "`"))
+		state.across[y] = primitives.MakeDefinite(primitives.ConcreteLine{Line: line})
+		for x, r := range line {
+			cols[x] = append(cols[x], r)
+		}
+	}
+	for x, col := range cols {
+		state.down[x] = primitives.MakeDefinite(primitives.ConcreteLine{Line: col})
+	}
+	return state
+}
+
+func TestHasUncheckedSquare(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows []string
+		want bool
+	}{
+		{name: "open", rows: []string{"abcd", "efgh", "ijkl", "mnop"}},
+		{name: "corner blocks", rows: []string{"#abc", "defg", "hijk", "lmn#"}},
+		{name: "blocked column edge", rows: []string{"abc#", "def#", "ghij", "klmn"}},
+		{name: "isolated across at edge", rows: []string{"a#bc", "defg", "hijk", "lmno"}, want: true},
+		{name: "isolated across in middle", rows: []string{"abcd", "e#f#", "ghij", "klmn"}, want: true},
+		{name: "isolated down at edge", rows: []string{"abcd", "#efg", "hijk", "lmno"}, want: true},
+		{name: "isolated down in middle", rows: []string{"ab#d", "efgh", "ij#l", "mnop"}, want: true},
+		{name: "isolated in both directions", rows: []string{"a#bc", "#def", "ghij", "klmn"}, want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasUncheckedSquare(stateFromRows(tc.rows...)); got != tc.want {
+				t.Errorf("hasUncheckedSquare(%q) = %v, want %v", tc.rows, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPossibleGrids_NoUncheckedSquares(t *testing.T) {
+	words := append(loadWords(t), "a", "i", "o")
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(4, words, nil, nil, rng, GeneratorParams{},
+		WithMinWordLength(1), WithNoUncheckedSquares())
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		for _, line := range gridLines(grid) {
+			for _, n := range runs(line) {
+				if n == 1 {
+					t.Errorf("grid #%d has an unchecked square:\n%s", count, grid.Repr())
+				}
+			}
+		}
+		if count >= 10 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestWithFullyChecked_Lines(t *testing.T) {
+	words := []string{"a", "i", "at", "it", "tan", "tin", "ant"}
+	gen, err := CreateGeneratorE(4, WithPreferredWords(words), WithMinWordLength(1), WithFullyChecked())
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	lines, err := gen.allPossibleLines(t.Context(), 4)
+	if err != nil {
+		t.Fatalf("allPossibleLines() error: %v", err)
+	}
+	count := 0
+	for line := range lines.Iterate() {
+		count++
+		for _, n := range runs(line.Line) {
+			if n == 1 {
+				t.Errorf("line %q has a single letter word", string(line.Line))
+			}
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one line")
+	}
+
+	if _, err := CreateGeneratorE(4, WithPreferredWords(words), WithMinWordLength(1), WithFullyChecked(), WithRequiredWords([]string{"a"})); err == nil {
+		t.Error("CreateGeneratorE() with a single letter required word succeeded, want an error")
+	}
+}
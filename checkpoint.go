@@ -0,0 +1,306 @@
+package xwgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"slices"
+)
+
+// ErrNoCheckpoint means the most recent search of a generator cannot be checkpointed, because its
+// context was not done before it ended, or because the generator does not support checkpoints.
+var ErrNoCheckpoint = errors.New("the search cannot be checkpointed")
+
+// checkpointVersion is the version of the format written by WriteCheckpoint. Version 1, which
+// held the reprs of the grids seen rather than their hashes, can still be read.
+const checkpointVersion = 2
+
+// checkpoint is the serialized state of an interrupted search.
+//
+// Rather than each line's remaining possibilities, it records the line chosen at each level of
+// the search and how far through its alternatives the search had got. Resuming replays those
+// choices from the word lists, which reproduces the same lines.
+type checkpoint struct {
+	Version int `json:"version"`
+	// Fingerprint identifies the generator's word lists and options, which must match on resume.
+	Fingerprint uint64 `json:"fingerprint"`
+	// InitialRand is the state of the generator's PCG source when it was created, which
+	// determines the order of the words in each line, and Rand its state when interrupted.
+	InitialRand []byte `json:"initial_rand"`
+	Rand        []byte `json:"rand"`
+	// Path holds the choice being made at each level of the search, from the root.
+	Path []checkpointFrame `json:"path"`
+	// SeenHashes holds the hashes of the grids the search remembers yielding, from the least
+	// recently yielded to the most. See WithDedupeCapacity.
+	SeenHashes []uint64 `json:"seen_hashes,omitempty"`
+	// Seen holds the Repr of every grid already yielded, with its rebus cells, if any, in
+	// checkpoints of version 1.
+	Seen []string `json:"seen,omitempty"`
+}
+
+type checkpointFrame struct {
+	Dir       Direction `json:"dir"`
+	Index     int       `json:"index"`
+	Splits    int       `json:"splits,omitempty"`
+	Tried     int       `json:"tried,omitempty"`
+	FailedAll bool      `json:"failed_all,omitempty"`
+	Failed    []uint64  `json:"failed,omitempty"`
+}
+
+// frame is a choice being made by iterateAllPossibleGrids: the line being decided, and how far
+// through its alternatives the search has got.
+type frame struct {
+	dir   Direction
+	index int
+	// splits is the number of times MakeChoice split the line's possibilities before the
+	// alternative being explored.
+	splits int
+	// tried is the number of lines from Iterate before the one being explored.
+	tried int
+	// failed accumulates the choices that caused the alternatives tried so far to fail.
+	failed conflictSet
+}
+
+// interruption is the state of a search when it first noticed that its context was done.
+type interruption struct {
+	path []frame
+	rand []byte
+	// seen holds the grids the search remembers yielding, including after it was interrupted.
+	seen *seenGrids
+}
+
+// checkpoint makes sr record where it was interrupted, so that the generator can write a
+// checkpoint, and continue the search from g.resume, if set. seen holds the grids the search
+// remembers yielding.
+func (g *Generator) checkpoint(sr *searcher, seen *seenGrids) {
+	g.errMu.Lock()
+	g.interrupted = nil
+	g.errMu.Unlock()
+	if g.pcg == nil || g.sampling {
+		return
+	}
+
+	if c := g.resume; c != nil {
+		g.resume = nil
+		for _, f := range c.Path {
+			sr.resume = append(sr.resume, frame{
+				dir:    f.Dir,
+				index:  f.Index,
+				splits: f.Splits,
+				tried:  f.Tried,
+				failed: conflictSet{all: f.FailedAll, bits: f.Failed},
+			})
+		}
+		for _, repr := range c.Seen {
+			seen.add(reprHash(repr))
+		}
+		for _, hash := range c.SeenHashes {
+			seen.add(hash)
+		}
+		// Validated by CreateGeneratorFromCheckpoint.
+		_ = g.pcg.UnmarshalBinary(c.Rand)
+	}
+
+	sr.onInterrupt = func(path []frame) {
+		state, _ := g.pcg.MarshalBinary()
+		g.errMu.Lock()
+		defer g.errMu.Unlock()
+		g.interrupted = &interruption{path: path, rand: state, seen: seen}
+	}
+}
+
+// interrupted records that the search noticed its context is done before exploring the current
+// point of the search, which is where a resumed search continues from.
+func (sr *searcher) interrupted() {
+	if sr.onInterrupt != nil {
+		sr.onInterrupt(slices.Clone(sr.path))
+		sr.onInterrupt = nil
+	}
+}
+
+// resumedLine returns the line that was being decided at the current point of the resumed search,
+// if any.
+func (sr *searcher) resumedLine() (Direction, int, bool) {
+	if d := len(sr.path); d < len(sr.resume) {
+		return sr.resume[d].dir, sr.resume[d].index, true
+	}
+	return 0, 0, false
+}
+
+// WriteCheckpoint writes the state of the most recent search to w, once its sequence has finished
+// because its context was done, e.g. on a timeout. CreateGeneratorFromCheckpoint resumes the search
+// from there, yielding exactly the grids the search would have gone on to yield.
+//
+// It returns ErrNoCheckpoint if the search was exhausted or stopped by the caller instead, or if
+// the generator was created with WithRand, WithWorkers, WithRestarts, WithGridTimeout,
+// WithIterativeDeepening, WithBeamSearch, or WithSampling. Searches started with PossibleGridsFrom
+// are not checkpointed either.
+func (g *Generator) WriteCheckpoint(w io.Writer) error {
+	g.errMu.Lock()
+	in := g.interrupted
+	g.errMu.Unlock()
+	if in == nil && g.resume != nil {
+		// The search was interrupted before it could continue from the checkpoint.
+		return json.NewEncoder(w).Encode(g.resume)
+	}
+	if in == nil {
+		return ErrNoCheckpoint
+	}
+
+	c := checkpoint{
+		Version:     checkpointVersion,
+		Fingerprint: g.fingerprint(),
+		InitialRand: g.initialRand,
+		Rand:        in.rand,
+		SeenHashes:  in.seen.hashes(),
+	}
+	for _, f := range in.path {
+		c.Path = append(c.Path, checkpointFrame{
+			Dir:       f.dir,
+			Index:     f.index,
+			Splits:    f.splits,
+			Tried:     f.tried,
+			FailedAll: f.failed.all,
+			Failed:    f.failed.bits,
+		})
+	}
+	return json.NewEncoder(w).Encode(c)
+}
+
+// CreateGeneratorFromCheckpoint creates a generator whose first search continues the search
+// checkpointed to r by WriteCheckpoint.
+//
+// size and opts must be the same as those of the generator that wrote the checkpoint, other than
+// WithRand or WithSeed, since the checkpoint holds the state of the generator's random source. An
+// error is returned if the checkpoint is invalid, or was written by a generator with different
+// word lists or options that shape the search, e.g. grid dimensions, symmetries, or the line
+// selector. Functions passed as options, e.g. to WithLineValidator, are only told apart by name,
+// and line selectors other than LineSelectorFuncs by type, so they must also hold the same values
+// as before. The search continues when PossibleGrids or PossibleGridsWithStats is called.
+func CreateGeneratorFromCheckpoint(r io.Reader, size int, opts ...GeneratorOption) (*Generator, error) {
+	var c checkpoint
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if c.Version != 1 && c.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", c.Version)
+	}
+	var initial, current rand.PCG
+	if err := initial.UnmarshalBinary(c.InitialRand); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint random state: %w", err)
+	}
+	if err := current.UnmarshalBinary(c.Rand); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint random state: %w", err)
+	}
+
+	opts = append(slices.Clone(opts), func(g *Generator) error {
+		g.pcg = &initial
+		g.rand = rand.New(g.pcg)
+		return nil
+	})
+	g, err := CreateGeneratorE(size, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if g.workers > 1 || g.restarts != nil || g.gridTimeout > 0 || g.iterativeDeepening || g.beamWidth > 0 || g.sampling {
+		return nil, errors.New("only depth-first searches without workers, restarts, or sampling can be resumed from a checkpoint")
+	}
+	if g.fingerprint() != c.Fingerprint {
+		return nil, errors.New("checkpoint was written by a generator with different words or options")
+	}
+	for _, f := range c.Path {
+		lines := g.LineLength
+		if f.Dir == DirectionHorizontal {
+			lines = g.Height
+		}
+		if f.Dir != DirectionHorizontal && f.Dir != DirectionVertical || f.Index < 0 || f.Index >= lines || f.Splits < 0 || f.Tried < 0 {
+			return nil, fmt.Errorf("invalid checkpoint choice %+v", f)
+		}
+	}
+	g.resume = &c
+	return g, nil
+}
+
+// fingerprint returns a hash of the word lists and every option that shapes the search tree or the
+// order it is explored in. Options that only change how the search runs or what it reports, e.g.
+// WithProgress, are left out.
+func (g *Generator) fingerprint() uint64 {
+	h := fnv.New64a()
+	intOr := func(n *int) int {
+		if n == nil {
+			return -1
+		}
+		return *n
+	}
+	fmt.Fprintln(h, g.LineLength, g.Height, intOr(g.MinWordLength), intOr(g.MaxWordLength), intOr(g.maxBlocks), g.noBackjumping, g.noUncheckedSquares)
+	for _, words := range [][]string{g.PreferredWords, g.ObscureWords, g.ExcludedWords, g.requiredWords} {
+		fmt.Fprintln(h, len(words))
+		for _, word := range words {
+			fmt.Fprintln(h, word)
+		}
+	}
+	for _, row := range g.partial {
+		fmt.Fprintln(h, string(row))
+	}
+	fmt.Fprintln(h, g.bannedSequences, g.banQWithoutU)
+	for _, p := range g.pinnedCells {
+		fmt.Fprintln(h, p.row, p.col, string(p.r))
+	}
+	for _, c := range g.rebusCells {
+		fmt.Fprintln(h, c.row, c.col, c.candidates)
+	}
+	if g.bars != nil {
+		fmt.Fprint(h, g.bars)
+	}
+	for _, sym := range g.symmetries {
+		fmt.Fprintln(h, funcName(sym))
+	}
+	floatOr := func(f *float64) float64 {
+		if f == nil {
+			return -1
+		}
+		return *f
+	}
+	fmt.Fprintln(h, g.maxBlockFraction, floatOr(g.maxObscureFraction), floatOr(g.minPreferredRatio), g.letterVariety)
+	fmt.Fprintln(h, g.frequencyBias, len(g.frequencies))
+	for _, word := range slices.Sorted(maps.Keys(g.frequencies)) {
+		fmt.Fprintln(h, word, g.frequencies[word])
+	}
+	// The penalty is a function, so it is identified by its value for every word.
+	if g.wordPenalty != nil {
+		for _, words := range [][]string{g.PreferredWords, g.ObscureWords, g.requiredWords} {
+			for _, word := range words {
+				fmt.Fprintln(h, g.wordPenalty(word))
+			}
+		}
+	}
+	fmt.Fprintln(h, selectorName(g.lineSelector))
+	for _, valid := range g.lineValidators {
+		fmt.Fprintln(h, funcName(valid))
+	}
+	return h.Sum64()
+}
+
+// funcName returns the name of the function f, which is the same in every run of a program,
+// unlike its address.
+func funcName(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// selectorName identifies the line selector s, or the default if s is nil: by the name of its
+// function if it is a LineSelectorFunc, and otherwise by its type.
+func selectorName(s LineSelector) string {
+	if s == nil {
+		s = MostConstrained
+	}
+	if f, ok := s.(LineSelectorFunc); ok {
+		return funcName(f)
+	}
+	return fmt.Sprintf("%T", s)
+}
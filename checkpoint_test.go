@@ -0,0 +1,295 @@
+package xwgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestCheckpoint(t *testing.T) {
+	words := loadTrimmedWords(t)
+	const numGrids = 40
+
+	opts := func(extra ...GeneratorOption) []GeneratorOption {
+		return append(extra, WithPreferredWords(words))
+	}
+	gen, err := CreateGeneratorE(4, opts(WithSeed(42, 1024))...)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	var want []string
+	for grid := range gen.PossibleGrids(t.Context()) {
+		if want = append(want, grid.Repr()); len(want) == numGrids {
+			break
+		}
+	}
+	if len(want) < numGrids {
+		t.Fatalf("found %d grids, want at least %d", len(want), numGrids)
+	}
+
+	for _, tc := range []struct {
+		name string
+		// interrupt returns the options for a generator that cancels the search, and a function
+		// called with each grid that may cancel it too.
+		interrupt func(cancel context.CancelFunc) ([]GeneratorOption, func(numGrids int))
+	}{
+		{
+			name: "between grids",
+			interrupt: func(cancel context.CancelFunc) ([]GeneratorOption, func(int)) {
+				return nil, func(numGrids int) {
+					if numGrids%7 == 0 {
+						cancel()
+					}
+				}
+			},
+		},
+		{
+			name: "during search",
+			interrupt: func(cancel context.CancelFunc) ([]GeneratorOption, func(int)) {
+				explored := 0
+				return []GeneratorOption{WithProgressCallback(func(int64, int64) {
+					if explored++; explored%3 == 0 {
+						cancel()
+					}
+				})}, func(int) {}
+			},
+		},
+		{
+			name: "timeout",
+			interrupt: func(cancel context.CancelFunc) ([]GeneratorOption, func(int)) {
+				time.AfterFunc(5*time.Millisecond, cancel)
+				return nil, func(int) {}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			var checkpoint []byte
+			for run := 0; len(got) < numGrids; run++ {
+				if run > 1000 {
+					t.Fatalf("got %d grids after %d runs", len(got), run)
+				}
+				ctx, cancel := context.WithCancel(t.Context())
+				extra, onGrid := tc.interrupt(cancel)
+
+				var gen *Generator
+				var err error
+				if checkpoint == nil {
+					gen, err = CreateGeneratorE(4, opts(append(extra, WithSeed(42, 1024))...)...)
+				} else {
+					gen, err = CreateGeneratorFromCheckpoint(bytes.NewReader(checkpoint), 4, opts(extra...)...)
+				}
+				if err != nil {
+					t.Fatalf("creating generator: %v", err)
+				}
+
+				for grid := range gen.PossibleGrids(ctx) {
+					got = append(got, grid.Repr())
+					onGrid(len(got))
+				}
+				cancel()
+
+				var buf bytes.Buffer
+				err = gen.WriteCheckpoint(&buf)
+				if errors.Is(err, ErrNoCheckpoint) && checkpoint == nil && len(got) == 0 {
+					// Interrupted before the search started, so start it again.
+					continue
+				}
+				if err != nil {
+					t.Fatalf("WriteCheckpoint() error after %d grids: %v", len(got), err)
+				}
+				checkpoint = buf.Bytes()
+			}
+
+			if got = got[:numGrids]; !slices.Equal(got, want) {
+				t.Errorf("resumed searches yielded:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckpoint_Errors(t *testing.T) {
+	words := []string{"abc", "def", "ghi", "adg", "beh", "cfi"}
+	gen, err := CreateGeneratorE(3, WithPreferredWords(words), WithSeed(1, 2))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	for range gen.PossibleGrids(t.Context()) {
+	}
+	if err := gen.WriteCheckpoint(&bytes.Buffer{}); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("WriteCheckpoint() after an exhausted search = %v, want ErrNoCheckpoint", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	for range gen.PossibleGrids(ctx) {
+	}
+	var buf bytes.Buffer
+	if err := gen.WriteCheckpoint(&buf); err != nil {
+		t.Fatalf("WriteCheckpoint() after a timeout = %v", err)
+	}
+
+	if _, err := CreateGeneratorFromCheckpoint(bytes.NewReader(buf.Bytes()), 3, WithPreferredWords(words[1:])); err == nil {
+		t.Error("CreateGeneratorFromCheckpoint() with different words succeeded, want an error")
+	}
+	for name, opt := range map[string]GeneratorOption{
+		"banned sequences":     WithBannedSequences("ab"),
+		"rotational symmetry":  WithRotationalSymmetry(),
+		"reflective symmetry":  WithReflectiveSymmetry("vertical"),
+		"max block fraction":   WithMaxBlockFraction(0.01),
+		"line selector":        WithLineSelector(FewestPossibilitiesPerDirection),
+		"max obscure fraction": WithMaxObscureFraction(0.5),
+		"letter variety":       WithLetterVariety(),
+		"bars":                 WithBars(&Bars{Right: [][]bool{{false, false, false}, {false, false, false}, {false, false, false}}, Below: [][]bool{{false, false, false}, {false, false, false}, {false, false, false}}}),
+	} {
+		if _, err := CreateGeneratorFromCheckpoint(bytes.NewReader(buf.Bytes()), 3, WithPreferredWords(words), opt); err == nil {
+			t.Errorf("CreateGeneratorFromCheckpoint() with %s succeeded, want an error", name)
+		}
+	}
+	if _, err := CreateGeneratorFromCheckpoint(strings.NewReader("{}"), 3, WithPreferredWords(words)); err == nil {
+		t.Error("CreateGeneratorFromCheckpoint() with an invalid checkpoint succeeded, want an error")
+	}
+	resumed, err := CreateGeneratorFromCheckpoint(bytes.NewReader(buf.Bytes()), 3, WithPreferredWords(words))
+	if err != nil {
+		t.Fatalf("CreateGeneratorFromCheckpoint() error: %v", err)
+	}
+	var grids []Grid
+	for grid := range resumed.PossibleGrids(t.Context()) {
+		grids = append(grids, grid)
+	}
+	if len(grids) == 0 {
+		t.Error("resumed search found no grids")
+	}
+	// Checkpoints of version 1 list the reprs of the grids seen instead of their hashes.
+	v1 := strings.Replace(buf.String(), `"version":2`, `"version":1,"seen":["abc\ndef\nghi"]`, 1)
+	if _, err := CreateGeneratorFromCheckpoint(strings.NewReader(v1), 3, WithPreferredWords(words)); err != nil {
+		t.Errorf("CreateGeneratorFromCheckpoint() of version 1 error: %v", err)
+	}
+
+	withRand, err := CreateGeneratorE(3, WithPreferredWords(words), WithRand(gen.rand))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	for range withRand.PossibleGrids(ctx) {
+	}
+	if err := withRand.WriteCheckpoint(&bytes.Buffer{}); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("WriteCheckpoint() with WithRand = %v, want ErrNoCheckpoint", err)
+	}
+}
+
+// TestCheckpoint_Fingerprint checks that every field of Generator is either fingerprinted, so that
+// a checkpoint cannot be resumed with it changed, or exempt, for a reason given here. A new field
+// must be added to one or the other.
+func TestCheckpoint_Fingerprint(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	floatPtr := func(f float64) *float64 { return &f }
+	noBars := func() *Bars {
+		return &Bars{Right: [][]bool{{false, false, false}, {false, false, false}, {false, false, false}}, Below: [][]bool{{false, false, false}, {false, false, false}, {false, false, false}}}
+	}
+	// fingerprinted holds a change to each fingerprinted field.
+	fingerprinted := map[string]func(g *Generator){
+		"LineLength":         func(g *Generator) { g.LineLength = 4 },
+		"Height":             func(g *Generator) { g.Height = 4 },
+		"PreferredWords":     func(g *Generator) { g.PreferredWords = append(g.PreferredWords, "xyz") },
+		"ObscureWords":       func(g *Generator) { g.ObscureWords = []string{"xyz"} },
+		"ExcludedWords":      func(g *Generator) { g.ExcludedWords = []string{"abc"} },
+		"MinWordLength":      func(g *Generator) { g.MinWordLength = intPtr(2) },
+		"MaxWordLength":      func(g *Generator) { g.MaxWordLength = intPtr(2) },
+		"symmetries":         func(g *Generator) { g.symmetries = []symmetry{rotationalSymmetry} },
+		"maxBlockFraction":   func(g *Generator) { g.maxBlockFraction = 0.5 },
+		"maxBlocks":          func(g *Generator) { g.maxBlocks = intPtr(1) },
+		"noUncheckedSquares": func(g *Generator) { g.noUncheckedSquares = true },
+		"noBackjumping":      func(g *Generator) { g.noBackjumping = true },
+		"maxObscureFraction": func(g *Generator) { g.maxObscureFraction = floatPtr(0.5) },
+		"requiredWords":      func(g *Generator) { g.requiredWords = []string{"abc"} },
+		"lineSelector":       func(g *Generator) { g.lineSelector = FewestPossibilitiesPerDirection },
+		"partial":            func(g *Generator) { g.partial = [][]rune{[]rune("a.."), []rune("..."), []rune("...")} },
+		"frequencies":        func(g *Generator) { g.frequencies = map[string]float64{"abc": 1} },
+		"frequencyBias":      func(g *Generator) { g.frequencyBias = 1 },
+		"minPreferredRatio":  func(g *Generator) { g.minPreferredRatio = floatPtr(0.5) },
+		"lineValidators": func(g *Generator) {
+			g.lineValidators = []func(primitives.ConcreteLine, LineContext) bool{func(primitives.ConcreteLine, LineContext) bool { return true }}
+		},
+		"letterVariety":   func(g *Generator) { g.letterVariety = true },
+		"bannedSequences": func(g *Generator) { g.bannedSequences = []string{"ab"} },
+		"banQWithoutU":    func(g *Generator) { g.banQWithoutU = true },
+		"wordPenalty":     func(g *Generator) { g.wordPenalty = func(string) float64 { return 1 } },
+		"pinnedCells":     func(g *Generator) { g.pinnedCells = []pinnedCell{{row: 0, col: 0, r: 'a'}} },
+		"rebusCells":      func(g *Generator) { g.rebusCells = []rebusCell{{row: 0, col: 0, candidates: []string{"ab"}}} },
+		"bars":            func(g *Generator) { g.bars = noBars() },
+	}
+	exempt := map[string]string{
+		"rand":                 "the checkpoint holds the state of the random source",
+		"pcg":                  "the checkpoint holds the state of the random source",
+		"initialRand":          "the checkpoint holds the state of the random source",
+		"progress":             "only reports on the search",
+		"progressCallback":     "only reports on the search",
+		"stats":                "only reports on the search",
+		"workers":              "searches with it cannot be checkpointed",
+		"restarts":             "searches with it cannot be checkpointed",
+		"propagationWorkers":   "the same grids are found in the same order",
+		"iterativeDeepening":   "searches with it cannot be checkpointed",
+		"beamWidth":            "searches with it cannot be checkpointed",
+		"scorer":               "only rates the grids found",
+		"memoizeFilters":       "the same grids are found in the same order",
+		"gridTimeout":          "searches with it cannot be checkpointed",
+		"consistencyChecks":    "the same grids are found in the same order",
+		"dictionary":           "only shares the words, which are fingerprinted",
+		"best":                 "only reports on the search",
+		"dedupeCapacity":       "only bounds how many of the grids found are remembered",
+		"sampling":             "searches with it cannot be checkpointed",
+		"resume":               "is the checkpoint itself",
+		"errMu":                "is the state of the most recent search",
+		"err":                  "is the state of the most recent search",
+		"interrupted":          "is the state of the most recent search",
+		"lazyAllPossibleLines": "is derived from other fields",
+		"lazyInitialState":     "is derived from other fields",
+		"lazyObscureWords":     "is derived from other fields",
+		"lazyUnbannedWords":    "is derived from other fields",
+	}
+
+	fields := make(map[string]bool)
+	typ := reflect.TypeFor[Generator]()
+	for i := range typ.NumField() {
+		name := typ.Field(i).Name
+		fields[name] = true
+		_, ok := fingerprinted[name]
+		if _, isExempt := exempt[name]; ok == isExempt {
+			t.Errorf("Generator.%s must be either fingerprinted or exempt from fingerprints", name)
+		}
+	}
+	for name := range fingerprinted {
+		if !fields[name] {
+			t.Errorf("fingerprinted field Generator.%s does not exist", name)
+		}
+	}
+	for name := range exempt {
+		if !fields[name] {
+			t.Errorf("exempt field Generator.%s does not exist", name)
+		}
+	}
+
+	newGenerator := func() *Generator {
+		g, err := CreateGeneratorE(3, WithPreferredWords([]string{"abc", "def", "ghi"}), WithSeed(1, 2))
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		return g
+	}
+	want := newGenerator().fingerprint()
+	for name, change := range fingerprinted {
+		g := newGenerator()
+		change(g)
+		if g.fingerprint() == want {
+			t.Errorf("fingerprint() with Generator.%s changed is unchanged", name)
+		}
+	}
+}
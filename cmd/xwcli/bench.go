@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/bench"
+)
+
+const benchUsage = `Usage: xwcli bench [flags]
+
+Measures how fast the generator finds grids at each width, with a fixed seed
+and word list, so that runs of different binaries can be compared, e.g.
+
+  xwcli bench -file words.txt -width 4..6 -seed 1 -duration 30s -format json
+
+Flags:
+`
+
+// runBench implements the 'bench' subcommand, returning the process exit code.
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), benchUsage)
+		fs.PrintDefaults()
+	}
+
+	widths := fs.String("width", "4..5", "The grid width, or an inclusive range of widths, e.g. '4..6'")
+	seed := fs.Uint64("seed", 1, "The random seed")
+	duration := fs.Duration("duration", 10*time.Second, "How long to generate grids for at each width")
+	gridsPerRun := fs.Int("grids-per-run", 100, "The number of grids to generate with each seed before starting over with the next")
+	format := fs.String("format", formatText, "The output format: 'text' or 'json'")
+	minWordLength := fs.Int("min-word-length", 3, "The minimum word length")
+	file := fs.String("file", "", "The file to load words from, or '-' for stdin")
+	obscureFile := fs.String("obscure", "", "The file to load obscure words from, or '-' for stdin")
+	excludedFile := fs.String("excluded", "", "The file to load excluded words from, or '-' for stdin")
+
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "unknown format %q; must be 'text' or 'json'\n", *format)
+		return 1
+	}
+	if *minWordLength < 1 {
+		fmt.Fprintln(os.Stderr, "-min-word-length must be at least 1")
+		return 1
+	}
+	minWidth, maxWidth, err := parseWidthRange(*widths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	preferredWords, obscureWords, excludedWords, err := loadWordLists(ctx, os.Stderr, wordListFiles{
+		preferred: *file,
+		obscure:   *obscureFile,
+		excluded:  *excludedFile,
+	}, *minWordLength, maxWidth)
+	if err != nil {
+		return 1
+	}
+
+	cfg := bench.Config{
+		Seed:           *seed,
+		Duration:       *duration,
+		GridsPerRun:    *gridsPerRun,
+		PreferredWords: preferredWords,
+		ObscureWords:   obscureWords,
+		ExcludedWords:  excludedWords,
+		MinWordLength:  *minWordLength,
+	}
+	for width := minWidth; width <= maxWidth; width++ {
+		cfg.Widths = append(cfg.Widths, width)
+	}
+
+	results, err := bench.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	if *format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	} else {
+		err = bench.WriteTable(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing results:", err)
+		return 1
+	}
+	return 0
+}
+
+// parseWidthRange parses a width such as "5", or an inclusive range of widths such as "4..6".
+func parseWidthRange(s string) (minWidth, maxWidth int, err error) {
+	lo, hi, isRange := strings.Cut(s, "..")
+	if !isRange {
+		hi = lo
+	}
+	if minWidth, err = strconv.Atoi(lo); err != nil {
+		return 0, 0, fmt.Errorf("invalid width %q: %w", s, err)
+	}
+	if maxWidth, err = strconv.Atoi(hi); err != nil {
+		return 0, 0, fmt.Errorf("invalid width %q: %w", s, err)
+	}
+	if minWidth < 1 || maxWidth < minWidth {
+		return 0, 0, fmt.Errorf("invalid width %q: must be at least 1, and ranges must not be empty", s)
+	}
+	return minWidth, maxWidth, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+const dictionaryUsage = `Usage: xwcli dictionary [flags]
+
+Prints the words the generator would use for a grid, one per line, preferred
+words first. Use the filters to check why a grid won't fill, e.g.
+
+  xwcli dictionary -file words.txt -width 5 -pattern "a??le"
+
+Flags:
+`
+
+// runDictionary implements the 'dictionary' subcommand, returning the process exit code.
+func runDictionary(args []string) int {
+	fs := flag.NewFlagSet("dictionary", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), dictionaryUsage)
+		fs.PrintDefaults()
+	}
+
+	width := fs.Int("width", 4, "The width of the grid")
+	height := fs.Int("height", 0, "The height of the grid (defaults to -width)")
+	minWordLength := fs.Int("min-word-length", 3, "The minimum word length")
+	file := fs.String("file", "", "The file to load words from")
+	obscureFile := fs.String("obscure", "", "The file to load obscure words from")
+	excludedFile := fs.String("excluded", "", "The file to load excluded words from")
+
+	pattern := fs.String("pattern", "", "Only print words matching this pattern, where '?' matches any letter, e.g. 'a??le'")
+	prefix := fs.String("prefix", "", "Only print words starting with this")
+	contains := fs.String("contains", "", "Only print words containing this")
+	obscureOnly := fs.Bool("obscure-only", false, "Only print obscure words")
+	countOnly := fs.Bool("count-only", false, "Only print the number of matching words")
+
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+	if *height <= 0 {
+		*height = *width
+	}
+
+	var p primitives.Pattern
+	if *pattern != "" {
+		var err error
+		if p, err = primitives.ParsePattern(*pattern); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	matches := func(word string) bool {
+		return (p == nil || p.Matches(word)) &&
+			strings.HasPrefix(word, strings.ToLower(*prefix)) &&
+			strings.Contains(word, strings.ToLower(*contains))
+	}
+
+	preferredWords, obscureWords, excludedWords, err := loadWordLists(context.Background(), os.Stderr, wordListFiles{
+		preferred: *file,
+		obscure:   *obscureFile,
+		excluded:  *excludedFile,
+	}, *minWordLength, max(*width, *height))
+	if err != nil {
+		return 1
+	}
+
+	// Mirror the generator: excluded words are never used, and a word in both lists is preferred.
+	excluded := make(map[string]bool, len(excludedWords))
+	for _, word := range excludedWords {
+		excluded[word] = true
+	}
+	var words []string
+	seen := make(map[string]bool)
+	add := func(list []string) {
+		for _, word := range list {
+			if excluded[word] || seen[word] {
+				continue
+			}
+			seen[word] = true
+			if matches(word) {
+				words = append(words, word)
+			}
+		}
+	}
+	if *obscureOnly {
+		for _, word := range preferredWords {
+			seen[word] = true
+		}
+	} else {
+		add(preferredWords)
+	}
+	add(obscureWords)
+
+	if *countOnly {
+		fmt.Println(len(words))
+		return 0
+	}
+	for _, word := range words {
+		fmt.Println(word)
+	}
+	return 0
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+const fillUsage = `Usage: xwcli fill [flags] puzzle.txt
+
+Completes the partial grid in puzzle.txt. Each line of the file is a row of
+the grid, where letters are fixed cells, '#' is a blocked cell, and '.' is a
+blank cell to be filled in.
+
+With -neighbors, puzzle.txt must be a complete grid, and grids that differ
+from it in at most that many entries are printed instead.
+
+Flags:
+`
+
+// runFill implements the 'fill' subcommand, returning the process exit code.
+func runFill(args []string) int {
+	fs := flag.NewFlagSet("fill", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), fillUsage)
+		fs.PrintDefaults()
+	}
+
+	count := fs.Int("count", 1, "The number of completions to print (0 for no limit)")
+	format := fs.String("format", formatText, "The output format: 'text', 'json', 'markdown', or 'puz' (requires -count 1)")
+	colorMode := fs.String("color", colorAuto, "Colorize text grids: 'auto' (if stdout is a terminal), 'always', or 'never'")
+	minWordLength := fs.Int("min-word-length", 3, "The minimum word length, e.g. 1 for word squares")
+	fs.IntVar(minWordLength, "min_length", 3, "Deprecated: use -min-word-length")
+	file := fs.String("file", "", "The file to load words from")
+	obscureFile := fs.String("obscure", "", "The file to load obscure words from")
+	excludedFile := fs.String("excluded", "", "The file to load excluded words from")
+	timeout := fs.Duration("timeout", 1*time.Minute, "The timeout for the generator")
+	neighbors := fs.Int("neighbors", 0, "Print grids that differ from the complete grid in puzzle.txt in at most this many entries, instead of completing it")
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	if err := validateFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	color, err := useColor(*colorMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *minWordLength < 1 {
+		fmt.Fprintln(os.Stderr, "-min-word-length must be at least 1")
+		return 1
+	}
+	if *format == formatPuz && *count != 1 {
+		fmt.Fprintln(os.Stderr, "-format puz writes a single grid, and requires -count 1")
+		return 1
+	}
+
+	partial, err := loadPartialGrid(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading puzzle:", err)
+		return 1
+	}
+	height, width := len(partial), len(partial[0])
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	preferredWords, obscureWords, excludedWords, err := loadWordLists(ctx, os.Stderr, wordListFiles{
+		preferred: *file,
+		obscure:   *obscureFile,
+		excluded:  *excludedFile,
+	}, *minWordLength, max(width, height))
+	if err != nil {
+		return 1
+	}
+
+	randSource := rand.NewPCG(uint64(time.Now().UnixNano()), uint64(time.Now().Nanosecond()))
+	generator, err := xwgen.CreateGeneratorE(
+		width,
+		xwgen.WithPreferredWords(preferredWords),
+		xwgen.WithObscureWords(obscureWords),
+		xwgen.WithExcludedWords(excludedWords),
+		xwgen.WithRand(rand.New(randSource)),
+		xwgen.WithMinWordLength(*minWordLength),
+		xwgen.WithMaxWordLength(max(width, height)),
+		xwgen.WithHeight(height),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	var grids iter.Seq[xwgen.Grid]
+	if *neighbors > 0 {
+		var grid xwgen.Grid
+		if grid, err = completeGrid(partial); err == nil {
+			grids, err = generator.Neighbors(ctx, grid, *neighbors)
+		}
+	} else {
+		grids, err = generator.PossibleGridsFrom(ctx, partial)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	output := &gridOutput{format: *format, color: color}
+	numGrids := 0
+	for grid := range grids {
+		if err := output.Emit(grid); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing grid:", err)
+			return 1
+		}
+		numGrids++
+		if *count > 0 && numGrids >= *count {
+			return 0
+		}
+	}
+
+	what := "completion"
+	if *neighbors > 0 {
+		what = "neighbor"
+	}
+	switch err := generator.Err(); {
+	case errors.Is(err, xwgen.ErrNoGridsPossible):
+		fmt.Fprintf(os.Stderr, "No %ss exist\n", what)
+		return exitNoGrids
+	case err != nil && numGrids == 0:
+		fmt.Fprintf(os.Stderr, "Timed out before finding any %ss\n", what)
+		return exitTimeout
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Timed out after finding %d %s(s)\n", numGrids, what)
+	}
+	return 0
+}
+
+// completeGrid returns partial as a grid, or an error if it has any blank cells.
+func completeGrid(partial [][]rune) (xwgen.Grid, error) {
+	grid := make([][]rune, len(partial))
+	for y, row := range partial {
+		grid[y] = make([]rune, len(row))
+		for x, r := range row {
+			switch r {
+			case xwgen.CellUnknown:
+				return xwgen.Grid{}, fmt.Errorf("cell (%d, %d) is blank, but -neighbors needs a complete grid", y, x)
+			case xwgen.CellBlocked:
+				grid[y][x] = primitives.Blocked
+			default:
+				grid[y][x] = unicode.ToLower(r)
+			}
+		}
+	}
+	return xwgen.NewGrid(grid), nil
+}
+
+// loadPartialGrid reads a partial grid from path. Blank lines are ignored, and each remaining line
+// is a row of the grid.
+func loadPartialGrid(path string) ([][]rune, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var partial [][]rune
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		partial = append(partial, []rune(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(partial) == 0 {
+		return nil, fmt.Errorf("%s contains no rows", path)
+	}
+	for i, row := range partial {
+		if len(row) != len(partial[0]) {
+			return nil, fmt.Errorf("row %d has %d cells, but row 1 has %d", i+1, len(row), len(partial[0]))
+		}
+	}
+	return partial, nil
+}
@@ -1,30 +1,101 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"math/rand/v2"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/history"
+	"github.com/Eyas/xwgen/pkg/wordlist"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fill":
+			os.Exit(runFill(os.Args[2:]))
+		case "dictionary":
+			os.Exit(runDictionary(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		}
+	}
+	os.Exit(runGenerate())
+}
+
+// Exit codes of xwcli when no grids are generated.
+const (
+	exitNoGrids = 2
+	exitTimeout = 3
+)
+
+// runGenerate generates grids, returning the process exit code.
+func runGenerate() int {
 
 	firstOnly := flag.Bool("first", false, "Only generate the first grid")
 	doAll := flag.Bool("all", false, "Generate all grids")
-	sideLength := flag.Int("width", 4, "The width of the grid")
-	minWordLength := flag.Int("min_length", 3, "The minimum word length")
-	file := flag.String("file", "", "The file to load words from")
-	obscureFile := flag.String("obscure", "", "The file to load obscure words from")
-	excludedFile := flag.String("excluded", "", "The file to load excluded words from")
+	count := flag.Int("count", 0, "Stop after generating this many grids (0 for no limit)")
+	dryRun := flag.Bool("dry-run", false, "Only estimate the size of the search, and whether any grid is possible, without searching")
+	countOnly := flag.Bool("count-only", false, "Only print the number of grids, counting until the timeout or -count")
+	unique := flag.Bool("unique", false, "Skip grids that are a transpose, rotation, or reflection of one already generated. Uses memory for every grid generated")
+	rank := flag.Int("rank", 0, "Generate grids until the timeout or -count, then only print the N best ones")
+	improve := flag.Bool("improve", false, "Polish each grid before printing it, refilling entries while that improves its -scorer score")
+	scorerName := flag.String("scorer", "classic", "How -rank scores grids: 'classic', 'scrabble' to also prefer rarer letters, or 'variety' to also prefer letters used evenly")
+	letterVariety := flag.Bool("letter-variety", false, "Try words that bring letters not yet in the grid first, to find grids with more distinct letters sooner")
+	sample := flag.Bool("sample", false, "Try lines sampled at random first, so that -seed shuffles which grids are found first. Cannot be combined with -checkpoint")
+	var banned stringList
+	historyPath := flag.String("history", "", "Record the words of each grid printed in this JSON file, and try words used in the last -history-days days last")
+	historyDays := flag.Int("history-days", 30, "The number of days -history avoids words for")
+	historyExclude := flag.Bool("history-exclude", false, "Never use words used in the last -history-days days with -history, rather than trying them last")
+	flag.Var(&banned, "ban", "A letter sequence no row or column may contain, e.g. 'qq', or 'q!u' for a q not followed by u. Can be repeated")
+	barsFile := flag.String("bars", "", "Generate barred grids with the bars in this file, with a line per row and a character per cell: '.' for none, '|' for a bar to the right, '_' for a bar below, or '+' for both")
+	format := flag.String("format", formatText, "The output format: 'text', 'json', 'markdown', or 'puz' (requires -first or -output-dir)")
+	colorMode := flag.String("color", colorAuto, "Colorize text grids: 'auto' (if stdout is a terminal), 'always', or 'never'")
+	batchCSV := flag.String("batch-csv", "", "Collect every grid generated into this CSV file, a row per grid, instead of printing them")
+	outputDir := flag.String("output-dir", "", "Write each grid to its own file in this directory, printing only a summary")
+	widthList := flag.String("width", "4", "The width of the grid, or a comma-separated list of widths, e.g. '4,5,7', to generate square grids of each size from the same words")
+	height := flag.Int("height", 0, "The height of the grid (defaults to -width)")
+	symmetry := flag.String("symmetry", "", "Comma-separated symmetries the blocked cells must have: 'rotational', 'vertical', and/or 'horizontal'")
+	maxBlocks := flag.Int("max-blocks", -1, "The maximum number of blocked cells per grid (-1 for no limit, 0 for word squares)")
+	minWordLength := flag.Int("min-word-length", 3, "The minimum word length, e.g. 1 for word squares")
+	flag.IntVar(minWordLength, "min_length", 3, "Deprecated: use -min-word-length")
+	checked := flag.Bool("checked", false, "Require every letter to be part of both an across and a down word, even with -min-word-length 1")
+	file := flag.String("file", "", "The file to load words from, or '-' for stdin")
+	obscureFile := flag.String("obscure", "", "The file to load obscure words from, or '-' for stdin")
+	excludedFile := flag.String("excluded", "", "The file to load excluded words from, or '-' for stdin")
+	excludeFile := flag.String("exclude-file", "", "Never use the words in this file, which is created if it does not exist, e.g. to keep words out of grids across runs with -exclude-used")
+	excludeUsed := flag.Bool("exclude-used", false, "Add the words of each grid printed to -exclude-file, so that later runs never use them")
+	frequencyBias := flag.Float64("frequency-bias", 0, "How strongly to try words with higher frequency scores in the word lists first, from 0 to 1")
 
+	showProgress := flag.Bool("progress", false, "Periodically print the progress of the search to stderr")
+	progressInterval := flag.Duration("progress-interval", 3*time.Second, "How often to print progress with -progress")
+	showStats := flag.Bool("stats", false, "Print search statistics after each grid, and a summary and the generator's statistics as JSON at exit")
+	workers := flag.Int("workers", 1, "The number of goroutines to search with")
+	restarts := flag.String("restarts", "", "Start the search over with reshuffled words after too many backtracks: 'luby' or 'doubling'")
+	restartBacktracks := flag.Int64("restart-backtracks", 100, "The number of backtracks before the first restart with -restarts")
+	validate := flag.Bool("validate", false, "Debug mode: check every line of every point of the search for broken invariants, and panic on the first, slowing the search down a lot")
+	noBackjump := flag.Bool("no-backjump", false, "Backtrack chronologically instead of jumping back to the choice that caused a dead end, e.g. to compare the two with -stats")
+	seed := flag.Uint64("seed", 0, "The random seed (0 for a time-based seed)")
 	timeout := flag.Duration("timeout", 1*time.Minute, "The timeout for the generator")
+	gridTimeout := flag.Duration("grid-timeout", 0, "Start the search over with reshuffled words if finding the next grid takes longer than this (0 for no limit); -timeout still bounds the whole run")
+	serveAddr := flag.String("serve", "", "Serve grids over HTTP on this address, e.g. ':8080', at POST /generate and the WebSocket /generate/stream, instead of generating them here")
+	streamBuffer := flag.Int("stream-buffer", defaultStreamBuffer, "The number of grids to buffer for a slow /generate/stream client before pausing its search with -serve")
+	checkpointPath := flag.String("checkpoint", "", "Save the search to this file on timeout or interrupt, and resume it from the file if it exists")
 
 	profile := flag.Bool("profile", false, "Profile the generator")
 	profileFile := flag.String("profile-file", "cpu.pprof", "The file to write the CPU profile to")
@@ -33,96 +104,367 @@ func main() {
 	flag.Parse()
 
 	if *firstOnly && *doAll {
-		fmt.Println("Cannot use both -first and -all")
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Cannot use both -first and -all")
+		return 1
+	}
+	if err := validateFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *format == formatPuz && !*firstOnly && *outputDir == "" {
+		fmt.Fprintln(os.Stderr, "-format puz writes a single grid, and requires -first or -output-dir")
+		return 1
+	}
+	if *batchCSV != "" && *outputDir != "" {
+		fmt.Fprintln(os.Stderr, "Cannot use both -batch-csv and -output-dir")
+		return 1
+	}
+	if *excludeUsed && *excludeFile == "" {
+		fmt.Fprintln(os.Stderr, "-exclude-used requires -exclude-file")
+		return 1
+	}
+	if *workers < 1 {
+		fmt.Fprintln(os.Stderr, "-workers must be at least 1")
+		return 1
+	}
+	if *scorerName != "classic" && *scorerName != "scrabble" && *scorerName != "variety" {
+		fmt.Fprintf(os.Stderr, "Unknown -scorer %q, want 'classic', 'scrabble', or 'variety'\n", *scorerName)
+		return 1
+	}
+	if *minWordLength < 1 {
+		fmt.Fprintln(os.Stderr, "-min-word-length must be at least 1")
+		return 1
 	}
 
-	ctx := context.Background()
+	widths, err := parseSizes(*widthList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	sideLength := &widths[0]
+	batch := len(widths) > 1
+	if batch && (*height > 0 || *barsFile != "" || *checkpointPath != "" || *dryRun || *countOnly || *rank > 0 || *serveAddr != "") {
+		fmt.Fprintln(os.Stderr, "-width with several sizes cannot be combined with -height, -bars, -checkpoint, -dry-run, -count-only, -rank, or -serve")
+		return 1
+	}
 
-	randSource := rand.NewPCG(uint64(time.Now().UnixNano()), uint64(time.Now().Nanosecond()))
+	// Keep stdout machine-readable when emitting JSON or .puz.
+	var info io.Writer = os.Stdout
+	if *format != formatText {
+		info = os.Stderr
+	}
 
-	var preferredWords, obscureWords, excludedWords []string
-	if *file != "" {
-		fmt.Println("Loading words from file...")
-		var err error
-		if preferredWords, err = loadFromFile(ctx, *file, *minWordLength, *sideLength); err != nil {
-			fmt.Println("Error loading words from file:", err)
-			os.Exit(1)
-		}
+	if *height <= 0 {
+		*height = *sideLength
 	}
-	if *obscureFile != "" {
-		fmt.Println("Loading obscure words from file...")
-		var err error
-		if obscureWords, err = loadFromFile(ctx, *obscureFile, *minWordLength, *sideLength); err != nil {
-			fmt.Println("Error loading obscure words from file:", err)
-			os.Exit(1)
+
+	color, err := useColor(*colorMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	output := &gridOutput{format: *format, color: color, csvPath: *batchCSV}
+	if *outputDir != "" {
+		files, err := newGridFileWriter(*outputDir, *format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating output directory:", err)
+			return 1
 		}
+		output.files = files
 	}
-	if *excludedFile != "" {
-		fmt.Println("Loading excluded words from file...")
-		var err error
-		if excludedWords, err = loadFromFile(ctx, *excludedFile, *minWordLength, *sideLength); err != nil {
-			fmt.Println("Error loading excluded words from file:", err)
-			os.Exit(1)
+
+	opts, err := symmetryOptions(*symmetry)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *restarts != "" {
+		policy, err := restartPolicy(*restarts, *restartBacktracks)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		opts = append(opts, xwgen.WithRestarts(policy))
 	}
 
-	fmt.Println("Preferred words:", len(preferredWords))
-	fmt.Println("Obscure words:", len(obscureWords))
-	fmt.Println("Excluded words:", len(excludedWords))
+	ctx := context.Background()
+
+	files := wordListFiles{
+		preferred: *file,
+		obscure:   *obscureFile,
+		excluded:  *excludedFile,
+	}
+	// Frequencies order the words tried, and count towards the score and difficulty of grids.
+	if *frequencyBias > 0 || *rank > 0 || *showStats || *format == formatJSON || *serveAddr != "" {
+		files.frequencies = make(map[string]float64)
+	}
+	// The Continue? prompt can't read answers from stdin once it has been read for words. Errors
+	// are reported by loadWordLists.
+	wordsFromStdin, _ := files.usesStdin()
+
+	preferredWords, obscureWords, excludedWords, err := loadWordLists(ctx, info, files, *minWordLength, max(slices.Max(widths), *height))
+	if err != nil {
+		return 1
+	}
+	if *excludeFile != "" {
+		words, err := wordlist.LoadExcludedWords(*excludeFile)
+		if err != nil {
+			fmt.Fprintln(info, "Error loading excluded words from file:", err)
+			return 1
+		}
+		fmt.Fprintln(info, "Excluded words from -exclude-file:", len(words))
+		excludedWords = append(excludedWords, words...)
+		if *excludeUsed {
+			output.excludePath = *excludeFile
+		}
+	}
+	output.wordScores = files.frequencies
 
 	var mf *os.File
 	if *profile {
 		f, err := os.Create(*profileFile)
 		if err != nil {
-			fmt.Println("Error creating profile file:", err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, "Error creating profile file:", err)
+			return 1
 		}
 		defer f.Close()
 
 		mf, err = os.Create(*memoryProfileFile)
 		if err != nil {
-			fmt.Println("Error creating memory profile file:", err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, "Error creating memory profile file:", err)
+			return 1
 		}
 		defer mf.Close()
 
 		if err := pprof.StartCPUProfile(f); err != nil {
-			fmt.Println("Error starting CPU profile:", err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, "Error starting CPU profile:", err)
+			return 1
 		}
 		defer pprof.StopCPUProfile()
 	}
 
-	grid := xwgen.CreateGenerator(
-		*sideLength,
-		preferredWords,
-		obscureWords,
-		excludedWords,
-		rand.New(randSource),
-		xwgen.GeneratorParams{
-			MinWordLength: 3,
-			MaxWordLength: *sideLength,
-		},
+	if *workers != 1 {
+		opts = append(opts, xwgen.WithWorkers(*workers))
+	}
+	if *gridTimeout > 0 {
+		opts = append(opts, xwgen.WithGridTimeout(*gridTimeout))
+	}
+	if *maxBlocks >= 0 {
+		opts = append(opts, xwgen.WithMaxBlocks(*maxBlocks))
+	}
+	if *noBackjump {
+		opts = append(opts, xwgen.WithoutBackjumping())
+	}
+	if *validate {
+		opts = append(opts, xwgen.WithConsistencyChecks())
+	}
+	if *checked {
+		opts = append(opts, xwgen.WithFullyChecked())
+	}
+	switch *scorerName {
+	case "scrabble":
+		opts = append(opts, xwgen.WithScorer(xwgen.ScrabbleScorer(files.frequencies)))
+	case "variety":
+		opts = append(opts, xwgen.WithScorer(xwgen.VarietyScorer(files.frequencies)))
+	}
+	if *letterVariety {
+		opts = append(opts, xwgen.WithLetterVariety())
+	}
+	if *sample {
+		opts = append(opts, xwgen.WithSampling())
+	}
+	if len(banned) > 0 {
+		opts = append(opts, xwgen.WithBannedSequences(banned...))
+	}
+	if *historyPath != "" {
+		store := history.NewFile(*historyPath)
+		lastUsed, err := store.LastUsed()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading history:", err)
+			return 1
+		}
+		penalty := history.Penalty
+		if *historyExclude {
+			penalty = history.Exclude
+		}
+		opts = append(opts, xwgen.WithWordPenalty(penalty(lastUsed, time.Now(), time.Duration(*historyDays)*24*time.Hour)))
+		output.history = store
+	}
+	if *barsFile != "" {
+		data, err := os.ReadFile(*barsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading bars:", err)
+			return 1
+		}
+		bars, err := xwgen.ParseBars(string(data))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing bars:", err)
+			return 1
+		}
+		opts = append(opts, xwgen.WithBars(bars))
+	}
+	if files.frequencies != nil {
+		opts = append(opts, xwgen.WithWordFrequencies(files.frequencies))
+	}
+	if *frequencyBias > 0 {
+		opts = append(opts, xwgen.WithFrequencyBias(*frequencyBias))
+	}
+
+	var stats xwgen.Stats
+	if *showStats {
+		opts = append(opts, xwgen.WithStats(&stats))
+	}
+
+	var progress *xwgen.Progress
+	if *showProgress {
+		progress = &xwgen.Progress{}
+		opts = append(opts, xwgen.WithProgress(progress))
+	}
+
+	if *serveAddr != "" {
+		return serve(*serveAddr, &generateHandler{
+			opts:          opts,
+			preferred:     preferredWords,
+			obscure:       obscureWords,
+			excluded:      excludedWords,
+			minWordLength: *minWordLength,
+			wordScores:    files.frequencies,
+			maxTimeout:    *timeout,
+			streamBuffer:  *streamBuffer,
+		})
+	}
+
+	opts = append(opts,
+		xwgen.WithPreferredWords(preferredWords),
+		xwgen.WithObscureWords(obscureWords),
+		xwgen.WithExcludedWords(excludedWords),
+		xwgen.WithMinWordLength(*minWordLength),
+	)
+	// Without -seed, the generator seeds itself from the time.
+	if *seed != 0 {
+		opts = append(opts, xwgen.WithSeed(*seed, *seed))
+	}
+	if batch {
+		// Without -first or -count, generate a single grid of each size.
+		perSize := *count
+		if *firstOnly || perSize == 0 && !*doAll {
+			perSize = 1
+		}
+		return generateBatch(info, output, widths, perSize, *timeout, opts)
+	}
+	opts = append(opts,
+		xwgen.WithMaxWordLength(max(*sideLength, *height)),
+		xwgen.WithHeight(*height),
 	)
+	gen, err := createGenerator(info, *checkpointPath, *sideLength, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
+	if *checkpointPath != "" {
+		// Stop the search on SIGINT, rather than exiting, so that it can be saved.
+		ctx, cancel = signal.NotifyContext(ctx, os.Interrupt)
+		defer cancel()
+	}
+
+	if progress != nil {
+		stopProgress := reportProgress(os.Stderr, progress, *progressInterval)
+		defer stopProgress()
+	}
 
-	for grid := range grid.PossibleGrids(ctx) {
-		if err := ctx.Err(); err != nil {
-			fmt.Println("Context error:", err)
+	if *dryRun {
+		e, err := gen.Estimate(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return exitTimeout
+			}
+			return 1
+		}
+		writeEstimate(info, e)
+		if problems := e.Problems(); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Fprintln(os.Stderr, "No grids are possible:", problem)
+			}
+			return exitNoGrids
+		}
+		return 0
+	}
+
+	if *countOnly {
+		n, exhausted := gen.CountGrids(ctx, int64(*count))
+		fmt.Println(n)
+		if !exhausted {
+			fmt.Fprintln(os.Stderr, "Stopped counting before the search was exhausted, so there may be more grids")
+		}
+		return 0
+	}
+
+	var ranked *topGrids
+	if *rank > 0 {
+		ranked = newTopGrids(*rank, gen.Scorer())
+	}
+
+	// seen holds the canonical key of every grid generated with -unique, about 100 bytes each.
+	var seen map[string]bool
+	if *unique {
+		seen = make(map[string]bool)
+	}
+	duplicates := 0
+
+	numGrids := 0
+	var totalStats xwgen.SearchStats
+	for grid, stats := range gen.PossibleGridsWithStats(ctx) {
+		// With -checkpoint, the search stops by itself once ctx is done, and every grid it yields
+		// must be kept, since the resumed search won't yield it again.
+		if err := ctx.Err(); err != nil && *checkpointPath == "" {
+			fmt.Fprintln(info, "Context error:", err)
 			break
 		}
 
-		fmt.Println("--------------------------------")
-		fmt.Println(grid.Repr())
+		if *improve {
+			improved, swaps, err := gen.Improve(ctx, grid, gen.Scorer())
+			if err != nil && *checkpointPath == "" {
+				fmt.Fprintln(info, "Context error:", err)
+				break
+			}
+			grid = improved
+			if *showStats {
+				fmt.Fprintln(info, "Swaps:", len(swaps))
+			}
+		}
+
+		totalStats.Add(stats)
+		if seen != nil {
+			key := grid.CanonicalKey()
+			if seen[key] {
+				duplicates++
+				continue
+			}
+			seen[key] = true
+		}
+		numGrids++
+
+		if ranked != nil {
+			ranked.Add(grid)
+		} else if err := output.Emit(grid); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing grid:", err)
+			return 1
+		}
+		if *showStats {
+			fmt.Fprintln(info, "Stats:", stats)
+			fmt.Fprintf(info, "Preferred words: %.0f%%\n", 100*grid.PreferredRatio())
+			fmt.Fprintln(info, "Difficulty:", xwgen.EstimateDifficulty(grid, files.frequencies))
+		}
 
-		if *firstOnly {
+		if *firstOnly || (*count > 0 && numGrids >= *count) {
 			break
 		}
 
-		if *doAll {
+		if *doAll || *format != formatText || ranked != nil || *batchCSV != "" || wordsFromStdin {
 			continue
 		}
 
@@ -139,44 +481,299 @@ func main() {
 		}
 	}
 
-	fmt.Println("--------------------------------")
-	fmt.Println("Done")
+	if ranked != nil {
+		fmt.Fprintf(info, "Best %d of %d grids:\n", len(ranked.heap), numGrids)
+		for _, sg := range ranked.Sorted() {
+			if err := output.Emit(sg.grid); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing grid:", err)
+				return 1
+			}
+			fmt.Fprintln(info, "Score:", sg)
+		}
+	}
+
+	if err := output.Flush(info); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing CSV:", err)
+		return 1
+	}
+
+	fmt.Fprintln(info, "--------------------------------")
+	fmt.Fprintln(info, "Done")
+
+	if *checkpointPath != "" {
+		if err := saveCheckpoint(info, gen, *checkpointPath); err != nil {
+			fmt.Fprintln(os.Stderr, "Error saving checkpoint:", err)
+		}
+	}
+
+	if *showStats {
+		writeStatsSummary(info, numGrids, totalStats)
+		if *unique {
+			fmt.Fprintln(info, "Duplicates skipped:", duplicates)
+		}
+		if err := json.NewEncoder(info).Encode(stats); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing stats:", err)
+		}
+	}
 
 	if mf != nil {
 		pprof.WriteHeapProfile(mf)
 	}
 
 	if ctx.Err() != nil {
-		fmt.Println("Context error:", ctx.Err())
+		fmt.Fprintln(info, "Context error:", ctx.Err())
+	}
+
+	if numGrids > 0 {
+		return 0
+	}
+	err = cmp.Or(gen.Err(), ctx.Err())
+	if err == nil || errors.Is(err, xwgen.ErrNoGridsPossible) {
+		fmt.Fprintln(os.Stderr, "No grids exist with these words and constraints")
+		return exitNoGrids
+	}
+	if !errors.Is(err, xwgen.ErrTimeout) && !errors.Is(err, context.DeadlineExceeded) {
+		// The search was interrupted, e.g. by SIGINT with -checkpoint, rather than timing out.
+		fmt.Fprintln(os.Stderr, "Stopped before finding any grids:", err)
+		return 1
 	}
+	fmt.Fprintln(os.Stderr, "Timed out before finding any grids")
+	if partial := gen.BestPartial(); partial != nil {
+		fmt.Fprintln(os.Stderr, "PARTIAL (timed out):")
+		for _, row := range partial {
+			fmt.Fprintln(os.Stderr, string(row))
+		}
+	}
+	return exitTimeout
 }
 
-func loadFromFile(ctx context.Context, path string, minWordLength int, maxWordLength int) ([]string, error) {
-	f, err := os.Open(path)
+// generateBatch generates up to perSize grids of each of sizes with xwgen.GenerateBatch, or every
+// grid with a perSize of 0, emitting each after a line labeling its size, and returns the process
+// exit code.
+func generateBatch(info io.Writer, output *gridOutput, sizes []int, perSize int, timeout time.Duration, opts []xwgen.GeneratorOption) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	batch, err := xwgen.GenerateBatch(ctx, sizes, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	code := 0
+	for _, size := range sizes {
+		numGrids := 0
+		for grid := range batch[size] {
+			fmt.Fprintf(info, "%dx%d grid #%d:\n", size, size, numGrids+1)
+			if err := output.Emit(grid); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing grid:", err)
+				return 1
+			}
+			if numGrids++; perSize > 0 && numGrids >= perSize {
+				break
+			}
+		}
+		switch {
+		case numGrids > 0:
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			fmt.Fprintf(os.Stderr, "Timed out before finding any %dx%d grids\n", size, size)
+			code = exitTimeout
+		default:
+			fmt.Fprintf(os.Stderr, "No %dx%d grids exist with these words and constraints\n", size, size)
+			if code == 0 {
+				code = exitNoGrids
+			}
+		}
+	}
+	if err := output.Flush(info); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing CSV:", err)
+		return 1
+	}
+	return code
+}
+
+// createGenerator creates the generator, resuming the search checkpointed to checkpointPath if it
+// is set and the file exists.
+func createGenerator(info io.Writer, checkpointPath string, size int, opts []xwgen.GeneratorOption) (*xwgen.Generator, error) {
+	if checkpointPath == "" {
+		return xwgen.CreateGeneratorE(size, opts...)
+	}
+	f, err := os.Open(checkpointPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return xwgen.CreateGeneratorE(size, opts...)
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
+	fmt.Fprintln(info, "Resuming from checkpoint", checkpointPath)
+	return xwgen.CreateGeneratorFromCheckpoint(f, size, opts...)
+}
 
-	var words []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
-		if strings.HasPrefix(word, "#") {
-			continue
+// saveCheckpoint writes the generator's search to path if it was interrupted, or else removes
+// any checkpoint at path, which would resume a search that has since ended.
+func saveCheckpoint(info io.Writer, gen *xwgen.Generator, path string) error {
+	var buf bytes.Buffer
+	err := gen.WriteCheckpoint(&buf)
+	if errors.Is(err, xwgen.ErrNoCheckpoint) {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
 		}
-		if len(word) < minWordLength || len(word) > maxWordLength {
-			continue
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintln(info, "Saved checkpoint to", path)
+	return nil
+}
+
+// symmetryOptions returns the generator options for a comma-separated list of symmetries.
+func symmetryOptions(symmetries string) ([]xwgen.GeneratorOption, error) {
+	var opts []xwgen.GeneratorOption
+	for _, sym := range strings.Split(symmetries, ",") {
+		switch sym = strings.TrimSpace(sym); sym {
+		case "":
+		case "rotational":
+			opts = append(opts, xwgen.WithRotationalSymmetry())
+		case "vertical", "horizontal":
+			opts = append(opts, xwgen.WithReflectiveSymmetry(sym))
+		default:
+			return nil, fmt.Errorf("unknown symmetry %q", sym)
+		}
+	}
+	return opts, nil
+}
+
+// parseSizes parses a comma-separated list of distinct grid sizes, each at least 1.
+func parseSizes(list string) ([]int, error) {
+	var sizes []int
+	for _, field := range strings.Split(list, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid -width %q: each width must be a positive number", list)
 		}
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+		if slices.Contains(sizes, size) {
+			return nil, fmt.Errorf("invalid -width %q: width %d is repeated", list, size)
 		}
-		for _, r := range word {
-			if r < 'a' || r > 'z' {
-				return nil, fmt.Errorf("word %s contains non-lowercase letter %q", word, r)
-			}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// stringList is a flag that can be repeated, collecting each of its values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// restartPolicy returns the restart policy with the given name, restarting first after base
+// backtracks.
+func restartPolicy(name string, base int64) (xwgen.RestartPolicy, error) {
+	if base < 1 {
+		return nil, fmt.Errorf("-restart-backtracks must be at least 1")
+	}
+	switch name {
+	case "luby":
+		return xwgen.LubyRestarts(base), nil
+	case "doubling":
+		return xwgen.DoublingRestarts(base), nil
+	default:
+		return nil, fmt.Errorf("unknown restart policy %q", name)
+	}
+}
+
+// wordListFiles holds the paths of the word lists to load. Empty paths are skipped.
+type wordListFiles struct {
+	preferred string
+	obscure   string
+	excluded  string
+
+	// frequencies, if non-nil, is filled with the frequency score of each preferred and obscure
+	// word that has one.
+	frequencies map[string]float64
+}
+
+// loadWordLists loads each of the word lists in files, reporting progress and errors to info.
+func loadWordLists(ctx context.Context, info io.Writer, files wordListFiles, minWordLength, maxWordLength int) (preferredWords, obscureWords, excludedWords []string, err error) {
+	if _, err := files.usesStdin(); err != nil {
+		fmt.Fprintln(info, err)
+		return nil, nil, nil, err
+	}
+	if files.preferred != "" {
+		fmt.Fprintln(info, "Loading words from file...")
+		if preferredWords, err = loadFromFile(ctx, files.preferred, minWordLength, maxWordLength, files.frequencies); err != nil {
+			fmt.Fprintln(info, "Error loading words from file:", err)
+			return nil, nil, nil, err
+		}
+	}
+	if files.obscure != "" {
+		fmt.Fprintln(info, "Loading obscure words from file...")
+		if obscureWords, err = loadFromFile(ctx, files.obscure, minWordLength, maxWordLength, files.frequencies); err != nil {
+			fmt.Fprintln(info, "Error loading obscure words from file:", err)
+			return nil, nil, nil, err
+		}
+	}
+	if files.excluded != "" {
+		fmt.Fprintln(info, "Loading excluded words from file...")
+		if excludedWords, err = loadFromFile(ctx, files.excluded, minWordLength, maxWordLength, nil); err != nil {
+			fmt.Fprintln(info, "Error loading excluded words from file:", err)
+			return nil, nil, nil, err
+		}
+	}
+
+	fmt.Fprintln(info, "Preferred words:", len(preferredWords))
+	fmt.Fprintln(info, "Obscure words:", len(obscureWords))
+	fmt.Fprintln(info, "Excluded words:", len(excludedWords))
+	return preferredWords, obscureWords, excludedWords, nil
+}
+
+// stdinPath is the word list path that reads words from stdin.
+const stdinPath = "-"
+
+// usesStdin returns true if any of the word lists are read from stdin, and an error if more than
+// one of them is.
+func (files wordListFiles) usesStdin() (bool, error) {
+	n := 0
+	for _, path := range []string{files.preferred, files.obscure, files.excluded} {
+		if path == stdinPath {
+			n++
 		}
-		words = append(words, word)
 	}
-	return words, scanner.Err()
+	if n > 1 {
+		return false, fmt.Errorf("only one word list can be read from stdin (%q)", stdinPath)
+	}
+	return n == 1, nil
+}
+
+// loadFromFile loads the words in path with between minWordLength and maxWordLength letters, or
+// from stdin if path is stdinPath. If frequencies is non-nil, the frequency scores of the words are
+// added to it.
+func loadFromFile(ctx context.Context, path string, minWordLength int, maxWordLength int, frequencies map[string]float64) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	words, scores, err := wordlist.ReadContext(ctx, r, minWordLength, maxWordLength)
+	if err != nil {
+		return nil, err
+	}
+	if frequencies != nil {
+		maps.Copy(frequencies, scores)
+	}
+	return words, nil
 }
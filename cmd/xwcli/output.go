@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export"
+	"github.com/Eyas/xwgen/pkg/export/puz"
+	"github.com/Eyas/xwgen/pkg/history"
+	"github.com/Eyas/xwgen/pkg/render"
+	"github.com/Eyas/xwgen/pkg/wordlist"
+)
+
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatPuz      = "puz"
+)
+
+func validateFormat(format string) error {
+	switch format {
+	case formatText, formatJSON, formatMarkdown, formatPuz:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, expected %q, %q, %q or %q", format, formatText, formatJSON, formatMarkdown, formatPuz)
+}
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor returns whether grids written to stdout as text should be colorized, for the given
+// -color mode. In auto mode, they are if stdout is a terminal and NO_COLOR is not set.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		return render.IsColorTerminal(os.Stdout), nil
+	}
+	return false, fmt.Errorf("unknown color mode %q, expected %q, %q or %q", mode, colorAuto, colorAlways, colorNever)
+}
+
+// jsonOutput is a grid as written by -format json: its export.JSONGrid, with its difficulty.
+type jsonOutput struct {
+	export.JSONGrid
+	Difficulty jsonDifficulty `json:"difficulty"`
+}
+
+// jsonDifficulty is the JSON representation of the difficulty of a grid.
+type jsonDifficulty struct {
+	xwgen.Difficulty
+	Band xwgen.DifficultyBand `json:"band"`
+}
+
+// writeGrid writes grid to w in the given format. The difficulty of JSON grids is estimated with
+// wordScores, which can be nil.
+func writeGrid(w io.Writer, grid xwgen.Grid, format string, wordScores map[string]float64) error {
+	switch format {
+	case formatJSON:
+		difficulty := xwgen.EstimateDifficulty(grid, wordScores)
+		jg := jsonOutput{
+			JSONGrid:   export.NewJSONGrid(grid),
+			Difficulty: jsonDifficulty{Difficulty: difficulty, Band: difficulty.Band()},
+		}
+		return json.NewEncoder(w).Encode(jg)
+	case formatMarkdown:
+		// A blank line after each table keeps consecutive tables apart.
+		_, err := fmt.Fprintf(w, "%s\n\n", grid.ReprMarkdown())
+		return err
+	case formatPuz:
+		return puz.Write(w, grid, puz.Puzzle{})
+	default:
+		if grid.Bars() != nil {
+			_, err := fmt.Fprintln(w, grid.ReprBarred())
+			return err
+		}
+		_, err := fmt.Fprintln(w, grid.Repr())
+		return err
+	}
+}
+
+// writeStatsSummary writes a table summarizing the statistics of all grids found.
+func writeStatsSummary(w io.Writer, numGrids int, total xwgen.SearchStats) {
+	var perGrid time.Duration
+	if numGrids > 0 {
+		perGrid = total.Elapsed / time.Duration(numGrids)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Grids\tChoices\tBacktracks\tBackjumps\tRestarts\tGrid timeouts\tDead ends\tPeak frontier\tTotal time\tTime per grid\t")
+	fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%v\t%v\t\n",
+		numGrids, total.ChoiceSteps, total.Backtracks, total.Backjumps, total.Restarts, total.GridTimeouts, total.DeadEnds, total.PeakFrontier,
+		total.Elapsed.Round(time.Millisecond), perGrid.Round(time.Millisecond))
+	tw.Flush()
+}
+
+// writeEstimate writes the number of possible lines of each row and column before and after
+// propagation, the number of characters that fit each cell, and the upper bound on the number of
+// grids.
+func writeEstimate(w io.Writer, e *xwgen.Estimate) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Line\tInitial\tPropagated\t")
+	for y, line := range e.Across {
+		fmt.Fprintf(tw, "Row %d\t%d\t%d\t\n", y, line.Initial, line.Propagated)
+	}
+	for x, line := range e.Down {
+		fmt.Fprintf(tw, "Column %d\t%d\t%d\t\n", x, line.Initial, line.Propagated)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "Characters that fit each cell:")
+	for _, row := range e.Cells {
+		for x, cell := range row {
+			if x > 0 {
+				fmt.Fprint(w, " ")
+			}
+			fmt.Fprintf(w, "%2d", cell.Count())
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "At most %.3g grids\n", e.UpperBound)
+}
+
+// gridFileWriter writes each grid to its own numbered file in a directory.
+type gridFileWriter struct {
+	dir    string
+	format string
+	next   int
+}
+
+var gridFileName = regexp.MustCompile(`^grid-(\d+)\.\w+$`)
+
+// newGridFileWriter creates dir if needed, and continues numbering after any grid files already
+// in it.
+func newGridFileWriter(dir, format string) (*gridFileWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	for _, entry := range entries {
+		m := gridFileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		if n, err := strconv.Atoi(m[1]); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return &gridFileWriter{dir: dir, format: format, next: next}, nil
+}
+
+// Write writes grid to the next numbered file, returning its index and path. wordScores are as for
+// writeGrid.
+func (w *gridFileWriter) Write(grid xwgen.Grid, wordScores map[string]float64) (int, string, error) {
+	ext := ".txt"
+	switch w.format {
+	case formatJSON:
+		ext = ".json"
+	case formatMarkdown:
+		ext = ".md"
+	case formatPuz:
+		ext = ".puz"
+	}
+	index := w.next
+	path := filepath.Join(w.dir, fmt.Sprintf("grid-%04d%s", index, ext))
+
+	// Never overwrite a grid, e.g. one written concurrently by another run.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := writeGrid(f, grid, w.format, wordScores); err != nil {
+		f.Close()
+		return 0, "", err
+	}
+	if err := f.Close(); err != nil {
+		return 0, "", err
+	}
+	w.next++
+	return index, path, nil
+}
+
+// gridOutput emits grids either to stdout, or to files with a one-line summary on stdout.
+type gridOutput struct {
+	format string
+	files  *gridFileWriter
+	// color colorizes text grids written to stdout.
+	color bool
+	// history, if set, records the words of each grid emitted.
+	history history.Store
+	// excludePath, if set, is the excluded word list that the words of each grid emitted are
+	// added to.
+	excludePath string
+	// wordScores, if set, are the frequency scores of words, to estimate the difficulty of grids.
+	wordScores map[string]float64
+	// csvPath, if set, is the CSV file that Flush writes the grids emitted to, instead of writing
+	// each one as it is emitted.
+	csvPath  string
+	csvGrids []xwgen.Grid
+}
+
+func (o *gridOutput) Emit(grid xwgen.Grid) error {
+	if err := o.emit(grid); err != nil {
+		return err
+	}
+	if o.history != nil {
+		if err := o.history.Record(grid.AllWords(), time.Now()); err != nil {
+			return fmt.Errorf("recording grid in history: %w", err)
+		}
+	}
+	if o.excludePath != "" {
+		if err := wordlist.AppendExcludedWords(o.excludePath, grid.AllWords()); err != nil {
+			return fmt.Errorf("adding grid to excluded words: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *gridOutput) emit(grid xwgen.Grid) error {
+	if o.csvPath != "" {
+		o.csvGrids = append(o.csvGrids, grid)
+		return nil
+	}
+	if o.files == nil {
+		if o.format == formatText {
+			fmt.Println("--------------------------------")
+			// Colored output has no way to draw bars.
+			if o.color && grid.Bars() == nil {
+				return render.Colored(os.Stdout, grid, grid.ObscureWords())
+			}
+		}
+		return writeGrid(os.Stdout, grid, o.format, o.wordScores)
+	}
+	index, path, err := o.files.Write(grid, o.wordScores)
+	if err != nil {
+		return fmt.Errorf("writing grid to file: %w", err)
+	}
+	_, err = fmt.Printf("#%d %s: %s (obscure: %d)\n", index, path, strings.Join(grid.AllWords(), ", "), len(grid.ObscureWords()))
+	return err
+}
+
+// Flush writes the grids collected for the CSV file, if any, and reports where to info.
+func (o *gridOutput) Flush(info io.Writer) error {
+	if o.csvPath == "" {
+		return nil
+	}
+	f, err := os.Create(o.csvPath)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteCSV(f, o.csvGrids); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(info, "Wrote %d grids to %s\n", len(o.csvGrids), o.csvPath)
+	return err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Eyas/xwgen"
+)
+
+// reportProgress writes the progress of a search to w every interval until the returned function
+// is called.
+func reportProgress(w io.Writer, progress *xwgen.Progress, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				writeProgress(w, progress.Snapshot())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func writeProgress(w io.Writer, s xwgen.ProgressSnapshot) {
+	fmt.Fprintf(w, "Progress: %d nodes explored, %d pruned, %d backtracks, depth %d\n", s.NodesExplored, s.Pruned, s.Backtracks, s.Depth)
+	if s.BestPartial == nil {
+		return
+	}
+	fmt.Fprintf(w, "Best partial grid (%d cells decided):\n", s.BestPartialCells)
+	for _, row := range s.BestPartial {
+		fmt.Fprintln(w, string(row))
+	}
+}
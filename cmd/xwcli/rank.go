@@ -0,0 +1,87 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+)
+
+type scoredGrid struct {
+	grid      xwgen.Grid
+	score     float64
+	breakdown map[string]float64
+}
+
+// String formats the score and its breakdown on one line, with the components in alphabetical
+// order.
+func (sg scoredGrid) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%.1f", sg.score)
+	for i, name := range slices.Sorted(maps.Keys(sg.breakdown)) {
+		sep := ", "
+		if i == 0 {
+			sep = " ("
+		}
+		fmt.Fprintf(&b, "%s%s: %.1f", sep, name, sg.breakdown[name])
+	}
+	if len(sg.breakdown) > 0 {
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// scoredGridHeap is a min-heap of grids by score, so the worst grid is always at the root.
+type scoredGridHeap []scoredGrid
+
+func (h scoredGridHeap) Len() int           { return len(h) }
+func (h scoredGridHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h scoredGridHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scoredGridHeap) Push(x any)        { *h = append(*h, x.(scoredGrid)) }
+func (h *scoredGridHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// topGrids keeps the n best grids added to it by scorer, without holding on to any others.
+type topGrids struct {
+	n      int
+	scorer xwgen.Scorer
+	heap   scoredGridHeap
+}
+
+func newTopGrids(n int, scorer xwgen.Scorer) *topGrids {
+	return &topGrids{n: n, scorer: scorer, heap: make(scoredGridHeap, 0, n)}
+}
+
+func (t *topGrids) Add(grid xwgen.Grid) {
+	sg := scoredGrid{grid: grid, score: t.scorer.Score(grid), breakdown: t.scorer.Breakdown(grid)}
+	if len(t.heap) < t.n {
+		heap.Push(&t.heap, sg)
+		return
+	}
+	if sg.score > t.heap[0].score {
+		t.heap[0] = sg
+		heap.Fix(&t.heap, 0)
+	}
+}
+
+// Sorted returns the grids kept, best first.
+func (t *topGrids) Sorted() []scoredGrid {
+	sorted := slices.Clone(t.heap)
+	slices.SortStableFunc(sorted, func(a, b scoredGrid) int {
+		if a.score > b.score {
+			return -1
+		}
+		if a.score < b.score {
+			return 1
+		}
+		return 0
+	})
+	return sorted
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export"
+	"golang.org/x/net/websocket"
+)
+
+// generateRequest is the JSON body of a request to POST /generate.
+type generateRequest struct {
+	// Width is the side length of the grids.
+	Width int `json:"width"`
+	// Words is the preferred words to fill grids from, and Obscure the obscure ones. If Words is
+	// empty, the server's words are used.
+	Words   []string `json:"words"`
+	Obscure []string `json:"obscure"`
+	// Timeout is how long to search for, e.g. "10s", up to the server's -timeout.
+	Timeout string `json:"timeout"`
+	// MaxGrids is the number of grids to return at most, or 0 to return every grid found before
+	// the timeout.
+	MaxGrids int `json:"maxGrids"`
+	// Difficulty, if set, only returns grids of this difficulty: "easy", "medium", or "hard". See
+	// xwgen.EstimateDifficulty.
+	Difficulty string `json:"difficulty"`
+}
+
+// generateHandler serves POST /generate, which responds with a JSON array of the grids found, in
+// the format of export.JSONGrid.
+type generateHandler struct {
+	// opts configure every generator, other than its size and words.
+	opts []xwgen.GeneratorOption
+	// preferred, obscure, and excluded are the words of requests without their own.
+	preferred, obscure, excluded []string
+	minWordLength                int
+	// wordScores are the frequency scores of words, to estimate the difficulty of grids. They can
+	// be nil.
+	wordScores map[string]float64
+	// maxTimeout bounds the timeout of every request.
+	maxTimeout time.Duration
+	// streamBuffer is the number of grids /generate/stream buffers for each client, or 0 for
+	// defaultStreamBuffer.
+	streamBuffer int
+}
+
+func (h *generateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	timeout, err := h.timeout(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	gen, err := h.generator(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	grids := []export.JSONGrid{}
+	for grid := range h.grids(ctx, gen, req) {
+		grids = append(grids, export.NewJSONGrid(grid))
+		if req.MaxGrids > 0 && len(grids) >= req.MaxGrids {
+			break
+		}
+	}
+
+	// The client has gone away, so there is no one to respond to.
+	if r.Context().Err() != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(grids); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing response:", err)
+	}
+}
+
+// timeout returns how long to search for req.
+func (h *generateHandler) timeout(req generateRequest) (time.Duration, error) {
+	if req.Timeout == "" {
+		return h.maxTimeout, nil
+	}
+	timeout, err := time.ParseDuration(req.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+	if timeout <= 0 {
+		return 0, errors.New("timeout must be positive")
+	}
+	return min(timeout, h.maxTimeout), nil
+}
+
+// generator returns the generator for req.
+func (h *generateHandler) generator(req generateRequest) (*xwgen.Generator, error) {
+	if req.MaxGrids < 0 {
+		return nil, errors.New("maxGrids must not be negative")
+	}
+	if req.Difficulty != "" {
+		if _, err := xwgen.ParseDifficultyBand(req.Difficulty); err != nil {
+			return nil, err
+		}
+	}
+	preferred, obscure, excluded := h.preferred, h.obscure, h.excluded
+	if len(req.Words) > 0 {
+		preferred, obscure = req.Words, req.Obscure
+	}
+	opts := append(h.opts[:len(h.opts):len(h.opts)],
+		xwgen.WithPreferredWords(preferred),
+		xwgen.WithObscureWords(obscure),
+		xwgen.WithExcludedWords(excluded),
+		xwgen.WithMinWordLength(h.minWordLength),
+		xwgen.WithMaxWordLength(req.Width),
+	)
+	return xwgen.CreateGeneratorE(req.Width, opts...)
+}
+
+// grids returns the grids gen finds for req, which generator has validated: only those of its
+// difficulty, if it has one.
+func (h *generateHandler) grids(ctx context.Context, gen *xwgen.Generator, req generateRequest) iter.Seq[xwgen.Grid] {
+	grids := gen.PossibleGrids(ctx)
+	if req.Difficulty == "" {
+		return grids
+	}
+	return func(yield func(xwgen.Grid) bool) {
+		for grid := range grids {
+			if string(xwgen.EstimateDifficulty(grid, h.wordScores).Band()) != req.Difficulty {
+				continue
+			}
+			if !yield(grid) {
+				return
+			}
+		}
+	}
+}
+
+// serve serves POST /generate and the WebSocket /generate/stream on addr until the server fails,
+// returning the process exit code.
+func serve(addr string, h *generateHandler) int {
+	fmt.Println("Serving POST /generate and /generate/stream on", addr)
+	if err := http.ListenAndServe(addr, h.mux()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// mux routes POST /generate and /generate/stream to h.
+func (h *generateHandler) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/generate", h)
+	mux.Handle("/generate/stream", websocket.Handler(h.stream))
+	return mux
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export"
+	"golang.org/x/net/websocket"
+)
+
+func TestServe(t *testing.T) {
+	h := &generateHandler{
+		preferred:     []string{"abc", "def", "ghi", "adg", "beh", "cfi"},
+		minWordLength: 3,
+		maxTimeout:    10 * time.Second,
+	}
+	srv := httptest.NewServer(h.mux())
+	defer srv.Close()
+
+	post := func(ctx context.Context, body string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/generate", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error: %v", err)
+		}
+		return srv.Client().Do(req)
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		want       []string
+	}{
+		{name: "server words", body: `{"width": 3}`, want: []string{"abc", "adg"}},
+		{name: "max grids", body: `{"width": 3, "maxGrids": 1, "timeout": "5s"}`},
+		{name: "request words", body: `{"width": 3, "words": ["abc", "bca", "cab"], "obscure": ["xyz"]}`},
+		// The server's words are preferred, with common letters.
+		{name: "easy", body: `{"width": 3, "difficulty": "easy"}`},
+		{name: "hard", body: `{"width": 3, "difficulty": "hard"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := post(t.Context(), tc.body)
+			if err != nil {
+				t.Fatalf("POST error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("POST status = %s", resp.Status)
+			}
+			var grids []export.JSONGrid
+			if err := json.NewDecoder(resp.Body).Decode(&grids); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			var got []string
+			for _, g := range grids {
+				if len(g.Across) > 0 {
+					got = append(got, g.Across[0].Answer)
+				}
+			}
+			switch tc.name {
+			case "server words":
+				if len(got) != 2 || got[0] == got[1] || !strings.Contains("abc adg", got[0]) || !strings.Contains("abc adg", got[1]) {
+					t.Errorf("grids start with %q, want %q", got, tc.want)
+				}
+			case "max grids":
+				if len(grids) != 1 {
+					t.Errorf("got %d grids, want 1", len(grids))
+				}
+			case "request words", "hard":
+				// The words of the request are rotations of each other, so every grid would repeat
+				// one, and the server's grids are easy.
+				if len(grids) != 0 {
+					t.Errorf("got %d grids, want none", len(grids))
+				}
+			case "easy":
+				if len(grids) != 2 {
+					t.Errorf("got %d grids, want 2", len(grids))
+				}
+			}
+		})
+	}
+
+	for _, body := range []string{`{`, `{"width": 0}`, `{"width": 3, "timeout": "soon"}`, `{"width": 3, "maxGrids": -1}`, `{"width": 3, "difficulty": "brutal"}`} {
+		resp, err := post(t.Context(), body)
+		if err != nil {
+			t.Fatalf("POST error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST %s status = %s, want 400 Bad Request", body, resp.Status)
+		}
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/generate")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %s, want 405 Method Not Allowed", resp.Status)
+	}
+
+	// Cancelling the request stops the search.
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	var many []string
+	for _, a := range "abcdef" {
+		for _, b := range "abcdef" {
+			for _, c := range "abcdef" {
+				many = append(many, string([]rune{a, b, c}))
+			}
+		}
+	}
+	body, _ := json.Marshal(generateRequest{Width: 3, Words: many})
+	start := time.Now()
+	if _, err := post(ctx, string(body)); err == nil {
+		t.Error("POST with a cancelled context succeeded, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled POST took %v", elapsed)
+	}
+}
+
+// dialStream connects to /generate/stream of srv and sends it req.
+func dialStream(t *testing.T, srv *httptest.Server, req string) *websocket.Conn {
+	t.Helper()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/generate/stream", "", srv.URL)
+	if err != nil {
+		t.Fatalf("Dial() error: %v", err)
+	}
+	if err := websocket.Message.Send(ws, req); err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+	return ws
+}
+
+func TestServeStream(t *testing.T) {
+	var words []string
+	for _, a := range "abcdef" {
+		for _, b := range "abcdef" {
+			for _, c := range "abcdef" {
+				words = append(words, string([]rune{a, b, c}))
+			}
+		}
+	}
+	h := &generateHandler{
+		preferred:     words,
+		minWordLength: 3,
+		maxTimeout:    10 * time.Second,
+		streamBuffer:  2,
+	}
+	srv := httptest.NewServer(h.mux())
+	defer srv.Close()
+
+	t.Run("max grids", func(t *testing.T) {
+		ws := dialStream(t, srv, `{"width": 3, "maxGrids": 3}`)
+		defer ws.Close()
+		for i := range 3 {
+			var grid export.JSONGrid
+			if err := websocket.JSON.Receive(ws, &grid); err != nil {
+				t.Fatalf("receiving grid %d: %v", i, err)
+			}
+			if len(grid.Across) != 3 || len(grid.Down) != 3 {
+				t.Errorf("grid %d has %d across and %d down words, want 3 and 3", i, len(grid.Across), len(grid.Down))
+			}
+		}
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); !errors.Is(err, io.EOF) {
+			t.Errorf("receiving after the last grid = %q, %v, want EOF", msg, err)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		ws := dialStream(t, srv, `{"width": 0}`)
+		defer ws.Close()
+		var msg streamError
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			t.Fatalf("receiving error: %v", err)
+		}
+		if msg.Error == "" {
+			t.Error("got no error for width 0")
+		}
+	})
+
+}
+
+func TestBufferGrids(t *testing.T) {
+	var words []string
+	for _, a := range "abcdef" {
+		for _, b := range "abcdef" {
+			for _, c := range "abcdef" {
+				words = append(words, string([]rune{a, b, c}))
+			}
+		}
+	}
+	progress := &xwgen.Progress{}
+	gen, err := xwgen.CreateGeneratorE(3, xwgen.WithPreferredWords(words), xwgen.WithProgress(progress))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	grids := bufferGrids(ctx, gen.PossibleGrids(ctx), 0, 2)
+
+	// Without anyone receiving them, the search fills the buffer and pauses.
+	time.Sleep(100 * time.Millisecond)
+	before := progress.Snapshot().NodesExplored
+	time.Sleep(100 * time.Millisecond)
+	if after := progress.Snapshot().NodesExplored; after != before {
+		t.Errorf("search explored %d more nodes with a full buffer", after-before)
+	}
+	for range 5 {
+		<-grids
+	}
+	if after := progress.Snapshot().NodesExplored; after == before {
+		t.Error("search did not resume once grids were received")
+	}
+
+	cancel()
+	for range grids {
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export"
+	"golang.org/x/net/websocket"
+)
+
+// defaultStreamBuffer is the number of grids /generate/stream buffers for a client that is slow to
+// receive them, unless -stream-buffer says otherwise.
+const defaultStreamBuffer = 16
+
+// streamError is the message /generate/stream sends instead of grids if the request is invalid.
+type streamError struct {
+	Error string `json:"error"`
+}
+
+// stream serves /generate/stream. The client sends a single generateRequest message, and receives
+// each grid as an export.JSONGrid message as soon as it is found. The connection is closed once the
+// search is over.
+//
+// Up to h.streamBuffer grids are buffered while the client is slow to receive them, on top of what
+// the connection itself buffers, after which the search pauses until it catches up. Closing the
+// connection stops the search.
+func (h *generateHandler) stream(ws *websocket.Conn) {
+	defer ws.Close()
+
+	var req generateRequest
+	if err := websocket.JSON.Receive(ws, &req); err != nil {
+		websocket.JSON.Send(ws, streamError{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	timeout, err := h.timeout(req)
+	if err != nil {
+		websocket.JSON.Send(ws, streamError{Error: err.Error()})
+		return
+	}
+	gen, err := h.generator(req)
+	if err != nil {
+		websocket.JSON.Send(ws, streamError{Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ws.Request().Context(), timeout)
+	defer cancel()
+	// The client sends nothing after its request, so once reading fails it has gone away.
+	go func() {
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		cancel()
+	}()
+
+	buffer := h.streamBuffer
+	if buffer <= 0 {
+		buffer = defaultStreamBuffer
+	}
+	grids := bufferGrids(ctx, h.grids(ctx, gen, req), req.MaxGrids, buffer)
+	for grid := range grids {
+		if err := websocket.JSON.Send(ws, grid); err != nil {
+			cancel()
+			break
+		}
+	}
+	// Wait for the search to stop before closing the connection.
+	for range grids {
+	}
+}
+
+// bufferGrids returns a channel of up to maxGrids of grids, or all of them if maxGrids is 0, which
+// is closed once they are done or ctx is. The grids are searched for while up to buffer of them are
+// waiting to be received, and the search is paused while the buffer is full.
+func bufferGrids(ctx context.Context, grids iter.Seq[xwgen.Grid], maxGrids, buffer int) <-chan export.JSONGrid {
+	buffered := make(chan export.JSONGrid, buffer)
+	go func() {
+		defer close(buffered)
+		n := 0
+		for grid := range grids {
+			select {
+			case buffered <- export.NewJSONGrid(grid):
+			case <-ctx.Done():
+				return
+			}
+			if n++; maxGrids > 0 && n >= maxGrids {
+				return
+			}
+		}
+	}()
+	return buffered
+}
@@ -0,0 +1,175 @@
+// Command xwclient-grpc generates grids like xwcli, but has an xwserver find them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Eyas/xwgen/pkg/export"
+	"github.com/Eyas/xwgen/pkg/render"
+	"github.com/Eyas/xwgen/pkg/wordlist"
+	"github.com/Eyas/xwgen/pkg/xwserver"
+)
+
+// Exit codes when no grids are generated, as for xwcli.
+const (
+	exitNoGrids = 2
+	exitTimeout = 3
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	addr := flag.String("addr", "localhost:50051", "The address of the xwserver")
+	firstOnly := flag.Bool("first", false, "Only generate the first grid")
+	doAll := flag.Bool("all", false, "Generate all grids")
+	count := flag.Int("count", 0, "Stop after generating this many grids (0 for no limit)")
+	format := flag.String("format", "text", "The output format: 'text' or 'json'")
+	sideLength := flag.Int("width", 4, "The width of the grid")
+	height := flag.Int("height", 0, "The height of the grid (defaults to -width)")
+	symmetry := flag.String("symmetry", "", "Comma-separated symmetries the blocked cells must have: 'rotational', 'vertical', and/or 'horizontal'")
+	maxBlocks := flag.Int("max-blocks", -1, "The maximum number of blocked cells per grid (-1 for no limit, 0 for word squares)")
+	minWordLength := flag.Int("min-word-length", 3, "The minimum word length")
+	file := flag.String("file", "", "The file to load words from, or none to use the server's words")
+	obscureFile := flag.String("obscure", "", "The file to load obscure words from")
+	excludedFile := flag.String("excluded", "", "The file to load excluded words from")
+	seed := flag.Uint64("seed", 0, "The random seed (0 for a time-based seed)")
+	timeout := flag.Duration("timeout", 1*time.Minute, "The timeout for the generator")
+	flag.Parse()
+
+	if *firstOnly && *doAll {
+		fmt.Println("Cannot use both -first and -all")
+		return 1
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Printf("Unknown -format %q, want 'text' or 'json'\n", *format)
+		return 1
+	}
+
+	req := &xwserver.GenerateRequest{
+		Width:         int32(*sideLength),
+		Height:        int32(*height),
+		MinWordLength: int32(*minWordLength),
+		Symmetry:      *symmetry,
+		Seed:          *seed,
+		Count:         int32(*count),
+	}
+	if *firstOnly {
+		req.Count = 1
+	}
+	if *maxBlocks >= 0 {
+		req.MaxBlocks = proto.Int32(int32(*maxBlocks))
+	}
+	for _, list := range []struct {
+		path  string
+		words *[]string
+	}{
+		{*file, &req.PreferredWords},
+		{*obscureFile, &req.ObscureWords},
+		{*excludedFile, &req.ExcludedWords},
+	} {
+		if list.path == "" {
+			continue
+		}
+		words, _, err := wordlist.LoadWordsFromFile(list.path, false, 0)
+		if err != nil {
+			fmt.Println("Error loading words from file:", err)
+			return 1
+		}
+		*list.words = words
+	}
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	stream, err := xwserver.NewCrosswordServiceClient(conn).GenerateGrids(ctx, req)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	// Keep stdout machine-readable when emitting JSON.
+	var info io.Writer = os.Stdout
+	if *format != "text" {
+		info = os.Stderr
+	}
+	// Color text grids on a terminal, as xwcli does by default.
+	color := render.IsColorTerminal(os.Stdout)
+
+	numGrids := 0
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return streamError(err, numGrids)
+		}
+		numGrids++
+		grid := xwserver.GridFromProto(resp.GetGrid())
+		if *format == "json" {
+			err = export.WriteJSON(os.Stdout, grid)
+		} else if color {
+			err = render.Colored(os.Stdout, grid, resp.GetGrid().GetObscureWords())
+		} else {
+			_, err = fmt.Println(grid.Repr())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing grid:", err)
+			return 1
+		}
+
+		if *doAll || *count > 0 || *firstOnly || *format != "text" {
+			continue
+		}
+		// Wait for user input and determine if they want to continue.
+		fmt.Print("Continue? [Y/n]: ")
+		var input string
+		fmt.Scanln(&input)
+		if input == "n" || input == "N" {
+			break
+		}
+	}
+
+	fmt.Fprintln(info, "--------------------------------")
+	fmt.Fprintln(info, "Done")
+	return 0
+}
+
+// streamError reports the error that ended the stream after numGrids grids, returning the process
+// exit code.
+func streamError(err error, numGrids int) int {
+	switch status.Code(err) {
+	case codes.NotFound:
+		fmt.Fprintln(os.Stderr, "No grids exist with these words and constraints")
+		return exitNoGrids
+	case codes.DeadlineExceeded:
+		if numGrids > 0 {
+			fmt.Fprintln(os.Stderr, "Context error:", err)
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "Timed out before finding any grids")
+		return exitTimeout
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return 1
+}
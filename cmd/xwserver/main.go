@@ -0,0 +1,53 @@
+// Command xwserver serves crossword grids over gRPC. See pkg/xwserver, and cmd/xwclient-grpc for a
+// client.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+
+	"github.com/Eyas/xwgen/pkg/wordlist"
+	"github.com/Eyas/xwgen/pkg/xwserver"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:50051", "The address to listen on")
+	file := flag.String("file", "", "The file to load words from, for requests without their own words")
+	obscureFile := flag.String("obscure", "", "The file to load obscure words from, for requests without their own words")
+	excludedFile := flag.String("excluded", "", "The file to load excluded words from, for requests without their own words")
+	maxTimeout := flag.Duration("max-timeout", xwserver.DefaultMaxTimeout, "The longest a request can search for grids, or 0 for no limit")
+	maxSize := flag.Int("max-size", xwserver.DefaultMaxSize, "The largest width and height of requested grids, or 0 for no limit")
+	flag.Parse()
+
+	load := func(path string) []string {
+		if path == "" {
+			return nil
+		}
+		words, _, err := wordlist.LoadWordsFromFile(path, false, 0)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading words:", err)
+			os.Exit(1)
+		}
+		return words
+	}
+	preferred, obscure, excluded := load(*file), load(*obscureFile), load(*excludedFile)
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	server := xwserver.NewServer(preferred, obscure, excluded)
+	server.MaxTimeout, server.MaxSize = *maxTimeout, *maxSize
+	s := grpc.NewServer()
+	xwserver.RegisterCrosswordServiceServer(s, server)
+	fmt.Printf("Serving %d preferred and %d obscure words on %s\n", len(preferred), len(obscure), lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package xwgen
+
+import (
+	"context"
+	"strings"
+)
+
+// CountGrids counts the distinct grids the generator can fill, without yielding them, stopping
+// once it has counted limit grids, if limit is positive, or once ctx is done. It returns the
+// number counted, and true if that is every grid, i.e. the search was exhausted.
+//
+// It runs the same depth-first search as PossibleGrids, but skips building the words of each grid
+// unless it needs them, e.g. for WithRequiredWords, which makes it faster than draining
+// PossibleGrids. It ignores WithWorkers, WithRestarts, WithIterativeDeepening and WithBeamSearch,
+// which would not visit every grid exactly once.
+func (g *Generator) CountGrids(ctx context.Context, limit int64) (int64, bool) {
+	root, err := g.initialState(ctx)
+	if err != nil {
+		return 0, false
+	}
+
+	sr := &searcher{g: g, ctx: ctx, countOnly: true, untracked: true}
+	seen := make(map[string]bool)
+	var n int64
+	for grid := range sr.possibleGridsAtRoot(root) {
+		key := gridKey(grid)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if n++; limit > 0 && n >= limit {
+			return n, false
+		}
+	}
+	return n, !sr.stopped && ctx.Err() == nil
+}
+
+// gridKey returns the letters of grid, which tell it apart from any other grid of the same size.
+func gridKey(grid Grid) string {
+	var b strings.Builder
+	for _, row := range grid.grid {
+		for _, r := range row {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isDecided returns true if every line of state has a single possibility, and every across line
+// has the same letters as the down lines crossing it.
+func isDecided(state *gridState) bool {
+	across := make([][]rune, len(state.across))
+	for y, line := range state.across {
+		if line.MaxPossibilities() != 1 {
+			return false
+		}
+		across[y] = line.FirstOrNull().Line
+	}
+	for x, line := range state.down {
+		if line.MaxPossibilities() != 1 {
+			return false
+		}
+		for y, r := range line.FirstOrNull().Line {
+			if across[y][x] != r {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package xwgen
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountGrids(t *testing.T) {
+	var words []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			words = append(words, word)
+		}
+	}
+	newGenerator := func() *Generator {
+		gen, err := CreateGeneratorE(3, WithPreferredWords(words), WithSeed(42, 1024))
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		return gen
+	}
+
+	want := int64(0)
+	for range newGenerator().PossibleGrids(t.Context()) {
+		want++
+	}
+	got, exhausted := newGenerator().CountGrids(t.Context(), 0)
+	if got != want || !exhausted {
+		t.Errorf("CountGrids(0) = %d, %t, want %d, true", got, exhausted, want)
+	}
+
+	if got, exhausted := newGenerator().CountGrids(t.Context(), 10); got != 10 || exhausted {
+		t.Errorf("CountGrids(10) = %d, %t, want 10, false", got, exhausted)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if got, exhausted := newGenerator().CountGrids(ctx, 0); got != 0 || exhausted {
+		t.Errorf("CountGrids() with a cancelled context = %d, %t, want 0, false", got, exhausted)
+	}
+}
+
+func BenchmarkCountGrids(b *testing.B) {
+	words := loadTrimmedWords(b)
+	const limit = 1000
+
+	b.Run("CountGrids", func(b *testing.B) {
+		for b.Loop() {
+			gen, _ := CreateGeneratorE(3, WithPreferredWords(words), WithSeed(42, 1024))
+			gen.CountGrids(b.Context(), limit)
+		}
+	})
+	b.Run("PossibleGrids", func(b *testing.B) {
+		for b.Loop() {
+			gen, _ := CreateGeneratorE(3, WithPreferredWords(words), WithSeed(42, 1024))
+			n := 0
+			for range gen.PossibleGrids(b.Context()) {
+				if n++; n >= limit {
+					break
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package xwgen
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"sync/atomic"
+)
+
+// defaultDedupeCapacity is the number of grids a search remembers by default, to skip repeats of
+// them.
+const defaultDedupeCapacity = 100_000
+
+// WithDedupeCapacity makes each search remember the n grids it yielded most recently, rather than
+// 100,000, and skip any repeat of them. Searches with WithRestarts or WithWorkers can find the same
+// grid more than once, and each grid remembered takes about 100 bytes, so the capacity bounds the
+// memory of long searches at the cost of possibly yielding a repeat of a grid it has forgotten.
+func WithDedupeCapacity(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("dedupe capacity must be at least 1, got %d", n)
+		}
+		g.dedupeCapacity = n
+		return nil
+	}
+}
+
+// seenGrids is the set of the hashes of the grids seen most recently, holding at most capacity of
+// them. It is not safe to use concurrently.
+type seenGrids struct {
+	capacity int
+	elements map[uint64]*list.Element
+	// order holds the hashes from the most recently seen to the least.
+	order *list.List
+}
+
+func newSeenGrids(capacity int) *seenGrids {
+	return &seenGrids{capacity: capacity, elements: make(map[uint64]*list.Element), order: list.New()}
+}
+
+// add marks the grid with the given hash as the most recently seen, forgetting the least recently
+// seen grid if the set is full. It returns false if the grid was already in the set.
+func (s *seenGrids) add(hash uint64) bool {
+	if e, ok := s.elements[hash]; ok {
+		s.order.MoveToFront(e)
+		return false
+	}
+	s.elements[hash] = s.order.PushFront(hash)
+	if s.order.Len() > s.capacity {
+		delete(s.elements, s.order.Remove(s.order.Back()).(uint64))
+	}
+	return true
+}
+
+// hashes returns the hashes in the set, from the least recently seen to the most, so that adding
+// them in order to an empty set restores it.
+func (s *seenGrids) hashes() []uint64 {
+	hashes := make([]uint64, 0, s.order.Len())
+	for e := s.order.Back(); e != nil; e = e.Prev() {
+		hashes = append(hashes, e.Value.(uint64))
+	}
+	return hashes
+}
+
+// gridHash returns a hash of the reprKey of grid, which tells it apart from other grids.
+func gridHash(grid Grid) uint64 {
+	return reprHash(grid.reprKey())
+}
+
+func reprHash(repr string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(repr))
+	return h.Sum64()
+}
+
+// uniqueGrids filters out grids that are in seen, and adds each grid it yields to seen, counting
+// the repeats it skips in g.stats.
+func (g *Generator) uniqueGrids(grids iter.Seq2[Grid, SearchStats], seen *seenGrids) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		for grid, stats := range grids {
+			if !seen.add(gridHash(grid)) {
+				if g.stats != nil {
+					atomic.AddInt64(&g.stats.RepeatsSkipped, 1)
+				}
+				continue
+			}
+			if !yield(grid, stats) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSeenGrids(t *testing.T) {
+	seen := newSeenGrids(2)
+	for _, step := range []struct {
+		hash uint64
+		want bool
+	}{
+		{1, true},
+		{2, true},
+		{1, false},
+		// 2 is forgotten, since 1 was seen more recently.
+		{3, true},
+		{1, false},
+		{2, true},
+	} {
+		if got := seen.add(step.hash); got != step.want {
+			t.Errorf("add(%d) = %v, want %v", step.hash, got, step.want)
+		}
+	}
+	if got, want := seen.hashes(), []uint64{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("hashes() = %v, want %v", got, want)
+	}
+}
+
+func TestWithDedupeCapacity(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var subset []string
+	for i, word := range words {
+		if i%3 == 0 {
+			subset = append(subset, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) ([]string, Stats) {
+		var stats Stats
+		gen, err := CreateGeneratorE(4, append(opts,
+			WithPreferredWords(subset),
+			WithRand(rand.New(rand.NewPCG(42, 1024))),
+			WithRestarts(LubyRestarts(2)),
+			WithStats(&stats),
+		)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		var grids []string
+		for grid := range gen.PossibleGrids(ctx) {
+			grids = append(grids, grid.Repr())
+		}
+		return grids, stats
+	}
+
+	grids, stats := search()
+	if stats.RepeatsSkipped == 0 {
+		t.Error("no repeats were skipped, want the restarts to find some grids again")
+	}
+	if distinct := slices.Compact(slices.Sorted(slices.Values(grids))); len(distinct) != len(grids) {
+		t.Errorf("got %d grids, but only %d distinct ones", len(grids), len(distinct))
+	}
+
+	// Remembering a single grid only skips a repeat of the previous grid.
+	forgetful, forgetfulStats := search(WithDedupeCapacity(1))
+	if len(forgetful) <= len(grids) {
+		t.Errorf("got %d grids with a capacity of 1, want more than the %d distinct ones", len(forgetful), len(grids))
+	}
+	if forgetfulStats.RepeatsSkipped >= stats.RepeatsSkipped {
+		t.Errorf("skipped %d repeats with a capacity of 1, want fewer than %d", forgetfulStats.RepeatsSkipped, stats.RepeatsSkipped)
+	}
+
+	if _, err := CreateGeneratorE(4, WithDedupeCapacity(0)); err == nil {
+		t.Error("CreateGeneratorE() with a dedupe capacity of 0 succeeded, want an error")
+	}
+}
@@ -0,0 +1,48 @@
+package xwgen
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// WithIterativeDeepening makes the search explore the choices of every line to a depth of one
+// choice, then starts over allowing one more, and so on until a round is not cut short by the
+// limit. Grids that need fewer choices are found first, and the search does not sink all of its
+// time into one deep subtree early on, at the cost of repeating the shallower levels every round.
+//
+// Grids found in an earlier round are not yielded again. Iterative deepening cannot be combined
+// with WithWorkers or WithRestarts.
+func WithIterativeDeepening() GeneratorOption {
+	return func(g *Generator) error {
+		g.iterativeDeepening = true
+		return nil
+	}
+}
+
+// searchIterativeDeepening is like searcher.grids, but searches root again with a depth limit one
+// greater than the last, for as long as the last search reached its limit.
+func (g *Generator) searchIterativeDeepening(ctx context.Context, root *gridState) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		// carried accumulates the statistics of the rounds since the last grid.
+		var carried SearchStats
+		for maxDepth := 1; ; maxDepth++ {
+			sr := &searcher{g: g, ctx: ctx, maxDepth: maxDepth}
+			last := time.Now()
+			for grid, stats := range sr.grids(root) {
+				stats.Add(carried)
+				carried = SearchStats{}
+				last = time.Now()
+				if !yield(grid, stats) {
+					return
+				}
+			}
+			if !sr.cutOff || ctx.Err() != nil {
+				return
+			}
+
+			carried.Add(sr.stats)
+			carried.Elapsed += time.Since(last)
+		}
+	}
+}
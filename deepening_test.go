@@ -0,0 +1,83 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithIterativeDeepening(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var subset []string
+	for i, word := range words {
+		if i%3 == 0 {
+			subset = append(subset, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(4, append(opts, WithPreferredWords(subset), WithSeed(42, 1024))...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		var grids []string
+		for grid := range gen.PossibleGrids(ctx) {
+			grids = append(grids, grid.Repr())
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		return grids
+	}
+
+	want := search()
+	if len(want) == 0 {
+		t.Fatal("expected at least one grid")
+	}
+	got := search(WithIterativeDeepening())
+
+	// Every grid is still found, and only once.
+	if len(got) != len(want) {
+		t.Errorf("got %d grids with iterative deepening, want %d", len(got), len(want))
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Error("got different grids with iterative deepening")
+	}
+}
+
+func TestWithIterativeDeepening_Timeout(t *testing.T) {
+	gen, err := CreateGeneratorE(5, WithPreferredWords(loadTrimmedWords(t)), WithIterativeDeepening())
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	for range gen.PossibleGrids(ctx) {
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("search took %v after a timeout of 200ms", elapsed)
+	}
+}
+
+func TestWithIterativeDeepening_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+	}{
+		{name: "workers", opts: []GeneratorOption{WithIterativeDeepening(), WithWorkers(2)}},
+		{name: "restarts", opts: []GeneratorOption{WithIterativeDeepening(), WithRestarts(LubyRestarts(10))}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CreateGeneratorE(4, tc.opts...); err == nil {
+				t.Error("CreateGeneratorE() succeeded, want an error")
+			}
+		})
+	}
+}
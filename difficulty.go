@@ -0,0 +1,164 @@
+package xwgen
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Weights of each component of a Difficulty, which add up to 1.
+const (
+	difficultyWeightObscureFraction = 0.4
+	difficultyWeightWordRarity      = 0.3
+	difficultyWeightLetterRarity    = 0.15
+	difficultyWeightCrossedObscure  = 0.15
+)
+
+// DifficultyBand is a coarse level of difficulty, for people choosing between grids.
+type DifficultyBand string
+
+const (
+	DifficultyEasy   DifficultyBand = "easy"
+	DifficultyMedium DifficultyBand = "medium"
+	DifficultyHard   DifficultyBand = "hard"
+)
+
+// Scores at which a grid moves from one DifficultyBand to the next. Without word scores, a grid of
+// preferred words with common letters is easy, one where a quarter of the words are obscure is
+// medium, and one where two fifths are is hard.
+const (
+	difficultyMediumScore = 0.1
+	difficultyHardScore   = 0.2
+)
+
+// ParseDifficultyBand returns the DifficultyBand named s, or an error if there is none.
+func ParseDifficultyBand(s string) (DifficultyBand, error) {
+	switch b := DifficultyBand(s); b {
+	case DifficultyEasy, DifficultyMedium, DifficultyHard:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown difficulty %q, expected %q, %q or %q", s, DifficultyEasy, DifficultyMedium, DifficultyHard)
+}
+
+// Difficulty is an estimate of how hard a grid will feel to solve, along with the components it is
+// made of. Each component is from 0 to 1, where higher is harder.
+type Difficulty struct {
+	// Score is the overall difficulty, from 0 to 1: the weighted mean of the other components.
+	Score float64 `json:"score"`
+
+	// ObscureFraction is the fraction of the words in the grid that are obscure.
+	ObscureFraction float64 `json:"obscure_fraction"`
+	// WordRarity is 1 minus the mean frequency score of the words, relative to the highest score
+	// of any word. Words without a score count as the rarest. It is 0, and left out of Score, if
+	// there are no word scores.
+	WordRarity float64 `json:"word_rarity"`
+	// LetterRarity is the mean Scrabble points of the letters, scaled from 1 point to 10.
+	LetterRarity float64 `json:"letter_rarity"`
+	// CrossedObscureLetters is the number of letters that are in both an obscure across entry and
+	// an obscure down entry, which a solver cannot get from an easier crossing entry.
+	CrossedObscureLetters int `json:"crossed_obscure_letters"`
+	// CrossedObscureFraction is the fraction of the letters that are CrossedObscureLetters.
+	CrossedObscureFraction float64 `json:"crossed_obscure_fraction"`
+}
+
+func (d Difficulty) String() string {
+	return fmt.Sprintf("%.2f, %s (obscure words: %.0f%%, word rarity: %.2f, letter rarity: %.2f, crossed obscure letters: %d)",
+		d.Score, d.Band(), 100*d.ObscureFraction, d.WordRarity, d.LetterRarity, d.CrossedObscureLetters)
+}
+
+// Band returns the DifficultyBand of d's score.
+func (d Difficulty) Band() DifficultyBand {
+	switch {
+	case d.Score >= difficultyHardScore:
+		return DifficultyHard
+	case d.Score >= difficultyMediumScore:
+		return DifficultyMedium
+	}
+	return DifficultyEasy
+}
+
+// EstimateDifficulty estimates how hard grid will feel to solve, from the fraction of its words
+// that are obscure, how rare its words are by wordScores, e.g. their frequencies, how rare its
+// letters are, and how many of its letters only obscure words cross. wordScores can be nil.
+//
+// Only grids created by a Generator have obscure words, so other grids are rated on their words
+// and letters alone.
+func EstimateDifficulty(grid Grid, wordScores map[string]float64) Difficulty {
+	d := Difficulty{
+		ObscureFraction: grid.ObscureWordFraction(),
+		LetterRarity:    (meanLetterPoints(grid) - 1) / 9,
+	}
+	d.LetterRarity = max(d.LetterRarity, 0)
+	d.CrossedObscureLetters, d.CrossedObscureFraction = crossedObscureLetters(grid)
+
+	total := difficultyWeightObscureFraction*d.ObscureFraction +
+		difficultyWeightLetterRarity*d.LetterRarity +
+		difficultyWeightCrossedObscure*d.CrossedObscureFraction
+	weights := difficultyWeightObscureFraction + difficultyWeightLetterRarity + difficultyWeightCrossedObscure
+	if rarity, ok := wordRarity(grid, wordScores); ok {
+		d.WordRarity = rarity
+		total += difficultyWeightWordRarity * rarity
+		weights += difficultyWeightWordRarity
+	}
+	d.Score = total / weights
+	return d
+}
+
+// wordRarity returns 1 minus the mean score of the words of grid, relative to the highest of
+// wordScores, or false if there are no positive scores or no words.
+func wordRarity(grid Grid, wordScores map[string]float64) (float64, bool) {
+	var highest float64
+	for _, score := range wordScores {
+		highest = max(highest, score)
+	}
+	words := grid.AllWords()
+	if len(words) == 0 {
+		for _, e := range grid.Entries() {
+			words = append(words, e.Answer)
+		}
+	}
+	if highest <= 0 || len(words) == 0 {
+		return 0, false
+	}
+	var total float64
+	for _, word := range words {
+		total += min(max(wordScores[word]/highest, 0), 1)
+	}
+	return 1 - total/float64(len(words)), true
+}
+
+// crossedObscureLetters returns the number of letters of grid in both an obscure across entry and
+// an obscure down entry, and the fraction of its letters they are.
+func crossedObscureLetters(grid Grid) (int, float64) {
+	width, height := grid.Size()
+	// inObscure counts, for each cell, the obscure entries it is in.
+	inObscure := make([]int, width*height)
+	for _, e := range grid.Entries() {
+		if !slices.Contains(grid.ObscureWords(), e.Answer) {
+			continue
+		}
+		dRow, dCol := 0, 1
+		if e.Direction == DirectionVertical {
+			dRow, dCol = 1, 0
+		}
+		for i := range e.Length {
+			inObscure[(e.Row+i*dRow)*width+e.Col+i*dCol]++
+		}
+	}
+
+	crossed, letters := 0, 0
+	for row := range height {
+		for col := range width {
+			if grid.IsBlocked(row, col) {
+				continue
+			}
+			letters++
+			if inObscure[row*width+col] == 2 {
+				crossed++
+			}
+		}
+	}
+	if letters == 0 {
+		return 0, 0
+	}
+	return crossed, float64(crossed) / float64(letters)
+}
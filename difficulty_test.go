@@ -0,0 +1,103 @@
+package xwgen
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateDifficulty(t *testing.T) {
+	// The letters a to i have 21 Scrabble points, and a, b, c, and d have 9.
+	const openLetterRarity, blockedLetterRarity = (21.0/9 - 1) / 9, (9.0/4 - 1) / 9
+
+	open := gridFromRows("abc", "def", "ghi")
+	obscure := gridFromRows("abc", "def", "ghi")
+	obscure.obscureWords = []string{"abc", "adg"}
+	blocked := gridFromRows("ab`", "cd`", "```")
+	blocked.obscureWords = []string{"ab", "ac"}
+
+	for _, tc := range []struct {
+		name       string
+		grid       Grid
+		wordScores map[string]float64
+		want       Difficulty
+		wantBand   DifficultyBand
+	}{
+		{
+			name: "Open",
+			grid: open,
+			want: Difficulty{
+				Score:        0.15 * openLetterRarity / 0.7,
+				LetterRarity: openLetterRarity,
+			},
+			wantBand: DifficultyEasy,
+		},
+		{
+			name:       "WordScores",
+			grid:       open,
+			wordScores: map[string]float64{"abc": 100, "def": 50, "xyz": 10},
+			want: Difficulty{
+				Score:        0.3*0.75 + 0.15*openLetterRarity,
+				WordRarity:   0.75,
+				LetterRarity: openLetterRarity,
+			},
+			wantBand: DifficultyHard,
+		},
+		{
+			name: "CrossedObscure",
+			grid: obscure,
+			want: Difficulty{
+				Score:                  (0.4/3 + 0.15*openLetterRarity + 0.15/9) / 0.7,
+				ObscureFraction:        1.0 / 3,
+				LetterRarity:           openLetterRarity,
+				CrossedObscureLetters:  1,
+				CrossedObscureFraction: 1.0 / 9,
+			},
+			wantBand: DifficultyHard,
+		},
+		{
+			name: "Blocked",
+			grid: blocked,
+			want: Difficulty{
+				Score:                  (0.4*0.5 + 0.15*blockedLetterRarity + 0.15*0.25) / 0.7,
+				ObscureFraction:        0.5,
+				LetterRarity:           blockedLetterRarity,
+				CrossedObscureLetters:  1,
+				CrossedObscureFraction: 0.25,
+			},
+			wantBand: DifficultyHard,
+		},
+		{
+			name:     "AllBlocked",
+			grid:     gridFromRows("``", "``"),
+			want:     Difficulty{},
+			wantBand: DifficultyEasy,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := EstimateDifficulty(tc.grid, tc.wordScores)
+			near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+			if !near(got.Score, tc.want.Score) || !near(got.ObscureFraction, tc.want.ObscureFraction) ||
+				!near(got.WordRarity, tc.want.WordRarity) || !near(got.LetterRarity, tc.want.LetterRarity) ||
+				got.CrossedObscureLetters != tc.want.CrossedObscureLetters || !near(got.CrossedObscureFraction, tc.want.CrossedObscureFraction) {
+				t.Errorf("EstimateDifficulty() = %+v, want %+v", got, tc.want)
+			}
+			if band := got.Band(); band != tc.wantBand {
+				t.Errorf("EstimateDifficulty().Band() = %q, want %q", band, tc.wantBand)
+			}
+			if again := EstimateDifficulty(tc.grid, tc.wordScores); again != got {
+				t.Errorf("EstimateDifficulty() = %+v, then %+v", got, again)
+			}
+		})
+	}
+}
+
+func TestParseDifficultyBand(t *testing.T) {
+	for _, band := range []DifficultyBand{DifficultyEasy, DifficultyMedium, DifficultyHard} {
+		if got, err := ParseDifficultyBand(string(band)); err != nil || got != band {
+			t.Errorf("ParseDifficultyBand(%q) = %q, %v", band, got, err)
+		}
+	}
+	if _, err := ParseDifficultyBand("brutal"); err == nil {
+		t.Error("ParseDifficultyBand(\"brutal\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,21 @@
+// Package xwgen generates crossword grids from word lists.
+//
+// A Generator starts with every row and column able to hold any line of words and blocks, and
+// repeatedly narrows one line down, filtering the lines that cross it to match, until every line
+// is decided. By default it does so depth-first: it follows one choice all the way down before
+// trying the next, so it only holds the choices on the current path in memory, and searched to
+// completion it finds every grid. Its weakness is that an early bad choice can trap it in a
+// subtree with no grids for a long time. Several options trade that off differently:
+//
+//   - WithRestarts abandons a subtree after too many backtracks and starts over with the words in
+//     a new order, still eventually finding every grid.
+//   - WithIterativeDeepening explores every choice to a limited depth before going deeper, finding
+//     grids that need fewer choices first, at the cost of repeating the top of the search.
+//   - WithBeamSearch explores one level of choices at a time, keeping only the most promising
+//     points at each level, those with the fewest ways left to fill in their lines. It never holds
+//     more than twice its width of them, and does not dwell in any subtree, but it is incomplete:
+//     the grids below the points it drops are never found, and it cannot tell whether a grid
+//     exists. The wider the beam, the more grids it finds, and the more memory and time it takes
+//     per level.
+//   - WithWorkers splits the depth-first search across goroutines.
+package xwgen
@@ -0,0 +1,95 @@
+package xwgen
+
+import (
+	"slices"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// removeDefiniteWords removes the words each line of state definitely holds from every other line,
+// since no word may appear twice in a grid. A line left with a single possibility by the removal,
+// or earlier by the letters crossing it, has its words removed from the others in turn, until no
+// line gains a definite word.
+//
+// It returns false if some word is definitely held twice, when no grid is possible, along with the
+// choices to blame. Lines it narrows depend on every choice that decided a word.
+func removeDefiniteWords(state *gridState) (conflictSet, bool) {
+	type owner struct {
+		dir   Direction
+		index int
+	}
+	axes := []struct {
+		dir   Direction
+		lines []primitives.PossibleLines
+		whys  []conflictSet
+	}{
+		{DirectionHorizontal, state.across, state.acrossWhy},
+		{DirectionVertical, state.down, state.downWhy},
+	}
+	tracking := state.acrossWhy != nil
+
+	for {
+		// words holds every definite word, and owners the line holding each. A grid has few enough
+		// words that searching them is quicker than hashing them.
+		var words []string
+		var owners []owner
+		var why conflictSet
+		for _, axis := range axes {
+			for i, line := range axis.lines {
+				definite := line.DefiniteWords()
+				for _, word := range definite {
+					if j := slices.Index(words, word); j >= 0 {
+						if !tracking {
+							return allLevels, false
+						}
+						return state.whyOf(owners[j].dir, owners[j].index).union(axis.whys[i]), false
+					}
+					words = append(words, word)
+					owners = append(owners, owner{dir: axis.dir, index: i})
+				}
+				if tracking && len(definite) > 0 {
+					why = why.union(axis.whys[i])
+				}
+			}
+		}
+		if len(words) == 0 {
+			return conflictSet{}, true
+		}
+
+		decided := false
+		for _, axis := range axes {
+			for i, line := range axis.lines {
+				if line.MaxPossibilities() <= 1 {
+					continue
+				}
+				definite := line.DefiniteWords()
+				others := words
+				if len(definite) > 0 {
+					others = nil
+					for j, word := range words {
+						if owners[j] != (owner{dir: axis.dir, index: i}) {
+							others = append(others, word)
+						}
+					}
+				}
+				narrowed := line.RemoveWordOptions(others)
+				if narrowed == line {
+					continue
+				}
+				axis.lines[i] = narrowed
+				if tracking {
+					axis.whys[i] = axis.whys[i].union(why)
+				}
+				if impossible(narrowed) {
+					return conflictSet{}, true
+				}
+				if len(narrowed.DefiniteWords()) > len(definite) {
+					decided = true
+				}
+			}
+		}
+		if !decided {
+			return conflictSet{}, true
+		}
+	}
+}
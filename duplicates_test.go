@@ -0,0 +1,56 @@
+package xwgen
+
+import (
+	"testing"
+)
+
+// checkNoRepeatedWords fails t if any word of grid appears more than once.
+func checkNoRepeatedWords(t *testing.T, grid Grid) {
+	t.Helper()
+	seen := make(map[string]bool)
+	for _, word := range grid.AllWords() {
+		if seen[word] {
+			t.Errorf("%q appears more than once in grid:\n%s", word, grid.Repr())
+		}
+		seen[word] = true
+	}
+}
+
+func TestNoRepeatedWords(t *testing.T) {
+	// The only ways to fill a 4x4 grid with these words are
+	//
+	//	` s t s      ` t o r
+	//	t e e s      s e p t
+	//	o p e d      t e e s
+	//	r t s `      s s d `
+	//
+	// which both repeat "tees". Crossing letters alone decide the rows and columns holding it, so
+	// it is never chosen for either.
+	words := []string{"oped", "rts", "sept", "ssd", "sts", "tees", "tor"}
+	for seed := range uint64(5) {
+		gen, err := CreateGeneratorE(4, WithPreferredWords(words), WithSeed(seed, 1024))
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		for grid := range gen.PossibleGrids(t.Context()) {
+			t.Errorf("PossibleGrids() yielded grid:\n%s\nwant none", grid.Repr())
+		}
+	}
+}
+
+func TestNoRepeatedWords_Generated(t *testing.T) {
+	words := loadTrimmedWords(t)
+	for _, size := range []int{3, 4, 5} {
+		gen, err := CreateGeneratorE(size, WithPreferredWords(words), WithSeed(7, uint64(size)))
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		n := 0
+		for grid := range gen.PossibleGrids(t.Context()) {
+			checkNoRepeatedWords(t, grid)
+			if n++; n >= 100 {
+				break
+			}
+		}
+	}
+}
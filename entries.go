@@ -0,0 +1,79 @@
+package xwgen
+
+import "strings"
+
+// Entry is a numbered word in a grid, i.e. a run of two or more letters between blocked cells, bars,
+// or the edges of the grid.
+type Entry struct {
+	Direction Direction
+	// Number is the entry's clue number. An across and a down entry that start at the same cell
+	// share a number.
+	Number int
+	// Row and Col are the position of the first letter of the entry.
+	Row, Col int
+	// Length is the number of cells of the entry, which is less than the length of Answer if it
+	// crosses a rebus cell.
+	Length int
+	Answer string
+}
+
+// Entries returns the entries of the grid in clue order: by number, with across before down.
+// Entries are numbered from the top left using standard crossword numbering, where each cell that
+// starts an across or down entry gets the next number.
+//
+// Unlike WordsAcross and WordsDown, Entries is computed from the cells of the grid, so it works for
+// any grid, and leaves out single letters.
+func (g Grid) Entries() []Entry {
+	width, height := g.Size()
+	open := func(row, col int) bool {
+		return row >= 0 && col >= 0 && row < height && col < width && !g.IsBlocked(row, col)
+	}
+	// joined returns true if the cell at row and col continues along dir into the next cell.
+	joined := func(row, col int, dir Direction) bool {
+		dRow, dCol := 0, 1
+		if dir == DirectionVertical {
+			dRow, dCol = 1, 0
+		}
+		return open(row, col) && open(row+dRow, col+dCol) && !g.barAfter(row, col, dir)
+	}
+	entry := func(number, row, col int, dir Direction) Entry {
+		dRow, dCol := 0, 1
+		if dir == DirectionVertical {
+			dRow, dCol = 1, 0
+		}
+		var b strings.Builder
+		length := 0
+		for r, c := row, col; ; r, c = r+dRow, c+dCol {
+			if s := g.Rebus(r, c); s != "" {
+				b.WriteString(s)
+			} else {
+				b.WriteRune(g.Cell(r, c))
+			}
+			length++
+			if !joined(r, c, dir) {
+				break
+			}
+		}
+		return Entry{Direction: dir, Number: number, Row: row, Col: col, Length: length, Answer: b.String()}
+	}
+
+	var entries []Entry
+	number := 0
+	for row := range height {
+		for col := range width {
+			startsAcross := !joined(row, col-1, DirectionHorizontal) && joined(row, col, DirectionHorizontal)
+			startsDown := !joined(row-1, col, DirectionVertical) && joined(row, col, DirectionVertical)
+			if !startsAcross && !startsDown {
+				continue
+			}
+			number++
+			if startsAcross {
+				entries = append(entries, entry(number, row, col, DirectionHorizontal))
+			}
+			if startsDown {
+				entries = append(entries, entry(number, row, col, DirectionVertical))
+			}
+		}
+	}
+	return entries
+}
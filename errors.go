@@ -0,0 +1,76 @@
+package xwgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+var (
+	// ErrNoGridsPossible means a search was exhausted without finding any grid, i.e. no grid
+	// satisfies the generator's word lists and constraints.
+	ErrNoGridsPossible = errors.New("no grids are possible")
+	// ErrSearchIncomplete means a search that skips part of the search space, e.g. with
+	// WithBeamSearch, ended without finding any grid. Unlike ErrNoGridsPossible, grids may still
+	// exist.
+	ErrSearchIncomplete = errors.New("the search skipped possible grids without finding any")
+	// ErrTimeout means a search's context deadline passed before the search was exhausted.
+	ErrTimeout = errors.New("timed out before the search was exhausted")
+)
+
+// Err returns why the most recent search of the generator ended, once its sequence has finished:
+//
+//   - nil if it was exhausted after yielding at least one grid, or the caller stopped it early;
+//   - ErrNoGridsPossible if it was exhausted without yielding any grid;
+//   - ErrSearchIncomplete if it ended without yielding any grid, but skipped part of the search;
+//   - an error wrapping both ErrTimeout and context.DeadlineExceeded if its context's deadline
+//     passed first, whether or not any grids were yielded;
+//   - the context's error if its context was otherwise cancelled first.
+//
+// Err is not meaningful while a search is running, or if several searches run concurrently.
+func (g *Generator) Err() error {
+	g.errMu.Lock()
+	defer g.errMu.Unlock()
+	return g.err
+}
+
+func (g *Generator) setErr(err error) {
+	g.errMu.Lock()
+	defer g.errMu.Unlock()
+	g.err = err
+}
+
+// searchErr returns the error for a search with ctx that ran to completion, or was stopped because
+// ctx is done.
+func searchErr(ctx context.Context, found bool) error {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case err != nil:
+		return err
+	case !found:
+		return ErrNoGridsPossible
+	}
+	return nil
+}
+
+// recordErr sets the generator's error once grids has finished, unless the caller stopped early.
+// incomplete is set by then if the search skipped part of the search space.
+func (g *Generator) recordErr(ctx context.Context, grids iter.Seq2[Grid, SearchStats], incomplete *bool) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		g.setErr(nil)
+		found := false
+		for grid, stats := range grids {
+			found = true
+			if !yield(grid, stats) {
+				return
+			}
+		}
+		err := searchErr(ctx, found)
+		if errors.Is(err, ErrNoGridsPossible) && *incomplete {
+			err = ErrSearchIncomplete
+		}
+		g.setErr(err)
+	}
+}
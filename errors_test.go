@@ -0,0 +1,59 @@
+package xwgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Err(t *testing.T) {
+	// The only grids of these words are "abs/bae/arc" and its transpose.
+	square := []string{"abs", "bae", "arc", "aba", "bar", "sec"}
+
+	expired, cancel := context.WithDeadline(t.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	cancelled, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	for _, tc := range []struct {
+		name      string
+		words     []string
+		ctx       context.Context
+		stopAfter int
+		wantGrids bool
+		wantErr   []error
+	}{
+		{name: "exhausted", words: square, ctx: t.Context(), wantGrids: true},
+		{name: "stopped early", words: square, ctx: t.Context(), stopAfter: 1, wantGrids: true},
+		{name: "no grids", words: square[:5], ctx: t.Context(), wantErr: []error{ErrNoGridsPossible}},
+		{name: "timeout", words: square, ctx: expired, wantErr: []error{ErrTimeout, context.DeadlineExceeded}},
+		{name: "cancelled", words: square, ctx: cancelled, wantErr: []error{context.Canceled}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			gen := CreateGenerator(3, tc.words, nil, nil, rng, GeneratorParams{})
+
+			count := 0
+			for range gen.PossibleGrids(tc.ctx) {
+				if count++; count == tc.stopAfter {
+					break
+				}
+			}
+
+			if gotGrids := count > 0; gotGrids != tc.wantGrids {
+				t.Errorf("got %d grids, want grids: %v", count, tc.wantGrids)
+			}
+			err := gen.Err()
+			if tc.wantErr == nil && err != nil {
+				t.Errorf("Err() = %v, want nil", err)
+			}
+			for _, want := range tc.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("Err() = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
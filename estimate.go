@@ -0,0 +1,110 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// Estimate describes how large the search of a generator could be, before searching it. See
+// Generator.Estimate.
+type Estimate struct {
+	// Across and Down describe the possible lines of each row, from top to bottom, and each column,
+	// from left to right.
+	Across, Down []LineEstimate
+	// Cells holds, for each row and column, the characters that both lines crossing at the cell
+	// allow there after propagation, including primitives.Blocked if the cell can be blocked.
+	Cells [][]primitives.CharSet
+	// UpperBound is an upper bound on the number of grids: the product of the possibilities of
+	// every row after propagation, or of every column if that is smaller. It is usually far too
+	// high, but is 0 if some line has no possibilities.
+	UpperBound float64
+}
+
+// LineEstimate describes the possible lines of a row or column.
+type LineEstimate struct {
+	// Initial is MaxPossibilities of the line before propagation, and Propagated after it.
+	Initial, Propagated int64
+}
+
+// Problems describes the rows and columns that no line fits after propagation, and the cells that
+// no character fits, which mean there are no grids at all. It is empty if the grid may be fillable.
+func (e *Estimate) Problems() []string {
+	var problems []string
+	for y, line := range e.Across {
+		if line.Propagated == 0 {
+			problems = append(problems, fmt.Sprintf("no words fit row %d", y))
+		}
+	}
+	for x, line := range e.Down {
+		if line.Propagated == 0 {
+			problems = append(problems, fmt.Sprintf("no words fit column %d", x))
+		}
+	}
+	for y, row := range e.Cells {
+		for x, cell := range row {
+			if cell.Count() == 0 && e.Across[y].Propagated > 0 && e.Down[x].Propagated > 0 {
+				problems = append(problems, fmt.Sprintf("no letter fits both row %d and column %d", y, x))
+			}
+		}
+	}
+	return problems
+}
+
+// Estimate returns the number of possible lines of every row and column before and after one
+// round of constraint propagation, where the rows are filtered by the letters the columns allow and
+// then the columns by the rows, the characters that fit each cell after that, and a rough upper
+// bound on the number of grids. It is quick compared to a search, so it can tell whether a search
+// is worth starting; see Estimate.Problems.
+//
+// It returns an error only if ctx is done before the lines are built.
+func (g *Generator) Estimate(ctx context.Context) (*Estimate, error) {
+	acrossLines, err := g.allPossibleLines(ctx, g.LineLength)
+	if err != nil {
+		return nil, err
+	}
+	downLines, err := g.allPossibleLines(ctx, g.Height)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := g.lineOverrides(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	initial := g.stateFromLines(acrossLines, downLines, overrides)
+	propagated := initial.clone()
+	for _, dir := range []Direction{DirectionHorizontal, DirectionVertical} {
+		*propagated, _ = prefilter(ctx, *propagated, dir, g.propagationWorkers)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e := &Estimate{
+		Across: make([]LineEstimate, g.Height),
+		Down:   make([]LineEstimate, g.LineLength),
+		Cells:  make([][]primitives.CharSet, g.Height),
+	}
+	acrossBound, downBound := 1.0, 1.0
+	for y := range e.Across {
+		e.Across[y] = LineEstimate{Initial: initial.across[y].MaxPossibilities(), Propagated: propagated.across[y].MaxPossibilities()}
+		acrossBound *= float64(e.Across[y].Propagated)
+	}
+	for x := range e.Down {
+		e.Down[x] = LineEstimate{Initial: initial.down[x].MaxPossibilities(), Propagated: propagated.down[x].MaxPossibilities()}
+		downBound *= float64(e.Down[x].Propagated)
+	}
+	e.UpperBound = min(acrossBound, downBound)
+
+	for y := range e.Cells {
+		e.Cells[y] = make([]primitives.CharSet, g.LineLength)
+		for x := range e.Cells[y] {
+			var down primitives.CharSet
+			propagated.across[y].CharsAt(&e.Cells[y][x], x)
+			propagated.down[x].CharsAt(&down, y)
+			e.Cells[y][x].Intersect(&down)
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,54 @@
+package xwgen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	var words []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			words = append(words, word)
+		}
+	}
+	gen, err := CreateGeneratorE(3, WithPreferredWords(words), WithSeed(42, 1024))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	e, err := gen.Estimate(t.Context())
+	if err != nil {
+		t.Fatalf("Estimate() error: %v", err)
+	}
+	if problems := e.Problems(); len(problems) > 0 {
+		t.Errorf("Problems() = %q, want none", problems)
+	}
+	for i, line := range slices.Concat(e.Across, e.Down) {
+		if line.Propagated > line.Initial || line.Propagated == 0 {
+			t.Errorf("line %d has %d possibilities after propagation, and %d before", i, line.Propagated, line.Initial)
+		}
+	}
+	for y, row := range e.Cells {
+		for x, cell := range row {
+			if cell.Count() == 0 {
+				t.Errorf("no characters fit cell (%d, %d)", y, x)
+			}
+		}
+	}
+	if n, _ := gen.CountGrids(t.Context(), 0); e.UpperBound < float64(n) {
+		t.Errorf("UpperBound = %g, want at least the %d grids", e.UpperBound, n)
+	}
+
+	// No row can be a column, since their first letters don't match.
+	dead, err := CreateGeneratorE(3, WithPreferredWords([]string{"abc", "def"}), WithMaxBlocks(0))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	e, err = dead.Estimate(t.Context())
+	if err != nil {
+		t.Fatalf("Estimate() error: %v", err)
+	}
+	if e.UpperBound != 0 || len(e.Problems()) == 0 {
+		t.Errorf("Estimate() of a dead setup has an upper bound of %g and problems %q, want 0 and some problems", e.UpperBound, e.Problems())
+	}
+}
@@ -0,0 +1,51 @@
+package xwgen
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithWordFrequencies sets the frequency score of words, e.g. as read by wordlist.Read, where
+// higher scores are more common words. Words without a score have a score of 0. Frequencies only
+// affect the search with WithFrequencyBias.
+func WithWordFrequencies(frequencies map[string]float64) GeneratorOption {
+	return func(g *Generator) error {
+		g.frequencies = frequencies
+		return nil
+	}
+}
+
+// WithFrequencyBias tries higher-frequency words before lower-frequency ones, so that the first
+// grids generated use more common words. weight, between 0 and 1, is how strongly frequency
+// overrides the order of the word lists: at 0 frequency is ignored, and at 1 preferred and obscure
+// words are each tried in order of descending frequency.
+//
+// Preferred words are still tried before obscure words, and required words before both.
+// CreateGeneratorE returns an error if a non-zero weight is used without WithWordFrequencies.
+func WithFrequencyBias(weight float64) GeneratorOption {
+	return func(g *Generator) error {
+		if weight < 0 || weight > 1 {
+			return fmt.Errorf("frequency bias must be between 0 and 1, got %v", weight)
+		}
+		g.frequencyBias = weight
+		return nil
+	}
+}
+
+// WithWordPenalty tries words with a higher penalty after those with a lower one, e.g. to avoid
+// words used in recent grids with history.Penalty. Words with a penalty of +Inf are never used.
+// Words with the same penalty keep the order they would otherwise have, e.g. with
+// WithFrequencyBias.
+//
+// Preferred words are still tried before obscure words, and required words before both, whatever
+// their penalty. penalty may be called more than once for the same word, and must return the same
+// penalty each time.
+func WithWordPenalty(penalty func(word string) float64) GeneratorOption {
+	return func(g *Generator) error {
+		if penalty == nil {
+			return errors.New("word penalty must not be nil")
+		}
+		g.wordPenalty = penalty
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// fakeFrequencies gives each word a score between 0 and 100 derived from its hash, as the test
+// word list has no frequencies.
+func fakeFrequencies(words []string) map[string]float64 {
+	frequencies := make(map[string]float64, len(words))
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		frequencies[word] = float64(h.Sum32() % 100)
+	}
+	return frequencies
+}
+
+// meanFrequency returns the mean frequency of the words of the first n grids generated with the
+// given frequency bias.
+func meanFrequency(tb testing.TB, words []string, frequencies map[string]float64, bias float64, n int) float64 {
+	tb.Helper()
+	gen, err := CreateGeneratorE(5,
+		WithPreferredWords(words),
+		WithWordFrequencies(frequencies),
+		WithFrequencyBias(bias),
+		WithRand(rand.New(rand.NewPCG(42, 1024))),
+	)
+	if err != nil {
+		tb.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, count, grids := 0.0, 0, 0
+	for grid := range gen.PossibleGrids(ctx) {
+		for _, word := range grid.AllWords() {
+			total += frequencies[word]
+			count++
+		}
+		if grids++; grids >= n {
+			break
+		}
+	}
+	if count == 0 {
+		tb.Fatal("expected at least one grid")
+	}
+	return total / float64(count)
+}
+
+func TestWithFrequencyBias(t *testing.T) {
+	words := loadTrimmedWords(t)
+	frequencies := fakeFrequencies(words)
+
+	unbiased := meanFrequency(t, words, frequencies, 0, 20)
+	biased := meanFrequency(t, words, frequencies, 1, 20)
+	if biased <= unbiased {
+		t.Errorf("mean word frequency with bias 1 = %.1f, want more than %.1f without bias", biased, unbiased)
+	}
+}
+
+func TestWithFrequencyBias_Invalid(t *testing.T) {
+	for _, weight := range []float64{-0.1, 1.1} {
+		if err := WithFrequencyBias(weight)(&Generator{}); err == nil {
+			t.Errorf("expected an error for weight %v", weight)
+		}
+	}
+	if _, err := CreateGeneratorE(4, WithFrequencyBias(0.5)); err == nil {
+		t.Error("expected an error for a frequency bias without frequencies")
+	}
+}
+
+// BenchmarkFrequencyBias reports the mean frequency of the words in the first grids generated at
+// each bias, as a measure of how much the bias improves them.
+func BenchmarkFrequencyBias(b *testing.B) {
+	words := loadTrimmedWords(b)
+	frequencies := fakeFrequencies(words)
+
+	for _, bias := range []float64{0, 0.5, 1} {
+		b.Run(fmt.Sprintf("bias=%v", bias), func(b *testing.B) {
+			var mean float64
+			for b.Loop() {
+				mean = meanFrequency(b, words, frequencies, bias, 20)
+			}
+			b.ReportMetric(mean, "mean_frequency")
+		})
+	}
+}
+
+func TestWithWordPenalty(t *testing.T) {
+	words := loadTrimmedWords(t)
+	generate := func(opts ...GeneratorOption) []Grid {
+		gen, err := CreateGeneratorE(5, append([]GeneratorOption{WithPreferredWords(words), WithSeed(42, 1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var grids []Grid
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if grids = append(grids, grid); len(grids) >= 20 {
+				break
+			}
+		}
+		return grids
+	}
+	meanPenalty := func(grids []Grid, penalty map[string]float64) float64 {
+		total, count := 0.0, 0
+		for _, grid := range grids {
+			for _, word := range grid.AllWords() {
+				total += penalty[word]
+				count++
+			}
+		}
+		return total / float64(count)
+	}
+
+	penalties := fakeFrequencies(words)
+	penalty := func(word string) float64 { return penalties[word] }
+	unpenalized := meanPenalty(generate(), penalties)
+	penalized := meanPenalty(generate(WithWordPenalty(penalty)), penalties)
+	if penalized >= unpenalized {
+		t.Errorf("mean word penalty with WithWordPenalty = %.1f, want less than %.1f without", penalized, unpenalized)
+	}
+
+	t.Run("excluded", func(t *testing.T) {
+		used := make(map[string]bool)
+		for _, word := range generate()[0].AllWords() {
+			used[word] = true
+		}
+		exclude := func(word string) float64 {
+			if used[word] {
+				return math.Inf(1)
+			}
+			return 0
+		}
+		grids := generate(WithWordPenalty(exclude))
+		if len(grids) == 0 {
+			t.Fatal("got no grids excluding the words of the first grid")
+		}
+		for _, grid := range grids {
+			for _, word := range grid.AllWords() {
+				if used[word] {
+					t.Errorf("grid has excluded word %q:\n%s", word, grid.Repr())
+				}
+			}
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if _, err := CreateGeneratorE(4, WithWordPenalty(nil)); err == nil {
+			t.Error("CreateGeneratorE() with a nil word penalty succeeded, want an error")
+		}
+	})
+}
@@ -1,10 +1,17 @@
 package xwgen
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"iter"
 	"math/rand/v2"
 	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/Eyas/xwgen/internal"
 	"github.com/Eyas/xwgen/pkg/primitives"
@@ -19,7 +26,11 @@ const (
 )
 
 type Generator struct {
-	LineLength     int
+	// LineLength is the width of the grid, i.e. the length of each across line.
+	LineLength int
+	// Height is the height of the grid, i.e. the length of each down line.
+	Height int
+
 	PreferredWords []string
 	ObscureWords   []string
 	ExcludedWords  []string
@@ -27,46 +38,426 @@ type Generator struct {
 	MaxWordLength  *int
 
 	rand *rand.Rand
+	// pcg is the source of rand, unless it was set by WithRand. Searches can only be checkpointed
+	// if it is known, and initialRand is its state when the generator was created.
+	pcg         *rand.PCG
+	initialRand []byte
+
+	// symmetries that the blocked cells of each grid must have.
+	symmetries []symmetry
+	// progress, if set, is updated as searches run.
+	progress *Progress
+	// progressCallback, if set, is called periodically as searches run.
+	progressCallback *progressCallback
+	// best is the partial grid with the most decided cells seen by the current search.
+	best bestPartial
+	// dedupeCapacity, if positive, is the number of grids each search remembers to skip repeats
+	// of, rather than defaultDedupeCapacity.
+	dedupeCapacity int
+	// stats, if set, accumulates statistics of every search.
+	stats *Stats
+	// workers is the number of goroutines to search with.
+	workers int
+	// propagationWorkers is the number of goroutines to filter lines on. See
+	// WithParallelPropagation.
+	propagationWorkers int
+	// memoizeFilters caches the results of filtering lines. See WithMemoizedFilters.
+	memoizeFilters bool
+	// consistencyChecks validates every line of every point of the search. See
+	// WithConsistencyChecks.
+	consistencyChecks bool
+	// maxBlockFraction, if non-zero, is the largest fraction of cells that can be blocked.
+	maxBlockFraction float64
+	// maxBlocks, if set, is the largest number of cells that can be blocked.
+	maxBlocks *int
+	// noUncheckedSquares requires every letter to be part of an across and a down word.
+	noUncheckedSquares bool
+	// noBackjumping makes the search backtrack chronologically. See WithoutBackjumping.
+	noBackjumping bool
+	// restarts, if set, makes the search start over after too many backtracks. See WithRestarts.
+	restarts RestartPolicy
+	// gridTimeout, if positive, is how long the search may spend on each grid before it starts
+	// over. See WithGridTimeout.
+	gridTimeout time.Duration
+	// iterativeDeepening searches with a growing depth limit. See WithIterativeDeepening.
+	iterativeDeepening bool
+	// beamWidth, if positive, is the number of points of the search kept at each level. See
+	// WithBeamSearch.
+	beamWidth int
+	// maxObscureFraction, if set, is the largest fraction of each grid's words that can be obscure.
+	maxObscureFraction *float64
+	// minPreferredRatio, if set, is the smallest fraction of each grid's words that must be
+	// preferred.
+	minPreferredRatio *float64
+	// requiredWords, if set, must include at least one word of each grid.
+	requiredWords []string
+	// lineSelector, if set, chooses the line to branch on. The default is MostConstrained.
+	lineSelector LineSelector
+	// partial, if set, is a partial grid that every grid completes.
+	partial [][]rune
+	// pinnedCells are cells that every grid has. See WithCell and WithBlockedCell.
+	pinnedCells []pinnedCell
+	// rebusCells are cells that hold one of several strings in every grid. See WithRebusCell.
+	rebusCells []rebusCell
+	// bars, if set, separate the entries of every grid in place of blocked cells. See WithBars.
+	bars *Bars
+	// frequencies are the frequency scores of words, and frequencyBias how strongly they order
+	// the words tried.
+	frequencies   map[string]float64
+	frequencyBias float64
+	// wordPenalty, if set, orders the words tried and excludes some. See WithWordPenalty.
+	wordPenalty func(string) float64
+	// scorer, if set, rates grids. See WithScorer.
+	scorer Scorer
+	// letterVariety explores words bringing new letters first. See WithLetterVariety.
+	letterVariety bool
+	// sampling explores a randomly sampled line's half of each split first. See WithSampling.
+	sampling bool
+	// lineValidators must all accept every decided line. See WithLineValidator.
+	lineValidators []func(primitives.ConcreteLine, LineContext) bool
+	// bannedSequences and banQWithoutU may not appear in any line. See WithBannedSequences.
+	bannedSequences []string
+	banQWithoutU    bool
+
+	// resume, if set, is the checkpoint the next search continues from.
+	resume *checkpoint
+
+	// errMu guards err, the error of the most recent search, and interrupted, its checkpoint.
+	errMu       sync.Mutex
+	err         error
+	interrupted *interruption
+
+	// dictionary, if set, holds the words of every line, shared with the generators of other
+	// sizes. See GenerateBatch.
+	dictionary *internal.Dictionary
 
 	// Do not access this field directly, use the allPossibleLines method instead.
-	lazyAllPossibleLines primitives.PossibleLines
+	lazyAllPossibleLines map[int]primitives.PossibleLines
+	// Do not access this field directly, use the initialState method instead.
+	lazyInitialState *gridState
+	// Do not access this field directly, use the isObscure method instead.
+	lazyObscureWords map[string]bool
+	// Do not access this field directly, use the unbannedWords method instead.
+	lazyUnbannedWords *[2][]string
 }
 
+// GeneratorParams are the word length bounds and height passed to CreateGenerator.
+//
+// Deprecated: Use WithMinWordLength, WithMaxWordLength, and WithHeight with CreateGeneratorE.
 type GeneratorParams struct {
 	MinWordLength int
 	MaxWordLength int
+	// Height is the height of the grid. If 0, the grid is square.
+	Height int
 }
 
-func CreateGenerator(lineLength int, preferredWords, obscureWords, excludedWords []string, rand *rand.Rand, params GeneratorParams) *Generator {
-	var minWordLength, maxWordLength *int
-	if params.MinWordLength > 0 {
-		minWordLength = &params.MinWordLength
+// GeneratorOption configures optional behavior of a Generator.
+type GeneratorOption func(*Generator) error
+
+// CreateGeneratorE creates a generator of size x size grids, configured by opts, e.g.
+//
+//	g, err := CreateGeneratorE(5, WithPreferredWords(words), WithRand(rng), WithRotationalSymmetry())
+//
+// It returns an error if size is not positive, if any of opts is invalid, or if the options
+// conflict, e.g. a minimum word length greater than the maximum. Without WithRand, grids are
+// generated in a time-seeded random order.
+func CreateGeneratorE(size int, opts ...GeneratorOption) (*Generator, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("grid size must be at least 1, got %d", size)
 	}
-	if params.MaxWordLength > 0 {
-		maxWordLength = &params.MaxWordLength
+	g := &Generator{LineLength: size, Height: size}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
 	}
-	return &Generator{
-		LineLength:     lineLength,
-		PreferredWords: preferredWords,
-		ObscureWords:   obscureWords,
-		ExcludedWords:  excludedWords,
-		MinWordLength:  minWordLength,
-		MaxWordLength:  maxWordLength,
-		rand:           rand,
+	if g.MinWordLength != nil && g.MaxWordLength != nil && *g.MinWordLength > *g.MaxWordLength {
+		return nil, fmt.Errorf("minimum word length %d is greater than maximum word length %d", *g.MinWordLength, *g.MaxWordLength)
+	}
+	if err := g.validateRequiredWords(); err != nil {
+		return nil, err
+	}
+	if err := g.validateBannedSequences(); err != nil {
+		return nil, err
+	}
+	if g.frequencyBias > 0 && g.frequencies == nil {
+		return nil, fmt.Errorf("frequency bias requires word frequencies")
+	}
+	if g.restarts != nil && g.workers > 1 {
+		return nil, fmt.Errorf("restarts cannot be combined with %d workers", g.workers)
+	}
+	if g.gridTimeout > 0 && (g.workers > 1 || g.iterativeDeepening || g.beamWidth > 0) {
+		return nil, fmt.Errorf("a grid timeout cannot be combined with workers, iterative deepening, or beam search")
+	}
+	if g.iterativeDeepening && (g.restarts != nil || g.workers > 1) {
+		return nil, fmt.Errorf("iterative deepening cannot be combined with restarts or workers")
+	}
+	if g.beamWidth > 0 && (g.restarts != nil || g.workers > 1 || g.iterativeDeepening) {
+		return nil, fmt.Errorf("beam search cannot be combined with restarts, workers, or iterative deepening")
+	}
+	if g.rand == nil {
+		now := time.Now()
+		g.pcg = rand.NewPCG(uint64(now.UnixNano()), uint64(now.Nanosecond()))
+		g.rand = rand.New(g.pcg)
+	}
+	if g.pcg != nil {
+		g.initialRand, _ = g.pcg.MarshalBinary()
+	}
+	if err := g.validatePinnedCells(); err != nil {
+		return nil, err
+	}
+	if err := g.validateRebusCells(); err != nil {
+		return nil, err
+	}
+	if err := g.validateBars(); err != nil {
+		return nil, err
+	}
+	if err := g.validatePartial(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// CreateGenerator creates a generator of grids lineLength cells wide. It panics if any of opts is
+// invalid.
+//
+// Deprecated: Use CreateGeneratorE, with WithPreferredWords, WithObscureWords, WithExcludedWords,
+// WithRand, WithMinWordLength, WithMaxWordLength, and WithHeight in place of the positional
+// arguments.
+func CreateGenerator(lineLength int, preferredWords, obscureWords, excludedWords []string, rand *rand.Rand, params GeneratorParams, opts ...GeneratorOption) *Generator {
+	// The positional arguments were never validated, so they are set as is rather than through
+	// the validating options.
+	positional := func(g *Generator) error {
+		g.PreferredWords = preferredWords
+		g.ObscureWords = obscureWords
+		g.ExcludedWords = excludedWords
+		g.rand = rand
+		if params.MinWordLength > 0 {
+			g.MinWordLength = &params.MinWordLength
+		}
+		if params.MaxWordLength > 0 {
+			g.MaxWordLength = &params.MaxWordLength
+		}
+		if params.Height > 0 {
+			g.Height = params.Height
+		}
+		return nil
+	}
+	g, err := CreateGeneratorE(lineLength, append([]GeneratorOption{positional}, opts...)...)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// WithPreferredWords sets the words that grids are filled with. Each word must consist only of
+// lowercase letters.
+func WithPreferredWords(words []string) GeneratorOption {
+	return func(g *Generator) error {
+		if err := validateWords("preferred", words); err != nil {
+			return err
+		}
+		g.PreferredWords = words
+		return nil
+	}
+}
+
+// WithObscureWords sets words that grids may use, but only after trying the preferred words. Each
+// word must consist only of lowercase letters.
+func WithObscureWords(words []string) GeneratorOption {
+	return func(g *Generator) error {
+		if err := validateWords("obscure", words); err != nil {
+			return err
+		}
+		g.ObscureWords = words
+		return nil
+	}
+}
+
+// WithExcludedWords sets words that never appear in a grid, even if they are preferred or obscure.
+// Each word must consist only of lowercase letters.
+func WithExcludedWords(words []string) GeneratorOption {
+	return func(g *Generator) error {
+		if err := validateWords("excluded", words); err != nil {
+			return err
+		}
+		g.ExcludedWords = words
+		return nil
+	}
+}
+
+// validateWords returns an error if any of words is empty or has a character other than a
+// lowercase letter.
+func validateWords(kind string, words []string) error {
+	for _, word := range words {
+		if word == "" {
+			return fmt.Errorf("%s words must not be empty", kind)
+		}
+		for _, r := range word {
+			if r < 'a' || r > 'z' {
+				return fmt.Errorf("%s word %q contains %q, want only lowercase letters", kind, word, r)
+			}
+		}
 	}
+	return nil
 }
 
-func (g *Generator) allPossibleLines(ctx context.Context) (primitives.PossibleLines, error) {
-	var err error
+// WithRand sets the source of randomness that determines the order in which grids are generated,
+// e.g. to generate the same grids in the same order on every run. Searches of a generator created
+// with WithRand cannot be checkpointed.
+func WithRand(r *rand.Rand) GeneratorOption {
+	return func(g *Generator) error {
+		if r == nil {
+			return fmt.Errorf("rand must not be nil")
+		}
+		g.rand, g.pcg = r, nil
+		return nil
+	}
+}
+
+// WithSeed is like WithRand, but generates grids from a PCG source seeded with seed1 and seed2.
+// Unlike with WithRand, searches can be checkpointed; see WriteCheckpoint.
+func WithSeed(seed1, seed2 uint64) GeneratorOption {
+	return func(g *Generator) error {
+		g.pcg = rand.NewPCG(seed1, seed2)
+		g.rand = rand.New(g.pcg)
+		return nil
+	}
+}
+
+// WithHeight makes grids n cells tall instead of square.
+func WithHeight(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("grid height must be at least 1, got %d", n)
+		}
+		g.Height = n
+		return nil
+	}
+}
+
+// WithMaxBlockFraction limits the blocked cells of each grid to at most the given fraction of all
+// cells, e.g. 1.0/6 for puzzle standards that allow no more than a sixth of cells to be blocked.
+// The fraction must be strictly between 0 and 1.
+//
+// Regardless of this option, grids are never more than 25% blocked.
+func WithMaxBlockFraction(fraction float64) GeneratorOption {
+	return func(g *Generator) error {
+		if fraction <= 0 || fraction >= 1 {
+			return fmt.Errorf("maximum block fraction must be between 0 and 1, got %v", fraction)
+		}
+		g.maxBlockFraction = fraction
+		return nil
+	}
+}
+
+// WithMaxBlocks limits each grid to at most n blocked cells, including any blocked cells of a
+// partial grid passed to PossibleGridsFrom. With n = 0, only grids without any blocked cells, i.e.
+// word squares, are generated.
+func WithMaxBlocks(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 0 {
+			return fmt.Errorf("maximum number of blocks must not be negative, got %d", n)
+		}
+		g.maxBlocks = &n
+		return nil
+	}
+}
+
+// WithMinWordLength excludes words shorter than n letters, so that every run of letters between
+// blocked cells in a grid is at least n long. The default is 3.
+func WithMinWordLength(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("minimum word length must be at least 1, got %d", n)
+		}
+		g.MinWordLength = &n
+		return nil
+	}
+}
+
+// WithMaxWordLength excludes words longer than n letters, so that every run of letters between
+// blocked cells in a grid is at most n long. The default is the length of the line.
+func WithMaxWordLength(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("maximum word length must be at least 1, got %d", n)
+		}
+		g.MaxWordLength = &n
+		return nil
+	}
+}
+
+// allPossibleLines returns all possible lines of the given length.
+func (g *Generator) allPossibleLines(ctx context.Context, lineLength int) (primitives.PossibleLines, error) {
+	if apl, ok := g.lazyAllPossibleLines[lineLength]; ok {
+		return apl, nil
+	}
+
+	apl, err := internal.AllPossibleLines(ctx, g.allPossibleLinesParams(lineLength))
+	if err != nil {
+		return nil, err
+	}
+	if g.memoizeFilters {
+		apl = primitives.MemoizedFilter(apl)
+	}
 	if g.lazyAllPossibleLines == nil {
-		g.lazyAllPossibleLines, err = internal.AllPossibleLines(ctx, internal.AllPossibleLinesParams{
-			LineLength:     g.LineLength,
-			PreferredWords: g.PreferredWords,
-			ObscureWords:   g.ObscureWords,
-			ExcludedWords:  g.ExcludedWords,
-		})
+		g.lazyAllPossibleLines = make(map[int]primitives.PossibleLines)
+	}
+	g.lazyAllPossibleLines[lineLength] = apl
+	return apl, nil
+}
+
+func (g *Generator) allPossibleLinesParams(lineLength int) internal.AllPossibleLinesParams {
+	minWordLength := g.minWordLength()
+	preferred, obscure := g.unbannedWords()
+	return internal.AllPossibleLinesParams{
+		LineLength:     lineLength,
+		RequiredWords:  g.requiredWords,
+		PreferredWords: preferred,
+		ObscureWords:   obscure,
+		ExcludedWords:  g.ExcludedWords,
+		MinWordLength:  &minWordLength,
+		MaxWordLength:  g.MaxWordLength,
+		Frequencies:    g.frequencies,
+		FrequencyBias:  g.frequencyBias,
+		Penalty:        g.wordPenalty,
+		Rand:           g.rand,
+		Dictionary:     g.dictionary,
+	}
+}
+
+// isObscure returns true if word is an obscure word that is not also a preferred or required word.
+func (g *Generator) isObscure(word string) bool {
+	if g.lazyObscureWords == nil {
+		g.lazyObscureWords = make(map[string]bool, len(g.ObscureWords))
+		for _, w := range g.ObscureWords {
+			g.lazyObscureWords[w] = true
+		}
+		for _, w := range g.PreferredWords {
+			delete(g.lazyObscureWords, w)
+		}
+		for _, w := range g.requiredWords {
+			delete(g.lazyObscureWords, w)
+		}
+	}
+	return g.lazyObscureWords[word]
+}
+
+// newGrid creates a grid from its rows and the words in it.
+func (g *Generator) newGrid(rows [][]rune, wordsAcross, wordsDown []string) Grid {
+	rebus := g.expandRebus(rows, wordsAcross, wordsDown)
+	grid := NewGrid(rows)
+	grid.wordsAcross = wordsAcross
+	grid.wordsDown = wordsDown
+	grid.rebus = rebus
+	grid.bars = g.bars
+	for _, word := range grid.AllWords() {
+		if g.isObscure(word) {
+			grid.obscureWords = append(grid.obscureWords, word)
+		}
 	}
-	return g.lazyAllPossibleLines, err
+	return grid
 }
 
 // gridState represents the state of a grid being generated so far.
@@ -74,9 +465,24 @@ type gridState struct {
 	down   []primitives.PossibleLines
 	across []primitives.PossibleLines
 
+	// level is the number of choices made to reach this state.
+	level int
+	// downWhy and acrossWhy hold the levels of the choices that narrowed each line, or are nil if
+	// backjumping is disabled.
+	downWhy   []conflictSet
+	acrossWhy []conflictSet
+
 	rand *rand.Rand
 }
 
+// clone returns a copy of s whose lines can be narrowed without affecting s.
+func (s *gridState) clone() *gridState {
+	c := *s
+	c.down, c.across = slices.Clone(s.down), slices.Clone(s.across)
+	c.downWhy, c.acrossWhy = slices.Clone(s.downWhy), slices.Clone(s.acrossWhy)
+	return &c
+}
+
 // getUndecidedIndexWLOG returns an index of an undecided line (i.e. a line that is not yet decided),
 // preferring to return the "least undecided" line (i.e. the line with the lest possible lines).
 func getUndecidedIndexWLOG(lines []primitives.PossibleLines, rand *rand.Rand) *int {
@@ -117,15 +523,9 @@ func getUndecidedIndexWLOG(lines []primitives.PossibleLines, rand *rand.Rand) *i
 	return &opts[0].idx
 }
 
-func (s gridState) getUndecidedIndexDown() *int {
-	return getUndecidedIndexWLOG(s.down, s.rand)
-}
-
-func (s gridState) getUndecidedIndexAcross() *int {
-	return getUndecidedIndexWLOG(s.across, s.rand)
-}
-
-func prefilter(ctx context.Context, s gridState, dir Direction) (gridState, bool) {
+// prefilter filters the lines in dir by the letters the crossing lines allow where they cross,
+// returning true if any line changed. It filters on up to workers goroutines at once.
+func prefilter(ctx context.Context, s gridState, dir Direction, workers int) (gridState, bool) {
 	if slices.ContainsFunc(s.down, impossible) || slices.ContainsFunc(s.across, impossible) {
 		return s, false
 	}
@@ -134,18 +534,22 @@ func prefilter(ctx context.Context, s gridState, dir Direction) (gridState, bool
 	}
 
 	var toFilter, constraint []primitives.PossibleLines
+	var toFilterWhy, constraintWhy []conflictSet
 	if dir == DirectionHorizontal {
-		toFilter = s.across
-		constraint = s.down
+		toFilter, toFilterWhy = s.across, s.acrossWhy
+		constraint, constraintWhy = s.down, s.downWhy
 	} else {
-		toFilter = s.down
-		constraint = s.across
+		toFilter, toFilterWhy = s.down, s.downWhy
+		constraint, constraintWhy = s.across, s.acrossWhy
 	}
 
 	// i and j here are abstracted wlog based on toFilter/constraint, not truly
 	// connected to Horizontal vs Vertical.
 	//
 	// available[i][j] is the set of characters that can be placed at (x, y) in the grid.
+	//
+	// This is computed before filtering any line, since CharsAt caches its results in the lines,
+	// which may be shared between lines filtered concurrently.
 	available := make([][]primitives.CharSet, len(constraint))
 	for i, constraintLine := range constraint {
 		available[i] = make([]primitives.CharSet, constraintLine.NumLetters())
@@ -156,96 +560,498 @@ func prefilter(ctx context.Context, s gridState, dir Direction) (gridState, bool
 		}
 	}
 
+	filter := func(j int) lineUpdate {
+		var why conflictSet
+		if toFilterWhy != nil {
+			why = toFilterWhy[j]
+		}
+		line, why := filterLine(toFilter[j], why, j, available, constraintWhy)
+		return lineUpdate{index: j, line: line, why: why}
+	}
+
 	anyChanged := false
-	for j := range toFilter {
-		tf := toFilter[j]
+	update := func(u lineUpdate) {
+		if u.line != toFilter[u.index] {
+			anyChanged = true
+			toFilter[u.index] = u.line
+		}
+		if toFilterWhy != nil {
+			toFilterWhy[u.index] = u.why
+		}
+	}
+	if workers > 1 {
+		for u := range filterLinesParallel(len(toFilter), workers, filter) {
+			update(u)
+		}
+	} else {
+		for j := range toFilter {
+			update(filter(j))
+		}
+	}
+
+	return s, anyChanged
+}
+
+// filterLine filters line, the jth of the lines being filtered, by the characters available where
+// each crossing line crosses it. It returns the filtered line, and why, the line's conflict set,
+// with those of the crossing lines that narrowed it added.
+func filterLine(line primitives.PossibleLines, why conflictSet, j int, available [][]primitives.CharSet, constraintWhy []conflictSet) (primitives.PossibleLines, conflictSet) {
+	// if all characters in available[i] are full, then the line cannot be filtered
+	// any further.
+	allFull := true
+	for i := range line.NumLetters() {
+		if !available[i][j].IsFull() {
+			allFull = false
+			break
+		}
+	}
+	if allFull {
+		return line, why
+	}
 
-		// if all characters in available[i] are full, then the line cannot be filtered
-		// any further.
-		allFull := true
-		for i := range tf.NumLetters() {
+	// Without conflict sets to track, every constraint is applied in a single pass over the words.
+	if constraintWhy == nil {
+		var constraints []primitives.IndexedCharSet
+		for i := range line.NumLetters() {
 			if !available[i][j].IsFull() {
-				allFull = false
-				break
+				constraints = append(constraints, primitives.IndexedCharSet{Index: i, Chars: available[i][j]})
 			}
 		}
-		if allFull {
-			continue
+		return line.FilterMulti(constraints), why
+	}
+
+	for i := range line.NumLetters() {
+		filtered := line.FilterAny(&available[i][j], i)
+		// The line now depends on whatever narrowed the crossing lines that constrained it.
+		if filtered != line {
+			why = why.union(constraintWhy[i])
 		}
+		line = filtered
+	}
+	return line, why
+}
+
+// lineUpdate is a line filtered by prefilter, and its conflict set.
+type lineUpdate struct {
+	index int
+	line  primitives.PossibleLines
+	why   conflictSet
+}
 
-		newTf := tf
-		for i := range tf.NumLetters() {
-			newTf = newTf.FilterAny(&available[i][j], i)
+// filterLinesParallel calls filter with each index below n on up to workers goroutines, sending
+// the results to the returned channel, which is closed once every line has been filtered. The
+// caller must receive every result.
+func filterLinesParallel(n, workers int, filter func(j int) lineUpdate) <-chan lineUpdate {
+	indices := make(chan int)
+	updates := make(chan lineUpdate)
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range indices {
+				updates <- filter(j)
+			}
+		}()
+	}
+	go func() {
+		for j := range n {
+			indices <- j
 		}
-		if newTf != tf {
-			anyChanged = true
-			toFilter[j] = newTf
+		close(indices)
+		wg.Wait()
+		close(updates)
+	}()
+	return updates
+}
+
+// initialState returns the root of the search, where every line can be any possible line that
+// matches the generator's partial grid and pinned cells, if any, and is arc consistent with the lines crossing it.
+func (g *Generator) initialState(ctx context.Context) (*gridState, error) {
+	if g.lazyInitialState == nil {
+		acrossLines, err := g.allPossibleLines(ctx, g.LineLength)
+		if err != nil {
+			return nil, err
+		}
+		downLines, err := g.allPossibleLines(ctx, g.Height)
+		if err != nil {
+			return nil, err
 		}
+		overrides, err := g.lineOverrides(ctx, false)
+		if err != nil {
+			return nil, err
+		}
+		gs := g.stateFromLines(acrossLines, downLines, overrides)
+		// If some line is impossible, the search finds so straight away.
+		if err := runAC3(ctx, gs, g.propagationWorkers); err != nil && !errors.Is(err, ErrNoGridsPossible) {
+			return nil, err
+		}
+		g.lazyInitialState = gs
 	}
+	return g.lazyInitialState.clone(), nil
+}
 
-	if dir == DirectionHorizontal {
-		return gridState{across: toFilter, down: constraint, rand: s.rand}, anyChanged
-	} else {
-		return gridState{down: toFilter, across: constraint, rand: s.rand}, anyChanged
+// lineOverride holds the lines of a row or column that differ from those of the other lines of its
+// length, e.g. the row through a rebus cell.
+type lineOverride struct {
+	dir   Direction
+	index int
+	lines primitives.PossibleLines
+}
+
+// lineOverrides returns the lines of every row and column that differ from those of the other
+// lines of their length, with their words in a new random order if shuffle is true.
+func (g *Generator) lineOverrides(ctx context.Context, shuffle bool) ([]lineOverride, error) {
+	bars, err := g.barLines(ctx, shuffle)
+	if err != nil {
+		return nil, err
 	}
+	rebus, err := g.rebusLines(ctx, shuffle)
+	if err != nil {
+		return nil, err
+	}
+	return append(bars, rebus...), nil
 }
 
-func (g *Generator) PossibleGrids(ctx context.Context) iter.Seq[Grid] {
-	return func(yield func(Grid) bool) {
-		gs := gridState{
-			down:   make([]primitives.PossibleLines, g.LineLength),
-			across: make([]primitives.PossibleLines, g.LineLength),
-			rand:   g.rand,
+// stateFromLines returns the root of the search where every across line can be any of acrossLines,
+// and every down line any of downLines, except for the lines of overrides, that matches the
+// generator's partial grid and pinned cells, if any.
+func (g *Generator) stateFromLines(acrossLines, downLines primitives.PossibleLines, overrides []lineOverride) *gridState {
+	// Rather than pruning every line with a block during the search, drop them from the start.
+	if g.maxBlocks != nil && *g.maxBlocks == 0 {
+		acrossLines, downLines = lettersOnly(acrossLines), lettersOnly(downLines)
+		for i := range overrides {
+			overrides[i].lines = lettersOnly(overrides[i].lines)
+		}
+	}
+
+	gs := &gridState{
+		down:   make([]primitives.PossibleLines, g.LineLength),
+		across: make([]primitives.PossibleLines, g.Height),
+		rand:   g.rand,
+	}
+	for i := range gs.down {
+		gs.down[i] = downLines
+	}
+	for i := range gs.across {
+		gs.across[i] = acrossLines
+	}
+	if !g.noBackjumping {
+		gs.downWhy = make([]conflictSet, len(gs.down))
+		gs.acrossWhy = make([]conflictSet, len(gs.across))
+	}
+	for _, o := range overrides {
+		if o.dir == DirectionHorizontal {
+			gs.across[o.index] = o.lines
+		} else {
+			gs.down[o.index] = o.lines
+		}
+	}
+	if g.partial != nil {
+		applyPartial(gs, g.partial)
+	}
+	applyPinnedCells(gs, g.pinnedCells)
+	deduplicateLines(gs)
+	return gs
+}
+
+// deduplicateLines drops the repeated children of every Compound line of gs, so that the search
+// never tries the same lines twice. Lines shared by several rows or columns stay shared.
+func deduplicateLines(gs *gridState) {
+	deduplicated := make(map[primitives.PossibleLines]primitives.PossibleLines)
+	for _, lines := range [][]primitives.PossibleLines{gs.across, gs.down} {
+		for i, l := range lines {
+			c, ok := l.(*primitives.Compound)
+			if !ok {
+				continue
+			}
+			if _, ok := deduplicated[c]; !ok {
+				deduplicated[c] = c.Deduplicate()
+			}
+			lines[i] = deduplicated[c]
 		}
+	}
+}
+
+// lettersOnly filters lines to those without any blocked cells.
+func lettersOnly(lines primitives.PossibleLines) primitives.PossibleLines {
+	for i := range lines.NumLetters() {
+		lines = lines.FilterAny(&letterCharSet, i)
+	}
+	return lines
+}
 
-		apl, err := g.allPossibleLines(ctx)
+func (g *Generator) PossibleGrids(ctx context.Context) iter.Seq[Grid] {
+	return gridsOnly(g.PossibleGridsWithStats(ctx))
+}
+
+// PossibleGridsWithStats is like PossibleGrids, but also yields statistics about the search
+// performed to find each grid since the previous one.
+func (g *Generator) PossibleGridsWithStats(ctx context.Context) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		gs, err := g.initialState(ctx)
 		if err != nil {
+			g.setErr(searchErr(ctx, false))
 			return
 		}
 
-		for i := range gs.down {
-			gs.down[i] = apl
+		for grid, stats := range g.search(ctx, gs, nil) {
+			if !yield(grid, stats) {
+				return
+			}
 		}
-		for i := range gs.across {
-			gs.across[i] = apl
+	}
+}
+
+const (
+	// CellUnknown marks a cell in a partial grid that can hold any letter, or be blocked.
+	CellUnknown = '.'
+	// CellBlocked marks a cell in a partial grid that must be blocked.
+	CellBlocked = '#'
+)
+
+// PossibleGridsFrom is like PossibleGrids, but only yields completions of the given partial grid.
+//
+// partial is indexed as partial[row][col], and each cell is either a letter, CellBlocked, or
+// CellUnknown. An error is returned if partial does not match the dimensions of the generator or
+// contains any other character. A partial grid that simply cannot be completed is not an error;
+// the returned sequence is empty in that case. If the generator was created with WithPartialGrid,
+// grids complete both partial grids.
+func (g *Generator) PossibleGridsFrom(ctx context.Context, partial [][]rune) (iter.Seq[Grid], error) {
+	if err := g.validatePartialSize(partial); err != nil {
+		return nil, err
+	}
+	if err := validatePartialCells(partial); err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Grid) bool) {
+		gs, err := g.initialState(ctx)
+		if err != nil {
+			g.setErr(searchErr(ctx, false))
+			return
 		}
+		applyPartial(gs, partial)
 
-		seenReprs := make(map[string]bool)
-		for grid := range possibleGridsAtRoot(ctx, &gs) {
-			repr := grid.Repr()
-			if seenReprs[repr] {
-				continue
-			}
-			seenReprs[repr] = true
+		for grid := range g.search(ctx, gs, partial) {
 			if !yield(grid) {
 				return
 			}
 		}
+	}, nil
+}
+
+// cellConstraint returns the rune a line must contain for a cell of a partial grid, or false if
+// the cell is unconstrained or invalid.
+func cellConstraint(r rune) (rune, bool) {
+	if r == CellBlocked {
+		return primitives.Blocked, true
 	}
+	r = unicode.ToLower(r)
+	if r < 'a' || r > 'z' {
+		return 0, false
+	}
+	return r, true
 }
 
-func impossible(p primitives.PossibleLines) bool {
-	return p.MaxPossibilities() == 0
+// searcher holds the state of a single search for grids.
+type searcher struct {
+	g   *Generator
+	ctx context.Context
+
+	// stats accumulates statistics since the last grid was found.
+	stats SearchStats
+	// depth is the number of choices made to reach the current point in the search.
+	depth int
+	// conflict holds the levels of the choices that caused the most recently explored subtree to
+	// have no grids.
+	conflict conflictSet
+
+	// maxBacktracks, if positive, is the number of backtracks the search may make without finding
+	// a grid before it is abandoned.
+	maxBacktracks int64
+	// abandoned is set once the search is abandoned, after which it yields no more grids.
+	abandoned bool
+	// gridTimeout, if positive, is how long the search may spend finding each grid. timedOut is
+	// set once it stops because it spent longer.
+	gridTimeout time.Duration
+	timedOut    bool
+
+	// maxDepth, if positive, is the number of choices the search may make at once. cutOff is set
+	// once the search skips a subtree because it is deeper than that.
+	maxDepth int
+	cutOff   bool
+
+	// filters counts the lines filtered since the context was last checked, and stopped is set
+	// once the search notices that its context is done.
+	filters int
+	stopped bool
+
+	// path holds the choice being made at each level above the current point in the search.
+	path []frame
+	// resume, if set, is the path of an interrupted search to continue from.
+	resume []frame
+	// onInterrupt, if set, is called with the path to the point where the search first noticed
+	// that its context is done, so that the search can be resumed from there.
+	onInterrupt func(path []frame)
+
+	// spawn, if set, is called with each subtree at splitDepth instead of searching it. It
+	// returns false if the search should stop.
+	spawn      func(*gridState) bool
+	splitDepth int
+
+	// countOnly is set if the grids found are only counted, so that they only need their letters
+	// where nothing else about them is checked.
+	countOnly bool
+	// untracked is set for searches that are not one of the generator's searches for grids, e.g.
+	// refilling a grid with Improve, so that they leave its best partial grid alone.
+	untracked bool
+	// origin is the first point the search visited, which every later point narrows down, if
+	// consistency checks are on.
+	origin *gridState
 }
 
-func countWhere[T any](s []T, f func(T) bool) int {
-	count := 0
-	for _, v := range s {
-		if f(v) {
-			count++
+// search yields every distinct grid reachable from root, along with the statistics of the search
+// since the previous grid. partial is the partial grid applied to root, if any, and is needed to
+// restart the search.
+func (g *Generator) search(ctx context.Context, root *gridState, partial [][]rune) iter.Seq2[Grid, SearchStats] {
+	seen := newSeenGrids(cmp.Or(g.dedupeCapacity, defaultDedupeCapacity))
+	incomplete := false
+	var grids iter.Seq2[Grid, SearchStats]
+	if g.workers > 1 {
+		grids = g.searchParallel(ctx, root)
+	} else if g.restarts != nil || g.gridTimeout > 0 {
+		grids = g.searchWithRestarts(ctx, root, partial)
+	} else if g.iterativeDeepening {
+		grids = g.searchIterativeDeepening(ctx, root)
+	} else if g.beamWidth > 0 {
+		grids = g.searchBeam(ctx, root, &incomplete)
+	} else {
+		sr := &searcher{g: g, ctx: ctx}
+		if partial == nil {
+			g.checkpoint(sr, seen)
 		}
+		grids = sr.grids(root)
+	}
+	grids = g.uniqueGrids(grids, seen)
+	if g.stats != nil {
+		grids = g.recordStats(grids)
+	}
+	return g.recordErr(ctx, g.resetBestPartial(grids), &incomplete)
+}
+
+// resetBestPartial forgets the best partial grid of the previous search once grids starts.
+func (g *Generator) resetBestPartial(grids iter.Seq2[Grid, SearchStats]) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		g.best.reset()
+		grids(yield)
 	}
-	return count
 }
 
-func possibleGridsAtRoot(ctx context.Context, root *gridState) iter.Seq[Grid] {
+// recordStats counts the grids found and the time spent searching in g.stats.
+func (g *Generator) recordStats(grids iter.Seq2[Grid, SearchStats]) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		start := time.Now()
+		defer func() {
+			atomic.AddInt64((*int64)(&g.stats.TimeElapsed), int64(time.Since(start)))
+		}()
+		for grid, stats := range grids {
+			atomic.AddInt64(&g.stats.GridsFound, 1)
+			if !yield(grid, stats) {
+				return
+			}
+		}
+	}
+}
+
+// grids yields every grid reachable from root, along with the statistics of the search since the
+// previous grid.
+//
+// With a grid timeout, the search runs with a child of its context that expires that long after
+// the search starts or last found a grid, and sets timedOut if that stops it.
+func (sr *searcher) grids(root *gridState) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		ctx, cancel := sr.ctx, context.CancelFunc(func() {})
+		// restartTimer gives the search gridTimeout from now to find the next grid.
+		restartTimer := func() {
+			cancel()
+			sr.ctx, cancel = context.WithTimeout(ctx, sr.gridTimeout)
+		}
+		if sr.gridTimeout > 0 {
+			restartTimer()
+		}
+		defer func() {
+			cancel()
+			sr.timedOut = sr.stopped && ctx.Err() == nil
+			sr.ctx = ctx
+		}()
+
+		last := time.Now()
+		for grid := range sr.possibleGridsAtRoot(root) {
+			now := time.Now()
+			stats := sr.stats
+			stats.Elapsed = now.Sub(last)
+			last = now
+			sr.stats = SearchStats{PeakFrontier: sr.depth}
+
+			if !yield(grid, stats) {
+				return
+			}
+			if sr.gridTimeout > 0 {
+				restartTimer()
+			}
+		}
+	}
+}
+
+// gridsOnly drops the statistics from a sequence of grids.
+func gridsOnly(grids iter.Seq2[Grid, SearchStats]) iter.Seq[Grid] {
 	return func(yield func(Grid) bool) {
-		if ctx.Err() != nil {
+		for grid := range grids {
+			if !yield(grid) {
+				return
+			}
+		}
+	}
+}
+
+func impossible(p primitives.PossibleLines) bool {
+	return p.MaxPossibilities() == 0
+}
+
+func (sr *searcher) possibleGridsAtRoot(root *gridState) iter.Seq[Grid] {
+	return func(yield func(Grid) bool) {
+		// Unless a prune below knows better, assume a failure here could be caused by any choice.
+		sr.conflict = allLevels
+		if sr.stopped || sr.ctx.Err() != nil {
+			sr.stopped = true
+			sr.interrupted()
 			return
 		}
+		if sr.abandoned {
+			return
+		}
+		if p := sr.g.progress; p != nil {
+			p.nodesExplored.Add(1)
+		}
+		if c := sr.g.progressCallback; c != nil {
+			c.nodeExplored()
+		}
+		if s := sr.g.stats; s != nil {
+			atomic.AddInt64(&s.NodesExplored, 1)
+		}
+		if sr.g.consistencyChecks {
+			checkConsistency(root)
+			if sr.origin == nil {
+				sr.origin = root
+			}
+			checkContained(sr.origin, root)
+		}
 
 		// If we are at a point in our tree some row/column is unfillable, prune this tree.
 		if slices.ContainsFunc(root.down, impossible) || slices.ContainsFunc(root.across, impossible) {
+			sr.conflict = root.impossibleWhy()
+			sr.deadEnd()
 			return
 		}
 
@@ -269,21 +1075,21 @@ func possibleGridsAtRoot(ctx context.Context, root *gridState) iter.Seq[Grid] {
 			}
 		}
 		if hasDupes {
+			sr.deadEnd()
 			return
 		}
 
-		priorNumBlocked := 0
-		lineLength := len(root.down)
-		for i := range lineLength {
-			priorNumBlocked += countWhere(root.down, func(p primitives.PossibleLines) bool {
-				return p.DefinitelyBlockedAt(i)
-			})
-		}
+		priorNumBlocked := numDefinitelyBlockedCells(root)
 
-		// Prefilter
+		// Prefilter, unless every line is decided and they agree, when it would change nothing.
+		// Only counting skips it, since the conflict sets of the lines are left as they are.
+		decided := sr.countOnly && isDecided(root)
 		direction := DirectionHorizontal
 		for try := range 4 {
-			newState, changed := prefilter(ctx, *root, direction)
+			if try == 0 && decided {
+				break
+			}
+			newState, changed := prefilter(sr.ctx, *root, direction, sr.g.propagationWorkers)
 			if !changed && try > 1 {
 				break
 			}
@@ -295,19 +1101,58 @@ func possibleGridsAtRoot(ctx context.Context, root *gridState) iter.Seq[Grid] {
 				direction = DirectionVertical
 			}
 		}
+		// prefilter stops early once the context is done, leaving root only partly filtered.
+		if sr.ctx.Err() != nil {
+			sr.stopped = true
+			sr.interrupted()
+			return
+		}
+		if len(sr.g.symmetries) > 0 {
+			enforceSymmetriesTracked(root, sr.g.symmetries)
+		}
+		// Filtering can leave lines with a single word, which no other line may then use. Decided
+		// lines were already checked for repeated words above.
+		if !decided {
+			if why, ok := removeDefiniteWords(root); !ok {
+				sr.conflict = why
+				sr.deadEnd()
+				return
+			}
+		}
 		if slices.ContainsFunc(root.down, impossible) || slices.ContainsFunc(root.across, impossible) {
+			sr.conflict = root.impossibleWhy()
+			sr.deadEnd()
 			return
 		}
 
 		// If board is > 25% blocked, it's not worth iterating in it.
-		numDefinitelyBlocked := 0
-		for i := range lineLength {
-			numDefinitelyBlocked += countWhere(root.down, func(p primitives.PossibleLines) bool {
-				return p.DefinitelyBlockedAt(i)
-			})
+		numDefinitelyBlocked := numDefinitelyBlockedCells(root)
+		if numDefinitelyBlocked > ((len(root.down) * len(root.across) * 25) / 100) {
+			sr.deadEnd()
+			return
 		}
-
-		if numDefinitelyBlocked > ((lineLength * lineLength * 25) / 100) {
+		if f := sr.g.maxBlockFraction; f > 0 && float64(numDefinitelyBlocked) > f*float64(len(root.down)*len(root.across)) {
+			sr.deadEnd()
+			return
+		}
+		if n := sr.g.maxBlocks; n != nil && numDefinitelyBlocked > *n {
+			sr.deadEnd()
+			return
+		}
+		if sr.g.noUncheckedSquares && hasUncheckedSquare(root) {
+			sr.deadEnd()
+			return
+		}
+		if !sr.g.validLines(root) {
+			sr.deadEnd()
+			return
+		}
+		if !sr.g.canFitRequiredWord(root) {
+			sr.deadEnd()
+			return
+		}
+		if !sr.g.canReachPreferredRatio(root) {
+			sr.deadEnd()
 			return
 		}
 
@@ -322,53 +1167,159 @@ func possibleGridsAtRoot(ctx context.Context, root *gridState) iter.Seq[Grid] {
 		// being cordoned off.
 		if numDefinitelyBlocked > priorNumBlocked {
 			if isBoardDefinitelyDivided(root) {
+				sr.deadEnd()
 				return
 			}
 		}
 
-		undecidedDown := root.getUndecidedIndexDown()
-		undecidedAcross := root.getUndecidedIndexAcross()
+		if p := sr.g.progress; p != nil {
+			p.best.observe(root)
+		}
+		if !sr.untracked {
+			sr.g.best.observe(root)
+		}
 
-		if undecidedDown == nil && undecidedAcross == nil {
+		dir, index, undecided := sr.resumedLine()
+		if !undecided {
+			dir, index, undecided = sr.g.selectLine(root)
+		}
+		if !undecided {
 			across := make([][]rune, len(root.across))
+			var wordsAcross, wordsDown []string
 
 			for i, ac := range root.across {
 				a := ac.FirstOrNull()
-				d := root.down[i].FirstOrNull()
+				if a == nil {
+					sr.deadEnd()
+					return
+				}
+				across[i] = a.Line
+				wordsAcross = append(wordsAcross, a.Words...)
+			}
 
-				if d == nil || a == nil {
+			for i, dc := range root.down {
+				d := dc.FirstOrNull()
+				if d == nil {
+					sr.deadEnd()
 					return
 				}
 
 				// If any column and row are completely the same, this is not a viable grid.
-				if slices.Equal(d.Line, a.Line) {
+				if i < len(across) && slices.Equal(d.Line, across[i]) {
+					sr.deadEnd()
 					return
 				}
-
-				across[i] = a.Line
+				wordsDown = append(wordsDown, d.Words...)
+			}
+			if sr.countOnly && len(sr.g.requiredWords) == 0 && sr.g.maxObscureFraction == nil {
+				yield(NewGrid(across))
+				return
+			}
+			grid := sr.g.newGrid(across, wordsAcross, wordsDown)
+			if !sr.g.containsRequiredWord(grid.AllWords()) {
+				sr.deadEnd()
+				return
+			}
+			if f := sr.g.maxObscureFraction; f != nil && grid.ObscureWordFraction() > *f {
+				sr.deadEnd()
+				return
 			}
 
-			yield(NewGrid(across))
+			yield(grid)
 			return
 		}
 
-		var possibleGrids iter.Seq[Grid]
+		possibleGrids := sr.iterateAllPossibleGrids(root, index, dir)
 
-		if undecidedAcross == nil {
-			possibleGrids = iterateAllPossibleGrids(ctx, root, *undecidedDown, DirectionVertical)
-		} else if undecidedDown == nil {
-			possibleGrids = iterateAllPossibleGrids(ctx, root, *undecidedAcross, DirectionHorizontal)
-		} else if root.down[*undecidedDown].MaxPossibilities() <= root.across[*undecidedAcross].MaxPossibilities() {
-			possibleGrids = iterateAllPossibleGrids(ctx, root, *undecidedDown, DirectionVertical)
-		} else {
-			possibleGrids = iterateAllPossibleGrids(ctx, root, *undecidedAcross, DirectionHorizontal)
+		for grid := range possibleGrids {
+			if !yield(grid) {
+				return
+			}
 		}
+	}
+}
 
-		for grid := range possibleGrids {
+// cancelCheckInterval is the number of lines the search filters between checks of whether its
+// context is done, on top of the check at every point in the search. Without them, a search of
+// large word lists can spend a long time filtering alternatives that are pruned without exploring
+// them.
+const cancelCheckInterval = 1024
+
+// cancelled counts n more lines filtered, and returns true if the search's context is done, which
+// it checks every cancelCheckInterval lines. Once it returns true, it always does.
+func (sr *searcher) cancelled(n int) bool {
+	if sr.stopped {
+		return true
+	}
+	if sr.filters += n; sr.filters < cancelCheckInterval {
+		return false
+	}
+	sr.filters = 0
+	sr.stopped = sr.ctx.Err() != nil
+	return sr.stopped
+}
+
+// deadEnd records that the search pruned the current subtree.
+func (sr *searcher) deadEnd() {
+	sr.stats.DeadEnds++
+	if p := sr.g.progress; p != nil {
+		p.pruned.Add(1)
+	}
+	if c := sr.g.progressCallback; c != nil {
+		c.pruned.Add(1)
+	}
+	if s := sr.g.stats; s != nil {
+		atomic.AddInt64(&s.NodesPruned, 1)
+	}
+}
+
+// explore searches the subtree rooted at a newly made choice, updating the search statistics.
+func (sr *searcher) explore(root *gridState) iter.Seq[Grid] {
+	return func(yield func(Grid) bool) {
+		if sr.spawn != nil && sr.depth+1 >= sr.splitDepth {
+			sr.conflict = allLevels
+			sr.spawn(root)
+			return
+		}
+		if sr.maxDepth > 0 && sr.depth >= sr.maxDepth {
+			// The subtree may well hold grids, so any choice could be to blame for not finding them.
+			sr.conflict = allLevels
+			sr.cutOff = true
+			return
+		}
+
+		sr.stats.ChoiceSteps++
+		sr.depth++
+		defer func() {
+			sr.depth--
+			if p := sr.g.progress; p != nil {
+				p.depth.Store(int64(sr.depth))
+			}
+		}()
+		sr.stats.PeakFrontier = max(sr.stats.PeakFrontier, sr.depth)
+		if p := sr.g.progress; p != nil {
+			p.depth.Store(int64(sr.depth))
+		}
+		if s := sr.g.stats; s != nil {
+			s.observeDepth(sr.depth)
+		}
+
+		found := false
+		for grid := range sr.possibleGridsAtRoot(root) {
+			found = true
 			if !yield(grid) {
 				return
 			}
 		}
+		if !found {
+			sr.stats.Backtracks++
+			if p := sr.g.progress; p != nil {
+				p.backtracks.Add(1)
+			}
+			if sr.maxBacktracks > 0 && sr.stats.Backtracks >= sr.maxBacktracks {
+				sr.abandoned = true
+			}
+		}
 	}
 }
 
@@ -445,24 +1396,107 @@ func isBoardDefinitelyDivided(state *gridState) bool {
 	return false
 }
 
-func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, dir Direction) iter.Seq[Grid] {
+func (sr *searcher) iterateAllPossibleGrids(root *gridState, index int, dir Direction) iter.Seq[Grid] {
 	return func(yield func(Grid) bool) {
-		if ctx.Err() != nil {
-			return
+		// conflict is reported to the caller if no grids are found. Unless the alternatives are
+		// all exhausted, any earlier choice could be to blame.
+		conflict := allLevels
+		defer func() {
+			sr.conflict = conflict
+		}()
+
+		depth := len(sr.path)
+		sr.path = append(sr.path, frame{dir: dir, index: index})
+		defer func() {
+			sr.path = sr.path[:depth]
+		}()
+		// from is how far through the alternatives an interrupted search had got, if this search
+		// resumes it. Alternatives before that point were already explored.
+		var from frame
+		if depth < len(sr.resume) {
+			from = sr.resume[depth]
+			sr.path[depth].failed = from.failed
+			// The search was interrupted at this level, so it continues as normal from here.
+			if depth == len(sr.resume)-1 {
+				sr.resume = nil
+			}
 		}
 
 		var optionAxis, oppositeAxis []primitives.PossibleLines
+		var oppositeWhy []conflictSet
 
 		if dir == DirectionHorizontal {
 			optionAxis = root.across
 			oppositeAxis = root.down
+			oppositeWhy = root.downWhy
 		} else {
 			optionAxis = root.down
 			oppositeAxis = root.across
+			oppositeWhy = root.acrossWhy
+		}
+
+		// Each alternative for the line is a choice at the next level.
+		level := root.level + 1
+		newRoot := func(option, opposite []primitives.PossibleLines) *gridState {
+			if dir == DirectionHorizontal {
+				return &gridState{
+					down:      opposite,
+					across:    option,
+					level:     level,
+					downWhy:   narrowedWhys(root.downWhy, root.down, opposite, level),
+					acrossWhy: narrowedWhys(root.acrossWhy, root.across, option, level),
+					rand:      root.rand,
+				}
+			}
+			return &gridState{
+				down:      option,
+				across:    opposite,
+				level:     level,
+				downWhy:   narrowedWhys(root.downWhy, root.down, option, level),
+				acrossWhy: narrowedWhys(root.acrossWhy, root.across, opposite, level),
+				rand:      root.rand,
+			}
+		}
+
+		tracking := oppositeWhy != nil
+		// explore yields the grids of the subtree rooted at state. It returns false if the search of
+		// this line should stop, either because yield did, or because the subtree failed
+		// regardless of the choice at this level, so every other alternative would fail too.
+		explore := func(state *gridState) bool {
+			found := false
+			for final := range sr.explore(state) {
+				found = true
+				if !yield(final) {
+					return false
+				}
+			}
+			if sr.abandoned {
+				return false
+			}
+			f := &sr.path[depth]
+			switch {
+			case !tracking:
+			case found:
+				f.failed = allLevels
+			case !sr.conflict.has(level):
+				sr.stats.Backjumps++
+				conflict = sr.conflict
+				return false
+			default:
+				f.failed = f.failed.union(sr.conflict.without(level))
+			}
+			return true
+		}
+		// exhausted records that every alternative failed, because of the choices that narrowed
+		// the line and the ones that caused each alternative to fail.
+		exhausted := func() {
+			if tracking {
+				conflict = sr.path[depth].failed.union(root.whyOf(dir, index))
+			}
 		}
 
 		// Trim situations where horizontal and vertal words are same.
-		for i := range optionAxis {
+		for i := range min(len(optionAxis), len(oppositeAxis)) {
 			if optionAxis[i].MaxPossibilities() > 1 {
 				continue
 			}
@@ -473,9 +1507,11 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 			optA := optionAxis[i].FirstOrNull()
 			oppA := oppositeAxis[i].FirstOrNull()
 			if optA == nil || oppA == nil {
+				sr.deadEnd()
 				return
 			}
 			if slices.Equal(optA.Line, oppA.Line) {
+				sr.deadEnd()
 				return
 			}
 		}
@@ -489,8 +1525,28 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 		}
 
 		if options.MaxPossibilities() >= 10 {
-			for options.MaxPossibilities() > 1 {
+			var present primitives.CharSet
+			if sr.g.letterVariety && !sr.g.sampling {
+				present = definiteLetters(root)
+			}
+			split := 0
+			for ; options.MaxPossibilities() > 1; split++ {
 				c := options.MakeChoice()
+				switch {
+				case sr.g.sampling:
+					c = preferSample(c, options, root.rand)
+				case sr.g.letterVariety:
+					c = preferVariety(c, present)
+				}
+				if split < from.splits {
+					options = c.Remaining
+					continue
+				}
+				sr.path[depth].splits = split
+				if sr.cancelled(1) {
+					sr.interrupted()
+					return
+				}
 
 				// Clone oppositeAxis into attemptOpposite.
 				attemptOpposite := make([]primitives.PossibleLines, len(oppositeAxis))
@@ -506,7 +1562,7 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 				// If any word appears more than once, this is not a valid grid.
 				{
 					duplicate := false
-					for k := range attemptOpposite {
+					for k := range min(len(attemptOpposite), len(optionFinal)) {
 						first := attemptOpposite[k]
 						second := optionFinal[k]
 						if first.MaxPossibilities() > 1 || second.MaxPossibilities() > 1 {
@@ -524,45 +1580,44 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 						}
 					}
 					if duplicate {
+						sr.deadEnd()
 						return
 					}
 				}
 
-				var newRoot *gridState
-				if dir == DirectionHorizontal {
-					newRoot = &gridState{
-						down:   attemptOpposite,
-						across: optionFinal,
-						rand:   root.rand,
-					}
-				} else {
-					newRoot = &gridState{
-						down:   optionFinal,
-						across: attemptOpposite,
-						rand:   root.rand,
-					}
-				}
+				state := newRoot(optionFinal, attemptOpposite)
 
 				if numDefiniteBlocks(c.Choice) > numDefiniteBlocks(options) {
-					if isBoardDefinitelyDivided(newRoot) {
+					if isBoardDefinitelyDivided(state) {
+						sr.deadEnd()
 						return
 					}
 				}
-				for final := range possibleGridsAtRoot(ctx, newRoot) {
-					if !yield(final) {
-						return
-					}
+				if !explore(state) {
+					return
 				}
 
 				options = c.Remaining
 			}
+			sr.path[depth].splits = split
 
 			if options.MaxPossibilities() == 0 {
+				exhausted()
 				return
 			}
 		}
 
+		tried := -1
 		for attempt := range options.Iterate() {
+			if tried++; tried < from.tried {
+				continue
+			}
+			sr.path[depth].tried = tried
+			if sr.cancelled(len(attempt.Line) + len(optionAxis)) {
+				sr.interrupted()
+				return
+			}
+
 			// If any word appears more than once, this is not a valid grid.
 			wordCounts := make(map[string]int)
 			hasDuplicate := false
@@ -598,12 +1653,21 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 				if attemptOpposite[i].MaxPossibilities() == 1 {
 					ao := attemptOpposite[i].FirstOrNull()
 					if ao == nil || slices.Equal(ao.Line, attempt.Line) {
+						sr.deadEnd()
 						return
 					}
 				}
 			}
 
 			if slices.ContainsFunc(attemptOpposite, impossible) {
+				if tracking {
+					for i, line := range attemptOpposite {
+						if impossible(line) {
+							sr.path[depth].failed = sr.path[depth].failed.union(oppositeWhy[i])
+						}
+					}
+				}
+				sr.deadEnd()
 				continue
 			}
 
@@ -618,7 +1682,7 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 
 			{
 				duplicate := false
-				for k := range attemptOpposite {
+				for k := range min(len(attemptOpposite), len(optionFinal)) {
 					first := attemptOpposite[k]
 					second := optionFinal[k]
 					if first.MaxPossibilities() > 1 || second.MaxPossibilities() > 1 {
@@ -635,32 +1699,16 @@ func iterateAllPossibleGrids(ctx context.Context, root *gridState, index int, di
 					}
 				}
 				if duplicate {
+					sr.deadEnd()
 					return
 				}
 			}
 
-			var newRoot *gridState
-			if dir == DirectionHorizontal {
-				newRoot = &gridState{
-					down:   oppositeFinal,
-					across: optionFinal,
-					rand:   root.rand,
-				}
-			} else {
-				newRoot = &gridState{
-					down:   optionFinal,
-					across: oppositeFinal,
-					rand:   root.rand,
-				}
-			}
-
-			for final := range possibleGridsAtRoot(ctx, newRoot) {
-				if !yield(final) {
-					return
-				}
+			if !explore(newRoot(optionFinal, oppositeFinal)) {
+				return
 			}
-
 		}
+		exhausted()
 	}
 }
 
@@ -672,6 +1720,15 @@ func sliceSelectFunc[From any, To any](slice []From, f func(From, int) To) []To
 	return result
 }
 
+// numDefinitelyBlockedCells returns the number of cells in the grid that are definitely blocked.
+func numDefinitelyBlockedCells(state *gridState) int {
+	acc := 0
+	for _, line := range state.down {
+		acc += numDefiniteBlocks(line)
+	}
+	return acc
+}
+
 func numDefiniteBlocks(state primitives.PossibleLines) int {
 	acc := 0
 	for i := range state.NumLetters() {
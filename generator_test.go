@@ -3,9 +3,12 @@ package xwgen
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"math/rand/v2"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,6 +31,16 @@ func loadWords(t testing.TB) []string {
 	return words
 }
 
+// loadTrimmedWords is like loadWords, but trims the line endings, so that the words can be passed
+// to the validating WithPreferredWords.
+func loadTrimmedWords(t testing.TB) []string {
+	var words []string
+	for _, word := range loadWords(t) {
+		words = append(words, strings.TrimSpace(word))
+	}
+	return words
+}
+
 func TestPossibleGrids_5x5(t *testing.T) {
 	words := loadWords(t)
 	// Use a fixed seed for reproducibility.
@@ -94,3 +107,764 @@ func BenchmarkPossibleGrids(b *testing.B) {
 		})
 	}
 }
+
+func TestPossibleGridsFrom(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	partial := [][]rune{
+		[]rune("A...."),
+		[]rune("....."),
+		[]rune("....."),
+		[]rune("....."),
+		[]rune("....#"),
+	}
+	grids, err := gen.PossibleGridsFrom(ctx, partial)
+	if err != nil {
+		t.Fatalf("PossibleGridsFrom() error = %v", err)
+	}
+
+	count := 0
+	for grid := range grids {
+		count++
+		if got := grid.Get(0, 0); got != 'a' {
+			t.Errorf("grid #%d has %q at (0, 0), want 'a':\n%s", count, got, grid.Repr())
+		}
+		if got := grid.Get(4, 4); got != '`' {
+			t.Errorf("grid #%d has %q at (4, 4), want blocked:\n%s", count, got, grid.Repr())
+		}
+		if count >= 3 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one completion")
+	}
+}
+
+func TestPossibleGridsFrom_Invalid(t *testing.T) {
+	gen := CreateGenerator(3, []string{"abc"}, nil, nil, rand.New(rand.NewPCG(1, 2)), GeneratorParams{})
+
+	for _, tc := range []struct {
+		name    string
+		partial []string
+	}{
+		{name: "too few rows", partial: []string{"...", "..."}},
+		{name: "short row", partial: []string{"...", "..", "..."}},
+		{name: "invalid character", partial: []string{"...", ".?.", "..."}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var partial [][]rune
+			for _, row := range tc.partial {
+				partial = append(partial, []rune(row))
+			}
+			if _, err := gen.PossibleGridsFrom(t.Context(), partial); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPossibleGrids_Rectangular(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+		Height:        4,
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		if grid.Width() != 5 || grid.Height() != 4 {
+			t.Errorf("grid #%d is %dx%d, want 5x4:\n%s", count, grid.Width(), grid.Height(), grid.Repr())
+		}
+		if count >= 3 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestPossibleGrids_RectangularTimeout(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(9, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+		Height:        7,
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	for range gen.PossibleGrids(ctx) {
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("PossibleGrids took %v to respect a 500ms timeout", elapsed)
+	}
+}
+
+func TestPossibleGrids_CancelPromptly(t *testing.T) {
+	// A huge list of random words, where most alternatives are pruned without being explored.
+	r := rand.New(rand.NewPCG(1, 2))
+	words := make([]string, 200_000)
+	for i := range words {
+		word := make([]byte, 6)
+		for j := range word {
+			word[j] = byte('a' + r.IntN(26))
+		}
+		words[i] = string(word)
+	}
+	gen, err := CreateGeneratorE(6, WithPreferredWords(words), WithMaxBlocks(0), WithSeed(1, 2))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancelled := make(chan time.Time, 1)
+	time.AfterFunc(time.Second, func() {
+		cancelled <- time.Now()
+		cancel()
+	})
+	for range gen.PossibleGrids(ctx) {
+	}
+	if elapsed := time.Since(<-cancelled); elapsed > 100*time.Millisecond {
+		t.Errorf("PossibleGrids returned %v after its context was cancelled", elapsed)
+	}
+}
+
+func TestPossibleGridsWithStats(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid, stats := range gen.PossibleGridsWithStats(ctx) {
+		count++
+		if stats.ChoiceSteps == 0 {
+			t.Errorf("grid #%d reported no choice steps:\n%s", count, grid.Repr())
+		}
+		if stats.PeakFrontier == 0 {
+			t.Errorf("grid #%d reported an empty frontier:\n%s", count, grid.Repr())
+		}
+		if stats.Backtracks > stats.ChoiceSteps {
+			t.Errorf("grid #%d reported more backtracks (%d) than choice steps (%d)", count, stats.Backtracks, stats.ChoiceSteps)
+		}
+		if stats.Elapsed <= 0 {
+			t.Errorf("grid #%d reported elapsed time %v", count, stats.Elapsed)
+		}
+		if count >= 3 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestPossibleGrids_RotationalSymmetry(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	}, WithRotationalSymmetry())
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		for y := range grid.Height() {
+			for x := range grid.Width() {
+				rx, ry := grid.Width()-1-x, grid.Height()-1-y
+				if (grid.Get(x, y) == '`') != (grid.Get(rx, ry) == '`') {
+					t.Errorf("grid #%d is not rotationally symmetric at (%d, %d):\n%s", count, x, y, grid.Repr())
+				}
+			}
+		}
+		if count >= 5 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	var progress Progress
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	}, WithProgress(&progress))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	for range gen.PossibleGrids(ctx) {
+		break
+	}
+
+	s := progress.Snapshot()
+	if s.NodesExplored == 0 {
+		t.Error("expected some nodes to have been explored")
+	}
+	if s.BestPartial == nil || s.BestPartialCells == 0 {
+		t.Fatalf("expected a best partial grid, got %v with %d cells", s.BestPartial, s.BestPartialCells)
+	}
+	if len(s.BestPartial) != 5 || len(s.BestPartial[0]) != 5 {
+		t.Errorf("best partial grid has the wrong dimensions: %q", s.BestPartial)
+	}
+}
+
+func TestBestPartial(t *testing.T) {
+	words := loadTrimmedWords(t)
+	// No 6x6 grid without blocked cells can be filled from the test words, but the search gets
+	// part of the way.
+	gen, err := CreateGeneratorE(6,
+		WithPreferredWords(words),
+		WithSeed(42, 1024),
+		WithMaxBlocks(0),
+	)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	if got := gen.BestPartial(); got != nil {
+		t.Errorf("BestPartial() before any search = %q, want nil", got)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	for grid := range gen.PossibleGrids(ctx) {
+		t.Fatalf("found a grid, want none:\n%s", grid.Repr())
+	}
+	if err := gen.Err(); !errors.Is(err, ErrNoGridsPossible) {
+		t.Fatalf("Err() = %v, want ErrNoGridsPossible", err)
+	}
+	partial := gen.BestPartial()
+	if len(partial) != 6 || len(partial[0]) != 6 {
+		t.Fatalf("BestPartial() = %q, want a 6x6 grid", partial)
+	}
+	decided := 0
+	for _, row := range partial {
+		for _, r := range row {
+			if r == CellBlocked {
+				t.Errorf("BestPartial() = %q has a blocked cell, want none", partial)
+			}
+			if r != CellUnknown {
+				decided++
+			}
+		}
+	}
+	if decided == 0 {
+		t.Errorf("BestPartial() = %q has no decided cells", partial)
+	}
+}
+
+func TestWithProgressCallback(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	type call struct{ explored, pruned int64 }
+	var calls []call
+	var progress Progress
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	}, WithProgress(&progress), WithProgressCallback(func(explored, pruned int64) {
+		calls = append(calls, call{explored, pruned})
+	}))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for range gen.PossibleGrids(ctx) {
+		if count++; count >= 500 {
+			break
+		}
+	}
+
+	s := progress.Snapshot()
+	if len(calls) == 0 {
+		t.Fatalf("callback never called for %d nodes explored", s.NodesExplored)
+	}
+	if want := int(s.NodesExplored / 1000); len(calls) != want {
+		t.Errorf("callback called %d times for %d nodes explored, want %d", len(calls), s.NodesExplored, want)
+	}
+	for i, c := range calls {
+		if want := int64(i+1) * 1000; c.explored != want {
+			t.Errorf("call %d: explored = %d, want %d", i, c.explored, want)
+		}
+		if c.pruned <= 0 || c.pruned > s.Pruned {
+			t.Errorf("call %d: pruned = %d, want between 1 and %d", i, c.pruned, s.Pruned)
+		}
+	}
+}
+
+func TestWithStats(t *testing.T) {
+	words := loadWords(t)
+
+	for _, workers := range []int{1, 2} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			var stats Stats
+			gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+				MinWordLength: 3,
+			}, WithStats(&stats), WithWorkers(workers))
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			count := 0
+			for range gen.PossibleGrids(ctx) {
+				if count++; count >= 5 {
+					break
+				}
+			}
+
+			if stats.GridsFound != int64(count) {
+				t.Errorf("GridsFound = %d, want %d", stats.GridsFound, count)
+			}
+			if stats.NodesExplored == 0 || stats.NodesPruned == 0 || stats.MaxDepthReached == 0 {
+				t.Errorf("expected some nodes explored and pruned at some depth, got %+v", stats)
+			}
+			if stats.NodesPruned > stats.NodesExplored {
+				t.Errorf("more nodes pruned than explored: %+v", stats)
+			}
+			if stats.TimeElapsed <= 0 {
+				t.Errorf("TimeElapsed = %v, want > 0", stats.TimeElapsed)
+			}
+		})
+	}
+}
+
+// TestPossibleGrids_Workers also checks, under go test -race as CI runs it, that the producer and
+// the workers share no random source.
+func TestPossibleGrids_Workers(t *testing.T) {
+	words := loadWords(t)
+
+	for _, workers := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("%d", workers), func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+				MinWordLength: 3,
+			}, WithWorkers(workers))
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			seen := make(map[string]bool)
+			for grid := range gen.PossibleGrids(ctx) {
+				if seen[grid.Repr()] {
+					t.Errorf("grid yielded twice:\n%s", grid.Repr())
+				}
+				seen[grid.Repr()] = true
+				if len(seen) >= 5 {
+					break
+				}
+			}
+			if len(seen) != 5 {
+				t.Errorf("expected 5 grids, got %d", len(seen))
+			}
+		})
+	}
+}
+
+func TestPossibleGrids_WorkersDeterministic(t *testing.T) {
+	words := loadWords(t)
+
+	run := func() []string {
+		rng := rand.New(rand.NewPCG(7, 7))
+		gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+			MinWordLength: 3,
+		}, WithWorkers(1))
+
+		var reprs []string
+		for grid := range gen.PossibleGrids(t.Context()) {
+			reprs = append(reprs, grid.Repr())
+			if len(reprs) >= 3 {
+				break
+			}
+		}
+		return reprs
+	}
+
+	if first, second := run(), run(); !slices.Equal(first, second) {
+		t.Errorf("expected identical output for the same seed, got:\n%v\nand:\n%v", first, second)
+	}
+}
+
+func TestWithParallelPropagation(t *testing.T) {
+	words := loadTrimmedWords(t)
+
+	run := func(opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(5, append(opts, WithPreferredWords(words), WithSeed(7, 7))...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var reprs []string
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if reprs = append(reprs, grid.Repr()); len(reprs) >= 20 {
+				break
+			}
+		}
+		return reprs
+	}
+
+	sequential := run()
+	if len(sequential) == 0 {
+		t.Fatal("found no grids")
+	}
+	if parallel := run(WithParallelPropagation(4)); !slices.Equal(sequential, parallel) {
+		t.Errorf("got grids:\n%v\nwith parallel propagation, want the same as without:\n%v", parallel, sequential)
+	}
+
+	if _, err := CreateGeneratorE(5, WithParallelPropagation(0)); err == nil {
+		t.Error("CreateGeneratorE(WithParallelPropagation(0)) succeeded, want an error")
+	}
+}
+
+// BenchmarkParallelPropagation measures filtering the rows, then the columns, of a 9x9 grid with
+// a few letters filled in.
+func BenchmarkParallelPropagation(b *testing.B) {
+	words := loadTrimmedWords(b)
+	gen, err := CreateGeneratorE(9, WithPreferredWords(words), WithSeed(42, 1024))
+	if err != nil {
+		b.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	root, err := gen.initialState(b.Context())
+	if err != nil {
+		b.Fatalf("initialState() error: %v", err)
+	}
+	partial := make([][]rune, 9)
+	for y := range partial {
+		partial[y] = []rune(strings.Repeat(string(CellUnknown), 9))
+	}
+	copy(partial[0], []rune("stars"))
+	copy(partial[4][4:], []rune("tea"))
+	partial[8][8] = 'e'
+	applyPartial(root, partial)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("9x9/%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				s := *root
+				s.across, s.down = slices.Clone(root.across), slices.Clone(root.down)
+				s.acrossWhy, s.downWhy = slices.Clone(root.acrossWhy), slices.Clone(root.downWhy)
+				s, _ = prefilter(b.Context(), s, DirectionHorizontal, workers)
+				prefilter(b.Context(), s, DirectionVertical, workers)
+			}
+		})
+	}
+}
+
+func BenchmarkPossibleGrids_Workers(b *testing.B) {
+	words := loadWords(b)
+	b.ReportAllocs()
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("5x5/%d", workers), func(b *testing.B) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			for b.Loop() {
+				gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+					MinWordLength: 3,
+				}, WithWorkers(workers))
+
+				numReturned := 0
+				for range gen.PossibleGrids(b.Context()) {
+					numReturned++
+					if numReturned >= 20 {
+						break
+					}
+				}
+				b.ReportMetric(float64(numReturned), "boards_returned")
+			}
+		})
+	}
+}
+
+// runs returns the lengths of the runs of letters between blocked cells in line.
+func runs(line []rune) []int {
+	var lengths []int
+	current := 0
+	for _, r := range line {
+		if r == '`' {
+			if current > 0 {
+				lengths = append(lengths, current)
+			}
+			current = 0
+			continue
+		}
+		current++
+	}
+	if current > 0 {
+		lengths = append(lengths, current)
+	}
+	return lengths
+}
+
+// countBlocks returns the number of blocked cells in grid.
+func countBlocks(grid Grid) int {
+	blocks := 0
+	for y := range grid.Height() {
+		for x := range grid.Width() {
+			if grid.Get(x, y) == '`' {
+				blocks++
+			}
+		}
+	}
+	return blocks
+}
+
+// gridLines returns every row and column of grid.
+func gridLines(grid Grid) [][]rune {
+	var lines [][]rune
+	for y := range grid.Height() {
+		var row []rune
+		for x := range grid.Width() {
+			row = append(row, grid.Get(x, y))
+		}
+		lines = append(lines, row)
+	}
+	for x := range grid.Width() {
+		var col []rune
+		for y := range grid.Height() {
+			col = append(col, grid.Get(x, y))
+		}
+		lines = append(lines, col)
+	}
+	return lines
+}
+
+func TestPossibleGrids_WordLengthBounds(t *testing.T) {
+	words := append(loadWords(t), "a", "i", "o")
+
+	for _, tc := range []struct {
+		name     string
+		size     int
+		opts     []GeneratorOption
+		min, max int
+		// wantGrids is false when the word list cannot fill any grid within the bounds.
+		wantGrids bool
+	}{
+		{name: "min 1", size: 3, opts: []GeneratorOption{WithMinWordLength(1)}, min: 1, max: 3, wantGrids: true},
+		{name: "min side length", size: 4, opts: []GeneratorOption{WithMinWordLength(4)}, min: 4, max: 4, wantGrids: true},
+		{name: "max 1", size: 3, opts: []GeneratorOption{WithMinWordLength(1), WithMaxWordLength(1)}, min: 1, max: 1},
+		{name: "max side length", size: 4, opts: []GeneratorOption{WithMaxWordLength(4)}, min: 3, max: 4, wantGrids: true},
+		{name: "max below side length", size: 5, opts: []GeneratorOption{WithMaxWordLength(4)}, min: 3, max: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			gen := CreateGenerator(tc.size, words, nil, nil, rng, GeneratorParams{}, tc.opts...)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			count := 0
+			for grid := range gen.PossibleGrids(ctx) {
+				count++
+				for _, line := range gridLines(grid) {
+					for _, n := range runs(line) {
+						if n < tc.min || n > tc.max {
+							t.Errorf("grid #%d has a word of length %d, want between %d and %d:\n%s", count, n, tc.min, tc.max, grid.Repr())
+						}
+					}
+				}
+				if count >= 3 {
+					break
+				}
+			}
+			if count == 0 && tc.wantGrids {
+				t.Error("expected at least one grid")
+			}
+		})
+	}
+}
+
+func TestWithWordLength_Invalid(t *testing.T) {
+	if err := WithMinWordLength(0)(&Generator{}); err == nil {
+		t.Error("expected an error for a minimum word length of 0")
+	}
+	if err := WithMaxWordLength(0)(&Generator{}); err == nil {
+		t.Error("expected an error for a maximum word length of 0")
+	}
+}
+
+func TestCreateGeneratorE(t *testing.T) {
+	words := loadTrimmedWords(t)
+
+	gen, err := CreateGeneratorE(5,
+		WithPreferredWords(words),
+		WithRand(rand.New(rand.NewPCG(42, 1024))),
+		WithHeight(4),
+		WithSymmetry("vertical"),
+	)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	if gen.LineLength != 5 || gen.Height != 4 {
+		t.Errorf("CreateGeneratorE() made a %dx%d generator, want 5x4", gen.LineLength, gen.Height)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	found := false
+	for grid := range gen.PossibleGrids(ctx) {
+		found = true
+		if width, height := grid.Size(); width != 5 || height != 4 {
+			t.Errorf("grid is %dx%d, want 5x4:\n%s", width, height, grid.Repr())
+		}
+		break
+	}
+	if !found {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestCreateGeneratorE_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		size int
+		opts []GeneratorOption
+	}{
+		{name: "zero size", size: 0},
+		{name: "uppercase word", size: 4, opts: []GeneratorOption{WithPreferredWords([]string{"able", "Bake"})}},
+		{name: "empty obscure word", size: 4, opts: []GeneratorOption{WithObscureWords([]string{""})}},
+		{name: "excluded word with digit", size: 4, opts: []GeneratorOption{WithExcludedWords([]string{"r2d2"})}},
+		{name: "nil rand", size: 4, opts: []GeneratorOption{WithRand(nil)}},
+		{name: "zero height", size: 4, opts: []GeneratorOption{WithHeight(0)}},
+		{name: "unknown symmetry", size: 4, opts: []GeneratorOption{WithSymmetry("diagonal")}},
+		{name: "min above max", size: 4, opts: []GeneratorOption{WithMinWordLength(4), WithMaxWordLength(3)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if gen, err := CreateGeneratorE(tc.size, tc.opts...); err == nil {
+				t.Errorf("CreateGeneratorE() = %v, want an error", gen)
+			}
+		})
+	}
+}
+
+func TestPossibleGrids_MaxBlockFraction(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+
+	const fraction = 1.0 / 6
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{
+		MinWordLength: 3,
+	}, WithMaxBlockFraction(fraction))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		blocks := countBlocks(grid)
+		if float64(blocks) > fraction*25 {
+			t.Errorf("grid #%d has %d blocks, more than %v of its cells:\n%s", count, blocks, fraction, grid.Repr())
+		}
+		if count >= 5 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestPossibleGrids_MaxBlocks(t *testing.T) {
+	words := loadWords(t)
+
+	for _, tc := range []struct {
+		name      string
+		size      int
+		maxBlocks int
+		opts      []GeneratorOption
+		partial   []string
+	}{
+		{name: "word squares", size: 4, maxBlocks: 0},
+		{name: "two blocks", size: 5, maxBlocks: 2},
+		{name: "with symmetry", size: 5, maxBlocks: 2, opts: []GeneratorOption{WithRotationalSymmetry()}},
+		{name: "template blocks count", size: 5, maxBlocks: 2, partial: []string{
+			"#....",
+			".....",
+			".....",
+			".....",
+			"....#",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			opts := append([]GeneratorOption{WithMaxBlocks(tc.maxBlocks)}, tc.opts...)
+			gen := CreateGenerator(tc.size, words, nil, nil, rng, GeneratorParams{}, opts...)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			grids := gen.PossibleGrids(ctx)
+			if tc.partial != nil {
+				partial := make([][]rune, len(tc.partial))
+				for i, row := range tc.partial {
+					partial[i] = []rune(row)
+				}
+				var err error
+				if grids, err = gen.PossibleGridsFrom(ctx, partial); err != nil {
+					t.Fatalf("PossibleGridsFrom() error: %v", err)
+				}
+			}
+
+			count := 0
+			for grid := range grids {
+				count++
+				if blocks := countBlocks(grid); blocks > tc.maxBlocks {
+					t.Errorf("grid #%d has %d blocks, more than %d:\n%s", count, blocks, tc.maxBlocks, grid.Repr())
+				}
+				if count >= 5 {
+					break
+				}
+			}
+			if count == 0 {
+				t.Error("expected at least one grid")
+			}
+		})
+	}
+}
+
+func TestWithMaxBlockFraction_Invalid(t *testing.T) {
+	for _, fraction := range []float64{-0.5, 0, 1, 1.5} {
+		if err := WithMaxBlockFraction(fraction)(&Generator{}); err == nil {
+			t.Errorf("expected an error for fraction %v", fraction)
+		}
+	}
+}
+
+func TestWithMaxBlocks_Invalid(t *testing.T) {
+	if err := WithMaxBlocks(-1)(&Generator{}); err == nil {
+		t.Error("expected an error for a negative maximum number of blocks")
+	}
+}
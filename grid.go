@@ -1,8 +1,17 @@
 package xwgen
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strings"
+	"unicode"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
 )
 
 // Grid is a 2D grid of runes.
@@ -10,6 +19,15 @@ import (
 // It represents a 'definite' possible grid
 type Grid struct {
 	grid [][]rune
+
+	// The words of the grid are only known for grids created by a Generator.
+	wordsAcross  []string
+	wordsDown    []string
+	obscureWords []string
+	// rebus holds the strings of the rebus cells of the grid, by row and column.
+	rebus map[[2]int]string
+	// bars, if set, separate the entries of a barred grid.
+	bars *Bars
 }
 
 func NewGrid(g [][]rune) Grid {
@@ -26,10 +44,145 @@ func (g Grid) Height() int {
 	return len(g.grid)
 }
 
+// Size returns the width and height of the grid.
+func (g Grid) Size() (width, height int) {
+	return g.Width(), g.Height()
+}
+
 func (g Grid) Get(x, y int) rune {
 	return g.grid[y][x]
 }
 
+// Cell returns the rune at the given row and column, which is primitives.Blocked for a blocked
+// cell. It is the same as Get(col, row).
+func (g Grid) Cell(row, col int) rune {
+	return g.grid[row][col]
+}
+
+// IsBlocked returns true if the cell at the given row and column is blocked.
+func (g Grid) IsBlocked(row, col int) bool {
+	return g.grid[row][col] == primitives.Blocked
+}
+
+// Rebus returns the string of the rebus cell at the given row and column, e.g. "heart", or "" if
+// the cell holds a single letter. Cell returns the first letter of the string.
+func (g Grid) Rebus(row, col int) string {
+	return g.rebus[[2]int{row, col}]
+}
+
+// WithRebus returns a copy of the grid where the cell at the given row and column is a rebus cell
+// holding s, a string of two or more lowercase letters. The words of the grid are left as they are.
+func (g Grid) WithRebus(row, col int, s string) Grid {
+	rows := slices.Clone(g.grid)
+	rows[row] = slices.Clone(rows[row])
+	rows[row][col] = []rune(s)[0]
+	g.grid = rows
+	rebus := maps.Clone(g.rebus)
+	if rebus == nil {
+		rebus = make(map[[2]int]string)
+	}
+	rebus[[2]int{row, col}] = s
+	g.rebus = rebus
+	return g
+}
+
+// Bars returns the bars of a barred grid, which separate its entries in place of blocked cells, or
+// nil if the grid is not barred.
+func (g Grid) Bars() *Bars {
+	return g.bars
+}
+
+// WithBars returns a copy of the grid that is barred by bars, which must have its size, or not
+// barred if bars is nil. The words of the grid are left as they are.
+func (g Grid) WithBars(bars *Bars) Grid {
+	g.bars = bars
+	return g
+}
+
+// barAfter returns true if a bar separates the cell at the given row and column from the next
+// cell along dir.
+func (g Grid) barAfter(row, col int, dir Direction) bool {
+	if g.bars == nil {
+		return false
+	}
+	if dir == DirectionVertical {
+		return g.bars.Below[row][col]
+	}
+	return g.bars.Right[row][col]
+}
+
+// WordsAcross returns the across words of the grid, from top to bottom and left to right. It is
+// empty unless the grid was created by a Generator.
+func (g Grid) WordsAcross() []string {
+	return g.wordsAcross
+}
+
+// WordsDown returns the down words of the grid, from left to right and top to bottom. It is empty
+// unless the grid was created by a Generator.
+func (g Grid) WordsDown() []string {
+	return g.wordsDown
+}
+
+// AllWords returns every word in the grid, across words first. It is empty unless the grid was
+// created by a Generator.
+func (g Grid) AllWords() []string {
+	return slices.Concat(g.wordsAcross, g.wordsDown)
+}
+
+// ObscureWords returns the words in the grid that only appear in the generator's obscure word list.
+func (g Grid) ObscureWords() []string {
+	return g.obscureWords
+}
+
+// ObscureWordFraction returns the fraction of the words in the grid that are obscure, from 0 to 1.
+// For grids not created by a Generator, the words are its Entries.
+func (g Grid) ObscureWordFraction() float64 {
+	total := len(g.AllWords())
+	if total == 0 {
+		total = len(g.Entries())
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(len(g.obscureWords)) / float64(total)
+}
+
+// PreferredRatio returns the fraction of the words in the grid that are not obscure, from 0 to 1.
+// It is 1 - ObscureWordFraction.
+func (g Grid) PreferredRatio() float64 {
+	return 1 - g.ObscureWordFraction()
+}
+
+// LetterHistogram returns the number of cells of the grid holding each letter.
+func (g Grid) LetterHistogram() map[rune]int {
+	histogram := make(map[rune]int)
+	for _, row := range g.grid {
+		for _, r := range row {
+			if r != primitives.Blocked {
+				histogram[r]++
+			}
+		}
+	}
+	return histogram
+}
+
+// LetterEntropy returns the Shannon entropy of the letters of the grid in bits, a measure of their
+// variety: 0 if every cell holds the same letter, and higher the more letters the grid uses and the
+// more evenly it uses them, up to log2(26), about 4.7, for every letter equally often.
+func (g Grid) LetterEntropy() float64 {
+	histogram := g.LetterHistogram()
+	total := 0
+	for _, n := range histogram {
+		total += n
+	}
+	var entropy float64
+	for _, n := range histogram {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
 func (g Grid) Repr() string {
 	lines := make([]string, g.Height())
 	for y := range g.Height() {
@@ -38,6 +191,183 @@ func (g Grid) Repr() string {
 	return strings.Join(lines, "\n")
 }
 
+// ReprBarred returns the grid with box-drawing characters around it, and between the cells that
+// its bars separate, with uppercase letters for filled cells and '#' for blocked cells, e.g.
+//
+//	┌─────────┐
+//	│C A T│S A│
+//	│  ───    │
+//	│O R E S T│
+//	└─────────┘
+//
+// A grid without bars is drawn in the same way, with only the border.
+func (g Grid) ReprBarred() string {
+	width, height := g.Size()
+	var b strings.Builder
+	border := strings.Repeat("─", 2*width-1)
+	b.WriteString("┌" + border + "┐\n")
+	for row := range height {
+		b.WriteString("│")
+		for col := range width {
+			if g.IsBlocked(row, col) {
+				b.WriteByte('#')
+			} else {
+				b.WriteRune(unicode.ToUpper(g.Cell(row, col)))
+			}
+			switch {
+			case col == width-1:
+				b.WriteString("│\n")
+			case g.barAfter(row, col, DirectionHorizontal):
+				b.WriteString("│")
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		if row == height-1 {
+			continue
+		}
+		b.WriteString("│")
+		for col := range width {
+			if g.barAfter(row, col, DirectionVertical) {
+				b.WriteString("─")
+			} else {
+				b.WriteByte(' ')
+			}
+			// Between two cells with bars below them, the bar continues.
+			if col < width-1 && g.barAfter(row, col, DirectionVertical) && g.barAfter(row, col+1, DirectionVertical) {
+				b.WriteString("─")
+			} else if col < width-1 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString("│\n")
+	}
+	b.WriteString("└" + border + "┘")
+	return b.String()
+}
+
+// reprKey returns Repr, followed by the strings of the rebus cells, if any, so that grids that
+// only differ in those have different keys.
+func (g Grid) reprKey() string {
+	repr := g.Repr()
+	if len(g.rebus) == 0 {
+		return repr
+	}
+	cells := slices.SortedFunc(maps.Keys(g.rebus), func(a, b [2]int) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	var b strings.Builder
+	b.WriteString(repr)
+	for _, cell := range cells {
+		fmt.Fprintf(&b, "\n%d,%d=%s", cell[0], cell[1], g.rebus[cell])
+	}
+	return b.String()
+}
+
+// ReprStandard returns the grid in the conventional form for people: one line per row, with '#'
+// for blocked cells, uppercase letters for filled cells, and spaces for cells that are not filled,
+// e.g. primitives.Wildcard in a grid made by NewGrid. Unlike Repr, it cannot be read back.
+func (g Grid) ReprStandard() string {
+	lines := make([]string, g.Height())
+	for y, row := range g.grid {
+		line := make([]rune, len(row))
+		for x, r := range row {
+			switch {
+			case r == primitives.Blocked:
+				line[x] = '#'
+			case unicode.IsLetter(r):
+				line[x] = unicode.ToUpper(r)
+			default:
+				line[x] = ' '
+			}
+		}
+		lines[y] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownBlocked is the cell of a blocked cell in ReprMarkdown: a non-breaking space, since
+// Markdown tables have no way to shade a cell.
+const markdownBlocked = "&nbsp;"
+
+// ReprMarkdown returns the grid as a GitHub-Flavored Markdown table, e.g. to paste into an issue
+// comment, with uppercase letters, or the strings of rebus cells, for filled cells, a non-breaking
+// space for blocked cells, and nothing for cells that are not filled, e.g.
+//
+//	|   |   |   |
+//	|:-:|:-:|:-:|
+//	| C | A | &nbsp; |
+//	| O | R | E |
+//
+// Markdown tables need a header, so the first row is an empty one.
+func (g Grid) ReprMarkdown() string {
+	width, height := g.Size()
+	var b strings.Builder
+	b.WriteString("|" + strings.Repeat("   |", width) + "\n")
+	b.WriteString("|" + strings.Repeat(":-:|", width) + "\n")
+	for row := range height {
+		b.WriteByte('|')
+		for col := range width {
+			cell := ""
+			switch r := g.Cell(row, col); {
+			case r == primitives.Blocked:
+				cell = markdownBlocked
+			case g.Rebus(row, col) != "":
+				cell = strings.ToUpper(g.Rebus(row, col))
+			case unicode.IsLetter(r):
+				cell = string(unicode.ToUpper(r))
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		if row < height-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
 func (g Grid) DebugString() string {
 	return fmt.Sprintf("Grid{width: %d, height: %d, grid: %v}", g.Width(), g.Height(), g.grid)
 }
+
+// CanonicalKey returns a key that is the same for grids that are equivalent under the symmetries
+// of a square: transposing (swapping across and down), rotating, and reflecting. It is a hash, so
+// that a set of seen keys takes a fixed amount of memory per grid regardless of its size.
+func (g Grid) CanonicalKey() string {
+	width, height := g.Size()
+	transforms := []func(x, y int) (int, int){
+		func(x, y int) (int, int) { return x, y },
+		func(x, y int) (int, int) { return width - 1 - x, y },
+		func(x, y int) (int, int) { return x, height - 1 - y },
+		func(x, y int) (int, int) { return width - 1 - x, height - 1 - y },
+	}
+
+	var canonical string
+	for _, transpose := range []bool{false, true} {
+		for _, transform := range transforms {
+			// Write the transformed grid row by row. Transposed grids are read column by column.
+			outer, inner := height, width
+			if transpose {
+				outer, inner = width, height
+			}
+			var b strings.Builder
+			fmt.Fprintf(&b, "%dx%d:", inner, outer)
+			for i := range outer {
+				for j := range inner {
+					x, y := j, i
+					if transpose {
+						x, y = i, j
+					}
+					b.WriteRune(g.Get(transform(x, y)))
+				}
+				b.WriteByte('\n')
+			}
+			if s := b.String(); canonical == "" || s < canonical {
+				canonical = s
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:16])
+}
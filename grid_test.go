@@ -0,0 +1,188 @@
+package xwgen
+
+import (
+	"context"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// splitWords returns the runs of letters in line.
+func splitWords(line []rune) []string {
+	return slices.DeleteFunc(strings.Split(string(line), "`"), func(s string) bool { return s == "" })
+}
+
+func TestGrid_Accessors(t *testing.T) {
+	words := loadWords(t)
+	rng := rand.New(rand.NewPCG(42, 1024))
+	gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{Height: 4})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		if count++; count > 5 {
+			break
+		}
+
+		if w, h := grid.Size(); w != 5 || h != 4 {
+			t.Fatalf("Size() = (%d, %d), want (5, 4)", w, h)
+		}
+
+		var wantAcross, wantDown []string
+		for row := range 4 {
+			var line []rune
+			for col := range 5 {
+				line = append(line, grid.Cell(row, col))
+				if grid.Cell(row, col) != grid.Get(col, row) {
+					t.Errorf("Cell(%d, %d) = %q, but Get(%d, %d) = %q", row, col, grid.Cell(row, col), col, row, grid.Get(col, row))
+				}
+				if got, want := grid.IsBlocked(row, col), grid.Cell(row, col) == '`'; got != want {
+					t.Errorf("IsBlocked(%d, %d) = %v, want %v", row, col, got, want)
+				}
+			}
+			wantAcross = append(wantAcross, splitWords(line)...)
+		}
+		for col := range 5 {
+			var line []rune
+			for row := range 4 {
+				line = append(line, grid.Cell(row, col))
+			}
+			wantDown = append(wantDown, splitWords(line)...)
+		}
+
+		if got := grid.WordsAcross(); !slices.Equal(got, wantAcross) {
+			t.Errorf("WordsAcross() = %q, want %q in grid:\n%s", got, wantAcross, grid.Repr())
+		}
+		if got := grid.WordsDown(); !slices.Equal(got, wantDown) {
+			t.Errorf("WordsDown() = %q, want %q in grid:\n%s", got, wantDown, grid.Repr())
+		}
+		if got, want := grid.AllWords(), slices.Concat(wantAcross, wantDown); !slices.Equal(got, want) {
+			t.Errorf("AllWords() = %q, want %q", got, want)
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestGrid_CanonicalKey(t *testing.T) {
+	grid := gridFromRows(
+		"ab#",
+		"cde",
+		"#fg",
+	)
+	for _, tc := range []struct {
+		name string
+		grid Grid
+		same bool
+	}{
+		{name: "itself", grid: grid, same: true},
+		{name: "transpose", grid: gridFromRows("ac#", "bdf", "#eg"), same: true},
+		{name: "rotated", grid: gridFromRows("#ca", "fdb", "ge#"), same: true},
+		{name: "reflected", grid: gridFromRows("#ba", "edc", "gf#"), same: true},
+		{name: "rotated 180", grid: gridFromRows("gf#", "edc", "#ba"), same: true},
+		{name: "different letter", grid: gridFromRows("ab#", "cxe", "#fg")},
+		{name: "different blocks", grid: gridFromRows("abh", "cde", "#fg")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.grid.CanonicalKey() == grid.CanonicalKey(); got != tc.same {
+				t.Errorf("CanonicalKey() of\n%s\nequal to that of\n%s\n= %v, want %v", tc.grid.Repr(), grid.Repr(), got, tc.same)
+			}
+		})
+	}
+
+	rect := gridFromRows("abc", "def")
+	if rect.CanonicalKey() != gridFromRows("ad", "be", "cf").CanonicalKey() {
+		t.Error("expected a rectangular grid and its transpose to have the same key")
+	}
+}
+
+func TestGrid_Entries(t *testing.T) {
+	across, down := DirectionHorizontal, DirectionVertical
+	for _, tc := range []struct {
+		name string
+		grid Grid
+		want []Entry
+	}{
+		{
+			name: "shared numbers",
+			grid: gridFromRows("`cat", "aloe", "tone", "ends"),
+			want: []Entry{
+				{across, 1, 0, 1, 3, "cat"}, {down, 1, 0, 1, 4, "clon"},
+				{down, 2, 0, 2, 4, "aond"}, {down, 3, 0, 3, 4, "tees"},
+				{across, 4, 1, 0, 4, "aloe"}, {down, 4, 1, 0, 3, "ate"},
+				{across, 5, 2, 0, 4, "tone"},
+				{across, 6, 3, 0, 4, "ends"},
+			},
+		},
+		{
+			name: "single letters are not entries",
+			grid: gridFromRows("a`b", "cde", "f`g"),
+			want: []Entry{
+				{down, 1, 0, 0, 3, "acf"},
+				{down, 2, 0, 2, 3, "beg"},
+				{across, 3, 1, 0, 3, "cde"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.grid.Entries(); !slices.Equal(got, tc.want) {
+				t.Errorf("Entries() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGrid_LetterHistogram(t *testing.T) {
+	for _, tc := range []struct {
+		rows        []string
+		want        map[rune]int
+		wantEntropy float64
+	}{
+		{rows: []string{"aaa", "aaa"}, want: map[rune]int{'a': 6}, wantEntropy: 0},
+		{rows: []string{"ab`", "`ab"}, want: map[rune]int{'a': 2, 'b': 2}, wantEntropy: 1},
+		{rows: []string{"abcd"}, want: map[rune]int{'a': 1, 'b': 1, 'c': 1, 'd': 1}, wantEntropy: 2},
+		{rows: []string{"```"}, want: map[rune]int{}, wantEntropy: 0},
+	} {
+		grid := gridFromRows(tc.rows...)
+		if got := grid.LetterHistogram(); !maps.Equal(got, tc.want) {
+			t.Errorf("LetterHistogram() of %q = %v, want %v", tc.rows, got, tc.want)
+		}
+		if got := grid.LetterEntropy(); got != tc.wantEntropy {
+			t.Errorf("LetterEntropy() of %q = %v, want %v", tc.rows, got, tc.wantEntropy)
+		}
+	}
+}
+
+func TestGrid_ReprStandard(t *testing.T) {
+	for _, tc := range []struct {
+		rows         []string
+		wantRepr     string
+		wantStandard string
+	}{
+		{rows: []string{"ab`", "`cd"}, wantRepr: "ab`\n`cd", wantStandard: "AB#\n#CD"},
+		{rows: []string{"a?c", "```"}, wantRepr: "a?c\n```", wantStandard: "A C\n###"},
+		{rows: []string{"abc"}, wantRepr: "abc", wantStandard: "ABC"},
+	} {
+		grid := gridFromRows(tc.rows...)
+		if got := grid.Repr(); got != tc.wantRepr {
+			t.Errorf("Repr() of %q = %q, want %q", tc.rows, got, tc.wantRepr)
+		}
+		if got := grid.ReprStandard(); got != tc.wantStandard {
+			t.Errorf("ReprStandard() of %q = %q, want %q", tc.rows, got, tc.wantStandard)
+		}
+	}
+}
+
+func TestGrid_ReprMarkdown(t *testing.T) {
+	grid := gridFromRows("ca`", "or?").WithRebus(1, 1, "rot")
+	want := "|   |   |   |\n|:-:|:-:|:-:|\n| C | A | &nbsp; |\n| O | ROT |  |"
+	if got := grid.ReprMarkdown(); got != want {
+		t.Errorf("ReprMarkdown() = %q, want %q", got, want)
+	}
+}
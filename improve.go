@@ -0,0 +1,151 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// maxImproveCandidates is the number of ways to refill an entry and its crossing entries that
+// Improve considers for each entry.
+const maxImproveCandidates = 1000
+
+// Swap is a change made by Improve: an entry was replaced, and the entries crossing it were
+// refilled to match.
+type Swap struct {
+	// Entry is the entry that was replaced, as it was before the swap.
+	Entry Entry
+	// Removed holds the answers that were in the grid before the swap, and Added the answers
+	// that replaced them, each in clue order.
+	Removed, Added []string
+	// Score is the score of the grid after the swap.
+	Score float64
+}
+
+// Improve polishes grid, one of the generator's grids, by local search: for each entry in turn,
+// it refills the entry and every entry crossing it, holding the rest of the grid fixed, and keeps
+// the refill that scorer rates highest if that beats the grid so far. It repeats until no entry can
+// be refilled for a better score, or ctx is done.
+//
+// It returns the improved grid, which has the same blocked cells as grid, and the swaps that led
+// to it, in order. If ctx is done first, it returns the grid and swaps so far along with ctx's
+// error. It returns an error without any swaps if grid does not have the generator's dimensions.
+func (g *Generator) Improve(ctx context.Context, grid Grid, scorer Scorer) (Grid, []Swap, error) {
+	if width, height := grid.Size(); width != g.LineLength || height != g.Height {
+		return grid, nil, fmt.Errorf("grid is %dx%d, expected %dx%d", width, height, g.LineLength, g.Height)
+	}
+
+	var swaps []Swap
+	score := scorer.Score(grid)
+	for improved := true; improved; {
+		improved = false
+		for _, entry := range grid.Entries() {
+			if err := ctx.Err(); err != nil {
+				return grid, swaps, err
+			}
+			better, betterScore, ok, err := g.refill(ctx, grid, entry, scorer, score)
+			if err != nil {
+				return grid, swaps, err
+			}
+			if !ok {
+				continue
+			}
+			removed, added := changedAnswers(grid, better)
+			swaps = append(swaps, Swap{Entry: entry, Removed: removed, Added: added, Score: betterScore})
+			grid, score, improved = better, betterScore, true
+			// The entries of the new grid differ, so start over from the first.
+			break
+		}
+	}
+	return grid, swaps, nil
+}
+
+// refill returns the best grid that differs from grid only in entry and the entries crossing it,
+// and has the same blocked cells, if it scores better than score.
+func (g *Generator) refill(ctx context.Context, grid Grid, entry Entry, scorer Scorer, score float64) (Grid, float64, bool, error) {
+	partial := make([][]rune, grid.Height())
+	for y := range partial {
+		partial[y] = make([]rune, grid.Width())
+		for x := range partial[y] {
+			partial[y][x] = grid.Get(x, y)
+			if grid.IsBlocked(y, x) {
+				partial[y][x] = CellBlocked
+			}
+		}
+	}
+	for _, cell := range entry.cells() {
+		for _, crossing := range grid.Entries() {
+			if crossing.Direction != entry.Direction && slices.Contains(crossing.cells(), cell) {
+				for _, c := range crossing.cells() {
+					partial[c[0]][c[1]] = CellUnknown
+				}
+			}
+		}
+		partial[cell[0]][cell[1]] = CellUnknown
+	}
+
+	state, err := g.initialState(ctx)
+	if err != nil {
+		return Grid{}, 0, false, err
+	}
+	applyPartial(state, partial)
+	// Don't draw from the generator's source, which would change the grids its searches go on to
+	// find.
+	state.rand = rand.New(rand.NewPCG(1, 2))
+
+	var best Grid
+	found := false
+	candidates := 0
+	sr := &searcher{g: g, ctx: ctx, untracked: true}
+	for candidate := range sr.possibleGridsAtRoot(state) {
+		if candidates++; candidates > maxImproveCandidates {
+			break
+		}
+		if !sameBlocks(grid, candidate) {
+			continue
+		}
+		if s := scorer.Score(candidate); s > score {
+			best, score, found = candidate, s, true
+		}
+	}
+	return best, score, found, nil
+}
+
+// cells returns the row and column of each cell of the entry.
+func (e Entry) cells() [][2]int {
+	cells := make([][2]int, e.Length)
+	for i := range cells {
+		if e.Direction == DirectionHorizontal {
+			cells[i] = [2]int{e.Row, e.Col + i}
+		} else {
+			cells[i] = [2]int{e.Row + i, e.Col}
+		}
+	}
+	return cells
+}
+
+// sameBlocks returns true if a and b have the same blocked cells.
+func sameBlocks(a, b Grid) bool {
+	for y := range a.Height() {
+		for x := range a.Width() {
+			if a.IsBlocked(y, x) != b.IsBlocked(y, x) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// changedAnswers returns the answers of the entries that differ between before and after, which
+// have the same blocked cells, in clue order.
+func changedAnswers(before, after Grid) (removed, added []string) {
+	a, b := before.Entries(), after.Entries()
+	for i := range a {
+		if a[i].Answer != b[i].Answer {
+			removed = append(removed, a[i].Answer)
+			added = append(added, b[i].Answer)
+		}
+	}
+	return removed, added
+}
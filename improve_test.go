@@ -0,0 +1,77 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestImprove(t *testing.T) {
+	var preferred, obscure []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			obscure = append(obscure, word)
+		} else {
+			preferred = append(preferred, word)
+		}
+	}
+	gen, err := CreateGeneratorE(4, WithPreferredWords(preferred), WithObscureWords(obscure), WithSeed(42, 1024))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+
+	scorer := ClassicScorer(nil)
+	improvedAny := false
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		if count++; count > 5 {
+			break
+		}
+		improved, swaps, err := gen.Improve(ctx, grid, scorer)
+		if err != nil {
+			t.Fatalf("Improve() error: %v", err)
+		}
+		if len(swaps) > 0 {
+			improvedAny = true
+		}
+
+		before, after := scorer.Score(grid), scorer.Score(improved)
+		if after < before {
+			t.Errorf("Improve() made the score worse, from %v to %v", before, after)
+		}
+		prev := before
+		for _, swap := range swaps {
+			if swap.Score <= prev {
+				t.Errorf("swap %+v did not improve the score from %v", swap, prev)
+			}
+			prev = swap.Score
+		}
+		if len(swaps) > 0 && swaps[len(swaps)-1].Score != after {
+			t.Errorf("last swap has score %v, want the improved grid's %v", swaps[len(swaps)-1].Score, after)
+		}
+
+		if !sameBlocks(grid, improved) {
+			t.Errorf("Improve() changed the blocked cells of\n%s\nto\n%s", grid.Repr(), improved.Repr())
+		}
+		for _, entry := range improved.Entries() {
+			if !slices.Contains(preferred, entry.Answer) && !slices.Contains(obscure, entry.Answer) {
+				t.Errorf("improved grid has entry %q, which is not a word:\n%s", entry.Answer, improved.Repr())
+			}
+		}
+
+		// Improving again finds nothing more.
+		if _, again, err := gen.Improve(ctx, improved, scorer); err != nil || len(again) > 0 {
+			t.Errorf("Improve() of an improved grid = %v, %v, want no swaps", again, err)
+		}
+	}
+	if !improvedAny {
+		t.Error("Improve() never improved a grid")
+	}
+
+	if _, _, err := gen.Improve(ctx, gridFromRows("abc", "def", "ghi"), scorer); err == nil {
+		t.Error("Improve() of a grid of the wrong size succeeded, want an error")
+	}
+}
@@ -3,34 +3,55 @@ package internal
 import (
 	"context"
 	"math/rand/v2"
+	"slices"
 
 	"github.com/Eyas/xwgen/pkg/primitives"
 )
 
 type AllPossibleLinesParams struct {
+	// RequiredWords are tried before PreferredWords, and need not be in any other list.
+	RequiredWords  []string
 	PreferredWords []string
 	ObscureWords   []string
 	ExcludedWords  []string
 	LineLength     int
 	MinWordLength  *int
 	MaxWordLength  *int
+	// Frequencies are the frequency scores of words, used to order them with FrequencyBias.
+	Frequencies map[string]float64
+	// FrequencyBias, between 0 and 1, is how strongly preferred and obscure words are each ordered
+	// by descending frequency rather than the given order.
+	FrequencyBias float64
+	// Rand is used to shuffle the possible lines. If nil, the global source is used.
+	Rand *rand.Rand
+	// ShuffleWords shuffles preferred and obscure words among themselves with Rand, rather than
+	// keeping the given order. Required words stay first.
+	ShuffleWords bool
+	// Penalty, if set, orders preferred and obscure words each by ascending penalty, after any
+	// other ordering, and drops those with a penalty of +Inf. Required words are kept first.
+	Penalty func(word string) float64
+	// Dictionary, if set, holds the words to use, already ordered and partitioned by length, in
+	// place of those of the other fields. It must have been built by NewDictionary from the same
+	// words, with a minimum word length no greater than MinWordLength. It is not used with
+	// ShuffleWords, which orders the words differently each time.
+	Dictionary *Dictionary
 }
 
 type params struct {
-	preferredWords []string
-	obscureWords   []string
-	excludedWords  []string
-	lineLength     int
-	minWordLength  int
-	maxWordLength  int
+	lineLength    int
+	minWordLength int
+	maxWordLength int
+	shuffle       func(n int, swap func(i, j int))
 }
 
 func asParams(p AllPossibleLinesParams) params {
+	shuffle := rand.Shuffle
+	if p.Rand != nil {
+		shuffle = p.Rand.Shuffle
+	}
 	pp := params{
-		preferredWords: p.PreferredWords,
-		obscureWords:   p.ObscureWords,
-		excludedWords:  p.ExcludedWords,
-		lineLength:     p.LineLength,
+		lineLength: p.LineLength,
+		shuffle:    shuffle,
 	}
 
 	if p.MinWordLength == nil {
@@ -48,6 +69,47 @@ func asParams(p AllPossibleLinesParams) params {
 	return pp
 }
 
+// orderedWords returns the preferred and obscure words of p in the order they are tried.
+func orderedWords(p AllPossibleLinesParams, shuffle func(n int, swap func(i, j int))) (preferredWords, obscureWords []string) {
+	preferredWords, obscureWords = p.PreferredWords, p.ObscureWords
+	if p.FrequencyBias > 0 {
+		preferredWords = orderByFrequency(preferredWords, p.Frequencies, p.FrequencyBias)
+		obscureWords = orderByFrequency(obscureWords, p.Frequencies, p.FrequencyBias)
+	}
+	if p.ShuffleWords {
+		preferredWords = shuffled(preferredWords, shuffle)
+		obscureWords = shuffled(obscureWords, shuffle)
+	}
+	if p.Penalty != nil {
+		preferredWords = orderByPenalty(preferredWords, p.Penalty)
+		obscureWords = orderByPenalty(obscureWords, p.Penalty)
+	}
+	return withRequiredWordsFirst(p.RequiredWords, preferredWords), obscureWords
+}
+
+// shuffled returns a shuffled copy of words.
+func shuffled(words []string, shuffle func(n int, swap func(i, j int))) []string {
+	words = slices.Clone(words)
+	shuffle(len(words), func(i, j int) {
+		words[i], words[j] = words[j], words[i]
+	})
+	return words
+}
+
+// withRequiredWordsFirst returns preferred with required moved (or added) to the front.
+func withRequiredWordsFirst(required, preferred []string) []string {
+	if len(required) == 0 {
+		return preferred
+	}
+	words := slices.Clone(required)
+	for _, word := range preferred {
+		if !slices.Contains(required, word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
 type allPossibleLineState struct {
 	lineLength    int
 	minWordLength int
@@ -56,7 +118,7 @@ type allPossibleLineState struct {
 	preferredWordsByLength map[int][]string
 	obscureWordsByLength   map[int][]string
 
-	excludedWords map[string]bool
+	shuffle func(n int, swap func(i, j int))
 
 	memoizedLines map[int]primitives.PossibleLines
 }
@@ -92,7 +154,7 @@ func (s *allPossibleLineState) allPossibleLines(ctx context.Context, atLength in
 	betweenIdxStart := s.minWordLength
 	betweenIdxFromEnd := (1 + s.minWordLength)
 	if atLength >= (betweenIdxStart + betweenIdxFromEnd) {
-		blockBetweenPossibilities = make([]primitives.PossibleLines, 0, atLength-6)
+		blockBetweenPossibilities = make([]primitives.PossibleLines, 0, atLength-betweenIdxFromEnd-betweenIdxStart+1)
 		for i := betweenIdxStart; i <= atLength-betweenIdxFromEnd; i++ {
 			firstLength := i                   // Always >= minWordLength.
 			secondLength := atLength - (i + 1) // Always >= minWordLength.
@@ -104,7 +166,7 @@ func (s *allPossibleLineState) allPossibleLines(ctx context.Context, atLength in
 		}
 
 		// Shuffle the possibilities
-		rand.Shuffle(len(blockBetweenPossibilities), func(i, j int) {
+		s.shuffle(len(blockBetweenPossibilities), func(i, j int) {
 			blockBetweenPossibilities[i], blockBetweenPossibilities[j] = blockBetweenPossibilities[j], blockBetweenPossibilities[i]
 		})
 	}
@@ -139,53 +201,36 @@ func (s *allPossibleLineState) allPossibleLines(ctx context.Context, atLength in
 
 // AllPossibleLines returns a set of all possible lines for the given parameters.
 func AllPossibleLines(ctx context.Context, p AllPossibleLinesParams) (primitives.PossibleLines, error) {
+	state := newAllPossibleLineState(p)
+	possibleLines := state.allPossibleLines(ctx, state.lineLength)
+	return possibleLines, ctx.Err()
+}
+
+// newAllPossibleLineState returns the state to build the possible lines of p with.
+func newAllPossibleLineState(p AllPossibleLinesParams) *allPossibleLineState {
 	params := asParams(p)
-	state := allPossibleLineState{
+	dict := p.Dictionary
+	if dict == nil || p.ShuffleWords {
+		dict = NewDictionary(p)
+	}
+	state := &allPossibleLineState{
 		lineLength:    params.lineLength,
 		minWordLength: params.minWordLength,
 		maxWordLength: params.maxWordLength,
+		shuffle:       params.shuffle,
 	}
 	state.memoizedLines = make(map[int]primitives.PossibleLines)
 
 	state.preferredWordsByLength = make(map[int][]string)
 	state.obscureWordsByLength = make(map[int][]string)
-	state.excludedWords = make(map[string]bool)
-
-	for _, word := range params.excludedWords {
-		state.excludedWords[word] = true
-	}
-
-	for _, word := range params.preferredWords {
-		if len(word) < params.minWordLength || len(word) > params.maxWordLength {
-			continue
-		}
-		if _, ok := state.excludedWords[word]; ok {
-			continue
-		}
-		state.preferredWordsByLength[len(word)] = append(state.preferredWordsByLength[len(word)], word)
-	}
-
-	for _, word := range params.obscureWords {
-		if len(word) < params.minWordLength || len(word) > params.maxWordLength {
-			continue
-		}
-		if _, ok := state.excludedWords[word]; ok {
-			continue
-		}
-		state.obscureWordsByLength[len(word)] = append(state.obscureWordsByLength[len(word)], word)
-	}
-
 	for i := params.minWordLength; i <= params.lineLength; i++ {
-		if _, ok := state.preferredWordsByLength[i]; !ok {
-			state.preferredWordsByLength[i] = []string{}
-		}
-		if _, ok := state.obscureWordsByLength[i]; !ok {
-			state.obscureWordsByLength[i] = []string{}
+		if i <= params.maxWordLength {
+			state.preferredWordsByLength[i], state.obscureWordsByLength[i] = dict.words(i)
+		} else {
+			state.preferredWordsByLength[i], state.obscureWordsByLength[i] = []string{}, []string{}
 		}
 	}
-
-	possibleLines := state.allPossibleLines(ctx, params.lineLength)
-	return possibleLines, ctx.Err()
+	return state
 }
 
 func isImpossible(p primitives.PossibleLines) bool {
@@ -0,0 +1,57 @@
+package internal
+
+import "slices"
+
+// Dictionary holds the words of an AllPossibleLinesParams in the order they are tried, partitioned
+// by length, so that the possible lines of several line lengths, e.g. of grids of several sizes,
+// can be built without ordering and partitioning the words for each. It is never modified once
+// built, and can be shared between goroutines.
+type Dictionary struct {
+	preferredByLength map[int][]string
+	obscureByLength   map[int][]string
+}
+
+// NewDictionary orders the words of p, without their excluded words, and partitions those between
+// its minimum and maximum word lengths by length. The maximum word length defaults to
+// p.LineLength.
+func NewDictionary(p AllPossibleLinesParams) *Dictionary {
+	params := asParams(p)
+	preferredWords, obscureWords := orderedWords(p, params.shuffle)
+
+	excluded := make(map[string]bool, len(p.ExcludedWords))
+	for _, word := range p.ExcludedWords {
+		excluded[word] = true
+	}
+	byLength := func(words []string) map[int][]string {
+		partitioned := make(map[int][]string)
+		for _, word := range words {
+			if len(word) < params.minWordLength || len(word) > params.maxWordLength || excluded[word] {
+				continue
+			}
+			partitioned[len(word)] = append(partitioned[len(word)], word)
+		}
+		// Clip each partition, so that appending to one of them, e.g. by a caller of words,
+		// copies it rather than writing into its spare capacity, which another caller may share.
+		for length, words := range partitioned {
+			partitioned[length] = slices.Clip(words)
+		}
+		return partitioned
+	}
+	return &Dictionary{
+		preferredByLength: byLength(preferredWords),
+		obscureByLength:   byLength(obscureWords),
+	}
+}
+
+// words returns the preferred and obscure words of the given length, which must not be modified.
+// They are empty rather than nil if there are none.
+func (d *Dictionary) words(length int) (preferred, obscure []string) {
+	preferred, obscure = d.preferredByLength[length], d.obscureByLength[length]
+	if preferred == nil {
+		preferred = []string{}
+	}
+	if obscure == nil {
+		obscure = []string{}
+	}
+	return preferred, obscure
+}
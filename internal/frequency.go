@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+)
+
+// orderByFrequency returns words reordered so that higher-frequency words come earlier, and so
+// are tried first. Each word is placed by a blend of its position in words and its rank by
+// descending frequency, where bias is the weight of the frequency rank: at 0 the order is
+// unchanged, and at 1 words are sorted by frequency. Words without a frequency have a frequency
+// of 0, and ties keep their order.
+func orderByFrequency(words []string, frequencies map[string]float64, bias float64) []string {
+	byFrequency := make([]int, len(words))
+	for i := range byFrequency {
+		byFrequency[i] = i
+	}
+	slices.SortStableFunc(byFrequency, func(a, b int) int {
+		return cmp.Compare(frequencies[words[b]], frequencies[words[a]])
+	})
+	rank := make([]int, len(words))
+	for r, i := range byFrequency {
+		rank[i] = r
+	}
+
+	order := make([]int, len(words))
+	for i := range order {
+		order[i] = i
+	}
+	key := func(i int) float64 {
+		return bias*float64(rank[i]) + (1-bias)*float64(i)
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(key(a), key(b))
+	})
+
+	ordered := make([]string, len(words))
+	for i, idx := range order {
+		ordered[i] = words[idx]
+	}
+	return ordered
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// orderByPenalty returns words without those whose penalty is +Inf, ordered by ascending penalty.
+// Words with the same penalty keep their order.
+func orderByPenalty(words []string, penalty func(word string) float64) []string {
+	type penalized struct {
+		word    string
+		penalty float64
+	}
+	byPenalty := make([]penalized, 0, len(words))
+	for _, word := range words {
+		if p := penalty(word); !math.IsInf(p, 1) {
+			byPenalty = append(byPenalty, penalized{word, p})
+		}
+	}
+	slices.SortStableFunc(byPenalty, func(a, b penalized) int {
+		return cmp.Compare(a.penalty, b.penalty)
+	})
+	ordered := make([]string, len(byPenalty))
+	for i, p := range byPenalty {
+		ordered[i] = p.word
+	}
+	return ordered
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// RebusWord is a word with a single cell standing for several letters, e.g. "sweet" followed by a
+// cell for "heart", written with a token letter for that cell.
+type RebusWord struct {
+	Word string
+	// Index is the index of the token letter in Word.
+	Index   int
+	Obscure bool
+}
+
+// RebusLines returns the lines of length p.LineLength where the word across index is one of words,
+// placed so that its token letter is at index. The other words of each line are those of p, as for
+// AllPossibleLines, and words must already be ordered as they should be tried.
+func RebusLines(ctx context.Context, p AllPossibleLinesParams, index int, words []RebusWord) (primitives.PossibleLines, error) {
+	state := newAllPossibleLineState(p)
+	lineLength := state.lineLength
+
+	// Words are grouped by length and the index of their token, which fixes where they start.
+	type group struct{ length, index int }
+	var groups []group
+	preferred, obscure := make(map[group][]string), make(map[group][]string)
+	for _, w := range words {
+		gr := group{len(w.Word), w.Index}
+		if gr.length < state.minWordLength || gr.length > state.maxWordLength {
+			continue
+		}
+		if _, ok := preferred[gr]; !ok {
+			groups = append(groups, gr)
+			preferred[gr] = []string{}
+		}
+		if w.Obscure {
+			obscure[gr] = append(obscure[gr], w.Word)
+		} else {
+			preferred[gr] = append(preferred[gr], w.Word)
+		}
+	}
+
+	// around returns the lines where middle starts at start, with any lines or blocked cells
+	// before and after it.
+	around := func(middle primitives.PossibleLines, start int) []primitives.PossibleLines {
+		end := start + middle.NumLetters()
+		var after []primitives.PossibleLines
+		if end == lineLength {
+			after = append(after, middle)
+		} else {
+			after = append(after, blockedAfter(middle, lineLength-end))
+			if rest := lineLength - end - 1; rest > 0 {
+				after = append(after, primitives.MakeBlockBetween(middle, state.allPossibleLines(ctx, rest)))
+			}
+		}
+		if start == 0 {
+			return after
+		}
+		var lines []primitives.PossibleLines
+		for _, line := range after {
+			lines = append(lines, blockedBefore(line, start))
+			if rest := start - 1; rest > 0 {
+				lines = append(lines, primitives.MakeBlockBetween(state.allPossibleLines(ctx, rest), line))
+			}
+		}
+		return lines
+	}
+
+	var possibilities []primitives.PossibleLines
+	for _, gr := range groups {
+		start := index - gr.index
+		if start < 0 || start+gr.length > lineLength {
+			continue
+		}
+		middle := primitives.MakeWordsFromPreferredAndObscure(preferred[gr], obscure[gr], gr.length)
+		possibilities = append(possibilities, around(middle, start)...)
+	}
+	return primitives.MakeCompound(possibilities, lineLength), ctx.Err()
+}
+
+// blockedBefore returns lines with n blocked cells before them.
+func blockedBefore(lines primitives.PossibleLines, n int) primitives.PossibleLines {
+	for range n {
+		lines = primitives.MakeBlockBefore(lines)
+	}
+	return lines
+}
+
+// blockedAfter returns lines with n blocked cells after them.
+func blockedAfter(lines primitives.PossibleLines, n int) primitives.PossibleLines {
+	for range n {
+		lines = primitives.MakeBlockAfter(lines)
+	}
+	return lines
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// SegmentLines returns the lines of length p.LineLength made of a word of each of lengths in turn,
+// which add up to p.LineLength, with no blocked cells between them, e.g. the entries of a row of a
+// barred grid. The words are those of p, ordered as for AllPossibleLines.
+func SegmentLines(ctx context.Context, p AllPossibleLinesParams, lengths []int) (primitives.PossibleLines, error) {
+	state := newAllPossibleLineState(p)
+	words := func(length int) primitives.PossibleLines {
+		return primitives.MakeWordsFromPreferredAndObscure(state.preferredWordsByLength[length], state.obscureWordsByLength[length], length)
+	}
+	lines := words(lengths[len(lengths)-1])
+	for i := len(lengths) - 2; i >= 0; i-- {
+		lines = primitives.MakeConcat(words(lengths[i]), lines)
+	}
+	return lines, ctx.Err()
+}
@@ -0,0 +1,15 @@
+package xwgen
+
+// WithMemoizedFilters caches the results of filtering lines on a letter, in a cache of fixed size
+// shared by every line of the search, so that branches of the search that filter the same lines
+// the same way reuse the result. See primitives.MemoizedFilter.
+//
+// The grids found, and their order, are the same as without it. Most of the filtering done by the
+// search is by sets of letters, which is not cached, so on 6x6 grids it is about as fast as without
+// it; see BenchmarkMemoizedFilters.
+func WithMemoizedFilters() GeneratorOption {
+	return func(g *Generator) error {
+		g.memoizeFilters = true
+		return nil
+	}
+}
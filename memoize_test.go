@@ -0,0 +1,62 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithMemoizedFilters(t *testing.T) {
+	words := loadTrimmedWords(t)
+	search := func(opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(5, append(opts, WithPreferredWords(words), WithSeed(42, 1024))...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+		var grids []string
+		for grid := range gen.PossibleGrids(ctx) {
+			if grids = append(grids, grid.Repr()); len(grids) == 20 {
+				break
+			}
+		}
+		return grids
+	}
+
+	want := search()
+	if got := search(WithMemoizedFilters()); !slices.Equal(got, want) {
+		t.Errorf("WithMemoizedFilters() found grids %q, want %q", got, want)
+	}
+}
+
+// BenchmarkMemoizedFilters compares finding 6x6 grids with and without WithMemoizedFilters, e.g.
+// with -benchtime 10x.
+func BenchmarkMemoizedFilters(b *testing.B) {
+	words := loadTrimmedWords(b)
+	b.ReportAllocs()
+
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+	}{
+		{name: "Plain"},
+		{name: "Memoized", opts: []GeneratorOption{WithMemoizedFilters()}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			for b.Loop() {
+				gen, err := CreateGeneratorE(6, append(tc.opts, WithPreferredWords(words), WithSeed(42, 1024))...)
+				if err != nil {
+					b.Fatalf("CreateGeneratorE() error: %v", err)
+				}
+				n := 0
+				for range gen.PossibleGrids(b.Context()) {
+					if n++; n >= 5 {
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// Neighbors yields the grids that differ from grid in at most k entries, e.g. to explore
+// alternatives to some entries of a grid one likes. grid itself is not yielded, nor is any grid
+// more than once. An error is returned if grid does not have the dimensions of g's grids, holds
+// characters other than letters and blocks, or if k is less than 1.
+//
+// Changing a letter changes both the row and the column it is in, so the search frees the cells
+// where some rows cross some columns, k rows and columns in all, and keeps every other cell of grid
+// as it is. It tries each choice of rows and columns in turn, from those freeing the fewest cells.
+// The freed cells may become blocked, or stop being blocked. k must be at least 2 for there to be
+// any neighbors.
+//
+// Once the sequence has finished, Err reports ErrNoGridsPossible if grid has no neighbors.
+func (g *Generator) Neighbors(ctx context.Context, grid Grid, k int) (iter.Seq[Grid], error) {
+	if k < 1 {
+		return nil, fmt.Errorf("the number of entries that may differ must be at least 1, got %d", k)
+	}
+	if width, height := grid.Size(); width != g.LineLength || height != g.Height {
+		return nil, fmt.Errorf("grid is %dx%d, expected %dx%d", width, height, g.LineLength, g.Height)
+	}
+	if err := validatePartialCells(freeCells(grid, nil, nil)); err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Grid) bool) {
+		seen := map[string]bool{grid.Repr(): true}
+		found := false
+		for lines := 2; lines <= k; lines++ {
+			for rows, cols := range freeLines(g.Height, g.LineLength, lines) {
+				// This cannot fail, since grid was validated as a partial grid.
+				grids, _ := g.PossibleGridsFrom(ctx, freeCells(grid, rows, cols))
+				for neighbor := range grids {
+					key := neighbor.Repr()
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					if changedEntries(grid, neighbor) > k {
+						continue
+					}
+					found = true
+					if !yield(neighbor) {
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					g.setErr(searchErr(ctx, found))
+					return
+				}
+			}
+		}
+		g.setErr(searchErr(ctx, found))
+	}, nil
+}
+
+// freeLines yields each choice of n lines of a grid of the given size, as the rows and columns
+// chosen, with at least one of each.
+func freeLines(height, width, n int) iter.Seq2[[]int, []int] {
+	return func(yield func([]int, []int) bool) {
+		for numRows := 1; numRows < n; numRows++ {
+			for rows := range combinations(height, numRows) {
+				for cols := range combinations(width, n-numRows) {
+					if !yield(rows, cols) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// combinations yields each set of k of the integers 0 to n-1, in increasing order. The slice
+// yielded is reused.
+func combinations(n, k int) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		if k > n {
+			return
+		}
+		c := make([]int, k)
+		for i := range c {
+			c[i] = i
+		}
+		for {
+			if !yield(c) {
+				return
+			}
+			// Advance the rightmost index that can be, and reset those after it.
+			i := k - 1
+			for i >= 0 && c[i] == n-k+i {
+				i--
+			}
+			if i < 0 {
+				return
+			}
+			c[i]++
+			for j := i + 1; j < k; j++ {
+				c[j] = c[j-1] + 1
+			}
+		}
+	}
+}
+
+// freeCells returns grid as a partial grid, with the cells where rows cross cols unknown.
+func freeCells(grid Grid, rows, cols []int) [][]rune {
+	width, height := grid.Size()
+	partial := make([][]rune, height)
+	for row := range partial {
+		partial[row] = make([]rune, width)
+		for col := range partial[row] {
+			if grid.IsBlocked(row, col) {
+				partial[row][col] = CellBlocked
+			} else {
+				partial[row][col] = grid.Cell(row, col)
+			}
+		}
+	}
+	for _, row := range rows {
+		for _, col := range cols {
+			partial[row][col] = CellUnknown
+		}
+	}
+	return partial
+}
+
+// changedEntries returns the number of entries of b that are not entries of a, with the same
+// answer at the same place, or of a that are not entries of b, whichever is greater.
+func changedEntries(a, b Grid) int {
+	type entry struct {
+		dir      Direction
+		row, col int
+		answer   string
+	}
+	inA := make(map[entry]bool)
+	for _, e := range a.Entries() {
+		inA[entry{e.Direction, e.Row, e.Col, e.Answer}] = true
+	}
+	added, kept := 0, 0
+	for _, e := range b.Entries() {
+		if inA[entry{e.Direction, e.Row, e.Col, e.Answer}] {
+			kept++
+		} else {
+			added++
+		}
+	}
+	return max(added, len(inA)-kept)
+}
@@ -0,0 +1,101 @@
+package xwgen
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNeighbors(t *testing.T) {
+	words := loadTrimmedWords(t)
+	gen, err := CreateGeneratorE(4, WithPreferredWords(words), WithSeed(42, 1024))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	// Every grid, to find the neighbors of the first one by brute force.
+	var grid Grid
+	var all []Grid
+	for g := range gen.PossibleGrids(t.Context()) {
+		if len(all) == 0 {
+			grid = g
+		}
+		all = append(all, g)
+	}
+
+	for _, k := range []int{2, 3, 4} {
+		neighbors, err := gen.Neighbors(t.Context(), grid, k)
+		if err != nil {
+			t.Fatalf("Neighbors(%d) error: %v", k, err)
+		}
+		seen := make(map[string]bool)
+		for neighbor := range neighbors {
+			key := neighbor.Repr()
+			if key == grid.Repr() {
+				t.Errorf("Neighbors(%d) yielded the original grid", k)
+			}
+			if seen[key] {
+				t.Errorf("Neighbors(%d) yielded a grid twice:\n%s", k, key)
+			}
+			seen[key] = true
+			if n := changedEntries(grid, neighbor); n > k {
+				t.Errorf("Neighbors(%d) yielded a grid with %d changed entries:\n%s", k, n, key)
+			}
+			for _, word := range neighbor.AllWords() {
+				if !slices.Contains(words, word) {
+					t.Errorf("Neighbors(%d) yielded a grid with %q, which is not a word:\n%s", k, word, key)
+				}
+			}
+		}
+		if len(seen) == 0 {
+			t.Errorf("Neighbors(%d) yielded no grids for\n%s", k, grid.Repr())
+		}
+		for _, g := range all {
+			if n := changedEntries(grid, g); n > 0 && n <= k && !seen[g.Repr()] {
+				t.Errorf("Neighbors(%d) did not yield a grid with %d changed entries:\n%s", k, n, g.Repr())
+			}
+		}
+		if err := gen.Err(); err != nil {
+			t.Errorf("Err() after Neighbors(%d) = %v, want nil", k, err)
+		}
+		t.Logf("%d neighbors within %d entries", len(seen), k)
+	}
+
+	t.Run("none", func(t *testing.T) {
+		neighbors, err := gen.Neighbors(t.Context(), grid, 1)
+		if err != nil {
+			t.Fatalf("Neighbors(1) error: %v", err)
+		}
+		for neighbor := range neighbors {
+			t.Errorf("Neighbors(1) yielded\n%s", neighbor.Repr())
+		}
+		if err := gen.Err(); !errors.Is(err, ErrNoGridsPossible) {
+			t.Errorf("Err() after Neighbors(1) = %v, want ErrNoGridsPossible", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := gen.Neighbors(t.Context(), grid, 0); err == nil {
+			t.Error("Neighbors(0) succeeded, want an error")
+		}
+		if _, err := gen.Neighbors(t.Context(), gridFromRows("abc", "def", "ghi"), 2); err == nil {
+			t.Error("Neighbors() of a 3x3 grid with a 4x4 generator succeeded, want an error")
+		}
+		if _, err := gen.Neighbors(t.Context(), gridFromRows("abcd", "ef?h", "ijkl", "mnop"), 2); err == nil {
+			t.Error("Neighbors() of a grid with an invalid cell succeeded, want an error")
+		}
+	})
+}
+
+func TestCombinations(t *testing.T) {
+	var got [][]int
+	for c := range combinations(4, 2) {
+		got = append(got, slices.Clone(c))
+	}
+	want := [][]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("combinations(4, 2) = %v, want %v", got, want)
+	}
+	if n := len(slices.Collect(combinations(2, 3))); n != 0 {
+		t.Errorf("combinations(2, 3) yielded %d sets, want 0", n)
+	}
+}
@@ -0,0 +1,143 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+)
+
+// WithWorkers searches for grids on n goroutines. The top of the search tree is explored on its
+// own goroutine, and each subtree below the first few choices is handed off to one of the workers.
+//
+// Grids are yielded in the order workers find them, so the order is only deterministic for n = 1,
+// which is the default.
+func WithWorkers(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("number of workers must be at least 1, got %d", n)
+		}
+		g.workers = n
+		return nil
+	}
+}
+
+// parallelResult is a grid found by one of the goroutines of a parallel search.
+type parallelResult struct {
+	grid  Grid
+	stats SearchStats
+}
+
+// searchParallel is like searcher.grids, but splits the search across g.workers goroutines.
+//
+// Every goroutine has exited by the time the returned sequence completes, including when the
+// caller stops early.
+func (g *Generator) searchParallel(ctx context.Context, root *gridState) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		// Build the lazily-initialized state of the generator before it is shared between
+		// goroutines.
+		g.isObscure("")
+
+		tasks := make(chan *gridState)
+		results := make(chan parallelResult)
+		send := func(r parallelResult) bool {
+			select {
+			case results <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// Seed the producer and each worker from the generator's source before any of them start,
+		// so that no two goroutines share a *rand.Rand, and the seeds depend only on g.rand.
+		root.rand = rand.New(rand.NewPCG(g.rand.Uint64(), g.rand.Uint64()))
+		rngs := make([]*rand.Rand, g.workers)
+		for i := range rngs {
+			rngs[i] = rand.New(rand.NewPCG(g.rand.Uint64(), g.rand.Uint64()))
+		}
+
+		var wg sync.WaitGroup
+
+		// The producer explores the first few levels of the tree, and hands off each subtree below
+		// them to a worker. Aim for a few subtrees per worker so they stay busy.
+		producer := &searcher{
+			g:   g,
+			ctx: ctx,
+			spawn: func(state *gridState) bool {
+				select {
+				case tasks <- state:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			},
+			splitDepth: bits.Len(uint(g.workers)) + 2,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(tasks)
+			for grid, stats := range producer.grids(root) {
+				if !send(parallelResult{grid: grid, stats: stats}) {
+					return
+				}
+			}
+		}()
+
+		for _, rng := range rngs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for task := range tasks {
+					task.rand = rng
+					worker := &searcher{g: g, ctx: ctx}
+					for grid, stats := range worker.grids(task) {
+						if !send(parallelResult{grid: grid, stats: stats}) {
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		defer func() {
+			cancel()
+			// Wait for every goroutine to exit.
+			for range results {
+			}
+		}()
+
+		for r := range results {
+			if !yield(r.grid, r.stats) {
+				return
+			}
+		}
+	}
+}
+
+// WithParallelPropagation filters the rows or columns of the grid by the lines crossing them on up
+// to n goroutines at once, rather than one at a time, at every point of the search. Filtering is
+// most of the work of the search for large grids, e.g. larger than 7x7; for small grids the cost of
+// coordinating the goroutines outweighs the gain.
+//
+// The grids found, and their order, are the same as without it. It can be combined with
+// WithWorkers, in which case each worker filters on up to n goroutines.
+func WithParallelPropagation(n int) GeneratorOption {
+	return func(g *Generator) error {
+		if n < 1 {
+			return fmt.Errorf("number of propagation workers must be at least 1, got %d", n)
+		}
+		g.propagationWorkers = n
+		return nil
+	}
+}
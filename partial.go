@@ -0,0 +1,127 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// WithPartialGrid makes every grid a completion of partial, in the format accepted by
+// PossibleGridsFrom: partial[row][col] is a letter, CellBlocked, or CellUnknown. partial must have
+// the dimensions of the generator, and must be possible to complete with its words.
+func WithPartialGrid(partial [][]rune) GeneratorOption {
+	return func(g *Generator) error {
+		if err := validatePartialCells(partial); err != nil {
+			return err
+		}
+		g.partial = make([][]rune, len(partial))
+		for y, row := range partial {
+			g.partial[y] = slices.Clone(row)
+		}
+		return nil
+	}
+}
+
+// CreateGeneratorFromPartial creates a generator of completions of partial, a grid size cells wide
+// and len(partial) cells tall, where '.' (CellUnknown) is an empty cell and '#' (CellBlocked) is a
+// blocked cell. If r is nil, grids are generated in a time-seeded random order.
+//
+// It returns an error if partial is self-contradictory, i.e. its filled cells rule out every
+// possible line for some row or column.
+func CreateGeneratorFromPartial(size int, partial [][]rune, preferred, obscure, excluded []string, r *rand.Rand) (*Generator, error) {
+	opts := []GeneratorOption{
+		WithPreferredWords(preferred),
+		WithObscureWords(obscure),
+		WithExcludedWords(excluded),
+		WithHeight(len(partial)),
+		WithPartialGrid(partial),
+	}
+	if r != nil {
+		opts = append(opts, WithRand(r))
+	}
+	return CreateGeneratorE(size, opts...)
+}
+
+// validatePartialCells returns an error if any cell of partial is not a letter, CellBlocked, or
+// CellUnknown.
+func validatePartialCells(partial [][]rune) error {
+	for y, row := range partial {
+		for x, r := range row {
+			if _, ok := cellConstraint(r); !ok && r != CellUnknown {
+				return fmt.Errorf("cell (%d, %d) of partial grid has invalid character %q", y, x, r)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePartialSize returns an error if partial does not have the dimensions of g's grids.
+func (g *Generator) validatePartialSize(partial [][]rune) error {
+	if len(partial) != g.Height {
+		return fmt.Errorf("partial grid has %d rows, expected %d", len(partial), g.Height)
+	}
+	for y, row := range partial {
+		if len(row) != g.LineLength {
+			return fmt.Errorf("row %d of partial grid has %d cells, expected %d", y, len(row), g.LineLength)
+		}
+	}
+	return nil
+}
+
+// applyPartial filters the lines of gs to those matching the letters and blocked cells of partial.
+func applyPartial(gs *gridState, partial [][]rune) {
+	for y, row := range partial {
+		for x, r := range row {
+			c, ok := cellConstraint(r)
+			if !ok {
+				continue
+			}
+			gs.across[y] = gs.across[y].Filter(c, x)
+			gs.down[x] = gs.down[x].Filter(c, y)
+		}
+	}
+}
+
+// validatePartial returns an error if g's partial grid does not have the dimensions of its grids,
+// or if propagating the constraints of the partial grid, pinned cells, rebus cells, and bars
+// between rows and columns, as initialState does, leaves some line with no possibilities.
+func (g *Generator) validatePartial() error {
+	var what []string
+	if g.partial != nil {
+		if err := g.validatePartialSize(g.partial); err != nil {
+			return err
+		}
+		what = append(what, "partial grid")
+	}
+	if len(g.pinnedCells) > 0 {
+		what = append(what, "pinned cells")
+	}
+	if len(g.rebusCells) > 0 {
+		what = append(what, "rebus cells")
+	}
+	if g.bars != nil {
+		what = append(what, "bars")
+	}
+	if len(what) == 0 {
+		return nil
+	}
+	are := strings.Join(what, " and ") + " are"
+	if len(what) == 1 && g.partial != nil {
+		are = "partial grid is"
+	}
+
+	ctx := context.Background()
+	gs, err := g.initialState(ctx)
+	if err != nil {
+		return err
+	}
+	if y := slices.IndexFunc(gs.across, impossible); y >= 0 {
+		return fmt.Errorf("%s self-contradictory: no words fit row %d", are, y)
+	}
+	if x := slices.IndexFunc(gs.down, impossible); x >= 0 {
+		return fmt.Errorf("%s self-contradictory: no words fit column %d", are, x)
+	}
+	return nil
+}
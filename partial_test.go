@@ -0,0 +1,72 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+func partialFromRows(rows ...string) [][]rune {
+	partial := make([][]rune, len(rows))
+	for i, row := range rows {
+		partial[i] = []rune(row)
+	}
+	return partial
+}
+
+func TestCreateGeneratorFromPartial(t *testing.T) {
+	words := loadTrimmedWords(t)
+	partial := partialFromRows(
+		"bae#",
+		"....",
+		"....",
+		"....",
+	)
+
+	gen, err := CreateGeneratorFromPartial(4, partial, words, nil, nil, rand.New(rand.NewPCG(42, 1024)))
+	if err != nil {
+		t.Fatalf("CreateGeneratorFromPartial() error: %v", err)
+	}
+	// The generator keeps its own copy of the partial grid.
+	partial[0][0] = 'z'
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		if row := strings.Split(grid.Repr(), "\n")[0]; row != "bae`" {
+			t.Errorf("grid #%d has first row %q, want \"bae`\":\n%s", count, row, grid.Repr())
+		}
+		if count >= 5 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Error("expected at least one grid")
+	}
+}
+
+func TestCreateGeneratorFromPartial_Invalid(t *testing.T) {
+	words := []string{"abc", "bca", "cab"}
+	for _, tc := range []struct {
+		name    string
+		size    int
+		partial [][]rune
+	}{
+		{name: "no rows", size: 3},
+		{name: "wrong width", size: 3, partial: partialFromRows("ab", "..", "..")},
+		{name: "invalid character", size: 3, partial: partialFromRows("a?c", "...", "...")},
+		{name: "no such word", size: 3, partial: partialFromRows("aaa", "...", "...")},
+		{name: "contradicting row and column", size: 3, partial: partialFromRows("abc", "c..", "...")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CreateGeneratorFromPartial(tc.size, tc.partial, words, nil, nil, nil); err == nil {
+				t.Error("CreateGeneratorFromPartial() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Package bench measures the throughput of the grid generator, so that changes to the search can be
+// compared between runs and binaries. It backs the 'xwcli bench' subcommand, and can also be driven
+// from Go tests.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Eyas/xwgen"
+)
+
+// Config configures a benchmark.
+type Config struct {
+	// Widths are the side lengths of the square grids to benchmark, each in turn.
+	Widths []int
+	// Seed seeds the generator, so that repeated benchmarks explore the same grids.
+	Seed uint64
+	// Duration is how long to generate grids for at each width.
+	Duration time.Duration
+	// GridsPerRun is the number of grids to take from each generator before starting a new one with
+	// the next seed, so that time to first grid is measured more than once. If 0, 100 is used.
+	GridsPerRun int
+
+	PreferredWords []string
+	ObscureWords   []string
+	ExcludedWords  []string
+	// MinWordLength is the minimum word length. If 0, the generator's default is used.
+	MinWordLength int
+}
+
+// Result is the measurements at one width.
+type Result struct {
+	Width int `json:"width"`
+	// Runs is the number of generators created, each with its own seed.
+	Runs int `json:"runs"`
+	// Grids is the number of grids generated across all runs.
+	Grids int `json:"grids"`
+	// Elapsed is the time spent generating grids.
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// GridsPerSecond is Grids over Elapsed.
+	GridsPerSecond float64 `json:"grids_per_second"`
+	// MedianTimeToFirstGrid is the median time from the start of a run to its first grid, over the
+	// runs that found one.
+	MedianTimeToFirstGrid time.Duration `json:"median_time_to_first_grid_ns"`
+	// Backtracks is the total number of choices undone across all runs.
+	Backtracks int64 `json:"backtracks"`
+	// Allocs and AllocBytes are the number and total size of heap allocations during the runs.
+	Allocs     uint64 `json:"allocs"`
+	AllocBytes uint64 `json:"alloc_bytes"`
+}
+
+// Run benchmarks the generator at each of cfg.Widths, returning the result at each width. It
+// returns early with the results so far if ctx is done.
+func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("benchmark duration must be positive, got %v", cfg.Duration)
+	}
+	results := make([]Result, 0, len(cfg.Widths))
+	for _, width := range cfg.Widths {
+		if width < 1 {
+			return results, fmt.Errorf("width must be at least 1, got %d", width)
+		}
+		result, err := runWidth(ctx, cfg, width)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// runWidth generates grids of the given width for cfg.Duration, starting a new generator every
+// cfg.GridsPerRun grids or whenever one runs out of grids. It returns an error if cfg cannot
+// configure a generator.
+func runWidth(ctx context.Context, cfg Config, width int) (Result, error) {
+	gridsPerRun := cfg.GridsPerRun
+	if gridsPerRun <= 0 {
+		gridsPerRun = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	result := Result{Width: width}
+	var firstGridTimes []time.Duration
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for ctx.Err() == nil {
+		var progress xwgen.Progress
+		opts := []xwgen.GeneratorOption{
+			xwgen.WithPreferredWords(cfg.PreferredWords),
+			xwgen.WithObscureWords(cfg.ObscureWords),
+			xwgen.WithExcludedWords(cfg.ExcludedWords),
+			xwgen.WithRand(rand.New(rand.NewPCG(cfg.Seed, uint64(result.Runs)))),
+			xwgen.WithProgress(&progress),
+		}
+		if cfg.MinWordLength > 0 {
+			opts = append(opts, xwgen.WithMinWordLength(cfg.MinWordLength))
+		}
+		generator, err := xwgen.CreateGeneratorE(width, opts...)
+		if err != nil {
+			return result, err
+		}
+		result.Runs++
+
+		runStart := time.Now()
+		n := 0
+		for range generator.PossibleGrids(ctx) {
+			if n == 0 {
+				firstGridTimes = append(firstGridTimes, time.Since(runStart))
+			}
+			n++
+			if n >= gridsPerRun {
+				break
+			}
+		}
+		result.Grids += n
+		result.Backtracks += progress.Snapshot().Backtracks
+
+		// Every seed explores the same tree in a different order, so if one run exhausts it
+		// without a grid, so will the rest.
+		if n == 0 {
+			break
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	result.Allocs = after.Mallocs - before.Mallocs
+	result.AllocBytes = after.TotalAlloc - before.TotalAlloc
+
+	if result.Elapsed > 0 {
+		result.GridsPerSecond = float64(result.Grids) / result.Elapsed.Seconds()
+	}
+	result.MedianTimeToFirstGrid = median(firstGridTimes)
+	return result, nil
+}
+
+// median returns the median of durations, or 0 if there are none.
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	slices.Sort(durations)
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2
+	}
+	return durations[mid]
+}
+
+// WriteTable writes results to w as an aligned table, one row per width.
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Width\tRuns\tGrids\tGrids/s\tMedian first grid\tBacktracks\tAllocs\tAlloc bytes\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.1f\t%v\t%d\t%d\t%d\t\n",
+			r.Width, r.Runs, r.Grids, r.GridsPerSecond, r.MedianTimeToFirstGrid.Round(time.Microsecond),
+			r.Backtracks, r.Allocs, r.AllocBytes)
+	}
+	return tw.Flush()
+}
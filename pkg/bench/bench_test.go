@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadWords(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile("../../testdata/words.txt")
+	if err != nil {
+		t.Fatalf("failed to read words: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(t.Context(), Config{
+		Widths:         []int{3, 4},
+		Seed:           1,
+		Duration:       200 * time.Millisecond,
+		GridsPerRun:    10,
+		PreferredWords: loadWords(t),
+	})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Run() returned %d results, want 2", len(results))
+	}
+	for i, r := range results {
+		if r.Width != 3+i {
+			t.Errorf("results[%d].Width = %d, want %d", i, r.Width, 3+i)
+		}
+		if r.Grids == 0 || r.Runs == 0 || r.GridsPerSecond <= 0 || r.MedianTimeToFirstGrid <= 0 {
+			t.Errorf("results[%d] = %+v, want grids from at least one run", i, r)
+		}
+		if r.Grids > 10*r.Runs {
+			t.Errorf("results[%d] has %d grids from %d runs, want at most 10 per run", i, r.Grids, r.Runs)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, results); err != nil {
+		t.Fatalf("WriteTable() error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Errorf("WriteTable() wrote %d lines, want a header and 2 rows:\n%s", len(lines), buf.String())
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	if _, err := Run(t.Context(), Config{Widths: []int{4}}); err == nil {
+		t.Error("Run() with no duration returned no error")
+	}
+	if _, err := Run(t.Context(), Config{Widths: []int{0}, Duration: time.Second}); err == nil {
+		t.Error("Run() with width 0 returned no error")
+	}
+}
+
+func TestMedian(t *testing.T) {
+	for _, tc := range []struct {
+		durations []time.Duration
+		want      time.Duration
+	}{
+		{nil, 0},
+		{[]time.Duration{5}, 5},
+		{[]time.Duration{9, 1, 5}, 5},
+		{[]time.Duration{4, 1, 2, 8}, 3},
+	} {
+		if got := median(tc.durations); got != tc.want {
+			t.Errorf("median(%v) = %v, want %v", tc.durations, got, tc.want)
+		}
+	}
+}
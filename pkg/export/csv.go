@@ -0,0 +1,46 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+)
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{"id", "across", "down", "obscure_words", "cells"}
+
+// WriteCSV writes grids to w as CSV, for analysis in spreadsheet tools: a header row, then a row
+// per grid with its 1-based index in grids as its ID, its across and down answers in clue order,
+// each joined with commas, its number of obscure words, and its number of cells.
+func WriteCSV(w io.Writer, grids []xwgen.Grid) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for i, g := range grids {
+		var across, down []string
+		for _, e := range g.Entries() {
+			if e.Direction == xwgen.DirectionHorizontal {
+				across = append(across, e.Answer)
+			} else {
+				down = append(down, e.Answer)
+			}
+		}
+		width, height := g.Size()
+		row := []string{
+			strconv.Itoa(i + 1),
+			strings.Join(across, ","),
+			strings.Join(down, ","),
+			strconv.Itoa(len(g.ObscureWords())),
+			strconv.Itoa(width * height),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
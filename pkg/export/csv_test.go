@@ -0,0 +1,34 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+func TestWriteCSV(t *testing.T) {
+	grids := []xwgen.Grid{
+		xwgen.NewGrid([][]rune{[]rune("cat"), []rune("are"), []rune("ten")}),
+		generateGridWithObscure(t),
+	}
+	var b strings.Builder
+	if err := WriteCSV(&b, grids); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("WriteCSV() wrote %d lines, want a header and 2 rows:\n%s", len(lines), b.String())
+	}
+	if got, want := lines[0], "id,across,down,obscure_words,cells"; got != want {
+		t.Errorf("header = %q, want %q", got, want)
+	}
+	if got, want := lines[1], `1,"cat,are,ten","cat,are,ten",0,9`; got != want {
+		t.Errorf("row 1 = %q, want %q", got, want)
+	}
+	if n := len(grids[1].ObscureWords()); n == 0 || !strings.HasSuffix(lines[2], fmt.Sprintf(",%d,25", n)) {
+		t.Errorf("row 2 = %q, want %d obscure words and 25 cells", lines[2], n)
+	}
+}
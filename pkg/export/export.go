@@ -0,0 +1,19 @@
+// Package export writes grids in formats used by other crossword tools.
+package export
+
+import (
+	"io"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export/puz"
+)
+
+// WritePuz writes g to w as an Across Lite .puz file, with the given clues.
+//
+// Clues are in the standard .puz order: by clue number, which increases from the top left of the
+// grid row by row, and with the across clue before the down clue when a cell starts both. This is
+// the order of g.Entries(). Entries without a clue, e.g. when clues is nil, get a placeholder
+// clue like "(clue for OTTER)". It is an error to pass more clues than g has entries.
+func WritePuz(w io.Writer, g xwgen.Grid, clues []string) error {
+	return puz.Write(w, g, puz.Puzzle{Ordered: clues})
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/export/puz"
+)
+
+func loadWords(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile("../../testdata/words.txt")
+	if err != nil {
+		t.Fatalf("failed to read words: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewPCG(42, 1024))
+}
+
+// firstGrid returns the first grid generated by gen.
+func firstGrid(t *testing.T, gen *xwgen.Generator) xwgen.Grid {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	for grid := range gen.PossibleGrids(ctx) {
+		return grid
+	}
+	t.Fatal("no grid generated")
+	return xwgen.Grid{}
+}
+
+// generateGrid returns the first grid generated from the repository's test word list.
+func generateGrid(t *testing.T) xwgen.Grid {
+	t.Helper()
+	return firstGrid(t, xwgen.CreateGenerator(5, loadWords(t), nil, nil, newRand(), xwgen.GeneratorParams{}))
+}
+
+func TestWritePuz(t *testing.T) {
+	grid := generateGrid(t)
+	entries := grid.Entries()
+	clues := make([]string, len(entries))
+	for i, entry := range entries {
+		clues[i] = fmt.Sprintf("Clue %d for %s", i, entry.Answer)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePuz(&buf, grid, clues); err != nil {
+		t.Fatalf("WritePuz() error: %v", err)
+	}
+
+	// Read verifies every checksum.
+	got, puzzle, err := puz.Read(&buf)
+	if err != nil {
+		t.Fatalf("puz.Read() error: %v", err)
+	}
+	if got.Repr() != grid.Repr() {
+		t.Errorf("read grid =\n%s\nwant\n%s", got.Repr(), grid.Repr())
+	}
+	for i, clue := range clues {
+		if puzzle.Ordered[i] != clue {
+			t.Errorf("clue %d = %q, want %q", i, puzzle.Ordered[i], clue)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// JSONVersion is the version of the format written by WriteJSON. It only changes when the format
+// changes in a way that is not backwards compatible.
+const JSONVersion = 1
+
+// JSONBlocked is the value of a blocked cell in JSONGrid.Cells.
+const JSONBlocked = "."
+
+// JSONGrid is the format written by WriteJSON, and described by grid.schema.json.
+type JSONGrid struct {
+	Version int `json:"version"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	// Cells is indexed as Cells[row][col], and each cell is a lowercase letter, the lowercase string
+	// of a rebus cell, e.g. "heart", or JSONBlocked.
+	Cells [][]string `json:"cells"`
+	// Across and Down are the entries of the grid, in clue number order.
+	Across []JSONEntry `json:"across"`
+	Down   []JSONEntry `json:"down"`
+	// ObscureMask has a character per entry, across entries first: '1' if the entry is an obscure
+	// word, and '0' otherwise.
+	ObscureMask string `json:"obscure_mask"`
+	// Bars, set only for barred grids, are the bars separating their entries.
+	Bars *JSONBars `json:"bars,omitempty"`
+}
+
+// JSONBars are the bars of a barred JSONGrid, as the [row, col] positions of the cells with a bar
+// after them.
+type JSONBars struct {
+	// Right are the cells with a bar between them and the next cell of their row.
+	Right [][2]int `json:"right"`
+	// Below are the cells with a bar between them and the next cell of their column.
+	Below [][2]int `json:"below"`
+}
+
+// JSONEntry is an entry of a JSONGrid.
+type JSONEntry struct {
+	Number int    `json:"number"`
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Answer string `json:"answer"`
+}
+
+// NewJSONGrid converts g to the format written by WriteJSON.
+func NewJSONGrid(g xwgen.Grid) JSONGrid {
+	width, height := g.Size()
+	jg := JSONGrid{
+		Version: JSONVersion,
+		Width:   width,
+		Height:  height,
+		Cells:   make([][]string, height),
+		Across:  []JSONEntry{},
+		Down:    []JSONEntry{},
+	}
+	for row := range height {
+		jg.Cells[row] = make([]string, width)
+		for col := range width {
+			if g.IsBlocked(row, col) {
+				jg.Cells[row][col] = JSONBlocked
+			} else if s := g.Rebus(row, col); s != "" {
+				jg.Cells[row][col] = s
+			} else {
+				jg.Cells[row][col] = string(g.Cell(row, col))
+			}
+		}
+	}
+
+	for _, entry := range g.Entries() {
+		e := JSONEntry{Number: entry.Number, Row: entry.Row, Col: entry.Col, Answer: entry.Answer}
+		if entry.Direction == xwgen.DirectionHorizontal {
+			jg.Across = append(jg.Across, e)
+		} else {
+			jg.Down = append(jg.Down, e)
+		}
+	}
+
+	var mask strings.Builder
+	for _, e := range slices.Concat(jg.Across, jg.Down) {
+		if slices.Contains(g.ObscureWords(), e.Answer) {
+			mask.WriteByte('1')
+		} else {
+			mask.WriteByte('0')
+		}
+	}
+	jg.ObscureMask = mask.String()
+
+	jg.Bars = NewJSONBars(g.Bars())
+	return jg
+}
+
+// NewJSONBars converts bars to their JSON form, or returns nil if bars is nil.
+func NewJSONBars(bars *xwgen.Bars) *JSONBars {
+	if bars == nil {
+		return nil
+	}
+	jb := &JSONBars{Right: [][2]int{}, Below: [][2]int{}}
+	for row := range bars.Right {
+		for col := range bars.Right[row] {
+			if bars.Right[row][col] {
+				jb.Right = append(jb.Right, [2]int{row, col})
+			}
+			if bars.Below[row][col] {
+				jb.Below = append(jb.Below, [2]int{row, col})
+			}
+		}
+	}
+	return jb
+}
+
+// Grid converts jg back to a grid. Only the cells and bars are used, so the words of the grid are
+// unknown.
+func (jg JSONGrid) Grid() xwgen.Grid {
+	rows := make([][]rune, len(jg.Cells))
+	for row, cells := range jg.Cells {
+		for _, cell := range cells {
+			if cell == JSONBlocked {
+				rows[row] = append(rows[row], primitives.Blocked)
+			} else {
+				rows[row] = append(rows[row], []rune(cell)[0])
+			}
+		}
+	}
+	grid := xwgen.NewGrid(rows)
+	for row, cells := range jg.Cells {
+		for col, cell := range cells {
+			if len(cell) > 1 {
+				grid = grid.WithRebus(row, col, cell)
+			}
+		}
+	}
+	if jg.Bars != nil {
+		bars := &xwgen.Bars{Right: make([][]bool, jg.Height), Below: make([][]bool, jg.Height)}
+		for row := range jg.Height {
+			bars.Right[row], bars.Below[row] = make([]bool, jg.Width), make([]bool, jg.Width)
+		}
+		for _, cell := range jg.Bars.Right {
+			bars.Right[cell[0]][cell[1]] = true
+		}
+		for _, cell := range jg.Bars.Below {
+			bars.Below[cell[0]][cell[1]] = true
+		}
+		grid = grid.WithBars(bars)
+	}
+	return grid
+}
+
+// WriteJSON writes g to w as a single line of JSON, in the format of JSONGrid.
+func WriteJSON(w io.Writer, g xwgen.Grid) error {
+	return json.NewEncoder(w).Encode(NewJSONGrid(g))
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+func TestWriteJSON(t *testing.T) {
+	grid := generateGrid(t)
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, grid); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	var got JSONGrid
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse JSON %s: %v", buf.String(), err)
+	}
+
+	if want := NewJSONGrid(grid); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+	if got.Grid().Repr() != grid.Repr() {
+		t.Errorf("round trip grid =\n%s\nwant\n%s", got.Grid().Repr(), grid.Repr())
+	}
+	if n := len(got.Across) + len(got.Down); len(got.ObscureMask) != n {
+		t.Errorf("obscure mask %q has %d entries, want %d", got.ObscureMask, len(got.ObscureMask), n)
+	}
+}
+
+func TestNewJSONGrid(t *testing.T) {
+	// Use a grid with known words, so that obscure words are known too.
+	grid := generateGridWithObscure(t)
+	jg := NewJSONGrid(grid)
+
+	for i, e := range slices.Concat(jg.Across, jg.Down) {
+		// Every entry should be at its position.
+		isAcross := i < len(jg.Across)
+		for j, r := range e.Answer {
+			row, col := e.Row, e.Col+j
+			if !isAcross {
+				row, col = e.Row+j, e.Col
+			}
+			if jg.Cells[row][col] != string(r) {
+				t.Errorf("entry %+v does not match cell (%d, %d) = %q", e, row, col, jg.Cells[row][col])
+			}
+		}
+		wantObscure := slices.Contains(grid.ObscureWords(), e.Answer)
+		if got := jg.ObscureMask[i] == '1'; got != wantObscure {
+			t.Errorf("entry %q: obscure = %v, want %v", e.Answer, got, wantObscure)
+		}
+	}
+	if !strings.Contains(jg.ObscureMask, "1") {
+		t.Errorf("expected some obscure entries in %+v", jg)
+	}
+}
+
+func TestNewJSONGrid_Rebus(t *testing.T) {
+	grid := xwgen.NewGrid([][]rune{[]rune("ab"), []rune("cd")}).WithRebus(0, 1, "heart")
+	jg := NewJSONGrid(grid)
+
+	if want := [][]string{{"a", "heart"}, {"c", "d"}}; !reflect.DeepEqual(jg.Cells, want) {
+		t.Errorf("cells = %q, want %q", jg.Cells, want)
+	}
+	var answers []string
+	for _, e := range slices.Concat(jg.Across, jg.Down) {
+		answers = append(answers, e.Answer)
+	}
+	if want := []string{"aheart", "cd", "ac", "heartd"}; !slices.Equal(answers, want) {
+		t.Errorf("answers = %q, want %q", answers, want)
+	}
+	if got := jg.Grid(); got.Repr() != grid.Repr() || got.Rebus(0, 1) != "heart" {
+		t.Errorf("round trip grid =\n%s\nwith rebus %q, want\n%s\nwith rebus %q", got.Repr(), got.Rebus(0, 1), grid.Repr(), "heart")
+	}
+}
+
+func TestNewJSONGrid_Bars(t *testing.T) {
+	bars, err := xwgen.ParseBars("._.\n.|.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := xwgen.NewGrid([][]rune{[]rune("abc"), []rune("def")}).WithBars(bars)
+	jg := NewJSONGrid(grid)
+
+	if want := (&JSONBars{Right: [][2]int{{1, 1}}, Below: [][2]int{{0, 1}}}); !reflect.DeepEqual(jg.Bars, want) {
+		t.Errorf("bars = %+v, want %+v", jg.Bars, want)
+	}
+	if got := jg.Grid().Bars(); got == nil || got.String() != bars.String() {
+		t.Errorf("round trip bars = %v, want\n%s", got, bars)
+	}
+	if jg := NewJSONGrid(xwgen.NewGrid([][]rune{[]rune("abc")})); jg.Bars != nil {
+		t.Errorf("bars of a grid without bars = %+v, want nil", jg.Bars)
+	}
+}
+
+// generateGridWithObscure generates a grid where every word with an 'e' is obscure.
+func generateGridWithObscure(t *testing.T) xwgen.Grid {
+	t.Helper()
+	var preferred, obscure []string
+	for _, word := range loadWords(t) {
+		if strings.Contains(word, "e") {
+			obscure = append(obscure, word)
+		} else {
+			preferred = append(preferred, word)
+		}
+	}
+	return firstGrid(t, xwgen.CreateGenerator(5, preferred, obscure, nil, newRand(), xwgen.GeneratorParams{}))
+}
+
+// TestJSONSchema checks that the schema describes exactly the fields of JSONGrid, and requires
+// those that are not omitted when empty.
+func TestJSONSchema(t *testing.T) {
+	data, err := os.ReadFile("grid.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       struct {
+			Entry struct {
+				Required []string `json:"required"`
+			} `json:"entry"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+
+	var optional []string
+	for _, tc := range []struct {
+		typ      reflect.Type
+		required []string
+	}{
+		{reflect.TypeFor[JSONGrid](), schema.Required},
+		{reflect.TypeFor[JSONEntry](), schema.Defs.Entry.Required},
+	} {
+		var fields []string
+		for i := range tc.typ.NumField() {
+			name, opts, _ := strings.Cut(tc.typ.Field(i).Tag.Get("json"), ",")
+			if opts == "omitempty" {
+				optional = append(optional, name)
+			} else {
+				fields = append(fields, name)
+			}
+		}
+		slices.Sort(fields)
+		required := slices.Sorted(slices.Values(tc.required))
+		if !slices.Equal(fields, required) {
+			t.Errorf("%v has JSON fields %q, but the schema requires %q", tc.typ, fields, required)
+		}
+	}
+	for _, name := range optional {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema has no property %q", name)
+		}
+	}
+	if len(schema.Properties) != len(schema.Required)+len(optional) {
+		t.Errorf("schema has %d properties, but requires %d and has %d optional", len(schema.Properties), len(schema.Required), len(optional))
+	}
+}
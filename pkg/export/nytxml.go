@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+)
+
+// NYTXMLNamespace is the XML namespace of the documents written by WriteNYTXML.
+const NYTXMLNamespace = "https://github.com/Eyas/xwgen/xml/crossword/1"
+
+// AcrossDownClues are the clues of a grid, by clue number.
+type AcrossDownClues struct {
+	Across map[int]string
+	Down   map[int]string
+}
+
+// nytCrossword is the root element written by WriteNYTXML.
+type nytCrossword struct {
+	XMLName xml.Name  `xml:"crossword"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Width   int       `xml:"width,attr"`
+	Height  int       `xml:"height,attr"`
+	Rows    []string  `xml:"grid>row"`
+	Across  []nytClue `xml:"across>clue"`
+	Down    []nytClue `xml:"down>clue"`
+}
+
+type nytClue struct {
+	Number int    `xml:"number,attr"`
+	Row    int    `xml:"row,attr"`
+	Col    int    `xml:"col,attr"`
+	Answer string `xml:"answer,attr"`
+	Text   string `xml:",chardata"`
+}
+
+// WriteNYTXML writes g and its clues to w in the XML layout used by puzzle editors such as the New
+// York Times': a <grid> of <row>s, where letters are uppercase and blocked cells are '.', then the
+// <across> and <down> <clue>s in clue number order. Each clue has the number, zero-based row and
+// column, and answer of its entry as attributes, e.g.
+//
+//	<clue number="1" row="0" col="0" answer="OTTER">River mammal</clue>
+//
+// Entries without a clue get a placeholder clue like "(clue for OTTER)".
+func WriteNYTXML(w io.Writer, g xwgen.Grid, clues AcrossDownClues) error {
+	width, height := g.Size()
+	doc := nytCrossword{Xmlns: NYTXMLNamespace, Width: width, Height: height}
+	for row := range height {
+		var b strings.Builder
+		for col := range width {
+			if g.IsBlocked(row, col) {
+				b.WriteByte('.')
+			} else {
+				b.WriteString(strings.ToUpper(string(g.Cell(row, col))))
+			}
+		}
+		doc.Rows = append(doc.Rows, b.String())
+	}
+
+	for _, entry := range g.Entries() {
+		answer := strings.ToUpper(entry.Answer)
+		clue := nytClue{Number: entry.Number, Row: entry.Row, Col: entry.Col, Answer: answer}
+		byNumber := clues.Down
+		if entry.Direction == xwgen.DirectionHorizontal {
+			byNumber = clues.Across
+		}
+		text, ok := byNumber[entry.Number]
+		if !ok {
+			text = fmt.Sprintf("(clue for %s)", answer)
+		}
+		clue.Text = text
+		if entry.Direction == xwgen.DirectionHorizontal {
+			doc.Across = append(doc.Across, clue)
+		} else {
+			doc.Down = append(doc.Down, clue)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+// nytPattern is a standard 15x15 block pattern with 180-degree rotational symmetry, where '#' is a
+// blocked cell.
+var nytPattern = []string{
+	"....#....#.....",
+	"....#....#.....",
+	"....#....#.....",
+	"...#.....#...##",
+	"###....#.......",
+	"......#....#...",
+	".....#....#....",
+	"...#.......#...",
+	"....#....#.....",
+	"...#....#......",
+	".......#....###",
+	"##...#.....#...",
+	".....#....#....",
+	".....#....#....",
+	".....#....#....",
+}
+
+// nytGrid fills nytPattern with letters. The letters need not spell words, as WriteNYTXML does not
+// check the fill.
+func nytGrid() xwgen.Grid {
+	rows := make([][]rune, len(nytPattern))
+	for r, line := range nytPattern {
+		for c, ch := range line {
+			if ch == '#' {
+				ch = '`'
+			} else {
+				ch = rune('a' + (r*7+c*3)%26)
+			}
+			rows[r] = append(rows[r], ch)
+		}
+	}
+	return xwgen.NewGrid(rows)
+}
+
+func TestWriteNYTXML(t *testing.T) {
+	grid := nytGrid()
+	clues := AcrossDownClues{
+		Across: map[int]string{1: "First across"},
+		Down:   map[int]string{1: "First down & <more>"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNYTXML(&buf, grid, clues); err != nil {
+		t.Fatalf("WriteNYTXML() error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("WriteNYTXML() output does not start with the XML declaration")
+	}
+
+	var got nytCrossword
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteNYTXML() wrote invalid XML: %v", err)
+	}
+	if got.XMLName.Space != NYTXMLNamespace {
+		t.Errorf("namespace = %q, want %q", got.XMLName.Space, NYTXMLNamespace)
+	}
+	if got.Width != 15 || got.Height != 15 || len(got.Rows) != 15 {
+		t.Fatalf("size = %dx%d with %d rows, want 15x15 with 15 rows", got.Width, got.Height, len(got.Rows))
+	}
+	for r, row := range got.Rows {
+		if want := strings.ReplaceAll(nytPattern[r], "#", "."); len(row) != len(want) {
+			t.Errorf("row %d = %q, want %d cells", r, row, len(want))
+		}
+		for c := range row {
+			if (row[c] == '.') != (nytPattern[r][c] == '#') {
+				t.Errorf("row %d = %q, blocked cells do not match %q", r, row, nytPattern[r])
+				break
+			}
+		}
+	}
+
+	if len(got.Across) != 43 || len(got.Down) != 37 {
+		t.Errorf("got %d across and %d down clues, want 43 and 37", len(got.Across), len(got.Down))
+	}
+	if got.Across[0].Text != "First across" || got.Down[0].Text != "First down & <more>" {
+		t.Errorf("first clues = %q, %q, want the given clues", got.Across[0].Text, got.Down[0].Text)
+	}
+	if want := "(clue for " + got.Across[1].Answer + ")"; got.Across[1].Text != want {
+		t.Errorf("unclued entry has clue %q, want %q", got.Across[1].Text, want)
+	}
+	for _, clue := range append(got.Across, got.Down...) {
+		if clue.Answer != strings.ToUpper(clue.Answer) || len(clue.Answer) < 3 {
+			t.Errorf("clue %d has answer %q, want an uppercase answer of at least 3 letters", clue.Number, clue.Answer)
+		}
+	}
+
+	checkGolden(t, "nyt.xml", buf.String())
+}
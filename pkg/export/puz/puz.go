@@ -0,0 +1,232 @@
+// Package puz writes completed grids in the Across Lite .puz format.
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+const (
+	magic   = "ACROSS&DOWN\x00"
+	version = "1.3\x00"
+
+	// headerSize is the size of the fixed header, up to and including the scrambled tag.
+	headerSize = 0x34
+	// cibOffset is the offset of the "CIB" region: width, height, number of clues, and flags.
+	cibOffset = 0x2C
+	cibSize   = 8
+
+	blackSquare = '.'
+	emptySquare = '-'
+
+	// rebusGrid and rebusTable are the titles of the extension sections of rebus cells: a byte per
+	// cell, which is 0 or 1 more than the key of the cell's string in the table, and the table of
+	// strings, e.g. " 0:HEART; 1:STAR;".
+	rebusGrid  = "GRBS"
+	rebusTable = "RTBL"
+)
+
+// Puzzle holds everything written to a .puz file besides the grid itself. All fields are optional.
+type Puzzle struct {
+	Title     string
+	Author    string
+	Copyright string
+	Notes     string
+
+	// Clues maps an answer, e.g. "otter", to its clue. Answers without a clue get a placeholder
+	// clue such as "(clue for OTTER)", so the file still opens in Across Lite.
+	Clues map[string]string
+	// Ordered are clues in the order of Entries, and take precedence over Clues. It can be shorter
+	// than the number of entries, but not longer.
+	Ordered []string
+}
+
+// Entry is a numbered word in a grid.
+//
+// Deprecated: Use xwgen.Entry.
+type Entry struct {
+	Number int
+	Across bool
+	Answer string
+	// Row and Col are the position of the first letter of the entry.
+	Row, Col int
+}
+
+// Entries returns the entries of grid in .puz clue order: by number, with across before down.
+//
+// Deprecated: Use grid.Entries(), which is in the same order.
+func Entries(grid xwgen.Grid) []Entry {
+	var entries []Entry
+	for _, e := range grid.Entries() {
+		entries = append(entries, Entry{Number: e.Number, Across: e.Direction == xwgen.DirectionHorizontal, Answer: e.Answer, Row: e.Row, Col: e.Col})
+	}
+	return entries
+}
+
+// Write writes grid and the given puzzle metadata to w as a .puz file. Rebus cells of grid are
+// written in the standard extension sections, with the first letter of their string as their
+// solution for readers that do not support them. Barred grids cannot be written, since .puz has
+// no way to hold their bars.
+func Write(w io.Writer, grid xwgen.Grid, puzzle Puzzle) error {
+	width, height := grid.Width(), grid.Height()
+	if width > 255 || height > 255 {
+		return fmt.Errorf("grid is %dx%d, but .puz supports at most 255x255", width, height)
+	}
+	if grid.Bars() != nil {
+		return fmt.Errorf("grid is barred, but .puz does not support bars")
+	}
+
+	solution := make([]byte, 0, width*height)
+	state := make([]byte, 0, width*height)
+	for y := range height {
+		for x := range width {
+			switch r := grid.Get(x, y); {
+			case r == primitives.Blocked:
+				solution = append(solution, blackSquare)
+				state = append(state, blackSquare)
+			case r >= 'a' && r <= 'z':
+				solution = append(solution, byte(r-'a'+'A'))
+				state = append(state, emptySquare)
+			default:
+				return fmt.Errorf("cell (%d, %d) is %q, but the grid must be complete", x, y, r)
+			}
+		}
+	}
+
+	entries := grid.Entries()
+	if len(puzzle.Ordered) > len(entries) {
+		return fmt.Errorf("got %d clues, but the grid only has %d entries", len(puzzle.Ordered), len(entries))
+	}
+	clues := make([]string, len(entries))
+	for i, entry := range entries {
+		clue, ok := puzzle.Clues[entry.Answer]
+		if i < len(puzzle.Ordered) {
+			clue, ok = puzzle.Ordered[i], true
+		}
+		if !ok {
+			clue = fmt.Sprintf("(clue for %s)", strings.ToUpper(entry.Answer))
+		}
+		clues[i] = clue
+	}
+
+	header := make([]byte, headerSize)
+	copy(header[0x02:], magic)
+	copy(header[0x18:], version)
+	header[0x2C] = byte(width)
+	header[0x2D] = byte(height)
+	binary.LittleEndian.PutUint16(header[0x2E:], uint16(len(clues)))
+	// Puzzle type: normal, unscrambled.
+	binary.LittleEndian.PutUint16(header[0x30:], 0x0001)
+	binary.LittleEndian.PutUint16(header[0x32:], 0x0000)
+
+	cib := checksum(header[cibOffset:cibOffset+cibSize], 0)
+	solutionSum := checksum(solution, 0)
+	stateSum := checksum(state, 0)
+	stringsSum := puzzle.stringsChecksum(clues, 0)
+
+	overall := puzzle.stringsChecksum(clues, checksum(state, checksum(solution, cib)))
+	binary.LittleEndian.PutUint16(header[0x00:], overall)
+	binary.LittleEndian.PutUint16(header[0x0E:], cib)
+
+	// The masked checksums are XORed with "ICHEATED".
+	sums := []uint16{cib, solutionSum, stateSum, stringsSum}
+	for i, sum := range sums {
+		header[0x10+i] = "ICHE"[i] ^ byte(sum)
+		header[0x14+i] = "ATED"[i] ^ byte(sum>>8)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(solution)
+	buf.Write(state)
+	for _, s := range []string{puzzle.Title, puzzle.Author, puzzle.Copyright} {
+		buf.WriteString(s)
+		buf.WriteByte(0)
+	}
+	for _, clue := range clues {
+		buf.WriteString(clue)
+		buf.WriteByte(0)
+	}
+	buf.WriteString(puzzle.Notes)
+	buf.WriteByte(0)
+	if grbs, rtbl := rebusSections(grid); grbs != nil {
+		writeSection(&buf, rebusGrid, grbs)
+		writeSection(&buf, rebusTable, rtbl)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// rebusSections returns the data of the GRBS and RTBL sections of grid, or nil if it has no rebus
+// cells. Each distinct string is in the table once, keyed in the order the cells are read.
+func rebusSections(grid xwgen.Grid) (grbs, rtbl []byte) {
+	width, height := grid.Size()
+	keys := make(map[string]int)
+	var table strings.Builder
+	for y := range height {
+		for x := range width {
+			s := grid.Rebus(y, x)
+			if s == "" {
+				continue
+			}
+			if grbs == nil {
+				grbs = make([]byte, width*height)
+			}
+			key, ok := keys[s]
+			if !ok {
+				key = len(keys)
+				keys[s] = key
+				fmt.Fprintf(&table, "%2d:%s;", key, strings.ToUpper(s))
+			}
+			grbs[y*width+x] = byte(key + 1)
+		}
+	}
+	return grbs, []byte(table.String())
+}
+
+// writeSection writes an extension section: its title, the length and checksum of its data, the
+// data, and a NUL.
+func writeSection(buf *bytes.Buffer, title string, data []byte) {
+	buf.WriteString(title)
+	binary.Write(buf, binary.LittleEndian, uint16(len(data)))
+	binary.Write(buf, binary.LittleEndian, checksum(data, 0))
+	buf.Write(data)
+	buf.WriteByte(0)
+}
+
+// stringsChecksum continues sum over the strings section. Empty metadata strings are skipped, and
+// clues are summed without their terminating NUL.
+func (p Puzzle) stringsChecksum(clues []string, sum uint16) uint16 {
+	for _, s := range []string{p.Title, p.Author, p.Copyright} {
+		if s != "" {
+			sum = checksum([]byte(s+"\x00"), sum)
+		}
+	}
+	for _, clue := range clues {
+		sum = checksum([]byte(clue), sum)
+	}
+	if p.Notes != "" {
+		sum = checksum([]byte(p.Notes+"\x00"), sum)
+	}
+	return sum
+}
+
+// checksum is the .puz checksum of data, continuing from sum.
+func checksum(data []byte, sum uint16) uint16 {
+	for _, b := range data {
+		if sum&1 != 0 {
+			sum = sum>>1 | 0x8000
+		} else {
+			sum >>= 1
+		}
+		sum += uint16(b)
+	}
+	return sum
+}
@@ -0,0 +1,221 @@
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+func gridFromRows(rows ...string) xwgen.Grid {
+	g := make([][]rune, len(rows))
+	for i, row := range rows {
+		g[i] = []rune(strings.ReplaceAll(row, "#", "`"))
+	}
+	return xwgen.NewGrid(g)
+}
+
+func TestChecksum(t *testing.T) {
+	if got, want := checksum([]byte("ab"), 0), uint16(0x8092); got != want {
+		t.Errorf("checksum(ab) = %#04x, want %#04x", got, want)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	grid := gridFromRows(
+		"#cat",
+		"aloe",
+		"tone",
+		"ends",
+	)
+	want := []Entry{
+		{1, true, "cat", 0, 1}, {1, false, "clon", 0, 1},
+		{2, false, "aond", 0, 2}, {3, false, "tees", 0, 3},
+		{4, true, "aloe", 1, 0}, {4, false, "ate", 1, 0},
+		{5, true, "tone", 2, 0},
+		{6, true, "ends", 3, 0},
+	}
+	if got := Entries(grid); !slices.Equal(got, want) {
+		t.Errorf("Entries() = %v, want %v", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	grid := gridFromRows(
+		"ab#",
+		"cde",
+		"#fg",
+	)
+	var buf bytes.Buffer
+	err := Write(&buf, grid, Puzzle{Title: "Test", Clues: map[string]string{"ab": "First two letters"}})
+	if err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	data := buf.Bytes()
+
+	if got := string(data[0x02:0x0E]); got != magic {
+		t.Errorf("magic = %q, want %q", got, magic)
+	}
+	if w, h := data[0x2C], data[0x2D]; w != 3 || h != 3 {
+		t.Errorf("size = %dx%d, want 3x3", w, h)
+	}
+
+	body := data[headerSize:]
+	if got, want := string(body[:9]), "AB.CDE.FG"; got != want {
+		t.Errorf("solution = %q, want %q", got, want)
+	}
+	if got, want := string(body[9:18]), "--.---.--"; got != want {
+		t.Errorf("player state = %q, want %q", got, want)
+	}
+
+	strs := strings.Split(string(body[18:]), "\x00")
+	wantStrs := []string{
+		"Test", "", "",
+		"First two letters", "(clue for AC)", "(clue for BDF)",
+		"(clue for CDE)", "(clue for EG)", "(clue for FG)",
+		"", // notes
+		"", // after the final NUL
+	}
+	if !slices.Equal(strs, wantStrs) {
+		t.Errorf("strings = %q, want %q", strs, wantStrs)
+	}
+	if got := binary.LittleEndian.Uint16(data[0x2E:]); got != 6 {
+		t.Errorf("number of clues = %d, want 6", got)
+	}
+
+	cib := checksum(data[cibOffset:cibOffset+cibSize], 0)
+	if got := binary.LittleEndian.Uint16(data[0x0E:]); got != cib {
+		t.Errorf("CIB checksum = %#04x, want %#04x", got, cib)
+	}
+	sum := checksum(body[:18], cib)
+	sum = checksum([]byte("Test\x00"), sum)
+	for _, clue := range wantStrs[3:9] {
+		sum = checksum([]byte(clue), sum)
+	}
+	if got := binary.LittleEndian.Uint16(data[0x00:]); got != sum {
+		t.Errorf("file checksum = %#04x, want %#04x", got, sum)
+	}
+	if got, want := data[0x10]^byte(cib), byte('I'); got != want {
+		t.Errorf("masked CIB checksum low byte unmasks to %q, want %q", got, want)
+	}
+}
+
+func TestWrite_Incomplete(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, gridFromRows("ab", "c."), Puzzle{}); err == nil {
+		t.Error("Write() of an incomplete grid succeeded, want error")
+	}
+}
+
+func TestWrite_Barred(t *testing.T) {
+	bars, err := xwgen.ParseBars("...\n...\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&bytes.Buffer{}, gridFromRows("abc", "def").WithBars(bars), Puzzle{}); err == nil {
+		t.Error("Write() of a barred grid succeeded, want error")
+	}
+}
+
+func TestRead(t *testing.T) {
+	grid := gridFromRows(
+		"ab#",
+		"cde",
+		"#fg",
+	)
+	want := Puzzle{Title: "Test", Author: "Someone", Notes: "Notes", Clues: map[string]string{"ab": "First two letters"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, grid, want); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	data := buf.Bytes()
+
+	gotGrid, got, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if gotGrid.Repr() != grid.Repr() {
+		t.Errorf("Read() grid =\n%s\nwant\n%s", gotGrid.Repr(), grid.Repr())
+	}
+	if got.Title != want.Title || got.Author != want.Author || got.Copyright != "" || got.Notes != want.Notes {
+		t.Errorf("Read() puzzle = %+v, want %+v", got, want)
+	}
+	if len(got.Ordered) != 6 || got.Ordered[0] != "First two letters" || got.Ordered[1] != "(clue for AC)" {
+		t.Errorf("Read() clues = %q", got.Ordered)
+	}
+
+	// Corrupting any part of the file should be caught by a checksum.
+	for _, offset := range []int{cibOffset, headerSize, headerSize + 9, len(data) - 3} {
+		corrupt := bytes.Clone(data)
+		corrupt[offset]++
+		if _, _, err := Read(bytes.NewReader(corrupt)); err == nil {
+			t.Errorf("Read() of file corrupted at %#x succeeded, want error", offset)
+		}
+	}
+}
+
+func TestWrite_Ordered(t *testing.T) {
+	grid := gridFromRows("ab", "cd")
+	var buf bytes.Buffer
+	if err := Write(&buf, grid, Puzzle{Ordered: []string{"one", "two", "three"}}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	_, got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	want := []string{"one", "two", "three", "(clue for CD)"}
+	if !slices.Equal(got.Ordered, want) {
+		t.Errorf("clues = %q, want %q", got.Ordered, want)
+	}
+
+	if err := Write(&buf, grid, Puzzle{Ordered: make([]string, 5)}); err == nil {
+		t.Error("Write() with more clues than entries succeeded, want error")
+	}
+}
+
+func TestWrite_Rebus(t *testing.T) {
+	grid := gridFromRows(
+		"ab#",
+		"cde",
+		"#fg",
+	).WithRebus(0, 0, "heart").WithRebus(1, 1, "star").WithRebus(2, 2, "heart")
+	var buf bytes.Buffer
+	if err := Write(&buf, grid, Puzzle{}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	data := buf.Bytes()
+
+	body := data[headerSize:]
+	if got, want := string(body[:9]), "HB.CSE.FH"; got != want {
+		t.Errorf("solution = %q, want %q", got, want)
+	}
+	i := bytes.Index(data, []byte(rebusGrid))
+	if i < 0 {
+		t.Fatalf("file has no %s section:\n%q", rebusGrid, data)
+	}
+	if got, want := data[i+8:i+17], []byte{1, 0, 0, 0, 2, 0, 0, 0, 1}; !bytes.Equal(got, want) {
+		t.Errorf("%s section = %v, want %v", rebusGrid, got, want)
+	}
+	if !bytes.Contains(data, []byte(" 0:HEART; 1:STAR;\x00")) {
+		t.Errorf("file has no RTBL section of HEART and STAR:\n%q", data)
+	}
+
+	got, puzzle, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	for _, c := range []struct {
+		row, col int
+		want     string
+	}{{0, 0, "heart"}, {1, 1, "star"}, {2, 2, "heart"}, {0, 1, ""}} {
+		if s := got.Rebus(c.row, c.col); s != c.want {
+			t.Errorf("Read() grid Rebus(%d, %d) = %q, want %q", c.row, c.col, s, c.want)
+		}
+	}
+	if want := "(clue for HEARTB)"; puzzle.Ordered[0] != want {
+		t.Errorf("first clue = %q, want %q", puzzle.Ordered[0], want)
+	}
+}
@@ -0,0 +1,163 @@
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// Read reads a .puz file, and verifies all of its checksums. It returns the solution grid, with
+// lowercase letters and primitives.Blocked for blocked cells, and the puzzle's metadata with its
+// clues in Ordered. Rebus cells, in the GRBS and RTBL extension sections, are read as rebus cells
+// of the grid.
+//
+// Scrambled solutions are not supported.
+func Read(r io.Reader) (xwgen.Grid, Puzzle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return xwgen.Grid{}, Puzzle{}, err
+	}
+	if len(data) < headerSize {
+		return xwgen.Grid{}, Puzzle{}, errors.New("file is too short for a .puz header")
+	}
+	if got := string(data[0x02:0x0E]); got != magic {
+		return xwgen.Grid{}, Puzzle{}, fmt.Errorf("bad magic %q, not a .puz file", got)
+	}
+	if binary.LittleEndian.Uint16(data[0x32:]) != 0 {
+		return xwgen.Grid{}, Puzzle{}, errors.New("scrambled .puz files are not supported")
+	}
+
+	width, height := int(data[0x2C]), int(data[0x2D])
+	numClues := int(binary.LittleEndian.Uint16(data[0x2E:]))
+	cells := width * height
+	body := data[headerSize:]
+	if len(body) < 2*cells {
+		return xwgen.Grid{}, Puzzle{}, errors.New("file is too short for its grid")
+	}
+	solution, state := body[:cells], body[cells:2*cells]
+
+	// The strings section is the title, author, copyright, clues, then notes, each NUL terminated.
+	// Anything after the notes is an extension section, of which only those of rebus cells are
+	// read.
+	rest := body[2*cells:]
+	next := func() (string, error) {
+		i := bytes.IndexByte(rest, 0)
+		if i < 0 {
+			return "", errors.New("unterminated string")
+		}
+		s := string(rest[:i])
+		rest = rest[i+1:]
+		return s, nil
+	}
+	var puzzle Puzzle
+	for _, field := range []*string{&puzzle.Title, &puzzle.Author, &puzzle.Copyright} {
+		if *field, err = next(); err != nil {
+			return xwgen.Grid{}, Puzzle{}, err
+		}
+	}
+	puzzle.Ordered = make([]string, numClues)
+	for i := range puzzle.Ordered {
+		if puzzle.Ordered[i], err = next(); err != nil {
+			return xwgen.Grid{}, Puzzle{}, err
+		}
+	}
+	if puzzle.Notes, err = next(); err != nil {
+		return xwgen.Grid{}, Puzzle{}, err
+	}
+
+	cib := checksum(data[cibOffset:cibOffset+cibSize], 0)
+	for _, c := range []struct {
+		name      string
+		got, want uint16
+	}{
+		{"CIB", binary.LittleEndian.Uint16(data[0x0E:]), cib},
+		{"file", binary.LittleEndian.Uint16(data[0x00:]), puzzle.stringsChecksum(puzzle.Ordered, checksum(state, checksum(solution, cib)))},
+	} {
+		if c.got != c.want {
+			return xwgen.Grid{}, Puzzle{}, fmt.Errorf("bad %s checksum %#04x, want %#04x", c.name, c.got, c.want)
+		}
+	}
+	sums := []uint16{cib, checksum(solution, 0), checksum(state, 0), puzzle.stringsChecksum(puzzle.Ordered, 0)}
+	for i, sum := range sums {
+		if data[0x10+i] != "ICHE"[i]^byte(sum) || data[0x14+i] != "ATED"[i]^byte(sum>>8) {
+			return xwgen.Grid{}, Puzzle{}, fmt.Errorf("bad masked checksum %d", i)
+		}
+	}
+
+	rows := make([][]rune, height)
+	for y := range rows {
+		rows[y] = make([]rune, width)
+		for x := range rows[y] {
+			switch c := solution[y*width+x]; {
+			case c == blackSquare:
+				rows[y][x] = primitives.Blocked
+			case c >= 'A' && c <= 'Z':
+				rows[y][x] = rune(c - 'A' + 'a')
+			default:
+				return xwgen.Grid{}, Puzzle{}, fmt.Errorf("cell (%d, %d) has unsupported solution %q", x, y, c)
+			}
+		}
+	}
+	grid := xwgen.NewGrid(rows)
+	if grid, err = readRebus(grid, rest); err != nil {
+		return xwgen.Grid{}, Puzzle{}, err
+	}
+	return grid, puzzle, nil
+}
+
+// readRebus returns grid with the rebus cells of the extension sections in data, if any.
+func readRebus(grid xwgen.Grid, data []byte) (xwgen.Grid, error) {
+	sections := make(map[string][]byte)
+	for len(data) >= 8 {
+		title := string(data[:4])
+		length := int(binary.LittleEndian.Uint16(data[4:]))
+		sum := binary.LittleEndian.Uint16(data[6:])
+		if len(data) < 8+length+1 {
+			return xwgen.Grid{}, fmt.Errorf("section %q is truncated", title)
+		}
+		section := data[8 : 8+length]
+		if got := checksum(section, 0); got != sum {
+			return xwgen.Grid{}, fmt.Errorf("bad %s checksum %#04x, want %#04x", title, sum, got)
+		}
+		sections[title] = section
+		data = data[8+length+1:]
+	}
+	grbs, ok := sections[rebusGrid]
+	if !ok {
+		return grid, nil
+	}
+	width, height := grid.Size()
+	if len(grbs) != width*height {
+		return xwgen.Grid{}, fmt.Errorf("%s section has %d cells, want %d", rebusGrid, len(grbs), width*height)
+	}
+	table := make(map[int]string)
+	for _, item := range strings.Split(string(sections[rebusTable]), ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, s, ok := strings.Cut(item, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(key))
+		if !ok || err != nil {
+			return xwgen.Grid{}, fmt.Errorf("bad %s entry %q", rebusTable, item)
+		}
+		table[n] = strings.ToLower(s)
+	}
+	for i, b := range grbs {
+		if b == 0 {
+			continue
+		}
+		s, ok := table[int(b)-1]
+		if !ok {
+			return xwgen.Grid{}, fmt.Errorf("cell (%d, %d) has rebus key %d, which is not in the %s section", i%width, i/width, int(b)-1, rebusTable)
+		}
+		grid = grid.WithRebus(i/width, i%width, s)
+	}
+	return grid, nil
+}
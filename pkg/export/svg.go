@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Eyas/xwgen"
+)
+
+// SVGOptions configures WriteSVG. The zero value of each field selects its default.
+type SVGOptions struct {
+	// CellSize is the width and height of each cell in pixels. The default is 32.
+	CellSize int
+	// Font is the font family of letters and numbers. The default is "sans-serif".
+	Font string
+	// BorderWidth is the width in pixels of the border around the grid. Lines between cells are
+	// always 1 pixel wide. The default is 2.
+	BorderWidth int
+	// ShowNumbers shows the clue number in the corner of each cell that starts an entry.
+	ShowNumbers bool
+}
+
+// WriteSVG writes g to w as an SVG image, with blocked cells filled in black and letters in
+// uppercase.
+func WriteSVG(w io.Writer, g xwgen.Grid, opts SVGOptions) error {
+	if opts.CellSize == 0 {
+		opts.CellSize = 32
+	}
+	if opts.Font == "" {
+		opts.Font = "sans-serif"
+	}
+	if opts.BorderWidth == 0 {
+		opts.BorderWidth = 2
+	}
+	if opts.CellSize < 0 || opts.BorderWidth < 0 {
+		return fmt.Errorf("cell size and border width must not be negative, got %d and %d", opts.CellSize, opts.BorderWidth)
+	}
+
+	var font bytes.Buffer
+	if err := xml.EscapeText(&font, []byte(opts.Font)); err != nil {
+		return err
+	}
+
+	width, height := g.Size()
+	cell, border := opts.CellSize, opts.BorderWidth
+	// The border is centered on the edge of the grid, so half of it is outside of the cells.
+	totalWidth, totalHeight := width*cell+border, height*cell+border
+	offset := float64(border) / 2
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		totalWidth, totalHeight, totalWidth, totalHeight)
+	fmt.Fprintf(&b, `<g transform="translate(%s %s)" font-family="%s">`+"\n", px(offset), px(offset), font.String())
+
+	for row := range height {
+		for col := range width {
+			fill := "white"
+			if g.IsBlocked(row, col) {
+				fill = "black"
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="black" stroke-width="1"/>`+"\n",
+				col*cell, row*cell, cell, cell, fill)
+		}
+	}
+
+	if opts.ShowNumbers {
+		numbered := make(map[[2]int]bool)
+		for _, entry := range g.Entries() {
+			if pos := [2]int{entry.Row, entry.Col}; !numbered[pos] {
+				numbered[pos] = true
+				fmt.Fprintf(&b, `<text x="%s" y="%s" font-size="%s">%d</text>`+"\n",
+					px(float64(entry.Col*cell)+float64(cell)*0.08), px(float64(entry.Row*cell)+float64(cell)*0.3), px(float64(cell)*0.28), entry.Number)
+			}
+		}
+	}
+
+	for row := range height {
+		for col := range width {
+			if g.IsBlocked(row, col) {
+				continue
+			}
+			fmt.Fprintf(&b, `<text x="%s" y="%s" font-size="%s" text-anchor="middle" dominant-baseline="central">%c</text>`+"\n",
+				px(float64(col*cell)+float64(cell)/2), px(float64(row*cell)+float64(cell)*0.58), px(float64(cell)*0.55), unicode.ToUpper(g.Cell(row, col)))
+		}
+	}
+
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="%d" height="%d" fill="none" stroke="black" stroke-width="%d"/>`+"\n",
+		width*cell, height*cell, border)
+	b.WriteString("</g>\n</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// px formats a length in pixels, rounded to hundredths.
+func px(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got against the golden file testdata/golden/name, or updates the file if
+// the -update flag is set.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s (run with -update to regenerate):\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	grid := xwgen.NewGrid([][]rune{
+		[]rune("`cat"),
+		[]rune("aloe"),
+		[]rune("tone"),
+		[]rune("ends"),
+	})
+
+	for _, tc := range []struct {
+		name string
+		opts SVGOptions
+	}{
+		{name: "default.svg"},
+		{name: "numbers.svg", opts: SVGOptions{CellSize: 40, Font: `"Courier New", monospace`, BorderWidth: 3, ShowNumbers: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteSVG(&buf, grid, tc.opts); err != nil {
+				t.Fatalf("WriteSVG() error: %v", err)
+			}
+
+			// The output must be well-formed XML.
+			d := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+			for {
+				if _, err := d.Token(); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatalf("WriteSVG() wrote invalid XML: %v", err)
+				}
+			}
+
+			checkGolden(t, tc.name, buf.String())
+		})
+	}
+}
+
+func TestWriteSVG_Invalid(t *testing.T) {
+	grid := xwgen.NewGrid([][]rune{[]rune("ab"), []rune("cd")})
+	if err := WriteSVG(io.Discard, grid, SVGOptions{CellSize: -1}); err == nil {
+		t.Error("WriteSVG() with a negative cell size succeeded, want error")
+	}
+}
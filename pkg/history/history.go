@@ -0,0 +1,171 @@
+// Package history records the words of the grids one keeps, e.g. for a daily puzzle, so that later
+// grids can avoid repeating them with xwgen.WithWordPenalty:
+//
+//	store := history.NewFile("history.json")
+//	lastUsed, err := store.LastUsed()
+//	...
+//	gen, err := xwgen.CreateGeneratorE(5, ..., xwgen.WithWordPenalty(history.Penalty(lastUsed, time.Now(), 30*24*time.Hour)))
+//	...
+//	err = store.Record(grid.AllWords(), time.Now())
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store records when words were last used.
+type Store interface {
+	// Record records that words were used at the given time. Words already used later keep their
+	// later time.
+	Record(words []string, at time.Time) error
+	// LastUsed returns when each word recorded was last used.
+	LastUsed() (map[string]time.Time, error)
+}
+
+// File is a Store kept in a JSON file, which is created by the first Record. Several processes
+// may use the same file at once: each Record replaces the file with an updated copy while holding
+// a lock, so that no update is lost and the file is never left partly written.
+type File struct {
+	path string
+}
+
+var _ Store = (*File)(nil)
+
+// NewFile returns the Store kept in the file at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// fileData is the contents of a history file.
+type fileData struct {
+	Words map[string]time.Time `json:"words"`
+}
+
+const (
+	// lockRetry is how often Record tries again to lock a file that another writer holds, for up
+	// to lockTimeout.
+	lockRetry   = 10 * time.Millisecond
+	lockTimeout = 10 * time.Second
+	// staleLock is how old a lock must be to be taken as left behind by a writer that stopped
+	// without unlocking. Writers hold it for milliseconds.
+	staleLock = 30 * time.Second
+)
+
+// Record implements Store.
+func (f *File) Record(words []string, at time.Time) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	lastUsed, err := f.LastUsed()
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		if at.After(lastUsed[word]) {
+			lastUsed[word] = at
+		}
+	}
+	return f.write(fileData{Words: lastUsed})
+}
+
+// LastUsed implements Store. A file that does not exist yet has no words.
+func (f *File) LastUsed() (map[string]time.Time, error) {
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(map[string]time.Time), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data fileData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("reading history %s: %w", f.path, err)
+	}
+	if data.Words == nil {
+		data.Words = make(map[string]time.Time)
+	}
+	return data.Words, nil
+}
+
+// write replaces the file with data, by writing it to a temporary file renamed over it, so that
+// readers never see it partly written.
+func (f *File) write(data fileData) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// lock takes the lock on the file, held by a lock file next to it, and returns the function that
+// releases it.
+func (f *File) lock() (unlock func(), err error) {
+	path := f.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lock, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history %s is locked by another writer: remove %s if none is running", f.path, path)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// Penalty returns a penalty for xwgen.WithWordPenalty that avoids the words of lastUsed used less
+// than window before now. A word used at now has a penalty of 1, which falls linearly to 0 for
+// words used window before now, so that the least recently used words are tried first.
+func Penalty(lastUsed map[string]time.Time, now time.Time, window time.Duration) func(word string) float64 {
+	return func(word string) float64 {
+		at, ok := lastUsed[word]
+		if !ok {
+			return 0
+		}
+		age := now.Sub(at)
+		if age >= window {
+			return 0
+		}
+		return 1 - max(age, 0).Seconds()/window.Seconds()
+	}
+}
+
+// Exclude returns a penalty for xwgen.WithWordPenalty that excludes the words of lastUsed used less
+// than window before now.
+func Exclude(lastUsed map[string]time.Time, now time.Time, window time.Duration) func(word string) float64 {
+	return func(word string) float64 {
+		if at, ok := lastUsed[word]; ok && now.Sub(at) < window {
+			return math.Inf(1)
+		}
+		return 0
+	}
+}
@@ -0,0 +1,130 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	store := NewFile(path)
+
+	lastUsed, err := store.LastUsed()
+	if err != nil {
+		t.Fatalf("LastUsed() of a missing file error: %v", err)
+	}
+	if len(lastUsed) != 0 {
+		t.Errorf("LastUsed() of a missing file = %v, want none", lastUsed)
+	}
+
+	day1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	for _, r := range []struct {
+		words []string
+		at    time.Time
+	}{
+		{[]string{"otter", "aloe"}, day2},
+		// Earlier uses do not replace later ones.
+		{[]string{"aloe", "area"}, day1},
+	} {
+		if err := store.Record(r.words, r.at); err != nil {
+			t.Fatalf("Record(%v) error: %v", r.words, err)
+		}
+	}
+	lastUsed, err = NewFile(path).LastUsed()
+	if err != nil {
+		t.Fatalf("LastUsed() error: %v", err)
+	}
+	want := map[string]time.Time{"otter": day2, "aloe": day2, "area": day1}
+	if diff := cmp.Diff(want, lastUsed); diff != "" {
+		t.Errorf("LastUsed() -want +got:\n%s", diff)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind after Record: %v", err)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.json")
+		if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFile(path).LastUsed(); err == nil {
+			t.Error("LastUsed() of an invalid file succeeded, want an error")
+		}
+	})
+}
+
+func TestFile_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	now := time.Now()
+	const writers, records = 8, 10
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each writer has a Store of its own, as separate processes would.
+			store := NewFile(path)
+			for r := range records {
+				if err := store.Record([]string{fmt.Sprintf("word%d_%d", w, r)}, now); err != nil {
+					t.Errorf("Record() error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	lastUsed, err := NewFile(path).LastUsed()
+	if err != nil {
+		t.Fatalf("LastUsed() error: %v", err)
+	}
+	if len(lastUsed) != writers*records {
+		t.Errorf("LastUsed() has %d words, want %d: some updates were lost", len(lastUsed), writers*records)
+	}
+}
+
+func TestFile_StaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path+".lock", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLock)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFile(path).Record([]string{"otter"}, time.Now()); err != nil {
+		t.Errorf("Record() with a stale lock error: %v", err)
+	}
+}
+
+func TestPenalty(t *testing.T) {
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	lastUsed := map[string]time.Time{
+		"today":   now,
+		"recent":  now.AddDate(0, 0, -5),
+		"old":     now.AddDate(0, 0, -20),
+		"ancient": now.AddDate(-1, 0, 0),
+	}
+	window := 10 * 24 * time.Hour
+
+	penalty := Penalty(lastUsed, now, window)
+	for word, want := range map[string]float64{"today": 1, "recent": 0.5, "old": 0, "ancient": 0, "unused": 0} {
+		if got := penalty(word); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Penalty()(%q) = %v, want %v", word, got, want)
+		}
+	}
+
+	exclude := Exclude(lastUsed, now, window)
+	for word, want := range map[string]float64{"today": math.Inf(1), "recent": math.Inf(1), "old": 0, "unused": 0} {
+		if got := exclude(word); got != want {
+			t.Errorf("Exclude()(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package primitives
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DebugTree renders the whole tree of p as text, one node per line, e.g. to debug how lines were
+// filtered. Each node shows its type, NumLetters, and MaxPossibilities, and the nodes it is made of
+// follow it, indented by two more spaces. Nodes holding words show them as String does. The tree
+// is indented by indent levels of two spaces.
+func DebugTree(p PossibleLines, indent int) string {
+	var b strings.Builder
+	debugTree(&b, p, indent)
+	return b.String()
+}
+
+// debugTree writes the tree of p to b at the given level of indentation.
+func debugTree(b *strings.Builder, p PossibleLines, indent int) {
+	var children []PossibleLines
+	label := ""
+	switch p := p.(type) {
+	case *BlockBefore:
+		label, children = "BlockBefore", []PossibleLines{p.lines}
+	case *BlockAfter:
+		label, children = "BlockAfter", []PossibleLines{p.lines}
+	case *BlockBetween:
+		label, children = "BlockBetween", []PossibleLines{p.first, p.second}
+	case *Concat:
+		label, children = "Concat", []PossibleLines{p.first, p.second}
+	case *Compound:
+		label, children = "Compound", p.possibilities
+	case *Memoized:
+		label, children = "Memoized", []PossibleLines{p.lines}
+	default:
+		label = p.String()
+	}
+	fmt.Fprintf(b, "%s%s letters=%d possibilities=%d\n", strings.Repeat("  ", indent), label, p.NumLetters(), p.MaxPossibilities())
+	for _, child := range children {
+		debugTree(b, child, indent+1)
+	}
+}
@@ -0,0 +1,34 @@
+package primitives
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDebugTree(t *testing.T) {
+	lines := MakeBlockBetween(
+		MakeWords([]string{"abc", "abd"}, 1, 3),
+		MakeWords([]string{"xy", "xz", "yy"}, 3, 2),
+	)
+	want := "BlockBetween letters=6 possibilities=6\n" +
+		"  Words([abc], [abd]) letters=3 possibilities=2\n" +
+		"  Words([xy, xz, yy], []) letters=2 possibilities=3\n"
+	if diff := cmp.Diff(want, DebugTree(lines, 0)); diff != "" {
+		t.Errorf("DebugTree() -want +got:\n%s", diff)
+	}
+
+	nested := MemoizedFilter(MakeCompound([]PossibleLines{
+		MakeBlockBefore(MakeDefinite(ConcreteLine{Line: []rune("ab"), Words: []string{"ab"}})),
+		MakeBlockAfter(MakeWords([]string{"cd", "ce"}, 2, 2)),
+	}, 3))
+	want = "    Memoized letters=3 possibilities=3\n" +
+		"      Compound letters=3 possibilities=3\n" +
+		"        BlockBefore letters=3 possibilities=1\n" +
+		"          Definite(ab) letters=2 possibilities=1\n" +
+		"        BlockAfter letters=3 possibilities=2\n" +
+		"          Words([cd, ce], []) letters=2 possibilities=2\n"
+	if diff := cmp.Diff(want, DebugTree(nested, 2)); diff != "" {
+		t.Errorf("DebugTree() with indent 2 -want +got:\n%s", diff)
+	}
+}
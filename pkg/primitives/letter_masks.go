@@ -0,0 +1,65 @@
+package primitives
+
+import "sync/atomic"
+
+// letterMasks caches, for each index of a set of words of the same length, the set of letters at
+// that index across all of them. Each mask is built on first use and published atomically, so the
+// words holding it can be read from several goroutines at once.
+type letterMasks struct {
+	// masks holds the bits of the CharSet of each index, or 0 for one not built yet, since every
+	// index of a word has a letter.
+	masks atomic.Pointer[[]atomic.Uint32]
+}
+
+// built returns the mask at index, and whether it has been built.
+func (m *letterMasks) built(index int) (CharSet, bool) {
+	masks := m.masks.Load()
+	if masks == nil {
+		return CharSet{}, false
+	}
+	bits := (*masks)[index].Load()
+	return CharSet{bits: bits}, bits != 0
+}
+
+// get returns the mask at index of words of numLetters letters, adding their letters at index to
+// it with build first if it has not been built. Goroutines that race to build the same mask build
+// the same one.
+func (m *letterMasks) get(numLetters, index int, build func(mask *CharSet)) CharSet {
+	if mask, ok := m.built(index); ok {
+		return mask
+	}
+	masks := m.masks.Load()
+	if masks == nil {
+		fresh := make([]atomic.Uint32, numLetters)
+		if !m.masks.CompareAndSwap(nil, &fresh) {
+			masks = m.masks.Load()
+		} else {
+			masks = &fresh
+		}
+	}
+	var mask CharSet
+	build(&mask)
+	(*masks)[index].Store(mask.bits)
+	return mask
+}
+
+// len returns the number of masks, or 0 if none has been built.
+func (m *letterMasks) len() int {
+	if masks := m.masks.Load(); masks != nil {
+		return len(*masks)
+	}
+	return 0
+}
+
+// copyFrom makes m hold a copy of the masks of other built so far.
+func (m *letterMasks) copyFrom(other *letterMasks) {
+	masks := other.masks.Load()
+	if masks == nil {
+		return
+	}
+	fresh := make([]atomic.Uint32, len(*masks))
+	for i := range fresh {
+		fresh[i].Store((*masks)[i].Load())
+	}
+	m.masks.Store(&fresh)
+}
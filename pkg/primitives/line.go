@@ -1,6 +1,9 @@
 package primitives
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 // ConcreteLine represents a single possible line in a puzzle.
 type ConcreteLine struct {
@@ -16,3 +19,16 @@ func (l *ConcreteLine) Length() int {
 func (l *ConcreteLine) String() string {
 	return strings.ToUpper(string(l.Line))
 }
+
+// Equals returns true if l and other have the same cells and the same words, in the same order. A
+// nil Words is the same as an empty one, so a line with no words equals a line whose words were
+// never set.
+func (l *ConcreteLine) Equals(other ConcreteLine) bool {
+	return ConcreteLineEqual(*l, other)
+}
+
+// ConcreteLineEqual returns true if a and b are equal as by Equals, e.g. for finding a line with
+// slices.ContainsFunc in tests.
+func ConcreteLineEqual(a, b ConcreteLine) bool {
+	return slices.Equal(a.Line, b.Line) && slices.Equal(a.Words, b.Words)
+}
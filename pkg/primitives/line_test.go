@@ -0,0 +1,42 @@
+package primitives
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConcreteLine_Equals(t *testing.T) {
+	abc := ConcreteLine{Line: []rune("abc"), Words: []string{"abc"}}
+	for _, tc := range []struct {
+		name string
+		a, b ConcreteLine
+		want bool
+	}{
+		{name: "Same", a: abc, b: ConcreteLine{Line: []rune("abc"), Words: []string{"abc"}}, want: true},
+		{name: "DifferentLine", a: abc, b: ConcreteLine{Line: []rune("abd"), Words: []string{"abc"}}},
+		{name: "ShorterLine", a: abc, b: ConcreteLine{Line: []rune("ab"), Words: []string{"abc"}}},
+		{name: "DifferentWords", a: abc, b: ConcreteLine{Line: []rune("abc"), Words: []string{"abd"}}},
+		{
+			name: "WordsInOtherOrder",
+			a:    ConcreteLine{Line: []rune("a`b"), Words: []string{"a", "b"}},
+			b:    ConcreteLine{Line: []rune("a`b"), Words: []string{"b", "a"}},
+		},
+		{name: "NilAndEmptyWords", a: ConcreteLine{Line: []rune("`")}, b: ConcreteLine{Line: []rune("`"), Words: []string{}}, want: true},
+		{name: "NilAndSomeWords", a: ConcreteLine{Line: []rune("abc")}, b: abc},
+		{name: "Empty", want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.a.Equals(tc.b); got != tc.want {
+				t.Errorf("%v.Equals(%v) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+			if got := ConcreteLineEqual(tc.b, tc.a); got != tc.want {
+				t.Errorf("ConcreteLineEqual(%v, %v) = %v, want %v", tc.b, tc.a, got, tc.want)
+			}
+		})
+	}
+
+	lines := slices.Collect(MakeWords([]string{"abc", "abd"}, 1, 3).Iterate())
+	if !slices.ContainsFunc(lines, func(l ConcreteLine) bool { return ConcreteLineEqual(l, abc) }) {
+		t.Errorf("lines %v do not contain %v", lines, abc)
+	}
+}
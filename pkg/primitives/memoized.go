@@ -0,0 +1,199 @@
+package primitives
+
+import (
+	"container/list"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"sync"
+)
+
+// memoizedFilterCacheSize is the number of results of Filter kept by the lines returned by
+// MemoizedFilter, and every line derived from them.
+const memoizedFilterCacheSize = 4096
+
+// Memoized represents the same set of possible lines as the lines it wraps, but caches the results
+// of Filter, so that filtering the same lines on the same letter at the same index, e.g. in
+// different branches of a search, reuses the earlier result.
+//
+// Lines derived from a Memoized, e.g. by FilterAny or MakeChoice, are wrapped too, and share its
+// cache, which holds the most recently used results. It is safe for concurrent use.
+type Memoized struct {
+	lines PossibleLines
+	cache *filterCache
+}
+
+// MemoizedFilter returns p with the results of Filter cached. Lines with at most one possibility
+// are returned as they are, since filtering them is already cheap.
+func MemoizedFilter(p PossibleLines) PossibleLines {
+	if m, ok := p.(*Memoized); ok {
+		return m
+	}
+	return memoized(p, newFilterCache(memoizedFilterCacheSize))
+}
+
+// memoized returns p wrapped to share cache, unless it has at most one possibility or is already
+// Memoized, e.g. a child of a Compound that a choice left on its own.
+func memoized(p PossibleLines, cache *filterCache) PossibleLines {
+	if _, ok := p.(*Memoized); ok || p.MaxPossibilities() <= 1 {
+		return p
+	}
+	return &Memoized{lines: p, cache: cache}
+}
+
+// with returns m wrapping p, or m itself if p is the lines it already wraps.
+func (m *Memoized) with(p PossibleLines) PossibleLines {
+	if p == m.lines {
+		return m
+	}
+	return memoized(p, m.cache)
+}
+
+func (m *Memoized) NumLetters() int {
+	return m.lines.NumLetters()
+}
+
+func (m *Memoized) MaxPossibilities() int64 {
+	return m.lines.MaxPossibilities()
+}
+
+func (m *Memoized) Count() int64 {
+	return m.lines.Count()
+}
+
+func (m *Memoized) CountAtMost(limit int64) int64 {
+	return m.lines.CountAtMost(limit)
+}
+
+func (m *Memoized) CharsAt(accumulate *CharSet, index int) {
+	m.lines.CharsAt(accumulate, index)
+}
+
+func (m *Memoized) DefinitelyBlockedAt(index int) bool {
+	return m.lines.DefinitelyBlockedAt(index)
+}
+
+func (m *Memoized) DefiniteWords() []string {
+	return m.lines.DefiniteWords()
+}
+
+func (m *Memoized) FilterAny(constraint *CharSet, index int) PossibleLines {
+	return m.with(m.lines.FilterAny(constraint, index))
+}
+
+func (m *Memoized) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	return m.with(m.lines.FilterMulti(constraints))
+}
+
+func (m *Memoized) Filter(constraint rune, index int) PossibleLines {
+	key := filterKey{lines: m.lines, constraint: constraint, index: index}
+	if filtered, ok := m.cache.get(key); ok {
+		return filtered
+	}
+	filtered := m.with(m.lines.Filter(constraint, index))
+	m.cache.add(key, filtered)
+	return filtered
+}
+
+func (m *Memoized) RemoveWordOptions(words []string) PossibleLines {
+	return m.with(m.lines.RemoveWordOptions(words))
+}
+
+func (m *Memoized) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return m.with(m.lines.RemoveWordSet(excluded))
+}
+
+func (m *Memoized) Contains(line []rune) bool {
+	return m.lines.Contains(line)
+}
+
+func (m *Memoized) Iterate() iter.Seq[ConcreteLine] {
+	return m.lines.Iterate()
+}
+
+func (m *Memoized) FirstOrNull() *ConcreteLine {
+	return m.lines.FirstOrNull()
+}
+
+func (m *Memoized) Sample(r *rand.Rand) *ConcreteLine {
+	return m.lines.Sample(r)
+}
+
+func (m *Memoized) MakeChoice() ChoiceStep {
+	c := m.lines.MakeChoice()
+	return ChoiceStep{
+		Choice:    memoized(c.Choice, m.cache),
+		Remaining: memoized(c.Remaining, m.cache),
+	}
+}
+
+func (m *Memoized) MakeChoiceN(n int) []PossibleLines {
+	parts := m.lines.MakeChoiceN(n)
+	for i, p := range parts {
+		parts[i] = memoized(p, m.cache)
+	}
+	return parts
+}
+
+// Clone returns a copy of m with an empty cache of its own, so that filtering it never returns
+// lines shared with m.
+func (m *Memoized) Clone() PossibleLines {
+	return &Memoized{lines: m.lines.Clone(), cache: newFilterCache(m.cache.size)}
+}
+
+func (m *Memoized) String() string {
+	return fmt.Sprintf("Memoized(%v)", m.lines)
+}
+
+// filterKey identifies a call to Filter on some lines.
+type filterKey struct {
+	lines      PossibleLines
+	constraint rune
+	index      int
+}
+
+// filterCache is a least-recently-used cache of the results of Filter.
+type filterCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *filterEntry, most recently used first
+	items map[filterKey]*list.Element
+}
+
+type filterEntry struct {
+	key      filterKey
+	filtered PossibleLines
+}
+
+func newFilterCache(size int) *filterCache {
+	return &filterCache{size: size, order: list.New(), items: make(map[filterKey]*list.Element, size)}
+}
+
+// get returns the cached result for key, if any, marking it as the most recently used.
+func (c *filterCache) get(key filterKey) (PossibleLines, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*filterEntry).filtered, true
+}
+
+// add caches the result for key, evicting the least recently used result if the cache is full.
+func (c *filterCache) add(key filterKey, filtered PossibleLines) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		// Another goroutine filtered the same lines at the same time.
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&filterEntry{key: key, filtered: filtered})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*filterEntry).key)
+	}
+}
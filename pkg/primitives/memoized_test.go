@@ -0,0 +1,74 @@
+package primitives
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestMemoizedFilter(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	for trial := range 20 {
+		all := slices.Compact(slices.Sorted(slices.Values(randomWords(r, 40, 5))))
+		numPreferred := r.IntN(len(all))
+		words := MakeWords(all, numPreferred, 5)
+		memoized := MemoizedFilter(words)
+
+		check := func(name string, got, want PossibleLines) {
+			t.Helper()
+			checkSameLines(t, fmt.Sprintf("trial %d: %s", trial, name), got, want)
+			if got, want := collectLines(got), collectLines(want); !slices.Equal(got, want) {
+				t.Errorf("trial %d: %s Iterate() = %v, want %v", trial, name, got, want)
+			}
+		}
+		check("MemoizedFilter", memoized, words)
+
+		for index := range 5 {
+			for _, letter := range "abcde" {
+				got := memoized.Filter(letter, index)
+				check(fmt.Sprintf("Filter(%c, %d)", letter, index), got, words.Filter(letter, index))
+				if again := memoized.Filter(letter, index); again != got {
+					t.Errorf("trial %d: Filter(%c, %d) = %v, then %v, want the cached result", trial, letter, index, got, again)
+				}
+			}
+			var cs CharSet
+			cs.Add('a')
+			cs.Add('c')
+			check(fmt.Sprintf("FilterAny(ac, %d)", index), memoized.FilterAny(&cs, index), words.FilterAny(&cs, index))
+		}
+		removed := []string{all[0], all[len(all)/2], "zzzzz"}
+		check("RemoveWordOptions", memoized.RemoveWordOptions(removed), words.RemoveWordOptions(removed))
+
+		c, want := memoized.MakeChoice(), words.MakeChoice()
+		check("MakeChoice().Choice", c.Choice, want.Choice)
+		check("MakeChoice().Remaining", c.Remaining, want.Remaining)
+	}
+}
+
+func TestMemoizedFilter_Evicts(t *testing.T) {
+	words := MakeWords([]string{"cat", "car", "cot", "dog"}, 4, 3)
+	cache := newFilterCache(2)
+	m := memoized(words, cache)
+
+	first := m.Filter('c', 0)
+	if _, ok := first.(*Memoized); !ok {
+		t.Errorf("Filter('c', 0) = %v, want Memoized lines", first)
+	}
+	if got := m.Filter('o', 1); !isActuallyImpossible(got.Filter('x', 0)) {
+		t.Errorf("Filter('o', 1).Filter('x', 0) = %v, want Impossible", got)
+	}
+	m.Filter('a', 1)
+	if len(cache.items) != 2 {
+		t.Errorf("cache holds %d results, want 2", len(cache.items))
+	}
+	if _, ok := cache.get(filterKey{lines: words, constraint: 'c', index: 0}); ok {
+		t.Error("cache holds the least recently used result, want it evicted")
+	}
+	if got := MemoizedFilter(m); got != m {
+		t.Errorf("MemoizedFilter(%v) = %v, want the same lines", m, got)
+	}
+	if got, ok := MemoizedFilter(words.Filter('d', 0)).(*Definite); !ok {
+		t.Errorf("MemoizedFilter() of a definite line = %v, want it unwrapped", got)
+	}
+}
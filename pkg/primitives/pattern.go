@@ -0,0 +1,47 @@
+package primitives
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Wildcard matches any single letter in a pattern.
+const Wildcard = '?'
+
+// Pattern matches words of a fixed length, where each position allows a set of letters. For
+// example, the pattern "a??le" matches "apple" and "addle".
+//
+// Each position is a CharSet, so a pattern can also constrain a line, e.g. with FilterAny.
+type Pattern []CharSet
+
+// ParsePattern parses a pattern of letters and Wildcards. Letters are case insensitive.
+func ParsePattern(s string) (Pattern, error) {
+	p := make(Pattern, 0, len(s))
+	for _, r := range s {
+		var cs CharSet
+		if r == Wildcard {
+			for l := 'a'; l <= 'z'; l++ {
+				cs.Add(l)
+			}
+		} else if r = unicode.ToLower(r); r >= 'a' && r <= 'z' {
+			cs.Add(r)
+		} else {
+			return nil, fmt.Errorf("pattern %q contains %q, expected letters or %q", s, r, Wildcard)
+		}
+		p = append(p, cs)
+	}
+	return p, nil
+}
+
+// Matches returns true if word is exactly as long as p, and every letter is allowed by p.
+func (p Pattern) Matches(word string) bool {
+	if len(word) != len(p) {
+		return false
+	}
+	for i, r := range word {
+		if !p[i].Contains(r) {
+			return false
+		}
+	}
+	return true
+}
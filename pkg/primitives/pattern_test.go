@@ -0,0 +1,59 @@
+package primitives
+
+import "testing"
+
+func TestPattern_Matches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		word    string
+		want    bool
+	}{
+		{"a??le", "apple", true},
+		{"a??le", "addle", true},
+		{"A??LE", "apple", true},
+		{"a??le", "apply", false},
+		{"a??le", "apples", false},
+		{"a??le", "appl", false},
+		{"?????", "otter", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.word, func(t *testing.T) {
+			p, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern(%q) error: %v", tt.pattern, err)
+			}
+			if got := p.Matches(tt.word); got != tt.want {
+				t.Errorf("ParsePattern(%q).Matches(%q) = %v, want %v", tt.pattern, tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePattern_Invalid(t *testing.T) {
+	for _, pattern := range []string{"a*le", "a le", "a#le", "é??"} {
+		if _, err := ParsePattern(pattern); err == nil {
+			t.Errorf("ParsePattern(%q) succeeded, want error", pattern)
+		}
+	}
+}
+
+func TestPattern_FilterAny(t *testing.T) {
+	p, err := ParsePattern("?a?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := MakeWords([]string{"bat", "bet", "cat", "cot"}, 4, 3)
+	for i := range p {
+		line = line.FilterAny(&p[i], i)
+	}
+
+	var got []string
+	for c := range line.Iterate() {
+		got = append(got, string(c.Line))
+	}
+	if len(got) != 2 || got[0] != "bat" || got[1] != "cat" {
+		t.Errorf("filtered words = %v, want [bat cat]", got)
+	}
+}
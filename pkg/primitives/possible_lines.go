@@ -1,14 +1,21 @@
 package primitives
 
 import (
+	"cmp"
 	"fmt"
 	"iter"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"slices"
 	"strings"
 )
 
 const kBlocked = '`'
 
+// Blocked is the rune used to represent a blocked cell in a line.
+const Blocked = kBlocked
+
 // ChoiceStep represents a single choice in deciding what the a given line in a puzzle should be,
 // dividing the set of possible lines into two sets that can be iterated over.
 type ChoiceStep struct {
@@ -16,11 +23,96 @@ type ChoiceStep struct {
 	Remaining PossibleLines
 }
 
+// IndexedCharSet constrains the character at Index of a line to one of Chars. See FilterMulti.
+type IndexedCharSet struct {
+	Index int
+	Chars CharSet
+}
+
+// allowsAnyLetter returns whether c allows every letter, so that it filters nothing out of lines of
+// words.
+func (c *IndexedCharSet) allowsAnyLetter() bool {
+	return c.Chars.IsFull() || (!c.Chars.Contains(kBlocked) && (c.Chars.Count()+1) == c.Chars.Capacity())
+}
+
+// splitConstraints returns the constraints before index n, and those at or after it with their
+// indices made relative to n.
+func splitConstraints(constraints []IndexedCharSet, n int) (before, after []IndexedCharSet) {
+	for _, c := range constraints {
+		if c.Index < n {
+			before = append(before, c)
+		} else {
+			after = append(after, IndexedCharSet{Index: c.Index - n, Chars: c.Chars})
+		}
+	}
+	return before, after
+}
+
+// allowBlockAt returns whether every constraint at index allows a blocked cell.
+func allowBlockAt(constraints []IndexedCharSet, index int) bool {
+	return !slices.ContainsFunc(constraints, func(c IndexedCharSet) bool {
+		return c.Index == index && !c.Chars.Contains(kBlocked)
+	})
+}
+
+// matchesAll returns whether word meets every constraint.
+func matchesAll(word string, constraints []IndexedCharSet) bool {
+	for _, c := range constraints {
+		if !c.Chars.Contains(rune(word[c.Index])) {
+			return false
+		}
+	}
+	return true
+}
+
+// makeChoiceN splits lines into at most n sets by splitting the set with the most possibilities
+// with MakeChoice until there are n sets, or none can be split.
+func makeChoiceN(lines PossibleLines, n int) []PossibleLines {
+	checkChoiceN(n)
+	parts := []PossibleLines{lines}
+	for len(parts) < n {
+		largest := 0
+		for i, p := range parts {
+			if p.MaxPossibilities() > parts[largest].MaxPossibilities() {
+				largest = i
+			}
+		}
+		// MakeChoice panics if lines themselves cannot be split.
+		if len(parts) > 1 && parts[largest].MaxPossibilities() <= 1 {
+			break
+		}
+		c := parts[largest].MakeChoice()
+		parts = slices.Replace(parts, largest, largest+1, c.Choice, c.Remaining)
+	}
+	return parts
+}
+
+// checkChoiceN panics if n is too small for MakeChoiceN.
+func checkChoiceN(n int) {
+	if n < 2 {
+		panic(fmt.Sprintf("Cannot call MakeChoiceN with n = %d, which is less than 2", n))
+	}
+}
+
+// chunks returns the bounds of at most n contiguous, non-empty ranges of nearly equal length that
+// together cover [0, length).
+func chunks(length, n int) [][2]int {
+	n = min(n, length)
+	bounds := make([][2]int, n)
+	for i := range bounds {
+		bounds[i] = [2]int{i * length / n, (i + 1) * length / n}
+	}
+	return bounds
+}
+
 type sealed = any
 
 // PossibleLines represents a set of possible lines in our puzzle. A 'Line' is a string of values
 // in the puzzle's boxes representing an entire line from start to end. It can include characters
 // and blocked cells.
+//
+// Possible lines are never modified once made, other than caches that are safe for concurrent
+// use, so the same lines can be read from several goroutines at once.
 type PossibleLines interface {
 	sealed // This interface is not meant to be implemented by anything other than the types below.
 
@@ -32,6 +124,17 @@ type PossibleLines interface {
 	// This can be lower since some lines might include repeated words, etc.
 	MaxPossibilities() int64
 
+	// Count returns the exact number of distinct possible lines, or math.MaxInt64 if there are
+	// more. Unlike MaxPossibilities, it counts a line that can be reached in several ways once,
+	// which for a Compound of overlapping possibilities takes iterating over their lines. The
+	// words that Words and SortedWords are made from are assumed to be distinct.
+	Count() int64
+
+	// CountAtMost returns Count if it is at most limit, or some number greater than limit
+	// otherwise, doing only as much work as it takes to tell which, e.g. to cheaply check whether
+	// there are more than 1000 lines.
+	CountAtMost(limit int64) int64
+
 	// CharsAt adds the characters that can appear at a given index to the given set.
 	CharsAt(accumulate *CharSet, index int)
 
@@ -51,21 +154,48 @@ type PossibleLines interface {
 	// character at the given index.
 	Filter(constraint rune, index int) PossibleLines
 
+	// FilterMulti returns the same lines as calling FilterAny with each constraint in turn, but
+	// goes over the words of the lines once rather than once per constraint. It returns the lines
+	// themselves if every line meets the constraints.
+	FilterMulti(constraints []IndexedCharSet) PossibleLines
+
 	// RemoveWordOptions strips the possible lines to no longer include a given set of word.
 	RemoveWordOptions(word []string) PossibleLines
 
+	// RemoveWordSet is like RemoveWordOptions, but takes the words to remove as a set, which is
+	// faster when there are many of them, e.g. every word used in earlier grids.
+	RemoveWordSet(excluded map[string]struct{}) PossibleLines
+
+	// Contains returns whether line is one of the possible lines, without iterating over them: it
+	// takes time proportional to the length of the line and the number of words at most.
+	Contains(line []rune) bool
+
 	// Iterate returns a sequence of all possible lines.
 	Iterate() iter.Seq[ConcreteLine]
 
 	// FirstOrNull returns the first possible line, or nil if there are no possible lines.
 	FirstOrNull() *ConcreteLine
 
+	// Sample returns a line chosen uniformly at random from those Iterate yields, without
+	// iterating over them, or nil if there are none. A line that Iterate yields more than once,
+	// e.g. from overlapping possibilities of a Compound, is that many times as likely.
+	Sample(r *rand.Rand) *ConcreteLine
+
 	// MakeChoice returns a choice step that divides the set of possible lines into two sets that
 	// can be iterated over.
 	//
 	// Ideally, MakeChoice will return two groups that are roughly equal in size.
 	MakeChoice() ChoiceStep
 
+	// MakeChoiceN divides the set of possible lines into at most n sets whose MaxPossibilities are
+	// roughly equal, e.g. one for each of n workers. It panics if n < 2, or if there is nothing to
+	// choose between, like MakeChoice.
+	MakeChoiceN(n int) []PossibleLines
+
+	// Clone returns a deep copy of the possible lines, which shares no mutable state with them, e.g.
+	// so that goroutines each fill their own caches. Words and other immutable data are shared.
+	Clone() PossibleLines
+
 	String() string
 }
 
@@ -82,6 +212,14 @@ func (i *Impossible) MaxPossibilities() int64 {
 	return 0
 }
 
+func (i *Impossible) Count() int64 {
+	return 0
+}
+
+func (i *Impossible) CountAtMost(limit int64) int64 {
+	return 0
+}
+
 func (i *Impossible) CharsAt(accumulate *CharSet, index int) {
 }
 
@@ -97,6 +235,10 @@ func (i *Impossible) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return i
 }
 
+func (i *Impossible) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	return i
+}
+
 func (i *Impossible) Filter(constraint rune, index int) PossibleLines {
 	return i
 }
@@ -105,6 +247,14 @@ func (i *Impossible) RemoveWordOptions(words []string) PossibleLines {
 	return i
 }
 
+func (i *Impossible) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return i
+}
+
+func (i *Impossible) Contains(line []rune) bool {
+	return false
+}
+
 func (i *Impossible) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {}
 }
@@ -113,21 +263,47 @@ func (i *Impossible) FirstOrNull() *ConcreteLine {
 	return nil
 }
 
+func (i *Impossible) Sample(r *rand.Rand) *ConcreteLine {
+	return nil
+}
+
 func (i *Impossible) MakeChoice() ChoiceStep {
 	panic("Cannot call MakeChoice on Impossible")
 }
 
+func (i *Impossible) MakeChoiceN(n int) []PossibleLines {
+	panic("Cannot call MakeChoiceN on Impossible")
+}
+
+// Clone returns i itself, since it has no mutable state.
+func (i *Impossible) Clone() PossibleLines {
+	return i
+}
+
 func (i *Impossible) String() string {
 	return fmt.Sprintf("Impossible(%d)", i.numLetters)
 }
 
-var ic = make([]Impossible, 25)
+// impossibles holds the Impossible lines of each length below len(impossibles), so that making
+// one doesn't allocate. They are made up front, so that goroutines can share them.
+var impossibles = func() []Impossible {
+	ic := make([]Impossible, 64)
+	for n := range ic {
+		ic[n] = Impossible{numLetters: n}
+	}
+	return ic
+}()
 
+// MakeImpossible returns the empty set of lines of numLetters letters. It panics if numLetters is
+// negative.
 func MakeImpossible(numLetters int) *Impossible {
-	if ic[numLetters] == (Impossible{}) {
-		ic[numLetters] = Impossible{numLetters: numLetters}
+	if numLetters < 0 {
+		panic(fmt.Sprintf("MakeImpossible: negative length %d", numLetters))
 	}
-	return &ic[numLetters]
+	if numLetters < len(impossibles) {
+		return &impossibles[numLetters]
+	}
+	return &Impossible{numLetters: numLetters}
 }
 
 // Words represents a set of possible lines that are exactly filled with any one of the given words.
@@ -138,21 +314,30 @@ type Words struct {
 	obscureIdx int      // Index of first obscure word, if 0, all words are obscure, if len(allWords), all words are preferred
 	// letterMasks caches, for each index, the bitmask of allowed runes across all words.
 	// It accelerates CharsAt and lets FilterAny early-return.
-	letterMasks []CharSet
+	letterMasks letterMasks
 }
 
+// MakeWordsFromPreferredAndObscure returns the possible lines filled with any one of preferred or
+// obscure. Each word is kept once, so that it is only one possibility: a word in both lists is
+// preferred, and only the first copy of a word repeated within a list is kept. Neither list is
+// modified.
 func MakeWordsFromPreferredAndObscure(preferred, obscure []string, numLetters int) PossibleLines {
-	if len(preferred) == 0 && len(obscure) == 0 {
-		return MakeImpossible(numLetters)
-	}
-	if len(preferred) == 1 && len(obscure) == 0 {
-		return MakeDefinite(ConcreteLine{Line: []rune(preferred[0]), Words: []string{preferred[0]}})
+	seen := make(map[string]bool, len(preferred)+len(obscure))
+	allWords := make([]string, 0, len(preferred)+len(obscure))
+	for _, word := range preferred {
+		if !seen[word] {
+			seen[word] = true
+			allWords = append(allWords, word)
+		}
 	}
-	if len(preferred) == 0 && len(obscure) == 1 {
-		return MakeDefinite(ConcreteLine{Line: []rune(obscure[0]), Words: []string{obscure[0]}})
+	obscureIdx := len(allWords)
+	for _, word := range obscure {
+		if !seen[word] {
+			seen[word] = true
+			allWords = append(allWords, word)
+		}
 	}
-	// Lazily allocate letterMasks on first use to avoid upfront cost when not needed.
-	return &Words{allWords: append(preferred, obscure...), obscureIdx: len(preferred)}
+	return MakeWords(allWords, obscureIdx, numLetters)
 }
 
 func MakeWords(allWords []string, obscureIdx int, numLetters int) PossibleLines {
@@ -174,22 +359,35 @@ func (w *Words) MaxPossibilities() int64 {
 	return int64(len(w.allWords))
 }
 
+func (w *Words) Count() int64 {
+	return int64(len(w.allWords))
+}
+
+func (w *Words) CountAtMost(limit int64) int64 {
+	return w.Count()
+}
+
 func (w *Words) CharsAt(accumulate *CharSet, index int) {
 	if accumulate.IsFull() || (!accumulate.Contains(kBlocked) && (accumulate.Count()+1) == accumulate.Capacity()) {
 		return
 	}
 	// Build masks lazily.
-	if w.letterMasks == nil {
-		w.letterMasks = make([]CharSet, w.NumLetters())
-	}
-	if w.letterMasks[index].bits == 0 {
-		w.letterMasks[index] = CharSet{}
+	mask := w.letterMasks.get(w.NumLetters(), index, func(mask *CharSet) {
 		for _, word := range w.allWords {
-			r := rune(word[index])
-			w.letterMasks[index].Add(r)
+			mask.Add(rune(word[index]))
 		}
+	})
+	accumulate.AddAll(&mask)
+}
+
+// LetterFrequency returns the number of words of w with each letter at index, which must be less
+// than NumLetters. Unlike CharsAt, it shows how much filtering on a letter would narrow w down.
+func (w *Words) LetterFrequency(index int) map[rune]int {
+	frequency := make(map[rune]int)
+	for _, word := range w.allWords {
+		frequency[rune(word[index])]++
 	}
-	accumulate.AddAll(&w.letterMasks[index])
+	return frequency
 }
 
 func (w *Words) DefinitelyBlockedAt(index int) bool {
@@ -209,8 +407,7 @@ func (w *Words) FilterAny(constraint *CharSet, index int) PossibleLines {
 	}
 
 	// If we have a mask and it is entirely contained by the constraint, nothing to filter.
-	if w.letterMasks != nil && w.letterMasks[index].bits != 0 {
-		mask := w.letterMasks[index]
+	if mask, ok := w.letterMasks.built(index); ok {
 		if constraint.ContainsAll(&mask) {
 			return w
 		}
@@ -241,6 +438,38 @@ func (w *Words) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return MakeWords(filtered, newNumPreferred, w.NumLetters())
 }
 
+func (w *Words) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	// Skip the constraints that every word meets.
+	var active []IndexedCharSet
+	for _, c := range constraints {
+		if c.allowsAnyLetter() {
+			continue
+		}
+		if mask, ok := w.letterMasks.built(c.Index); ok && c.Chars.ContainsAll(&mask) {
+			continue
+		}
+		active = append(active, c)
+	}
+	first := slices.IndexFunc(w.allWords, func(word string) bool {
+		return !matchesAll(word, active)
+	})
+	if first < 0 {
+		return w
+	}
+
+	filtered := append(make([]string, 0, len(w.allWords)-1), w.allWords[:first]...)
+	numPreferred := min(first, w.obscureIdx)
+	for idx, word := range w.allWords[first+1:] {
+		if matchesAll(word, active) {
+			filtered = append(filtered, word)
+			if first+1+idx < w.obscureIdx {
+				numPreferred++
+			}
+		}
+	}
+	return MakeWords(filtered, numPreferred, w.NumLetters())
+}
+
 func (w *Words) Filter(constraint rune, index int) PossibleLines {
 	if constraint == kBlocked {
 		return MakeImpossible(w.NumLetters())
@@ -276,6 +505,77 @@ func (w *Words) Filter(constraint rune, index int) PossibleLines {
 	return MakeWords(filtered, newNumPreferred, w.NumLetters())
 }
 
+// FilterByPrefix returns the words that start with prefix. It is equivalent to calling Filter with
+// each character of prefix, but scans the words once and allocates at most one new list.
+func (w *Words) FilterByPrefix(prefix string) PossibleLines {
+	if len(prefix) > w.NumLetters() || strings.ContainsRune(prefix, kBlocked) {
+		return MakeImpossible(w.NumLetters())
+	}
+
+	if prefix == "" {
+		return w
+	}
+	// Every word is at least as long as prefix, so comparing the first byte before the rest of
+	// the prefix skips most words cheaply.
+	matches := func(word string) bool {
+		return word[0] == prefix[0] && word[:len(prefix)] == prefix
+	}
+
+	// Count the matches first, so that the new list can be allocated at its final size. If every
+	// word matches, there is nothing to filter.
+	numMatches := 0
+	for _, word := range w.allWords {
+		if matches(word) {
+			numMatches++
+		}
+	}
+	if numMatches == len(w.allWords) {
+		return w
+	}
+
+	filtered := make([]string, 0, numMatches)
+	newNumPreferred := 0
+	for idx, word := range w.allWords {
+		if matches(word) {
+			if idx < w.obscureIdx {
+				newNumPreferred++
+			}
+			filtered = append(filtered, word)
+		}
+	}
+
+	return MakeWords(filtered, newNumPreferred, w.NumLetters())
+}
+
+// SortByFrequency returns the same words ordered by descending freq, the preferred words first and
+// then the obscure ones as before, so that the most frequent words come first when iterating and
+// end up in the first half when making a choice. Words of the same frequency keep their order, and
+// words missing from freq have a frequency of 0. w is not modified.
+func (w *Words) SortByFrequency(freq map[string]float64) PossibleLines {
+	// Looking up each frequency once is much quicker than in every comparison.
+	type scored struct {
+		word string
+		freq float64
+	}
+	scoredWords := make([]scored, len(w.allWords))
+	for i, word := range w.allWords {
+		scoredWords[i] = scored{word: word, freq: freq[word]}
+	}
+	byFrequency := func(a, b scored) int {
+		return cmp.Compare(b.freq, a.freq)
+	}
+	slices.SortStableFunc(scoredWords[:w.obscureIdx], byFrequency)
+	slices.SortStableFunc(scoredWords[w.obscureIdx:], byFrequency)
+
+	allWords := make([]string, len(scoredWords))
+	for i, s := range scoredWords {
+		allWords[i] = s.word
+	}
+	// The letters at each index are the same, but the masks are left to be built again, so that the
+	// sorted words share nothing with w.
+	return &Words{allWords: allWords, obscureIdx: w.obscureIdx}
+}
+
 func (w *Words) RemoveWordOptions(words []string) PossibleLines {
 	// Figure out if any (or both) lists need filtering. For any that doesn't,
 	// we don't need to allocate a new list.
@@ -306,6 +606,33 @@ func (w *Words) RemoveWordOptions(words []string) PossibleLines {
 	return MakeWords(fp, fPreferred, w.NumLetters())
 }
 
+func (w *Words) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	first := slices.IndexFunc(w.allWords, func(word string) bool {
+		_, ok := excluded[word]
+		return ok
+	})
+	if first < 0 {
+		return w
+	}
+
+	fp := append(make([]string, 0, len(w.allWords)-1), w.allWords[:first]...)
+	fPreferred := min(first, w.obscureIdx)
+	for idx, p := range w.allWords[first+1:] {
+		if _, ok := excluded[p]; !ok {
+			fp = append(fp, p)
+			if first+1+idx < w.obscureIdx {
+				fPreferred++
+			}
+		}
+	}
+
+	return MakeWords(fp, fPreferred, w.NumLetters())
+}
+
+func (w *Words) Contains(line []rune) bool {
+	return len(line) == w.NumLetters() && slices.Contains(w.allWords, string(line))
+}
+
 func (w *Words) FirstOrNull() *ConcreteLine {
 	if len(w.allWords) == 0 {
 		return nil
@@ -313,6 +640,11 @@ func (w *Words) FirstOrNull() *ConcreteLine {
 	return &ConcreteLine{Line: []rune(w.allWords[0]), Words: []string{w.allWords[0]}}
 }
 
+func (w *Words) Sample(r *rand.Rand) *ConcreteLine {
+	word := w.allWords[r.IntN(len(w.allWords))]
+	return &ConcreteLine{Line: []rune(word), Words: []string{word}}
+}
+
 func (w *Words) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {
 		for _, word := range w.allWords {
@@ -345,6 +677,21 @@ func (w *Words) MakeChoice() ChoiceStep {
 	}
 }
 
+// MakeChoiceN splits allWords into n runs of nearly equal length, adjusting obscureIdx for each.
+func (w *Words) MakeChoiceN(n int) []PossibleLines {
+	checkChoiceN(n)
+	if w.MaxPossibilities() <= 1 {
+		panic("Cannot call MakeChoiceN on entity with 1 or less options")
+	}
+
+	var parts []PossibleLines
+	for _, b := range chunks(len(w.allWords), n) {
+		numPreferred := min(max(w.obscureIdx-b[0], 0), b[1]-b[0])
+		parts = append(parts, MakeWords(w.allWords[b[0]:b[1]], numPreferred, w.NumLetters()))
+	}
+	return parts
+}
+
 func arrayStr(arr []string) string {
 	const maxPrint = 3
 
@@ -359,10 +706,41 @@ func arrayStr(arr []string) string {
 	return fmt.Sprintf("[%s, ...%d]", strings.Join(print, ", "), len(rest))
 }
 
+// Clone returns a copy of w sharing its words, which are never modified.
+func (w *Words) Clone() PossibleLines {
+	c := &Words{allWords: w.allWords, obscureIdx: w.obscureIdx}
+	c.letterMasks.copyFrom(&w.letterMasks)
+	return c
+}
+
 func (w *Words) String() string {
 	return fmt.Sprintf("Words(%s, %s)", arrayStr(w.allWords[0:w.obscureIdx]), arrayStr(w.allWords[w.obscureIdx:]))
 }
 
+// DebugString returns String followed by the LetterFrequency of each index on its own line, with
+// the most frequent letters first, e.g.
+//
+//	Words([cat, cot], [car])
+//	  0: c=3
+//	  1: a=2 o=1
+//	  2: t=2 r=1
+func (w *Words) DebugString() string {
+	var b strings.Builder
+	b.WriteString(w.String())
+	for index := range w.NumLetters() {
+		frequency := w.LetterFrequency(index)
+		letters := slices.Collect(maps.Keys(frequency))
+		slices.SortFunc(letters, func(x, y rune) int {
+			return cmp.Or(cmp.Compare(frequency[y], frequency[x]), cmp.Compare(x, y))
+		})
+		fmt.Fprintf(&b, "\n  %d:", index)
+		for _, r := range letters {
+			fmt.Fprintf(&b, " %c=%d", r, frequency[r])
+		}
+	}
+	return b.String()
+}
+
 // BlockBefore represents a line that has a blocked cell at the beginning.
 type BlockBefore struct {
 	lines PossibleLines
@@ -383,6 +761,14 @@ func (b *BlockBefore) MaxPossibilities() int64 {
 	return b.lines.MaxPossibilities()
 }
 
+func (b *BlockBefore) Count() int64 {
+	return b.lines.Count()
+}
+
+func (b *BlockBefore) CountAtMost(limit int64) int64 {
+	return b.lines.CountAtMost(limit)
+}
+
 func (b *BlockBefore) CharsAt(accumulate *CharSet, index int) {
 	if accumulate.IsFull() {
 		return
@@ -429,6 +815,14 @@ func (b *BlockBefore) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return b.build(b.lines.FilterAny(constraint, index-1))
 }
 
+func (b *BlockBefore) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	if !allowBlockAt(constraints, 0) {
+		return MakeImpossible(b.NumLetters())
+	}
+	_, inner := splitConstraints(constraints, 1)
+	return b.build(b.lines.FilterMulti(inner))
+}
+
 func (b *BlockBefore) Filter(constraint rune, index int) PossibleLines {
 	if index == 0 {
 		if constraint == kBlocked {
@@ -443,6 +837,14 @@ func (b *BlockBefore) RemoveWordOptions(words []string) PossibleLines {
 	return b.build(b.lines.RemoveWordOptions(words))
 }
 
+func (b *BlockBefore) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return b.build(b.lines.RemoveWordSet(excluded))
+}
+
+func (b *BlockBefore) Contains(line []rune) bool {
+	return len(line) == b.NumLetters() && line[0] == kBlocked && b.lines.Contains(line[1:])
+}
+
 func (b *BlockBefore) FirstOrNull() *ConcreteLine {
 	c := b.lines.FirstOrNull()
 	if c == nil {
@@ -451,6 +853,14 @@ func (b *BlockBefore) FirstOrNull() *ConcreteLine {
 	return &ConcreteLine{Line: append([]rune{kBlocked}, c.Line...), Words: c.Words}
 }
 
+func (b *BlockBefore) Sample(r *rand.Rand) *ConcreteLine {
+	c := b.lines.Sample(r)
+	if c == nil {
+		return nil
+	}
+	return &ConcreteLine{Line: append([]rune{kBlocked}, c.Line...), Words: c.Words}
+}
+
 func (b *BlockBefore) MakeChoice() ChoiceStep {
 	c := b.lines.MakeChoice()
 	return ChoiceStep{
@@ -459,6 +869,14 @@ func (b *BlockBefore) MakeChoice() ChoiceStep {
 	}
 }
 
+func (b *BlockBefore) MakeChoiceN(n int) []PossibleLines {
+	parts := b.lines.MakeChoiceN(n)
+	for i, p := range parts {
+		parts[i] = &BlockBefore{lines: p}
+	}
+	return parts
+}
+
 func (b *BlockBefore) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {
 		for line := range b.lines.Iterate() {
@@ -469,6 +887,10 @@ func (b *BlockBefore) Iterate() iter.Seq[ConcreteLine] {
 	}
 }
 
+func (b *BlockBefore) Clone() PossibleLines {
+	return &BlockBefore{lines: b.lines.Clone()}
+}
+
 func (b *BlockBefore) String() string {
 	return fmt.Sprintf("BlockBefore(%s)", b.lines.String())
 }
@@ -493,6 +915,14 @@ func (b *BlockAfter) MaxPossibilities() int64 {
 	return b.lines.MaxPossibilities()
 }
 
+func (b *BlockAfter) Count() int64 {
+	return b.lines.Count()
+}
+
+func (b *BlockAfter) CountAtMost(limit int64) int64 {
+	return b.lines.CountAtMost(limit)
+}
+
 func (b *BlockAfter) CharsAt(accumulate *CharSet, index int) {
 	if accumulate.IsFull() {
 		return
@@ -539,6 +969,14 @@ func (b *BlockAfter) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return b.build(b.lines.FilterAny(constraint, index))
 }
 
+func (b *BlockAfter) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	if !allowBlockAt(constraints, b.lines.NumLetters()) {
+		return MakeImpossible(b.NumLetters())
+	}
+	inner, _ := splitConstraints(constraints, b.lines.NumLetters())
+	return b.build(b.lines.FilterMulti(inner))
+}
+
 func (b *BlockAfter) Filter(constraint rune, index int) PossibleLines {
 	if index == b.lines.NumLetters() {
 		if constraint == kBlocked {
@@ -553,18 +991,35 @@ func (b *BlockAfter) RemoveWordOptions(words []string) PossibleLines {
 	return b.build(b.lines.RemoveWordOptions(words))
 }
 
+func (b *BlockAfter) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return b.build(b.lines.RemoveWordSet(excluded))
+}
+
+func (b *BlockAfter) Contains(line []rune) bool {
+	n := b.lines.NumLetters()
+	return len(line) == n+1 && line[n] == kBlocked && b.lines.Contains(line[:n])
+}
+
 func (b *BlockAfter) FirstOrNull() *ConcreteLine {
 	c := b.lines.FirstOrNull()
 	if c == nil {
 		return nil
 	}
-	return &ConcreteLine{Line: append(c.Line, kBlocked), Words: c.Words}
+	return &ConcreteLine{Line: slices.Concat(c.Line, []rune{kBlocked}), Words: c.Words}
+}
+
+func (b *BlockAfter) Sample(r *rand.Rand) *ConcreteLine {
+	c := b.lines.Sample(r)
+	if c == nil {
+		return nil
+	}
+	return &ConcreteLine{Line: slices.Concat(c.Line, []rune{kBlocked}), Words: c.Words}
 }
 
 func (b *BlockAfter) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {
 		for line := range b.lines.Iterate() {
-			if !yield(ConcreteLine{Line: append(line.Line, kBlocked), Words: line.Words}) {
+			if !yield(ConcreteLine{Line: slices.Concat(line.Line, []rune{kBlocked}), Words: line.Words}) {
 				return
 			}
 		}
@@ -579,6 +1034,18 @@ func (b *BlockAfter) MakeChoice() ChoiceStep {
 	}
 }
 
+func (b *BlockAfter) MakeChoiceN(n int) []PossibleLines {
+	parts := b.lines.MakeChoiceN(n)
+	for i, p := range parts {
+		parts[i] = &BlockAfter{lines: p}
+	}
+	return parts
+}
+
+func (b *BlockAfter) Clone() PossibleLines {
+	return &BlockAfter{lines: b.lines.Clone()}
+}
+
 func (b *BlockAfter) String() string {
 	return fmt.Sprintf("BlockAfter(%s)", b.lines.String())
 }
@@ -604,6 +1071,43 @@ func (b *BlockBetween) MaxPossibilities() int64 {
 	return b.first.MaxPossibilities() * b.second.MaxPossibilities()
 }
 
+func (b *BlockBetween) Count() int64 {
+	return b.CountAtMost(math.MaxInt64)
+}
+
+func (b *BlockBetween) CountAtMost(limit int64) int64 {
+	return productAtMost(b.first, b.second, limit)
+}
+
+// over returns a number greater than limit, or math.MaxInt64 if there is none.
+func over(limit int64) int64 {
+	if limit == math.MaxInt64 {
+		return limit
+	}
+	return limit + 1
+}
+
+// productAtMost returns the number of pairs of a line of first and a line of second if it is at
+// most limit, or some number greater than limit otherwise, as for CountAtMost.
+func productAtMost(first, second PossibleLines, limit int64) int64 {
+	a := first.CountAtMost(limit)
+	if a == 0 {
+		return 0
+	}
+	if a > limit {
+		// Any line of second makes more than limit pairs.
+		if second.CountAtMost(0) == 0 {
+			return 0
+		}
+		return over(limit)
+	}
+	b := second.CountAtMost(limit / a)
+	if b > limit/a {
+		return over(limit)
+	}
+	return a * b
+}
+
 func (b *BlockBetween) CharsAt(accumulate *CharSet, index int) {
 	if accumulate.IsFull() {
 		return
@@ -638,7 +1142,7 @@ func (b *BlockBetween) build(first, second PossibleLines) PossibleLines {
 }
 
 func (b *BlockBetween) DefiniteWords() []string {
-	return append(b.first.DefiniteWords(), b.second.DefiniteWords()...)
+	return slices.Concat(b.first.DefiniteWords(), b.second.DefiniteWords())
 }
 
 func (b *BlockBetween) FilterAny(constraint *CharSet, index int) PossibleLines {
@@ -664,6 +1168,16 @@ func (b *BlockBetween) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return b.build(f, s)
 }
 
+func (b *BlockBetween) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	n := b.first.NumLetters()
+	if !allowBlockAt(constraints, n) {
+		return MakeImpossible(b.NumLetters())
+	}
+	first, rest := splitConstraints(constraints, n)
+	_, second := splitConstraints(rest, 1)
+	return b.build(b.first.FilterMulti(first), b.second.FilterMulti(second))
+}
+
 func (b *BlockBetween) Filter(constraint rune, index int) PossibleLines {
 	if index == b.first.NumLetters() {
 		if constraint == kBlocked {
@@ -687,13 +1201,32 @@ func (b *BlockBetween) RemoveWordOptions(words []string) PossibleLines {
 	return b.build(b.first.RemoveWordOptions(words), b.second.RemoveWordOptions(words))
 }
 
+func (b *BlockBetween) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return b.build(b.first.RemoveWordSet(excluded), b.second.RemoveWordSet(excluded))
+}
+
+func (b *BlockBetween) Contains(line []rune) bool {
+	n := b.first.NumLetters()
+	return len(line) == b.NumLetters() && line[n] == kBlocked && b.first.Contains(line[:n]) && b.second.Contains(line[n+1:])
+}
+
 func (b *BlockBetween) FirstOrNull() *ConcreteLine {
 	f := b.first.FirstOrNull()
 	s := b.second.FirstOrNull()
 	if f == nil || s == nil {
 		return nil
 	}
-	return &ConcreteLine{Line: append(append(f.Line, kBlocked), s.Line...), Words: append(f.Words, s.Words...)}
+	return &ConcreteLine{Line: slices.Concat(f.Line, []rune{kBlocked}, s.Line), Words: slices.Concat(f.Words, s.Words)}
+}
+
+// Sample samples each side independently, which is uniform since every pair of lines is a line.
+func (b *BlockBetween) Sample(r *rand.Rand) *ConcreteLine {
+	f := b.first.Sample(r)
+	s := b.second.Sample(r)
+	if f == nil || s == nil {
+		return nil
+	}
+	return &ConcreteLine{Line: slices.Concat(f.Line, []rune{kBlocked}, s.Line), Words: slices.Concat(f.Words, s.Words)}
 }
 
 func (b *BlockBetween) Iterate() iter.Seq[ConcreteLine] {
@@ -701,8 +1234,8 @@ func (b *BlockBetween) Iterate() iter.Seq[ConcreteLine] {
 		for first := range b.first.Iterate() {
 			for second := range b.second.Iterate() {
 				if !yield(ConcreteLine{
-					Line:  append(append(first.Line, kBlocked), second.Line...),
-					Words: append(first.Words, second.Words...),
+					Line:  slices.Concat(first.Line, []rune{kBlocked}, second.Line),
+					Words: slices.Concat(first.Words, second.Words),
 				}) {
 					return
 				}
@@ -727,10 +1260,201 @@ func (b *BlockBetween) MakeChoice() ChoiceStep {
 	}
 }
 
+// MakeChoiceN splits whichever of first and second has more possibilities, like MakeChoice.
+func (b *BlockBetween) MakeChoiceN(n int) []PossibleLines {
+	if b.first.MaxPossibilities() > b.second.MaxPossibilities() {
+		parts := b.first.MakeChoiceN(n)
+		for i, p := range parts {
+			parts[i] = &BlockBetween{first: p, second: b.second}
+		}
+		return parts
+	}
+
+	parts := b.second.MakeChoiceN(n)
+	for i, p := range parts {
+		parts[i] = &BlockBetween{first: b.first, second: p}
+	}
+	return parts
+}
+
+func (b *BlockBetween) Clone() PossibleLines {
+	return &BlockBetween{first: b.first.Clone(), second: b.second.Clone()}
+}
+
 func (b *BlockBetween) String() string {
 	return fmt.Sprintf("BlockBetween(%s, %s)", b.first.String(), b.second.String())
 }
 
+// Concat represents the lines made of a line of first directly followed by a line of second, with
+// no blocked cell between them, e.g. the entries of a row of a barred grid.
+type Concat struct {
+	first  PossibleLines
+	second PossibleLines
+}
+
+func MakeConcat(first, second PossibleLines) PossibleLines {
+	if isImpossible(first) || isImpossible(second) {
+		return MakeImpossible(first.NumLetters() + second.NumLetters())
+	}
+	return &Concat{first: first, second: second}
+}
+
+func (c *Concat) NumLetters() int {
+	return c.first.NumLetters() + c.second.NumLetters()
+}
+
+func (c *Concat) MaxPossibilities() int64 {
+	return c.first.MaxPossibilities() * c.second.MaxPossibilities()
+}
+
+func (c *Concat) Count() int64 {
+	return c.CountAtMost(math.MaxInt64)
+}
+
+func (c *Concat) CountAtMost(limit int64) int64 {
+	return productAtMost(c.first, c.second, limit)
+}
+
+func (c *Concat) CharsAt(accumulate *CharSet, index int) {
+	if accumulate.IsFull() {
+		return
+	}
+	if index < c.first.NumLetters() {
+		c.first.CharsAt(accumulate, index)
+	} else {
+		c.second.CharsAt(accumulate, index-c.first.NumLetters())
+	}
+}
+
+func (c *Concat) DefinitelyBlockedAt(index int) bool {
+	if index < c.first.NumLetters() {
+		return c.first.DefinitelyBlockedAt(index)
+	}
+	return c.second.DefinitelyBlockedAt(index - c.first.NumLetters())
+}
+
+func (c *Concat) build(first, second PossibleLines) PossibleLines {
+	if isImpossible(first) || isImpossible(second) {
+		return MakeImpossible(c.NumLetters())
+	}
+	if first == c.first && second == c.second {
+		return c
+	}
+	return &Concat{first: first, second: second}
+}
+
+func (c *Concat) DefiniteWords() []string {
+	return slices.Concat(c.first.DefiniteWords(), c.second.DefiniteWords())
+}
+
+func (c *Concat) FilterAny(constraint *CharSet, index int) PossibleLines {
+	if constraint.IsFull() {
+		return c
+	}
+	if index < c.first.NumLetters() {
+		return c.build(c.first.FilterAny(constraint, index), c.second)
+	}
+	return c.build(c.first, c.second.FilterAny(constraint, index-c.first.NumLetters()))
+}
+
+func (c *Concat) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	first, second := splitConstraints(constraints, c.first.NumLetters())
+	return c.build(c.first.FilterMulti(first), c.second.FilterMulti(second))
+}
+
+func (c *Concat) Filter(constraint rune, index int) PossibleLines {
+	if index < c.first.NumLetters() {
+		return c.build(c.first.Filter(constraint, index), c.second)
+	}
+	return c.build(c.first, c.second.Filter(constraint, index-c.first.NumLetters()))
+}
+
+func (c *Concat) RemoveWordOptions(words []string) PossibleLines {
+	return c.build(c.first.RemoveWordOptions(words), c.second.RemoveWordOptions(words))
+}
+
+func (c *Concat) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	return c.build(c.first.RemoveWordSet(excluded), c.second.RemoveWordSet(excluded))
+}
+
+func (c *Concat) Contains(line []rune) bool {
+	n := c.first.NumLetters()
+	return len(line) == c.NumLetters() && c.first.Contains(line[:n]) && c.second.Contains(line[n:])
+}
+
+func (c *Concat) FirstOrNull() *ConcreteLine {
+	f := c.first.FirstOrNull()
+	s := c.second.FirstOrNull()
+	if f == nil || s == nil {
+		return nil
+	}
+	return &ConcreteLine{Line: slices.Concat(f.Line, s.Line), Words: slices.Concat(f.Words, s.Words)}
+}
+
+func (c *Concat) Sample(r *rand.Rand) *ConcreteLine {
+	f := c.first.Sample(r)
+	s := c.second.Sample(r)
+	if f == nil || s == nil {
+		return nil
+	}
+	return &ConcreteLine{Line: slices.Concat(f.Line, s.Line), Words: slices.Concat(f.Words, s.Words)}
+}
+
+func (c *Concat) Iterate() iter.Seq[ConcreteLine] {
+	return func(yield func(ConcreteLine) bool) {
+		for first := range c.first.Iterate() {
+			for second := range c.second.Iterate() {
+				if !yield(ConcreteLine{
+					Line:  slices.Concat(first.Line, second.Line),
+					Words: slices.Concat(first.Words, second.Words),
+				}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Concat) MakeChoice() ChoiceStep {
+	if c.first.MaxPossibilities() > c.second.MaxPossibilities() {
+		firstChoice := c.first.MakeChoice()
+		return ChoiceStep{
+			Choice:    &Concat{first: firstChoice.Choice, second: c.second},
+			Remaining: &Concat{first: firstChoice.Remaining, second: c.second},
+		}
+	}
+
+	secondChoice := c.second.MakeChoice()
+	return ChoiceStep{
+		Choice:    &Concat{first: c.first, second: secondChoice.Choice},
+		Remaining: &Concat{first: c.first, second: secondChoice.Remaining},
+	}
+}
+
+func (c *Concat) MakeChoiceN(n int) []PossibleLines {
+	if c.first.MaxPossibilities() > c.second.MaxPossibilities() {
+		parts := c.first.MakeChoiceN(n)
+		for i, p := range parts {
+			parts[i] = &Concat{first: p, second: c.second}
+		}
+		return parts
+	}
+
+	parts := c.second.MakeChoiceN(n)
+	for i, p := range parts {
+		parts[i] = &Concat{first: c.first, second: p}
+	}
+	return parts
+}
+
+func (c *Concat) Clone() PossibleLines {
+	return &Concat{first: c.first.Clone(), second: c.second.Clone()}
+}
+
+func (c *Concat) String() string {
+	return fmt.Sprintf("Concat(%s, %s)", c.first.String(), c.second.String())
+}
+
 // Compound represents a set of possible lines that are the union of the given sets.
 type Compound struct {
 	possibilities []PossibleLines
@@ -780,6 +1504,45 @@ func MakeCompound(possibilities []PossibleLines, numLetters int) PossibleLines {
 		return MakeCompound(filtered, numLetters)
 	}
 
+	c := &Compound{possibilities: possibilities}
+	if len(possibilities) > compoundSortThreshold {
+		return c.SortBySize()
+	}
+	return c
+}
+
+// compoundSortThreshold is the number of possibilities above which MakeCompound orders them with
+// SortBySize. The lines built for a grid have fewer, one per position of the first block, and keep
+// the order they were given in, which tries lines with fewer blocks first.
+const compoundSortThreshold = 64
+
+// SortBySize returns a Compound of the same possibilities as c ordered by increasing
+// MaxPossibilities, so that the cheapest ones are visited first, or c itself if they already are.
+// Possibilities of the same size keep their order. Since lines are iterated in the order of the
+// possibilities, those of the smallest come first.
+//
+// Filter and FilterAny visit every possibility whatever their order, so sorting does not make them
+// cheaper by itself: see BenchmarkCompound_SortBySize.
+func (c *Compound) SortBySize() *Compound {
+	type sized struct {
+		lines PossibleLines
+		size  int64
+	}
+	bySize := make([]sized, len(c.possibilities))
+	for i, p := range c.possibilities {
+		bySize[i] = sized{p, p.MaxPossibilities()}
+	}
+	compare := func(a, b sized) int {
+		return cmp.Compare(a.size, b.size)
+	}
+	if slices.IsSortedFunc(bySize, compare) {
+		return c
+	}
+	slices.SortStableFunc(bySize, compare)
+	possibilities := make([]PossibleLines, len(bySize))
+	for i, s := range bySize {
+		possibilities[i] = s.lines
+	}
 	return &Compound{possibilities: possibilities}
 }
 
@@ -795,6 +1558,83 @@ func (c *Compound) MaxPossibilities() int64 {
 	return sum
 }
 
+func (c *Compound) Count() int64 {
+	return c.CountAtMost(math.MaxInt64)
+}
+
+func (c *Compound) CountAtMost(limit int64) int64 {
+	disjoint := c.disjointPossibilities()
+	var count int64
+	var overlapping []PossibleLines
+	for i, p := range c.possibilities {
+		if !disjoint[i] {
+			overlapping = append(overlapping, p)
+			continue
+		}
+		n := p.CountAtMost(limit - count)
+		if n > limit-count {
+			return over(limit)
+		}
+		count += n
+	}
+
+	// The lines of possibilities that may share lines with others are listed to count each once.
+	seen := make(map[string]bool)
+	for _, p := range overlapping {
+		for line := range p.Iterate() {
+			key := string(line.Line)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if count++; count > limit {
+				return count
+			}
+		}
+	}
+	return count
+}
+
+// disjointPossibilities returns whether each possibility of c shares no line with any other, as
+// when the possibilities differ in where their first blocked cell is. It only compares the
+// characters each can have at each index, so possibilities reported as overlapping may not.
+func (c *Compound) disjointPossibilities() []bool {
+	numLetters := c.NumLetters()
+	chars := make([][]CharSet, len(c.possibilities))
+	for i, p := range c.possibilities {
+		chars[i] = make([]CharSet, numLetters)
+		for index := range numLetters {
+			p.CharsAt(&chars[i][index], index)
+			// CharsAt may stop adding once every letter is in the set, which must not hide that
+			// the cell can also be blocked.
+			if set := &chars[i][index]; set.Count()+1 == set.Capacity() {
+				set.Add(kBlocked)
+			}
+		}
+	}
+	// disjoint returns true if no index allows a character of both i and j.
+	disjoint := func(i, j int) bool {
+		for index := range numLetters {
+			if !chars[i][index].Intersects(chars[j][index]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]bool, len(c.possibilities))
+	for i := range c.possibilities {
+		result[i] = true
+		for j := range c.possibilities {
+			if i != j && !disjoint(i, j) {
+				result[i] = false
+				break
+			}
+		}
+	}
+	return result
+}
+
 func (c *Compound) CharsAt(accumulate *CharSet, index int) {
 	for _, p := range c.possibilities {
 		p.CharsAt(accumulate, index)
@@ -859,6 +1699,30 @@ func (c *Compound) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return MakeCompound(filtered, c.NumLetters())
 }
 
+func (c *Compound) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	anyChanged := false
+	var filtered []PossibleLines
+	for i, p := range c.possibilities {
+		f := p.FilterMulti(constraints)
+		if f == p && !anyChanged {
+			continue
+		}
+		if f != p && !anyChanged {
+			anyChanged = true
+			filtered = slices.Clone(c.possibilities[:i])
+		}
+		if !isImpossible(f) {
+			filtered = append(filtered, f)
+		}
+	}
+
+	if !anyChanged {
+		return c
+	}
+
+	return MakeCompound(filtered, c.NumLetters())
+}
+
 func (c *Compound) Filter(constraint rune, index int) PossibleLines {
 	var filtered []PossibleLines
 	anyChangeInSubParts := false
@@ -905,9 +1769,7 @@ func (c *Compound) RemoveWordOptions(words []string) PossibleLines {
 		if f != p && !anyChanged {
 			// We are the first to change.
 			anyChanged = true
-			if i > 0 {
-				maybeFiltered = c.possibilities[:i]
-			}
+			maybeFiltered = slices.Clone(c.possibilities[:i])
 		}
 
 		if !isImpossible(f) {
@@ -922,6 +1784,36 @@ func (c *Compound) RemoveWordOptions(words []string) PossibleLines {
 	return MakeCompound(maybeFiltered, c.NumLetters())
 }
 
+func (c *Compound) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	anyChanged := false
+	var maybeFiltered []PossibleLines
+	for i, p := range c.possibilities {
+		f := p.RemoveWordSet(excluded)
+		if f == p && !anyChanged {
+			continue
+		}
+		if f != p && !anyChanged {
+			anyChanged = true
+			maybeFiltered = slices.Clone(c.possibilities[:i])
+		}
+		if !isImpossible(f) {
+			maybeFiltered = append(maybeFiltered, f)
+		}
+	}
+
+	if !anyChanged {
+		return c
+	}
+
+	return MakeCompound(maybeFiltered, c.NumLetters())
+}
+
+func (c *Compound) Contains(line []rune) bool {
+	return slices.ContainsFunc(c.possibilities, func(p PossibleLines) bool {
+		return p.Contains(line)
+	})
+}
+
 func (c *Compound) FirstOrNull() *ConcreteLine {
 	for _, p := range c.possibilities {
 		if f := p.FirstOrNull(); f != nil {
@@ -931,6 +1823,23 @@ func (c *Compound) FirstOrNull() *ConcreteLine {
 	return nil
 }
 
+// Sample picks a possibility weighted by MaxPossibilities, the number of lines Iterate yields from
+// it, and samples a line from it.
+func (c *Compound) Sample(r *rand.Rand) *ConcreteLine {
+	total := c.MaxPossibilities()
+	if total == 0 {
+		return nil
+	}
+	n := r.Int64N(total)
+	for _, p := range c.possibilities {
+		if n < p.MaxPossibilities() {
+			return p.Sample(r)
+		}
+		n -= p.MaxPossibilities()
+	}
+	return nil
+}
+
 func (c *Compound) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {
 		for _, p := range c.possibilities {
@@ -975,6 +1884,78 @@ func (c *Compound) MakeChoice() ChoiceStep {
 	}
 }
 
+// MakeChoiceN partitions the possibilities into at most n contiguous groups, each ending once the
+// groups so far hold their share of the MaxPossibilities of c.
+func (c *Compound) MakeChoiceN(n int) []PossibleLines {
+	checkChoiceN(n)
+	if len(c.possibilities) <= 1 {
+		panic("BUG: Whenever this was created, it should have already been reduced to returning c.possibilities[1] alone")
+	}
+	if c.MaxPossibilities() <= 1 {
+		panic("Cannot make a choice if MaxPossibilities <= 1")
+	}
+
+	groups := min(n, len(c.possibilities))
+	total := int64(0)
+	for _, p := range c.possibilities {
+		total += p.MaxPossibilities()
+	}
+	var parts []PossibleLines
+	start, acc := 0, int64(0)
+	for i, p := range c.possibilities[:len(c.possibilities)-1] {
+		acc += p.MaxPossibilities()
+		remaining := groups - 1 - len(parts)
+		if remaining == 0 {
+			break
+		}
+		// Each later group needs at least one possibility of its own.
+		if acc >= total/int64(groups)*int64(len(parts)+1) || len(c.possibilities)-(i+1) == remaining {
+			parts = append(parts, MakeCompound(c.possibilities[start:i+1], c.NumLetters()))
+			start = i + 1
+		}
+	}
+	return append(parts, MakeCompound(c.possibilities[start:], c.NumLetters()))
+}
+
+// Deduplicate returns c without the children that repeat an earlier child, so that their lines are
+// not counted or searched twice, or c itself if no child repeats.
+//
+// It is a conservative approximation: children repeat if they are the same lines, or Definite
+// lines with the same cells, so some children holding equal lines may remain. String is not used
+// to compare them, since it elides all but a few words, so equal Strings do not imply equal lines.
+func (c *Compound) Deduplicate() PossibleLines {
+	seen := make(map[PossibleLines]bool, len(c.possibilities))
+	seenDefinite := make(map[string]bool)
+	var kept []PossibleLines
+	for i, p := range c.possibilities {
+		repeated := seen[p]
+		if d, ok := p.(*Definite); ok {
+			repeated = seenDefinite[string(d.line.Line)]
+			seenDefinite[string(d.line.Line)] = true
+		}
+		seen[p] = true
+		if repeated && kept == nil {
+			kept = slices.Clone(c.possibilities[:i])
+		}
+		if !repeated && kept != nil {
+			kept = append(kept, p)
+		}
+	}
+
+	if kept == nil {
+		return c
+	}
+	return MakeCompound(kept, c.NumLetters())
+}
+
+func (c *Compound) Clone() PossibleLines {
+	possibilities := make([]PossibleLines, len(c.possibilities))
+	for i, p := range c.possibilities {
+		possibilities[i] = p.Clone()
+	}
+	return &Compound{possibilities: possibilities}
+}
+
 func (c *Compound) String() string {
 	return fmt.Sprintf("Compound(%v and %d others)", c.possibilities[0], len(c.possibilities)-1)
 }
@@ -996,6 +1977,14 @@ func (d *Definite) MaxPossibilities() int64 {
 	return 1
 }
 
+func (d *Definite) Count() int64 {
+	return 1
+}
+
+func (d *Definite) CountAtMost(limit int64) int64 {
+	return 1
+}
+
 func (d *Definite) CharsAt(accumulate *CharSet, index int) {
 	accumulate.Add(rune(d.line.Line[index]))
 }
@@ -1019,6 +2008,15 @@ func (d *Definite) FilterAny(constraint *CharSet, index int) PossibleLines {
 	return MakeImpossible(d.NumLetters())
 }
 
+func (d *Definite) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	for _, c := range constraints {
+		if !c.Chars.Contains(d.line.Line[c.Index]) {
+			return MakeImpossible(d.NumLetters())
+		}
+	}
+	return d
+}
+
 func (d *Definite) Filter(constraint rune, index int) PossibleLines {
 	if constraint == rune(d.line.Line[index]) {
 		return d
@@ -1038,6 +2036,20 @@ func (d *Definite) RemoveWordOptions(words []string) PossibleLines {
 	return d
 }
 
+func (d *Definite) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	// As for RemoveWordOptions, only a word filling the whole line removes it.
+	for _, word := range d.line.Words {
+		if _, ok := excluded[word]; ok && len(word) == d.NumLetters() {
+			return MakeImpossible(d.NumLetters())
+		}
+	}
+	return d
+}
+
+func (d *Definite) Contains(line []rune) bool {
+	return slices.Equal(d.line.Line, line)
+}
+
 func (d *Definite) Iterate() iter.Seq[ConcreteLine] {
 	return func(yield func(ConcreteLine) bool) {
 		yield(d.line)
@@ -1048,10 +2060,40 @@ func (d *Definite) FirstOrNull() *ConcreteLine {
 	return &d.line
 }
 
+func (d *Definite) Sample(r *rand.Rand) *ConcreteLine {
+	return &d.line
+}
+
 func (d *Definite) MakeChoice() ChoiceStep {
 	panic("Cannot make a choice on a definite line")
 }
 
+func (d *Definite) MakeChoiceN(n int) []PossibleLines {
+	panic("Cannot make a choice on a definite line")
+}
+
+func (d *Definite) Clone() PossibleLines {
+	return &Definite{line: ConcreteLine{Line: slices.Clone(d.line.Line), Words: slices.Clone(d.line.Words)}}
+}
+
 func (d *Definite) String() string {
 	return fmt.Sprintf("Definite(%s)", string(d.line.Line))
 }
+
+// DefiniteToWords returns the possible lines filled with the word of d or any of alternatives, e.g.
+// to try variations of a word that filtering narrowed a line down to. The word of d is first, and
+// all of them are preferred. Alternatives that repeat a word are kept once.
+//
+// It panics if d is not a single word filling its line, or if an alternative has a different
+// length.
+func DefiniteToWords(d *Definite, alternatives []string) PossibleLines {
+	if len(d.line.Words) != 1 || len(d.line.Words[0]) != d.NumLetters() {
+		panic(fmt.Sprintf("DefiniteToWords: %s is not a single word", d))
+	}
+	for _, word := range alternatives {
+		if len(word) != d.NumLetters() {
+			panic(fmt.Sprintf("DefiniteToWords: alternative %q has %d letters, want %d", word, len(word), d.NumLetters()))
+		}
+	}
+	return MakeWordsFromPreferredAndObscure(slices.Concat(d.line.Words, alternatives), nil, d.NumLetters())
+}
@@ -1,8 +1,15 @@
 package primitives
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"reflect"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -101,6 +108,54 @@ func TestImpossible(t *testing.T) {
 			t.Error("Expected MakeImpossible to return different instance for different length")
 		}
 	})
+
+	t.Run("Lengths", func(t *testing.T) {
+		for _, n := range []int{0, 1, 21, 25, 63, 64, 100} {
+			if got := MakeImpossible(n).NumLetters(); got != n {
+				t.Errorf("MakeImpossible(%d).NumLetters() = %d", n, got)
+			}
+		}
+		if MakeImpossible(0) != MakeImpossible(0) {
+			t.Error("Expected MakeImpossible(0) to return cached instance")
+		}
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected MakeImpossible(-1) to panic")
+			}
+		}()
+		MakeImpossible(-1)
+	})
+}
+
+func TestMakeWordsFromPreferredAndObscure_Deduplicates(t *testing.T) {
+	preferred := []string{"cat", "dog", "cat"}
+	obscure := []string{"dog", "emu", "emu", "fox"}
+	words := MakeWordsFromPreferredAndObscure(preferred, obscure, 3)
+
+	if diff := cmp.Diff([]string{"cat", "dog", "emu", "fox"}, collectLines(words)); diff != "" {
+		t.Errorf("lines: -want +got %s", diff)
+	}
+	if got := words.MaxPossibilities(); got != 4 {
+		t.Errorf("MaxPossibilities() = %d, want 4", got)
+	}
+	// "dog" is kept as preferred.
+	if got := words.(*Words).obscureIdx; got != 2 {
+		t.Errorf("%d preferred words, want 2", got)
+	}
+	if diff := cmp.Diff([]string{"cat", "dog", "cat"}, preferred); diff != "" {
+		t.Errorf("preferred was modified: -want +got %s", diff)
+	}
+
+	// A word in both lists is a single possibility.
+	if _, ok := MakeWordsFromPreferredAndObscure([]string{"cat"}, []string{"cat"}, 3).(*Definite); !ok {
+		t.Error("a word in both lists is not Definite")
+	}
+
+	// Each occurrence of a word in a longer line yields one concrete line.
+	between := MakeBlockBetween(words, MakeWordsFromPreferredAndObscure([]string{"ox"}, []string{"ox"}, 2))
+	if got := len(collectLines(between)); got != 4 {
+		t.Errorf("got %d lines with a block between, want 4", got)
+	}
 }
 
 func TestWords_FilterAny(t *testing.T) {
@@ -143,6 +198,165 @@ func TestWords_FilterAny(t *testing.T) {
 	}
 }
 
+func TestWords_FilterByPrefix(t *testing.T) {
+	words := MakeWords([]string{"cat", "car", "cot", "cop", "dog"}, 3, 3)
+
+	for _, tc := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"cat", "car", "cot", "cop", "dog"}},
+		{"c", []string{"cat", "car", "cot", "cop"}},
+		{"ca", []string{"cat", "car"}},
+		{"cop", []string{"cop"}},
+		{"x", []string{}},
+		{"ca" + string(Blocked), []string{}},
+		{"cats", []string{}},
+	} {
+		t.Run(tc.prefix, func(t *testing.T) {
+			got := words.(*Words).FilterByPrefix(tc.prefix)
+			if diff := cmp.Diff(tc.want, collectLines(got)); diff != "" {
+				t.Errorf("FilterByPrefix(%q) lines: -want +got %s", tc.prefix, diff)
+			}
+
+			// FilterByPrefix is equivalent to calling Filter for each character.
+			if len(tc.prefix) <= words.NumLetters() {
+				chained := words
+				for i, r := range tc.prefix {
+					chained = chained.Filter(r, i)
+				}
+				if !reflect.DeepEqual(got, chained) {
+					t.Errorf("FilterByPrefix(%q) = %v, want %v", tc.prefix, got, chained)
+				}
+			}
+		})
+	}
+
+	if got := words.(*Words).FilterByPrefix("c"); got.(*Words).obscureIdx != 3 {
+		t.Errorf("FilterByPrefix(\"c\") has %d preferred words, want 3", got.(*Words).obscureIdx)
+	}
+	if got := words.(*Words).FilterByPrefix(""); got != words {
+		t.Error("FilterByPrefix(\"\") returned a new list, want the original")
+	}
+}
+
+// benchmarkWords returns a deterministic list of n distinct words of length 7.
+func benchmarkWords(n int) *Words {
+	words := make([]string, n)
+	for i := range words {
+		word := make([]byte, 7)
+		for j, x := len(word)-1, i*7919; j >= 0; j, x = j-1, x/26 {
+			word[j] = byte('a' + x%26)
+		}
+		words[i] = string(word)
+	}
+	return MakeWords(words, n, 7).(*Words)
+}
+
+func BenchmarkWords_FilterByPrefix(b *testing.B) {
+	words := benchmarkWords(100_000)
+	b.Run("FilterByPrefix", func(b *testing.B) {
+		for b.Loop() {
+			words.FilterByPrefix("ab")
+		}
+	})
+	b.Run("Filter", func(b *testing.B) {
+		for b.Loop() {
+			words.Filter('a', 0).Filter('b', 1)
+		}
+	})
+}
+
+func TestWords_SortByFrequency(t *testing.T) {
+	words := MakeWords([]string{"ant", "bee", "cat", "dog", "eel"}, 3, 3).(*Words)
+	freq := map[string]float64{"cat": 3, "ant": 1, "eel": 5}
+
+	sorted := words.SortByFrequency(freq).(*Words)
+	if diff := cmp.Diff([]string{"cat", "ant", "bee", "eel", "dog"}, sorted.allWords); diff != "" {
+		t.Errorf("sorted words: -want +got %s", diff)
+	}
+	if sorted.obscureIdx != 3 {
+		t.Errorf("%d preferred words, want 3", sorted.obscureIdx)
+	}
+	if diff := cmp.Diff([]string{"ant", "bee", "cat", "dog", "eel"}, words.allWords); diff != "" {
+		t.Errorf("receiver was modified: -want +got %s", diff)
+	}
+
+	// The letters are the same, but computed afresh.
+	var before, after CharSet
+	words.CharsAt(&before, 0)
+	if sorted.letterMasks.len() != 0 {
+		t.Error("sorted words share letter masks")
+	}
+	sorted.CharsAt(&after, 0)
+	if before != after {
+		t.Errorf("CharsAt(0) = %v after sorting, want %v", after.String(), before.String())
+	}
+
+	// The most frequent preferred words are chosen first.
+	if got := collectLines(sorted.MakeChoice().Choice); !slices.Equal(got, []string{"cat", "ant"}) {
+		t.Errorf("MakeChoice().Choice = %q, want the most frequent words", got)
+	}
+}
+
+func TestWords_LetterFrequency(t *testing.T) {
+	words := MakeWords([]string{"cat", "cot", "car", "bat"}, 2, 3).(*Words)
+	for _, tc := range []struct {
+		index int
+		want  map[rune]int
+	}{
+		{index: 0, want: map[rune]int{'c': 3, 'b': 1}},
+		{index: 1, want: map[rune]int{'a': 3, 'o': 1}},
+		{index: 2, want: map[rune]int{'t': 3, 'r': 1}},
+	} {
+		if diff := cmp.Diff(tc.want, words.LetterFrequency(tc.index)); diff != "" {
+			t.Errorf("LetterFrequency(%d): -want +got %s", tc.index, diff)
+		}
+	}
+
+	// Filtering narrows the frequencies down.
+	filtered := words.Filter('a', 1).(*Words)
+	if diff := cmp.Diff(map[rune]int{'c': 2, 'b': 1}, filtered.LetterFrequency(0)); diff != "" {
+		t.Errorf("LetterFrequency(0) after filtering: -want +got %s", diff)
+	}
+
+	want := "Words([cat, cot], [car, bat])\n" +
+		"  0: c=3 b=1\n" +
+		"  1: a=3 o=1\n" +
+		"  2: t=3 r=1"
+	if diff := cmp.Diff(want, words.DebugString()); diff != "" {
+		t.Errorf("DebugString(): -want +got %s", diff)
+	}
+}
+
+// BenchmarkWords_SortByFrequency compares the words chosen by repeatedly taking the first half of
+// a choice, reported as their frequency, with and without sorting the words by frequency first.
+func BenchmarkWords_SortByFrequency(b *testing.B) {
+	words := benchmarkWords(100_000)
+	freq := make(map[string]float64, len(words.allWords))
+	for i, word := range words.allWords {
+		// Frequencies unrelated to the order of the words.
+		freq[word] = float64((i * 7919) % len(words.allWords))
+	}
+	choose := func(b *testing.B, lines PossibleLines) {
+		for lines.MaxPossibilities() > 1 {
+			lines = lines.MakeChoice().Choice
+		}
+		b.ReportMetric(freq[lines.FirstOrNull().Words[0]], "freq/choice")
+	}
+
+	b.Run("Unsorted", func(b *testing.B) {
+		for b.Loop() {
+			choose(b, words)
+		}
+	})
+	b.Run("Sorted", func(b *testing.B) {
+		for b.Loop() {
+			choose(b, words.SortByFrequency(freq))
+		}
+	})
+}
+
 func TestWords(t *testing.T) {
 	// Test MakeWordsFromPreferredAndObscure
 	t.Run("MakeWordsFromPreferredAndObscure", func(t *testing.T) {
@@ -593,14 +807,14 @@ func TestDefinite(t *testing.T) {
 		if count != 1 {
 			t.Errorf("Iterate should yield 1 item, got %d", count)
 		}
-		if string(iteratedLine.Line) != "test" {
-			t.Errorf("Iterated line expected TEST, got %s", string(iteratedLine.Line))
+		if !iteratedLine.Equals(line) {
+			t.Errorf("Iterated line expected %v, got %v", line, iteratedLine)
 		}
 	})
 
 	t.Run("FirstOrNull", func(t *testing.T) {
 		first := definite.FirstOrNull()
-		if first == nil || string(first.Line) != "test" {
+		if first == nil || !first.Equals(line) {
 			t.Errorf("Expected FirstOrNull to be TEST, got %v", first)
 		}
 	})
@@ -621,6 +835,35 @@ func TestDefinite(t *testing.T) {
 	})
 }
 
+func TestDefiniteToWords(t *testing.T) {
+	definite := MakeDefinite(ConcreteLine{Line: []rune("cat"), Words: []string{"cat"}})
+	words := DefiniteToWords(definite, []string{"cot", "cat", "cut"})
+	if diff := cmp.Diff([]string{"cat", "cot", "cut"}, collectLines(words)); diff != "" {
+		t.Errorf("DefiniteToWords() lines: -want +got %s", diff)
+	}
+	if got := DefiniteToWords(definite, nil); !slices.Equal(collectLines(got), []string{"cat"}) {
+		t.Errorf("DefiniteToWords(nil) = %s, want cat", got)
+	}
+
+	for _, tc := range []struct {
+		name         string
+		definite     *Definite
+		alternatives []string
+	}{
+		{"alternative of another length", definite, []string{"cart"}},
+		{"several words", MakeDefinite(ConcreteLine{Line: []rune("ab`cd"), Words: []string{"ab", "cd"}}), []string{"efghi"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("DefiniteToWords() did not panic")
+				}
+			}()
+			DefiniteToWords(tc.definite, tc.alternatives)
+		})
+	}
+}
+
 func TestBlockBefore(t *testing.T) {
 	innerWord := MakeWordsFromPreferredAndObscure([]string{"hi"}, []string{}, 2)
 	bb := MakeBlockBefore(innerWord)
@@ -1119,6 +1362,60 @@ func TestBlockAfter(t *testing.T) {
 	})
 }
 
+func TestConcat(t *testing.T) {
+	c := MakeConcat(MakeWords([]string{"ab", "cd"}, 2, 2), MakeWords([]string{"efg", "hij"}, 2, 3))
+
+	if got := c.NumLetters(); got != 5 {
+		t.Errorf("NumLetters() = %d, want 5", got)
+	}
+	if got := c.MaxPossibilities(); got != 4 {
+		t.Errorf("MaxPossibilities() = %d, want 4", got)
+	}
+	if diff := cmp.Diff([]string{"abefg", "abhij", "cdefg", "cdhij"}, collectLines(c)); diff != "" {
+		t.Errorf("Iterate() -want +got: %s", diff)
+	}
+	var chars CharSet
+	c.CharsAt(&chars, 2)
+	if !chars.Contains('e') || !chars.Contains('h') || chars.Count() != 2 {
+		t.Errorf("CharsAt(2) = %v, want e and h", &chars)
+	}
+	if c.DefinitelyBlockedAt(2) {
+		t.Error("DefinitelyBlockedAt(2) = true, want false")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+		want  []string
+	}{
+		{"Filter first", c.Filter('c', 0), []string{"cdefg", "cdhij"}},
+		{"Filter second", c.Filter('j', 4), []string{"abhij", "cdhij"}},
+		{"Filter impossible", c.Filter('z', 2), []string{}},
+		{"RemoveWordOptions", c.RemoveWordOptions([]string{"ab", "hij"}), []string{"cdefg"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, collectLines(tc.lines)); diff != "" {
+				t.Errorf("lines -want +got: %s", diff)
+			}
+		})
+	}
+
+	line := c.RemoveWordOptions([]string{"ab", "hij"}).FirstOrNull()
+	if want := (ConcreteLine{Line: []rune("cdefg"), Words: []string{"cd", "efg"}}); line == nil || !line.Equals(want) {
+		t.Errorf("FirstOrNull() = %v, want %v", line, want)
+	}
+	if got := MakeConcat(MakeImpossible(2), c); !isActuallyImpossible(got) || got.NumLetters() != 7 {
+		t.Errorf("MakeConcat() with an impossible line = %v, want Impossible(7)", got)
+	}
+
+	choice := c.MakeChoice()
+	got := append(collectLines(choice.Choice), collectLines(choice.Remaining)...)
+	slices.Sort(got)
+	if diff := cmp.Diff(collectLines(c), got); diff != "" {
+		t.Errorf("MakeChoice() lines -want +got: %s", diff)
+	}
+}
+
 func TestBlockBetween(t *testing.T) {
 	firstInner := MakeWordsFromPreferredAndObscure([]string{"ab"}, []string{}, 2)
 	secondInner := MakeWordsFromPreferredAndObscure([]string{"cd"}, []string{}, 2)
@@ -1396,6 +1693,44 @@ func TestBlockBetween(t *testing.T) {
 	})
 }
 
+// TestBlockBetween_NoAliasing checks that the lines yielded by the block types each have their own
+// slices, even when the lines they are made of have room to append to.
+func TestBlockBetween_NoAliasing(t *testing.T) {
+	// first's line and words have spare capacity, so appending to them would write into the same
+	// arrays for every line.
+	first := MakeDefinite(ConcreteLine{
+		Line:  append(make([]rune, 0, 16), []rune("ab")...),
+		Words: append(make([]string, 0, 4), "ab"),
+	})
+	second := MakeWords([]string{"cd", "ef", "gh"}, 3, 2)
+	for _, tc := range []struct {
+		lines PossibleLines
+		want  []string
+	}{
+		{MakeBlockBetween(first, second), []string{"ab`cd", "ab`ef", "ab`gh"}},
+		{MakeBlockBetween(MakeBlockAfter(first), second), []string{"ab``cd", "ab``ef", "ab``gh"}},
+		{MakeCompound([]PossibleLines{MakeBlockAfter(MakeBlockAfter(first)), MakeBlockBefore(MakeBlockBefore(first))}, 4), []string{"ab``", "``ab"}},
+	} {
+		var got []ConcreteLine
+		for line := range tc.lines.Iterate() {
+			got = append(got, line)
+		}
+		got = append(got, *tc.lines.FirstOrNull())
+		want := append(slices.Clone(tc.want), tc.want[0])
+		for i, line := range got {
+			if string(line.Line) != want[i] {
+				t.Errorf("line %d of %s = %q after iterating, want %q", i, tc.lines, string(line.Line), want[i])
+			}
+			if words := strings.FieldsFunc(want[i], func(r rune) bool { return r == kBlocked }); !slices.Equal(line.Words, words) {
+				t.Errorf("words of line %d of %s = %v after iterating, want %v", i, tc.lines, line.Words, words)
+			}
+		}
+	}
+	if got := first.FirstOrNull(); string(got.Line) != "ab" || !slices.Equal(got.Words, []string{"ab"}) {
+		t.Errorf("first = %v after iterating, want ab", got)
+	}
+}
+
 func TestCompound(t *testing.T) {
 	t.Run("MakeCompound", func(t *testing.T) {
 		t.Run("empty possibilities", func(t *testing.T) {
@@ -1712,11 +2047,704 @@ func TestCompound(t *testing.T) {
 	})
 }
 
-// Helper function to collect all lines from a PossibleLines iterator
+func TestCompound_SortBySize(t *testing.T) {
+	words := func(words ...string) PossibleLines {
+		return MakeWords(words, len(words), 2)
+	}
+	big, small, mid, small2 := words("aa", "ab", "ac"), words("ba", "bb"), words("ca", "cb", "cd"), words("da", "db")
+	c := &Compound{possibilities: []PossibleLines{big, small, mid, small2}}
+
+	sorted := c.SortBySize()
+	if want := []PossibleLines{small, small2, big, mid}; !slices.Equal(sorted.possibilities, want) {
+		t.Errorf("SortBySize() = %v, want %v", sorted.possibilities, want)
+	}
+	if !slices.Equal(c.possibilities, []PossibleLines{big, small, mid, small2}) {
+		t.Errorf("SortBySize() modified the original: %v", c.possibilities)
+	}
+	if diff := cmp.Diff([]string{"ba", "bb", "da", "db", "aa", "ab", "ac", "ca", "cb", "cd"}, collectLines(sorted)); diff != "" {
+		t.Errorf("SortBySize() lines: -want +got %s", diff)
+	}
+	if again := sorted.SortBySize(); again != sorted {
+		t.Errorf("SortBySize() of sorted possibilities = %v, want the same Compound", again)
+	}
+
+	t.Run("MakeCompound", func(t *testing.T) {
+		makeCompound := func(n int) *Compound {
+			possibilities := make([]PossibleLines, n)
+			all := benchmarkWords(3 * n).allWords
+			for i := range possibilities {
+				// Sizes 3, 2, 1, 3, 2, 1, ...
+				size := 3 - i%3
+				possibilities[i] = MakeWords(all[3*i:3*i+size], size, 7)
+			}
+			return MakeCompound(possibilities, 7).(*Compound)
+		}
+		bySize := func(a, b PossibleLines) int {
+			return int(a.MaxPossibilities() - b.MaxPossibilities())
+		}
+		if c := makeCompound(compoundSortThreshold); slices.IsSortedFunc(c.possibilities, bySize) {
+			t.Errorf("MakeCompound() sorted %d possibilities, want them in order", compoundSortThreshold)
+		}
+		if c := makeCompound(compoundSortThreshold + 1); !slices.IsSortedFunc(c.possibilities, bySize) {
+			t.Errorf("MakeCompound() kept %d possibilities in order, want them sorted", compoundSortThreshold+1)
+		}
+	})
+}
+
+func TestCompound_Deduplicate(t *testing.T) {
+	words := MakeWords([]string{"ab", "ba"}, 2, 2)
+	for _, tc := range []struct {
+		name          string
+		possibilities []PossibleLines
+		want          []string
+	}{
+		{
+			name:          "same lines",
+			possibilities: []PossibleLines{words, MakeDefinite(ConcreteLine{Line: []rune("aa"), Words: []string{"aa"}}), words},
+			want:          []string{"ab", "ba", "aa"},
+		},
+		{
+			name: "equal definite lines",
+			possibilities: []PossibleLines{
+				MakeDefinite(ConcreteLine{Line: []rune("aa"), Words: []string{"aa"}}),
+				words,
+				MakeDefinite(ConcreteLine{Line: []rune("aa"), Words: []string{"aa"}}),
+			},
+			want: []string{"aa", "ab", "ba"},
+		},
+		{
+			// Both print as Words([aa, ab, ba, ...1], []).
+			name: "same String",
+			possibilities: []PossibleLines{
+				MakeWords([]string{"aa", "ab", "ba", "bb"}, 4, 2),
+				MakeWords([]string{"aa", "ab", "ba", "ca"}, 4, 2),
+			},
+			want: []string{"aa", "ab", "ba", "bb", "aa", "ab", "ba", "ca"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := MakeCompound(tc.possibilities, 2).(*Compound)
+			got := c.Deduplicate()
+			if diff := cmp.Diff(tc.want, collectLines(got)); diff != "" {
+				t.Errorf("Deduplicate() of %s: -want +got %s", c, diff)
+			}
+			if len(tc.want) == len(collectLines(c)) && got != c {
+				t.Errorf("Deduplicate() of %s = %s, want the lines themselves", c, got)
+			}
+		})
+	}
+}
+
+func BenchmarkCompound_SortBySize(b *testing.B) {
+	// 1000 possibilities of 2 to 501 words each.
+	all := benchmarkWords(300_000).allWords
+	possibilities := make([]PossibleLines, 1000)
+	for i := range possibilities {
+		size := 2 + (i*7919)%500
+		possibilities[i] = MakeWords(all[:size], size, 7)
+		all = all[size:]
+	}
+	unsorted := &Compound{possibilities: possibilities}
+	sorted := unsorted.SortBySize()
+	for _, c := range []struct {
+		name     string
+		compound *Compound
+	}{{"unsorted", unsorted}, {"sorted", sorted}} {
+		b.Run(c.name+"/Filter", func(b *testing.B) {
+			for b.Loop() {
+				c.compound.Filter('a', 0)
+			}
+		})
+		b.Run(c.name+"/FilterAny", func(b *testing.B) {
+			var vowels CharSet
+			for _, r := range "aeiou" {
+				vowels.Add(r)
+			}
+			for b.Loop() {
+				c.compound.FilterAny(&vowels, 3)
+			}
+		})
+		b.Run(c.name+"/CharsAt", func(b *testing.B) {
+			for b.Loop() {
+				var chars CharSet
+				c.compound.CharsAt(&chars, 6)
+			}
+		})
+	}
+	b.Run("SortBySize", func(b *testing.B) {
+		for b.Loop() {
+			unsorted.SortBySize()
+		}
+	})
+}
+
+// randomLines returns random lines of n letters, made from words over a two-letter alphabet so
+// that the possibilities of compounds often overlap.
+func randomLines(r *rand.Rand, n, depth int) PossibleLines {
+	words := func() []string {
+		var words []string
+		for range 1 + r.IntN(4) {
+			word := make([]byte, n)
+			for i := range word {
+				word[i] = "ab"[r.IntN(2)]
+			}
+			if !slices.Contains(words, string(word)) {
+				words = append(words, string(word))
+			}
+		}
+		return words
+	}
+	choice := r.IntN(9)
+	if depth == 0 {
+		choice = r.IntN(4)
+	}
+	switch {
+	case choice == 0:
+		w := words()
+		return MakeWordsFromPreferredAndObscure(w[:len(w)/2], w[len(w)/2:], n)
+	case choice == 1:
+		w := words()
+		return MakeSortedWords(w, r.IntN(len(w)+1))
+	case choice == 2:
+		w := words()
+		return MakeTrieWords(w[:len(w)/2], w[len(w)/2:], n)
+	case choice == 3:
+		word := words()[0]
+		return MakeDefinite(ConcreteLine{Line: []rune(word), Words: []string{word}})
+	case choice == 4 && n > 1:
+		return MakeBlockBefore(randomLines(r, n-1, depth-1))
+	case choice == 5 && n > 1:
+		return MakeBlockAfter(randomLines(r, n-1, depth-1))
+	case choice == 6 && n > 2:
+		first := 1 + r.IntN(n-2)
+		return MakeBlockBetween(randomLines(r, first, depth-1), randomLines(r, n-first-1, depth-1))
+	case choice == 7 && n > 1:
+		first := 1 + r.IntN(n-1)
+		return MakeConcat(randomLines(r, first, depth-1), randomLines(r, n-first, depth-1))
+	case choice == 8:
+		var possibilities []PossibleLines
+		for range 2 + r.IntN(3) {
+			possibilities = append(possibilities, randomLines(r, n, depth-1))
+		}
+		return MakeCompound(possibilities, n)
+	}
+	return MemoizedFilter(randomLines(r, n, depth-1))
+}
+
+func TestCount(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	overlapping := 0
+	for range 2000 {
+		lines := randomLines(r, 1+r.IntN(5), 4)
+		distinct := make(map[string]bool)
+		for line := range lines.Iterate() {
+			distinct[string(line.Line)] = true
+		}
+		want := int64(len(distinct))
+
+		if got := lines.Count(); got != want {
+			t.Fatalf("Count() of %s = %d, want %d", lines, got, want)
+		}
+		if max := lines.MaxPossibilities(); max < want {
+			t.Errorf("MaxPossibilities() of %s = %d, want at least Count() = %d", lines, max, want)
+		} else if max > want {
+			overlapping++
+		}
+		for limit := range want + 2 {
+			got := lines.CountAtMost(limit)
+			if want <= limit && got != want || want > limit && got <= limit {
+				t.Fatalf("CountAtMost(%d) of %s = %d, but Count() = %d", limit, lines, got, want)
+			}
+		}
+	}
+	// The test is only meaningful if MaxPossibilities overcounts some of the lines.
+	if overlapping == 0 {
+		t.Error("no lines had overlapping possibilities")
+	}
+}
+
+func TestCountAtMost_Large(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = string([]byte{'a' + byte(i/100), 'a' + byte(i/10%10), 'a' + byte(i%10)})
+	}
+	w := MakeWords(words, len(words), 3)
+	// 1000^7 lines overflow an int64.
+	lines := w
+	for range 6 {
+		lines = MakeBlockBetween(lines, w)
+	}
+	if got := lines.CountAtMost(1000); got <= 1000 {
+		t.Errorf("CountAtMost(1000) = %d, want more than 1000", got)
+	}
+	if got := lines.Count(); got != math.MaxInt64 {
+		t.Errorf("Count() = %d, want math.MaxInt64", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	if got := MakeImpossible(3).Sample(r); got != nil {
+		t.Errorf("Sample() of Impossible = %v, want nil", got)
+	}
+
+	for range 300 {
+		lines := randomLines(r, 1+r.IntN(5), 4)
+		// want counts how many times Iterate yields each line, with its words.
+		want := make(map[string]int)
+		for line := range lines.Iterate() {
+			want[fmt.Sprint(string(line.Line), line.Words)]++
+		}
+		total := lines.MaxPossibilities()
+		if total == 0 {
+			if got := lines.Sample(r); got != nil {
+				t.Fatalf("Sample() of %s = %v, want nil", lines, got)
+			}
+			continue
+		}
+
+		samples := 200 * int(total)
+		got := make(map[string]int)
+		for range samples {
+			line := lines.Sample(r)
+			if line == nil {
+				t.Fatalf("Sample() of %s = nil, want a line", lines)
+			}
+			key := fmt.Sprint(string(line.Line), line.Words)
+			if want[key] == 0 {
+				t.Fatalf("Sample() of %s = %s, which Iterate does not yield", lines, key)
+			}
+			got[key]++
+		}
+		// Each line should be sampled in proportion to how often Iterate yields it, within six
+		// standard deviations.
+		for key, n := range want {
+			expected := float64(samples) * float64(n) / float64(total)
+			if diff := math.Abs(float64(got[key]) - expected); diff > 6*math.Sqrt(expected) {
+				t.Errorf("Sample() of %s returned %s %d times in %d, want about %.0f", lines, key, got[key], samples, expected)
+			}
+		}
+	}
+}
+
+func TestSample_Large(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = string([]byte{'a' + byte(i/100), 'a' + byte(i/10%10), 'a' + byte(i%10)})
+	}
+	r := rand.New(rand.NewPCG(5, 6))
+	for _, lines := range []PossibleLines{
+		MakeWords(words, 500, 3),
+		MakeSortedWords(words, 500),
+		MakeTrieWords(words[:500], words[500:], 3),
+	} {
+		seen := make(map[string]bool)
+		for range 2000 {
+			line := lines.Sample(r)
+			if !slices.Contains(words, string(line.Line)) || !slices.Equal(line.Words, []string{string(line.Line)}) {
+				t.Fatalf("Sample() of %s = %v, want one of the words", lines, line)
+			}
+			seen[string(line.Line)] = true
+		}
+		// 2000 uniform samples of 1000 words miss about 135 of them.
+		if len(seen) < 800 || len(seen) > 930 {
+			t.Errorf("Sample() of %s returned %d distinct words in 2000 samples, want about 865", lines, len(seen))
+		}
+	}
+}
+
+func TestRemoveWordSet(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 8))
+	for range 2000 {
+		lines := randomLines(r, 1+r.IntN(5), 4)
+		before := collectLines(lines)
+		var words []string
+		excluded := make(map[string]struct{})
+		for range r.IntN(7) {
+			word := make([]byte, 1+r.IntN(5))
+			for i := range word {
+				word[i] = "ab"[r.IntN(2)]
+			}
+			words = append(words, string(word))
+			excluded[string(word)] = struct{}{}
+		}
+
+		got := lines.RemoveWordSet(excluded)
+		if diff := cmp.Diff(collectLines(lines.RemoveWordOptions(words)), collectLines(got)); diff != "" {
+			t.Fatalf("RemoveWordSet(%v) of %s: -RemoveWordOptions +RemoveWordSet %s", words, lines, diff)
+		}
+		if diff := cmp.Diff(before, collectLines(lines)); diff != "" {
+			t.Fatalf("RemoveWordSet(%v) modified %s: -before +after %s", words, lines, diff)
+		}
+		if len(excluded) == 0 && got != lines {
+			t.Errorf("RemoveWordSet(nil) of %s = %s, want the lines themselves", lines, got)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	r := rand.New(rand.NewPCG(9, 10))
+	for range 2000 {
+		n := 1 + r.IntN(5)
+		lines := randomLines(r, n, 4)
+		want := make(map[string]bool)
+		for _, line := range collectLines(lines) {
+			want[line] = true
+		}
+
+		// Every line, and a few others, including ones of the wrong length.
+		candidates := slices.Collect(maps.Keys(want))
+		for range 10 {
+			line := make([]rune, n-1+r.IntN(3))
+			for i := range line {
+				line[i] = []rune{'a', 'b', kBlocked}[r.IntN(3)]
+			}
+			candidates = append(candidates, string(line))
+		}
+		for _, line := range candidates {
+			if got := lines.Contains([]rune(line)); got != want[line] {
+				t.Fatalf("Contains(%q) of %s = %v, want %v", line, lines, got, want[line])
+			}
+		}
+	}
+	if MakeImpossible(3).Contains([]rune("abc")) {
+		t.Error("Impossible contains abc")
+	}
+}
+
+func TestFilterMulti(t *testing.T) {
+	r := rand.New(rand.NewPCG(11, 12))
+	for range 2000 {
+		n := 1 + r.IntN(5)
+		lines := randomLines(r, n, 4)
+		before := collectLines(lines)
+		var constraints []IndexedCharSet
+		for range r.IntN(4) {
+			c := IndexedCharSet{Index: r.IntN(n)}
+			for _, ch := range []rune{'a', 'b', kBlocked} {
+				if r.IntN(3) > 0 {
+					c.Chars.Add(ch)
+				}
+			}
+			constraints = append(constraints, c)
+		}
+
+		got := lines.FilterMulti(constraints)
+		want := lines
+		for _, c := range constraints {
+			want = want.FilterAny(&c.Chars, c.Index)
+		}
+		if diff := cmp.Diff(collectLines(want), collectLines(got)); diff != "" {
+			t.Fatalf("FilterMulti(%v) of %s: -FilterAny +FilterMulti %s", constraints, lines, diff)
+		}
+		if diff := cmp.Diff(before, collectLines(lines)); diff != "" {
+			t.Fatalf("FilterMulti(%v) modified %s: -before +after %s", constraints, lines, diff)
+		}
+		if len(constraints) == 0 && got != lines {
+			t.Errorf("FilterMulti(nil) of %s = %s, want the lines themselves", lines, got)
+		}
+	}
+}
+
+func BenchmarkFilterMulti(b *testing.B) {
+	// The constraints of four crossing lines, each allowing half the letters.
+	words := benchmarkWords(10_000)
+	var constraints []IndexedCharSet
+	for _, index := range []int{0, 2, 4, 6} {
+		c := IndexedCharSet{Index: index}
+		for ch := 'a' + rune(index%2); ch <= 'z'; ch += 2 {
+			c.Chars.Add(ch)
+		}
+		constraints = append(constraints, c)
+	}
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+	}{
+		{name: "Words", lines: words},
+		{name: "SortedWords", lines: MakeSortedWords(words.allWords, len(words.allWords))},
+	} {
+		b.Run("FilterAny/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				lines := tc.lines
+				for _, c := range constraints {
+					lines = lines.FilterAny(&c.Chars, c.Index)
+				}
+			}
+		})
+		b.Run("FilterMulti/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.FilterMulti(constraints)
+			}
+		})
+	}
+}
+
+func TestMakeChoiceN(t *testing.T) {
+	r := rand.New(rand.NewPCG(13, 14))
+	for range 2000 {
+		lines := randomLines(r, 1+r.IntN(5), 4)
+		if lines.MaxPossibilities() <= 1 {
+			continue
+		}
+		n := 2 + r.IntN(5)
+		parts := lines.MakeChoiceN(n)
+		if len(parts) < 2 || len(parts) > n {
+			t.Fatalf("MakeChoiceN(%d) of %s returned %d sets, want between 2 and %d", n, lines, len(parts), n)
+		}
+		var got []string
+		for _, p := range parts {
+			got = append(got, collectLines(p)...)
+		}
+		want := collectLines(lines)
+		slices.Sort(want)
+		slices.Sort(got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("MakeChoiceN(%d) of %s: -Iterate +sets %s", n, lines, diff)
+		}
+	}
+
+	t.Run("Balanced", func(t *testing.T) {
+		words := benchmarkWords(1000)
+		for _, lines := range []PossibleLines{
+			words,
+			MakeSortedWords(words.allWords, 400),
+			MakeCompound([]PossibleLines{words, MakeBlockBefore(MakeWords(words.allWords[:500], 500, 7)), MakeBlockAfter(words)}, 8),
+			MakeBlockBetween(MakeWords([]string{"a", "b"}, 2, 1), MakeWords(words.allWords[:300], 300, 7)),
+		} {
+			parts := lines.MakeChoiceN(3)
+			if len(parts) != 3 {
+				t.Fatalf("MakeChoiceN(3) of %s returned %d sets, want 3", lines, len(parts))
+			}
+			for _, p := range parts {
+				if got, want := p.MaxPossibilities(), lines.MaxPossibilities()/3; got < want/2 || got > want*2 {
+					t.Errorf("MakeChoiceN(3) of %s returned a set of %d lines, want about %d", lines, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("Panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("MakeChoiceN(1) did not panic")
+			}
+		}()
+		benchmarkWords(10).MakeChoiceN(1)
+	})
+}
+
+func BenchmarkRemoveWordSet(b *testing.B) {
+	// Every other word is removed, 10,000 in all.
+	words := benchmarkWords(20_000)
+	var removed []string
+	excluded := make(map[string]struct{})
+	for i, word := range words.allWords {
+		if i%2 == 0 {
+			removed = append(removed, word)
+			excluded[word] = struct{}{}
+		}
+	}
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+	}{
+		{name: "Words", lines: words},
+		{name: "SortedWords", lines: MakeSortedWords(words.allWords, len(words.allWords))},
+		{name: "TrieWords", lines: MakeTrieWords(words.allWords, nil, 7)},
+	} {
+		b.Run("RemoveWordOptions/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.RemoveWordOptions(removed)
+			}
+		})
+		b.Run("RemoveWordSet/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.RemoveWordSet(excluded)
+			}
+		})
+	}
+}
+
+// TestConcurrentReads reads the same lines from several goroutines at once, which fills their
+// letter masks concurrently. Run with -race.
+func TestConcurrentReads(t *testing.T) {
+	words := []string{"abc", "abd", "bcd", "xyz", "xbz"}
+	for _, lines := range []PossibleLines{
+		MakeWords(words, 3, 3),
+		MakeSortedWords(words, 3),
+		MakeTrieWords(words[:3], words[3:], 3),
+		MakeBlockBetween(MakeWords(words, 3, 3), MakeTrieWords(words, nil, 3)),
+	} {
+		var constraint CharSet
+		constraint.Add('b')
+		constraint.Add('c')
+		want := make([]CharSet, lines.NumLetters())
+		for i := range want {
+			lines.Clone().CharsAt(&want[i], i)
+		}
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range lines.NumLetters() {
+					var got CharSet
+					lines.CharsAt(&got, i)
+					if got != want[i] {
+						t.Errorf("CharsAt(%d) of %s = %v, want %v", i, lines, &got, &want[i])
+					}
+					lines.FilterAny(&constraint, i)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// sharedState returns a description of the mutable state that a and its clone b share, or "" if
+// they share none.
+func sharedState(a, b PossibleLines) string {
+	sameMasks := func(a, b *letterMasks) bool {
+		masks := a.masks.Load()
+		return masks != nil && masks == b.masks.Load()
+	}
+	if a == b {
+		if _, ok := a.(*Impossible); ok {
+			return ""
+		}
+		return fmt.Sprintf("%v is the same pointer", a)
+	}
+	switch a := a.(type) {
+	case *Words:
+		if sameMasks(&a.letterMasks, &b.(*Words).letterMasks) {
+			return fmt.Sprintf("%v shares letter masks", a)
+		}
+	case *SortedWords:
+		if sameMasks(&a.letterMasks, &b.(*SortedWords).letterMasks) {
+			return fmt.Sprintf("%v shares letter masks", a)
+		}
+	case *TrieWords:
+		if sameMasks(&a.letterMasks, &b.(*TrieWords).letterMasks) {
+			return fmt.Sprintf("%v shares letter masks", a)
+		}
+	case *Definite:
+		if &a.line.Line[0] == &b.(*Definite).line.Line[0] || &a.line.Words[0] == &b.(*Definite).line.Words[0] {
+			return fmt.Sprintf("%v shares its line", a)
+		}
+	case *BlockBefore:
+		return sharedState(a.lines, b.(*BlockBefore).lines)
+	case *BlockAfter:
+		return sharedState(a.lines, b.(*BlockAfter).lines)
+	case *BlockBetween:
+		return sharedState(a.first, b.(*BlockBetween).first) + sharedState(a.second, b.(*BlockBetween).second)
+	case *Concat:
+		return sharedState(a.first, b.(*Concat).first) + sharedState(a.second, b.(*Concat).second)
+	case *Compound:
+		shared := ""
+		for i, p := range a.possibilities {
+			shared += sharedState(p, b.(*Compound).possibilities[i])
+		}
+		return shared
+	case *Memoized:
+		if a.cache == b.(*Memoized).cache {
+			return fmt.Sprintf("%v shares its cache", a)
+		}
+		return sharedState(a.lines, b.(*Memoized).lines)
+	}
+	return ""
+}
+
+func TestClone(t *testing.T) {
+	makeLines := func() PossibleLines {
+		return MakeCompound([]PossibleLines{
+			MakeBlockBefore(MakeWords([]string{"abcd", "abce", "bcde"}, 2, 4)),
+			MakeBlockAfter(MakeSortedWords([]string{"wxyz", "vwxy", "abcd"}, 1)),
+			MakeBlockBetween(
+				MakeTrieWords([]string{"ab", "cd"}, []string{"ef"}, 2),
+				MakeDefinite(ConcreteLine{Line: []rune("xy"), Words: []string{"xy"}}),
+			),
+			MakeConcat(MakeWords([]string{"ab", "cd"}, 2, 2), MakeSortedWords([]string{"xyz", "xyw"}, 2)),
+		}, 5)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+	}{
+		{"Compound", makeLines()},
+		{"Memoized", MemoizedFilter(makeLines())},
+		{"Impossible", MakeImpossible(5)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Fill the letter masks, so that there is state to copy.
+			for i := range tc.lines.NumLetters() {
+				var chars CharSet
+				tc.lines.CharsAt(&chars, i)
+			}
+			clone := tc.lines.Clone()
+			if diff := cmp.Diff(collectLines(tc.lines), collectLines(clone)); diff != "" {
+				t.Errorf("Clone() lines: -want +got %s", diff)
+			}
+			if shared := sharedState(tc.lines, clone); shared != "" {
+				t.Errorf("Clone() shares mutable state: %s", shared)
+			}
+			for i := range tc.lines.NumLetters() {
+				var want, got CharSet
+				tc.lines.CharsAt(&want, i)
+				clone.CharsAt(&got, i)
+				if want != got {
+					t.Errorf("Clone().CharsAt(%d) = %v, want %v", i, &got, &want)
+				}
+			}
+		})
+	}
+
+	t.Run("independent", func(t *testing.T) {
+		words := MakeWords([]string{"abc", "abd", "xyz"}, 3, 3).(*Words)
+		clone := words.Clone().(*Words)
+		var chars CharSet
+		clone.CharsAt(&chars, 2)
+		if words.letterMasks.len() != 0 {
+			t.Error("CharsAt on the clone filled the letter masks of the original")
+		}
+
+		// Each goroutine fills the letter masks of its own clone.
+		lines := makeLines()
+		var wg sync.WaitGroup
+		for range 4 {
+			clone := lines.Clone()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range clone.NumLetters() {
+					var chars CharSet
+					clone.CharsAt(&chars, i)
+				}
+			}()
+		}
+		wg.Wait()
+
+		definite := MakeDefinite(ConcreteLine{Line: []rune("abc"), Words: []string{"abc"}})
+		cloned := definite.Clone().(*Definite)
+		cloned.line.Line[0] = 'x'
+		cloned.line.Words[0] = "xbc"
+		if got := collectLines(definite); !slices.Equal(got, []string{"abc"}) || !slices.Equal(definite.DefiniteWords(), []string{"abc"}) {
+			t.Errorf("modifying the clone changed the original to %v, %v", got, definite.DefiniteWords())
+		}
+	})
+}
+
+// collectLines returns the lines of pl, and panics if pl is not valid, so that every test that
+// collects lines also checks the tree they came from.
 func collectLines(pl PossibleLines) []string {
 	if pl == nil || isActuallyImpossible(pl) {
 		return []string{}
 	}
+	if errs := Validate(pl); len(errs) > 0 {
+		panic(fmt.Sprintf("invalid lines: %v\n%s", errors.Join(errs...), DebugTree(pl, 0)))
+	}
 	lines := []string{}
 	for item := range pl.Iterate() {
 		lines = append(lines, string(item.Line))
@@ -0,0 +1,356 @@
+package primitives
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// SortedWords represents the same set of possible lines as Words, but keeps the preferred and the
+// obscure words each in sorted order. Filtering on the first letter of the line then takes two
+// binary searches per letter instead of a scan, and returns sub-slices rather than copies. Filtering
+// at any other index scans the words, as Words does.
+//
+// Preferred words are iterated before obscure ones, and each in alphabetical order rather than the
+// order they were given in.
+type SortedWords struct {
+	numLetters int
+	// preferred and obscure are each sorted. At least two words are in one or the other.
+	preferred, obscure []string
+	// letterMasks caches, for each index, the bitmask of allowed runes across all words.
+	letterMasks letterMasks
+}
+
+// MakeSortedWords returns the possible lines filled with any one of words, where the words before
+// obscureIdx are preferred and the rest obscure, like MakeWords. words is not modified. Since the
+// length of a line is taken from the words, words must not be empty.
+func MakeSortedWords(words []string, obscureIdx int) PossibleLines {
+	if len(words) == 0 {
+		return MakeImpossible(0)
+	}
+	preferred := slices.Sorted(slices.Values(words[:obscureIdx]))
+	obscure := slices.Sorted(slices.Values(words[obscureIdx:]))
+	return makeSortedWords(len(words[0]), preferred, obscure)
+}
+
+// makeSortedWords returns the possible lines with the given sorted words, simplified to Impossible
+// or Definite where possible.
+func makeSortedWords(numLetters int, preferred, obscure []string) PossibleLines {
+	switch len(preferred) + len(obscure) {
+	case 0:
+		return MakeImpossible(numLetters)
+	case 1:
+		word := slices.Concat(preferred, obscure)[0]
+		return MakeDefinite(ConcreteLine{Line: []rune(word), Words: []string{word}})
+	}
+	return &SortedWords{numLetters: numLetters, preferred: preferred, obscure: obscure}
+}
+
+// with returns w with the given words, or w itself if they are unchanged. They must be a subset
+// of w's words.
+func (w *SortedWords) with(preferred, obscure []string) PossibleLines {
+	if len(preferred) == len(w.preferred) && len(obscure) == len(w.obscure) {
+		return w
+	}
+	return makeSortedWords(w.numLetters, preferred, obscure)
+}
+
+// wordsStartingWith returns the range of sorted words whose first letters are kept. If the words
+// kept are not contiguous, they are copied to a new slice.
+func wordsStartingWith(sorted []string, keep func(letter byte) bool) []string {
+	var kept []string
+	// While the words kept so far are contiguous, they are sorted[start:end], and aren't copied.
+	start, end, contiguous := 0, 0, true
+	for i := 0; i < len(sorted); {
+		letter := sorted[i][0]
+		next := i + endOfLetter(sorted[i:], letter)
+		if keep(letter) {
+			switch {
+			case !contiguous:
+				kept = append(kept, sorted[i:next]...)
+			case start == end || end == i:
+				if start == end {
+					start = i
+				}
+				end = next
+			default:
+				contiguous = false
+				kept = append(slices.Clone(sorted[start:end]), sorted[i:next]...)
+			}
+		}
+		i = next
+	}
+	if contiguous {
+		return sorted[start:end]
+	}
+	return kept
+}
+
+// wordsStartingWithLetter returns the range of sorted words whose first letter is letter.
+func wordsStartingWithLetter(sorted []string, letter byte) []string {
+	start := sort.SearchStrings(sorted, string([]byte{letter}))
+	return sorted[start : start+endOfLetter(sorted[start:], letter)]
+}
+
+// endOfLetter returns the index of the first of the sorted words that starts after letter.
+func endOfLetter(sorted []string, letter byte) int {
+	return sort.Search(len(sorted), func(i int) bool {
+		return sorted[i][0] > letter
+	})
+}
+
+// wordsMatching returns the sorted words whose letter at index is kept, or sorted itself if they
+// all are.
+func wordsMatching(sorted []string, index int, keep func(letter byte) bool) []string {
+	first := slices.IndexFunc(sorted, func(word string) bool {
+		return !keep(word[index])
+	})
+	if first < 0 {
+		return sorted
+	}
+	kept := append(make([]string, 0, len(sorted)-1), sorted[:first]...)
+	for _, word := range sorted[first+1:] {
+		if keep(word[index]) {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// filter returns w with only the words whose letter at index is kept.
+func (w *SortedWords) filter(index int, keep func(letter byte) bool) PossibleLines {
+	if index == 0 {
+		return w.with(wordsStartingWith(w.preferred, keep), wordsStartingWith(w.obscure, keep))
+	}
+	return w.with(wordsMatching(w.preferred, index, keep), wordsMatching(w.obscure, index, keep))
+}
+
+func (w *SortedWords) NumLetters() int {
+	return w.numLetters
+}
+
+func (w *SortedWords) MaxPossibilities() int64 {
+	return int64(len(w.preferred) + len(w.obscure))
+}
+
+func (w *SortedWords) Count() int64 {
+	return w.MaxPossibilities()
+}
+
+func (w *SortedWords) CountAtMost(limit int64) int64 {
+	return w.Count()
+}
+
+func (w *SortedWords) CharsAt(accumulate *CharSet, index int) {
+	if accumulate.IsFull() || (!accumulate.Contains(kBlocked) && (accumulate.Count()+1) == accumulate.Capacity()) {
+		return
+	}
+	mask := w.letterMask(index)
+	accumulate.AddAll(&mask)
+}
+
+// letterMask returns the set of letters at index across all words.
+func (w *SortedWords) letterMask(index int) CharSet {
+	// Build masks lazily.
+	return w.letterMasks.get(w.numLetters, index, func(mask *CharSet) {
+		for _, words := range [][]string{w.preferred, w.obscure} {
+			for _, word := range words {
+				mask.Add(rune(word[index]))
+			}
+		}
+	})
+}
+
+func (w *SortedWords) DefinitelyBlockedAt(index int) bool {
+	return false
+}
+
+func (w *SortedWords) DefiniteWords() []string {
+	return nil
+}
+
+func (w *SortedWords) FilterAny(constraint *CharSet, index int) PossibleLines {
+	if constraint.IsFull() || (!constraint.Contains(kBlocked) && (constraint.Count()+1) == constraint.Capacity()) {
+		return w
+	}
+
+	// If the mask is entirely contained by the constraint, nothing to filter.
+	if mask := w.letterMask(index); constraint.ContainsAll(&mask) {
+		return w
+	}
+
+	return w.filter(index, func(letter byte) bool {
+		return constraint.Contains(rune(letter))
+	})
+}
+
+func (w *SortedWords) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	constraints = slices.DeleteFunc(slices.Clone(constraints), func(c IndexedCharSet) bool {
+		return c.allowsAnyLetter()
+	})
+	if len(constraints) == 0 {
+		return w
+	}
+	keep := func(sorted []string) []string {
+		first := slices.IndexFunc(sorted, func(word string) bool {
+			return !matchesAll(word, constraints)
+		})
+		if first < 0 {
+			return sorted
+		}
+		kept := append(make([]string, 0, len(sorted)-1), sorted[:first]...)
+		for _, word := range sorted[first+1:] {
+			if matchesAll(word, constraints) {
+				kept = append(kept, word)
+			}
+		}
+		return kept
+	}
+	return w.with(keep(w.preferred), keep(w.obscure))
+}
+
+func (w *SortedWords) Filter(constraint rune, index int) PossibleLines {
+	if constraint == kBlocked {
+		return MakeImpossible(w.numLetters)
+	}
+
+	if index == 0 {
+		if constraint > 0xff {
+			return MakeImpossible(w.numLetters)
+		}
+		return w.with(wordsStartingWithLetter(w.preferred, byte(constraint)), wordsStartingWithLetter(w.obscure, byte(constraint)))
+	}
+	return w.filter(index, func(letter byte) bool {
+		return rune(letter) == constraint
+	})
+}
+
+func (w *SortedWords) RemoveWordOptions(words []string) PossibleLines {
+	preferred, obscure := w.preferred, w.obscure
+	remove := func(sorted []string, word string) []string {
+		i, found := slices.BinarySearch(sorted, word)
+		if !found {
+			return sorted
+		}
+		j := i + 1
+		for j < len(sorted) && sorted[j] == word {
+			j++
+		}
+		return slices.Delete(slices.Clone(sorted), i, j)
+	}
+	for _, word := range words {
+		if len(word) != w.numLetters {
+			continue
+		}
+		preferred = remove(preferred, word)
+		obscure = remove(obscure, word)
+	}
+	return w.with(preferred, obscure)
+}
+
+func (w *SortedWords) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	remove := func(sorted []string) []string {
+		if !slices.ContainsFunc(sorted, func(word string) bool {
+			_, ok := excluded[word]
+			return ok
+		}) {
+			return sorted
+		}
+		return slices.DeleteFunc(slices.Clone(sorted), func(word string) bool {
+			_, ok := excluded[word]
+			return ok
+		})
+	}
+	return w.with(remove(w.preferred), remove(w.obscure))
+}
+
+// Contains binary searches the preferred and the obscure words.
+func (w *SortedWords) Contains(line []rune) bool {
+	if len(line) != w.numLetters {
+		return false
+	}
+	word := string(line)
+	_, preferred := slices.BinarySearch(w.preferred, word)
+	_, obscure := slices.BinarySearch(w.obscure, word)
+	return preferred || obscure
+}
+
+func (w *SortedWords) FirstOrNull() *ConcreteLine {
+	for line := range w.Iterate() {
+		return &line
+	}
+	return nil
+}
+
+func (w *SortedWords) Sample(r *rand.Rand) *ConcreteLine {
+	i := r.IntN(len(w.preferred) + len(w.obscure))
+	word := ""
+	if i < len(w.preferred) {
+		word = w.preferred[i]
+	} else {
+		word = w.obscure[i-len(w.preferred)]
+	}
+	return &ConcreteLine{Line: []rune(word), Words: []string{word}}
+}
+
+func (w *SortedWords) Iterate() iter.Seq[ConcreteLine] {
+	return func(yield func(ConcreteLine) bool) {
+		for _, words := range [][]string{w.preferred, w.obscure} {
+			for _, word := range words {
+				if !yield(ConcreteLine{Line: []rune(word), Words: []string{word}}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// MakeChoice splits the words in half, in the order they are iterated, like Words.
+func (w *SortedWords) MakeChoice() ChoiceStep {
+	if w.MaxPossibilities() <= 1 {
+		panic("Cannot call MakeChoice on entity with 1 or less options")
+	}
+
+	half := (len(w.preferred) + len(w.obscure)) / 2
+	if half <= len(w.preferred) {
+		return ChoiceStep{
+			Choice:    makeSortedWords(w.numLetters, w.preferred[:half], nil),
+			Remaining: makeSortedWords(w.numLetters, w.preferred[half:], w.obscure),
+		}
+	}
+	half -= len(w.preferred)
+	return ChoiceStep{
+		Choice:    makeSortedWords(w.numLetters, w.preferred, w.obscure[:half]),
+		Remaining: makeSortedWords(w.numLetters, nil, w.obscure[half:]),
+	}
+}
+
+// MakeChoiceN splits the words into n runs of nearly equal length, in the order they are iterated,
+// like Words.
+func (w *SortedWords) MakeChoiceN(n int) []PossibleLines {
+	checkChoiceN(n)
+	if w.MaxPossibilities() <= 1 {
+		panic("Cannot call MakeChoiceN on entity with 1 or less options")
+	}
+
+	var parts []PossibleLines
+	for _, b := range chunks(len(w.preferred)+len(w.obscure), n) {
+		preferred := w.preferred[min(b[0], len(w.preferred)):min(b[1], len(w.preferred))]
+		obscure := w.obscure[max(b[0]-len(w.preferred), 0):max(b[1]-len(w.preferred), 0)]
+		parts = append(parts, makeSortedWords(w.numLetters, preferred, obscure))
+	}
+	return parts
+}
+
+// Clone returns a copy of w sharing its words, which are never modified.
+func (w *SortedWords) Clone() PossibleLines {
+	c := &SortedWords{numLetters: w.numLetters, preferred: w.preferred, obscure: w.obscure}
+	c.letterMasks.copyFrom(&w.letterMasks)
+	return c
+}
+
+func (w *SortedWords) String() string {
+	return fmt.Sprintf("SortedWords(%s, %s)", arrayStr(w.preferred), arrayStr(w.obscure))
+}
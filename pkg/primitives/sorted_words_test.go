@@ -0,0 +1,114 @@
+package primitives
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSortedWords(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	for trial := range 20 {
+		all := slices.Compact(slices.Sorted(slices.Values(randomWords(r, 40, 5))))
+		r.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+		numPreferred := r.IntN(len(all))
+
+		sorted := MakeSortedWords(all, numPreferred)
+		words := MakeWords(slices.Clone(all), numPreferred, 5)
+
+		check := func(name string, got, want PossibleLines) {
+			t.Helper()
+			checkSameLines(t, fmt.Sprintf("trial %d: %s", trial, name), got, want)
+		}
+		check("MakeSortedWords", sorted, words)
+
+		// Preferred words come first.
+		lines := collectLines(sorted)
+		if want := slices.Sorted(slices.Values(all[:numPreferred])); !slices.Equal(lines[:numPreferred], want) {
+			t.Errorf("trial %d: Iterate() = %v, want the preferred words %v first", trial, lines, want)
+		}
+
+		for index := range 5 {
+			for _, letter := range "abcde" {
+				check(fmt.Sprintf("Filter(%c, %d)", letter, index), sorted.Filter(letter, index), words.Filter(letter, index))
+			}
+			for _, letters := range []string{"a", "ac", "bd", "abc", "abcd"} {
+				var cs CharSet
+				for _, letter := range letters {
+					cs.Add(letter)
+				}
+				check(fmt.Sprintf("FilterAny(%s, %d)", letters, index), sorted.FilterAny(&cs, index), words.FilterAny(&cs, index))
+			}
+		}
+		removed := []string{all[0], all[len(all)/2], "zzzzz", "abc"}
+		check("RemoveWordOptions", sorted.RemoveWordOptions(removed), words.RemoveWordOptions(removed))
+
+		c := sorted.MakeChoice()
+		if c.Choice.MaxPossibilities()+c.Remaining.MaxPossibilities() != sorted.MaxPossibilities() {
+			t.Errorf("trial %d: MakeChoice() = %v, %v, want a split of %v", trial, c.Choice, c.Remaining, sorted)
+		}
+		if got := append(collectLines(c.Choice), collectLines(c.Remaining)...); !slices.Equal(got, lines) {
+			t.Errorf("trial %d: MakeChoice() lines = %v, want %v", trial, got, lines)
+		}
+	}
+}
+
+func TestSortedWords_Simplifies(t *testing.T) {
+	words := MakeSortedWords([]string{"cat", "car", "cot"}, 2)
+	if got := words.Filter('c', 0); got != words {
+		t.Errorf("Filter('c', 0) = %v, want the same SortedWords", got)
+	}
+	if got := words.RemoveWordOptions([]string{"dog"}); got != words {
+		t.Errorf("RemoveWordOptions(dog) = %v, want the same SortedWords", got)
+	}
+	if got, ok := words.Filter('o', 1).(*Definite); !ok || string(got.line.Line) != "cot" {
+		t.Errorf("Filter('o', 1) = %v, want Definite(cot)", words.Filter('o', 1))
+	}
+	if got := words.Filter('d', 0); !isActuallyImpossible(got) {
+		t.Errorf("Filter('d', 0) = %v, want Impossible", got)
+	}
+	if got := words.Filter(Blocked, 0); !isActuallyImpossible(got) {
+		t.Errorf("Filter(Blocked, 0) = %v, want Impossible", got)
+	}
+	if got := MakeSortedWords(nil, 0); !isActuallyImpossible(got) {
+		t.Errorf("MakeSortedWords(nil) = %v, want Impossible", got)
+	}
+	if got := words.String(); got != "SortedWords([car, cat], [cot])" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func BenchmarkSortedWords(b *testing.B) {
+	words := benchmarkWords(50_000)
+	sorted := MakeSortedWords(words.allWords, len(words.allWords))
+
+	var cs CharSet
+	for _, r := range "bdf" {
+		cs.Add(r)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+	}{
+		{name: "Words", lines: words},
+		{name: "SortedWords", lines: sorted},
+	} {
+		b.Run("Filter/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.Filter('b', 0)
+			}
+		})
+		b.Run("FilterAny/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.FilterAny(&cs, 0)
+			}
+		})
+		b.Run("FilterAnyLast/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.FilterAny(&cs, 6)
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package primitives
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// TrieWords represents the same set of possible lines as Words, but stores the words in a
+// compressed prefix trie, so that filtering on a letter near the start of the line only visits the
+// words that can match, rather than scanning them all. It suits very large word lists.
+//
+// Preferred words are kept in a separate trie from obscure words, and iterated first. Within each
+// trie, words are iterated in alphabetical order rather than the order they were given in.
+type TrieWords struct {
+	numLetters int
+	// preferred and obscure are the roots of the tries of each kind of word. Either can be nil,
+	// but not both.
+	preferred, obscure *trieNode
+	// letterMasks caches, for each index, the bitmask of allowed runes across all words.
+	letterMasks letterMasks
+}
+
+// trieNode is a node of a compressed prefix trie of words of the same length. Every node other than
+// a leaf has at least two children.
+type trieNode struct {
+	// label is the letters between the parent node and this one.
+	label string
+	// children are ordered by the first letter of their labels, and are nil for a leaf, which ends
+	// a word.
+	children []*trieNode
+	// size is the number of words below this node.
+	size int64
+}
+
+// MakeTrieWords returns the possible lines filled with any one of the given words, stored in a
+// trie. Words that are both preferred and obscure are treated as preferred.
+func MakeTrieWords(preferred, obscure []string, numLetters int) PossibleLines {
+	isPreferred := make(map[string]bool, len(preferred))
+	for _, word := range preferred {
+		isPreferred[word] = true
+	}
+	obscure = slices.DeleteFunc(slices.Clone(obscure), func(word string) bool {
+		return isPreferred[word]
+	})
+	return makeTrieWords(numLetters, buildTrie(preferred), buildTrie(obscure))
+}
+
+// makeTrieWords returns the possible lines in the given tries, simplified to Impossible or
+// Definite where possible.
+func makeTrieWords(numLetters int, preferred, obscure *trieNode) PossibleLines {
+	size := preferred.wordCount() + obscure.wordCount()
+	switch {
+	case size == 0:
+		return MakeImpossible(numLetters)
+	case size == 1:
+		word := slices.Collect((&TrieWords{numLetters: numLetters, preferred: preferred, obscure: obscure}).words())[0]
+		return MakeDefinite(ConcreteLine{Line: []rune(word), Words: []string{word}})
+	}
+	return &TrieWords{numLetters: numLetters, preferred: preferred, obscure: obscure}
+}
+
+// buildTrie returns the trie of words, or nil if there are none.
+func buildTrie(words []string) *trieNode {
+	if len(words) == 0 {
+		return nil
+	}
+	words = slices.Clone(words)
+	slices.Sort(words)
+	return buildTrieNode(slices.Compact(words), 0)
+}
+
+// buildTrieNode returns the node of the sorted, distinct words, which all share their first depth
+// letters.
+func buildTrieNode(words []string, depth int) *trieNode {
+	first, last := words[0], words[len(words)-1]
+	end := depth
+	for end < len(first) && first[end] == last[end] {
+		end++
+	}
+	node := &trieNode{label: first[depth:end], size: int64(len(words))}
+	if end == len(first) {
+		return node
+	}
+	for len(words) > 0 {
+		n := 1
+		for n < len(words) && words[n][end] == words[0][end] {
+			n++
+		}
+		node.children = append(node.children, buildTrieNode(words[:n], end))
+		words = words[n:]
+	}
+	return node
+}
+
+// newTrieNode returns a node with the given label and children, merging it into its only child if
+// it has one, or nil if it has none.
+func newTrieNode(label string, children []*trieNode) *trieNode {
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return &trieNode{label: label + children[0].label, children: children[0].children, size: children[0].size}
+	}
+	node := &trieNode{label: label, children: children}
+	for _, child := range children {
+		node.size += child.size
+	}
+	return node
+}
+
+func (t *trieNode) wordCount() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// filter returns t with only the words whose letter at index is kept, where t starts at depth. It
+// returns t itself if nothing is filtered out, and nil if everything is.
+func (t *trieNode) filter(depth, index int, keep func(letter byte) bool) *trieNode {
+	if t == nil {
+		return nil
+	}
+	if index < depth+len(t.label) {
+		if keep(t.label[index-depth]) {
+			return t
+		}
+		return nil
+	}
+
+	depth += len(t.label)
+	var children []*trieNode
+	changed := false
+	for i, child := range t.children {
+		filtered := child.filter(depth, index, keep)
+		if filtered != child && !changed {
+			changed = true
+			children = slices.Clone(t.children[:i])
+		}
+		if changed && filtered != nil {
+			children = append(children, filtered)
+		}
+	}
+	if !changed {
+		return t
+	}
+	return newTrieNode(t.label, children)
+}
+
+// remove returns t without word, where t starts at depth. It returns t itself if it does not
+// contain word.
+func (t *trieNode) remove(depth int, word string) *trieNode {
+	if t == nil {
+		return nil
+	}
+	end := depth + len(t.label)
+	if word[depth:end] != t.label {
+		return t
+	}
+	if end == len(word) {
+		return nil
+	}
+	for i, child := range t.children {
+		if child.label[0] != word[end] {
+			continue
+		}
+		removed := child.remove(end, word)
+		if removed == child {
+			return t
+		}
+		children := slices.Clone(t.children)
+		if removed == nil {
+			children = slices.Delete(children, i, i+1)
+		} else {
+			children[i] = removed
+		}
+		return newTrieNode(t.label, children)
+	}
+	return t
+}
+
+// contains returns whether word is below t, where t starts at depth.
+func (t *trieNode) contains(depth int, word string) bool {
+	for t != nil {
+		end := depth + len(t.label)
+		if end > len(word) || word[depth:end] != t.label {
+			return false
+		}
+		if end == len(word) {
+			return true
+		}
+		next := t.children
+		t, depth = nil, end
+		for _, child := range next {
+			if child.label[0] == word[end] {
+				t = child
+				break
+			}
+		}
+	}
+	return false
+}
+
+// charsAt adds the letters at index of every word below t, which starts at depth, to accumulate.
+func (t *trieNode) charsAt(accumulate *CharSet, depth, index int) {
+	if t == nil {
+		return
+	}
+	if index < depth+len(t.label) {
+		accumulate.Add(rune(t.label[index-depth]))
+		return
+	}
+	for _, child := range t.children {
+		child.charsAt(accumulate, depth+len(t.label), index)
+	}
+}
+
+// words yields every word below t in alphabetical order, each prefixed by prefix.
+func (t *trieNode) words(prefix []byte, yield func(string) bool) bool {
+	if t == nil {
+		return true
+	}
+	prefix = append(prefix, t.label...)
+	if t.children == nil {
+		return yield(string(prefix))
+	}
+	for _, child := range t.children {
+		if !child.words(prefix, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// word returns the i-th word below t in alphabetical order, prefixed by prefix, going down only
+// the children that hold it.
+func (t *trieNode) word(prefix []byte, i int64) string {
+	prefix = append(prefix, t.label...)
+	for _, child := range t.children {
+		if i < child.size {
+			return child.word(prefix, i)
+		}
+		i -= child.size
+	}
+	return string(prefix)
+}
+
+// words yields every word, preferred ones first.
+func (w *TrieWords) words() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		prefix := make([]byte, 0, w.numLetters)
+		if w.preferred.words(prefix, yield) {
+			w.obscure.words(prefix, yield)
+		}
+	}
+}
+
+// with returns w with the given tries, or w itself if they are unchanged.
+func (w *TrieWords) with(preferred, obscure *trieNode) PossibleLines {
+	if preferred == w.preferred && obscure == w.obscure {
+		return w
+	}
+	return makeTrieWords(w.numLetters, preferred, obscure)
+}
+
+func (w *TrieWords) NumLetters() int {
+	return w.numLetters
+}
+
+func (w *TrieWords) MaxPossibilities() int64 {
+	return w.preferred.wordCount() + w.obscure.wordCount()
+}
+
+// Count is exact, since the words of each trie are distinct, and a word is never in both.
+func (w *TrieWords) Count() int64 {
+	return w.MaxPossibilities()
+}
+
+func (w *TrieWords) CountAtMost(limit int64) int64 {
+	return w.Count()
+}
+
+func (w *TrieWords) CharsAt(accumulate *CharSet, index int) {
+	if accumulate.IsFull() || (!accumulate.Contains(kBlocked) && (accumulate.Count()+1) == accumulate.Capacity()) {
+		return
+	}
+	mask := w.letterMask(index)
+	accumulate.AddAll(&mask)
+}
+
+// letterMask returns the set of letters at index across all words.
+func (w *TrieWords) letterMask(index int) CharSet {
+	// Build masks lazily.
+	return w.letterMasks.get(w.numLetters, index, func(mask *CharSet) {
+		w.preferred.charsAt(mask, 0, index)
+		w.obscure.charsAt(mask, 0, index)
+	})
+}
+
+func (w *TrieWords) DefinitelyBlockedAt(index int) bool {
+	return false
+}
+
+func (w *TrieWords) DefiniteWords() []string {
+	if w.MaxPossibilities() == 1 {
+		return slices.Collect(w.words())
+	}
+	return nil
+}
+
+func (w *TrieWords) FilterAny(constraint *CharSet, index int) PossibleLines {
+	if constraint.IsFull() || (!constraint.Contains(kBlocked) && (constraint.Count()+1) == constraint.Capacity()) {
+		return w
+	}
+
+	// If the mask is entirely contained by the constraint, nothing to filter.
+	if mask := w.letterMask(index); constraint.ContainsAll(&mask) {
+		return w
+	}
+
+	keep := func(letter byte) bool {
+		return constraint.Contains(rune(letter))
+	}
+	return w.with(w.preferred.filter(0, index, keep), w.obscure.filter(0, index, keep))
+}
+
+// FilterMulti filters the tries with each constraint in turn, like FilterAny, since each filter
+// already only visits the nodes above the index it filters.
+func (w *TrieWords) FilterMulti(constraints []IndexedCharSet) PossibleLines {
+	var lines PossibleLines = w
+	for _, c := range constraints {
+		lines = lines.FilterAny(&c.Chars, c.Index)
+	}
+	return lines
+}
+
+func (w *TrieWords) Filter(constraint rune, index int) PossibleLines {
+	if constraint == kBlocked {
+		return MakeImpossible(w.numLetters)
+	}
+
+	keep := func(letter byte) bool {
+		return rune(letter) == constraint
+	}
+	return w.with(w.preferred.filter(0, index, keep), w.obscure.filter(0, index, keep))
+}
+
+func (w *TrieWords) RemoveWordOptions(words []string) PossibleLines {
+	preferred, obscure := w.preferred, w.obscure
+	for _, word := range words {
+		if len(word) != w.numLetters {
+			continue
+		}
+		preferred = preferred.remove(0, word)
+		obscure = obscure.remove(0, word)
+	}
+	return w.with(preferred, obscure)
+}
+
+// RemoveWordSet removes the excluded words one at a time, like RemoveWordOptions, if there are few
+// of them, and otherwise builds a new trie of the words that are not excluded, which is faster once
+// about an eighth of the words are.
+func (w *TrieWords) RemoveWordSet(excluded map[string]struct{}) PossibleLines {
+	if int64(len(excluded)) < w.MaxPossibilities()/8 {
+		preferred, obscure := w.preferred, w.obscure
+		for word := range excluded {
+			if len(word) != w.numLetters {
+				continue
+			}
+			preferred = preferred.remove(0, word)
+			obscure = obscure.remove(0, word)
+		}
+		return w.with(preferred, obscure)
+	}
+
+	keep := func(t *trieNode) *trieNode {
+		var kept []string
+		changed := false
+		t.words(nil, func(word string) bool {
+			if _, ok := excluded[word]; ok {
+				changed = true
+			} else {
+				kept = append(kept, word)
+			}
+			return true
+		})
+		if !changed {
+			return t
+		}
+		return buildTrie(kept)
+	}
+	return w.with(keep(w.preferred), keep(w.obscure))
+}
+
+// Contains follows the line down each trie, in time proportional to its length.
+func (w *TrieWords) Contains(line []rune) bool {
+	if len(line) != w.numLetters {
+		return false
+	}
+	word := string(line)
+	return w.preferred.contains(0, word) || w.obscure.contains(0, word)
+}
+
+func (w *TrieWords) Iterate() iter.Seq[ConcreteLine] {
+	return func(yield func(ConcreteLine) bool) {
+		for word := range w.words() {
+			if !yield(ConcreteLine{Line: []rune(word), Words: []string{word}}) {
+				return
+			}
+		}
+	}
+}
+
+func (w *TrieWords) FirstOrNull() *ConcreteLine {
+	for line := range w.Iterate() {
+		return &line
+	}
+	return nil
+}
+
+// Sample finds the word at a random index by the sizes of the nodes of the tries, in time
+// proportional to the length of the line rather than the number of words.
+func (w *TrieWords) Sample(r *rand.Rand) *ConcreteLine {
+	i := r.Int64N(w.MaxPossibilities())
+	root := w.preferred
+	if i >= w.preferred.wordCount() {
+		i -= w.preferred.wordCount()
+		root = w.obscure
+	}
+	word := root.word(make([]byte, 0, w.numLetters), i)
+	return &ConcreteLine{Line: []rune(word), Words: []string{word}}
+}
+
+// MakeChoice splits preferred words from obscure ones, choosing the preferred words first. If the
+// words are all of one kind, it splits the children of the root of the trie, which is its first
+// branch point, into two groups as close in size as possible.
+func (w *TrieWords) MakeChoice() ChoiceStep {
+	if w.MaxPossibilities() <= 1 {
+		panic("Cannot call MakeChoice on entity with 1 or less options")
+	}
+
+	if w.preferred != nil && w.obscure != nil {
+		return ChoiceStep{
+			Choice:    makeTrieWords(w.numLetters, w.preferred, nil),
+			Remaining: makeTrieWords(w.numLetters, nil, w.obscure),
+		}
+	}
+
+	root, isPreferred := w.preferred, true
+	if root == nil {
+		root, isPreferred = w.obscure, false
+	}
+
+	// Find the split where the sizes on either side differ the least.
+	split, best := 1, root.size
+	var before int64
+	for i, child := range root.children[:len(root.children)-1] {
+		before += child.size
+		if diff := max(before, root.size-before) - min(before, root.size-before); diff < best {
+			split, best = i+1, diff
+		}
+	}
+	choice := newTrieNode(root.label, slices.Clone(root.children[:split]))
+	remaining := newTrieNode(root.label, slices.Clone(root.children[split:]))
+	if isPreferred {
+		return ChoiceStep{
+			Choice:    makeTrieWords(w.numLetters, choice, nil),
+			Remaining: makeTrieWords(w.numLetters, remaining, nil),
+		}
+	}
+	return ChoiceStep{
+		Choice:    makeTrieWords(w.numLetters, nil, choice),
+		Remaining: makeTrieWords(w.numLetters, nil, remaining),
+	}
+}
+
+// MakeChoiceN splits the words with MakeChoice until there are n sets of them.
+func (w *TrieWords) MakeChoiceN(n int) []PossibleLines {
+	return makeChoiceN(w, n)
+}
+
+// Clone returns a copy of w sharing its tries, which are never modified.
+func (w *TrieWords) Clone() PossibleLines {
+	c := &TrieWords{numLetters: w.numLetters, preferred: w.preferred, obscure: w.obscure}
+	c.letterMasks.copyFrom(&w.letterMasks)
+	return c
+}
+
+func (w *TrieWords) String() string {
+	return fmt.Sprintf("TrieWords(%s, %s)", trieStr(w.preferred, w.numLetters), trieStr(w.obscure, w.numLetters))
+}
+
+// trieStr is like arrayStr, for the words of a trie.
+func trieStr(t *trieNode, numLetters int) string {
+	const maxPrint = 3
+
+	var words []string
+	t.words(make([]byte, 0, numLetters), func(word string) bool {
+		words = append(words, word)
+		return len(words) < maxPrint
+	})
+	if rest := t.wordCount() - int64(len(words)); rest > 0 {
+		return fmt.Sprintf("[%s, ...%d]", strings.Join(words, ", "), rest)
+	}
+	return fmt.Sprintf("[%s]", strings.Join(words, ", "))
+}
@@ -0,0 +1,169 @@
+package primitives
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// sortedLines returns the lines of pl in alphabetical order.
+func sortedLines(pl PossibleLines) []string {
+	lines := collectLines(pl)
+	slices.Sort(lines)
+	return lines
+}
+
+// randomWords returns n random words of length numLetters, using only the first few letters of the
+// alphabet so that they share prefixes.
+func randomWords(r *rand.Rand, n, numLetters int) []string {
+	words := make([]string, n)
+	for i := range words {
+		word := make([]byte, numLetters)
+		for j := range word {
+			word[j] = byte('a' + r.IntN(4))
+		}
+		words[i] = string(word)
+	}
+	return words
+}
+
+// checkSameLines checks that got has the same lines as want, in any order.
+func checkSameLines(t *testing.T, name string, got, want PossibleLines) {
+	t.Helper()
+	if diff := cmp.Diff(sortedLines(want), sortedLines(got)); diff != "" {
+		t.Errorf("%s lines: -want +got %s", name, diff)
+	}
+	if got.MaxPossibilities() != want.MaxPossibilities() {
+		t.Errorf("%s MaxPossibilities() = %d, want %d", name, got.MaxPossibilities(), want.MaxPossibilities())
+	}
+	for i := range got.NumLetters() {
+		var gotChars, wantChars CharSet
+		got.CharsAt(&gotChars, i)
+		want.CharsAt(&wantChars, i)
+		if gotChars != wantChars {
+			t.Errorf("%s CharsAt(%d) = %v, want %v", name, i, gotChars, wantChars)
+		}
+	}
+}
+
+func TestTrieWords(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for trial := range 20 {
+		all := slices.Compact(slices.Sorted(slices.Values(randomWords(r, 40, 5))))
+		r.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+		numPreferred := r.IntN(len(all))
+		preferred, obscure := all[:numPreferred], all[numPreferred:]
+
+		trie := MakeTrieWords(preferred, obscure, 5)
+		words := MakeWordsFromPreferredAndObscure(slices.Clone(preferred), slices.Clone(obscure), 5)
+
+		check := func(name string, got, want PossibleLines) {
+			t.Helper()
+			checkSameLines(t, fmt.Sprintf("trial %d: %s", trial, name), got, want)
+		}
+		check("MakeTrieWords", trie, words)
+
+		// Preferred words come first.
+		lines := collectLines(trie)
+		if !slices.Equal(slices.Sorted(slices.Values(lines[:numPreferred])), slices.Sorted(slices.Values(preferred))) {
+			t.Errorf("trial %d: Iterate() = %v, want the preferred words %v first", trial, lines, preferred)
+		}
+
+		for index := range 5 {
+			for _, letter := range "abcde" {
+				check("Filter", trie.Filter(letter, index), words.Filter(letter, index))
+			}
+			var cs CharSet
+			cs.Add('a')
+			cs.Add('c')
+			check("FilterAny", trie.FilterAny(&cs, index), words.FilterAny(&cs, index))
+		}
+		removed := []string{all[0], all[len(all)/2], "zzzzz", "abc"}
+		check("RemoveWordOptions", trie.RemoveWordOptions(removed), words.RemoveWordOptions(removed))
+
+		c := trie.MakeChoice()
+		if got := c.Choice.MaxPossibilities() + c.Remaining.MaxPossibilities(); got != trie.MaxPossibilities() {
+			t.Errorf("trial %d: MakeChoice() splits %d words into %d", trial, trie.MaxPossibilities(), got)
+		}
+		if c.Choice.MaxPossibilities() == 0 || c.Remaining.MaxPossibilities() == 0 {
+			t.Errorf("trial %d: MakeChoice() = %v, %v, want two non-empty sets", trial, c.Choice, c.Remaining)
+		}
+		union := append(sortedLines(c.Choice), sortedLines(c.Remaining)...)
+		slices.Sort(union)
+		if diff := cmp.Diff(sortedLines(words), union); diff != "" {
+			t.Errorf("trial %d: MakeChoice() lines: -want +got %s", trial, diff)
+		}
+	}
+}
+
+func TestTrieWords_Simplifies(t *testing.T) {
+	trie := MakeTrieWords([]string{"cat", "car"}, []string{"cot", "car"}, 3)
+	if got := trie.MaxPossibilities(); got != 3 {
+		t.Errorf("MaxPossibilities() = %d, want 3", got)
+	}
+	if got := trie.Filter('c', 0); got != trie {
+		t.Errorf("Filter('c', 0) = %v, want the same TrieWords", got)
+	}
+	var cs CharSet
+	cs.Add('a')
+	cs.Add('o')
+	if got := trie.FilterAny(&cs, 1); got != trie {
+		t.Errorf("FilterAny(ao, 1) = %v, want the same TrieWords", got)
+	}
+	if got := trie.RemoveWordOptions([]string{"dog"}); got != trie {
+		t.Errorf("RemoveWordOptions(dog) = %v, want the same TrieWords", got)
+	}
+
+	if got, ok := trie.Filter('o', 1).(*Definite); !ok || string(got.line.Line) != "cot" {
+		t.Errorf("Filter('o', 1) = %v, want Definite(cot)", trie.Filter('o', 1))
+	}
+	if got := trie.Filter('x', 2); !isActuallyImpossible(got) {
+		t.Errorf("Filter('x', 2) = %v, want Impossible", got)
+	}
+	if got := trie.Filter(Blocked, 2); !isActuallyImpossible(got) {
+		t.Errorf("Filter(Blocked, 2) = %v, want Impossible", got)
+	}
+	if got := MakeTrieWords(nil, nil, 3); !isActuallyImpossible(got) {
+		t.Errorf("MakeTrieWords(nil, nil) = %v, want Impossible", got)
+	}
+	if got := trie.String(); got != "TrieWords([car, cat], [cot])" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func BenchmarkTrieWords(b *testing.B) {
+	words := benchmarkWords(100_000)
+	trie := MakeTrieWords(words.allWords, nil, words.NumLetters())
+
+	var cs CharSet
+	for _, r := range "aeiou" {
+		cs.Add(r)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+	}{
+		{name: "Words", lines: words},
+		{name: "TrieWords", lines: trie},
+	} {
+		b.Run("Filter/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.Filter('b', 0)
+			}
+		})
+		b.Run("FilterLast/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.Filter('b', 6)
+			}
+		})
+		b.Run("FilterAny/"+tc.name, func(b *testing.B) {
+			for b.Loop() {
+				tc.lines.FilterAny(&cs, 1)
+			}
+		})
+	}
+}
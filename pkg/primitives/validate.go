@@ -0,0 +1,165 @@
+package primitives
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Validate checks the whole tree of p for the invariants that the constructors of each type keep,
+// e.g. that the words of a Words all have the same length, or that a Compound has no Impossible
+// possibilities, and returns an error for each node that breaks one. A tree built and filtered
+// only through this package is always valid, so any error is a bug.
+func Validate(p PossibleLines) []error {
+	var errs []error
+	validate(p, "", &errs)
+	return errs
+}
+
+// validate appends to errs the errors of p and the nodes below it. path describes where p is in
+// the tree being validated.
+func validate(p PossibleLines, path string, errs *[]error) {
+	if path == "" {
+		path = nodeName(p)
+	} else {
+		path += " > " + nodeName(p)
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	child := func(c PossibleLines, name string) {
+		if c == nil {
+			fail("%s is nil", name)
+			return
+		}
+		if isImpossible(c) {
+			fail("%s is Impossible, so the line should be too", name)
+		}
+		validate(c, path, errs)
+	}
+
+	switch p := p.(type) {
+	case *Impossible:
+		if p.numLetters < 0 {
+			fail("negative length %d", p.numLetters)
+		}
+	case *Words:
+		if len(p.allWords) < 2 {
+			fail("%d words, which should be Impossible or Definite", len(p.allWords))
+		}
+		if p.obscureIdx < 0 || p.obscureIdx > len(p.allWords) {
+			fail("obscure index %d is out of range for %d words", p.obscureIdx, len(p.allWords))
+		}
+		validateWords(p.allWords, fail)
+		if n := p.letterMasks.len(); n != 0 && n != p.NumLetters() {
+			fail("%d letter masks for %d letters", n, p.NumLetters())
+		}
+	case *SortedWords:
+		if n := len(p.preferred) + len(p.obscure); n < 2 {
+			fail("%d words, which should be Impossible or Definite", n)
+		}
+		for _, words := range [][]string{p.preferred, p.obscure} {
+			if !slices.IsSorted(words) {
+				fail("words are not sorted")
+			}
+			for _, word := range words {
+				if len(word) != p.numLetters {
+					fail("word %q does not have %d letters", word, p.numLetters)
+				}
+			}
+		}
+	case *TrieWords:
+		if n := p.MaxPossibilities(); n < 2 {
+			fail("%d words, which should be Impossible or Definite", n)
+		}
+		for word := range p.words() {
+			if len(word) != p.numLetters {
+				fail("word %q does not have %d letters", word, p.numLetters)
+			}
+		}
+	case *BlockBefore:
+		child(p.lines, "lines")
+	case *BlockAfter:
+		child(p.lines, "lines")
+	case *BlockBetween:
+		child(p.first, "first")
+		child(p.second, "second")
+		if p.first != nil && p.first.NumLetters() < 1 || p.second != nil && p.second.NumLetters() < 1 {
+			fail("a line on either side of the block is empty")
+		}
+	case *Concat:
+		child(p.first, "first")
+		child(p.second, "second")
+		if p.first != nil && p.first.NumLetters() < 1 || p.second != nil && p.second.NumLetters() < 1 {
+			fail("a line on either side is empty")
+		}
+	case *Compound:
+		if len(p.possibilities) < 2 {
+			fail("%d possibilities, which should not be a Compound", len(p.possibilities))
+		}
+		for i, c := range p.possibilities {
+			name := fmt.Sprintf("possibility %d", i)
+			if _, ok := c.(*Compound); ok {
+				fail("%s is a Compound, which should be flattened", name)
+			}
+			if c != nil && c.NumLetters() != p.possibilities[0].NumLetters() {
+				fail("%s has %d letters, but possibility 0 has %d", name, c.NumLetters(), p.possibilities[0].NumLetters())
+			}
+			child(c, name)
+		}
+	case *Definite:
+		if len(p.line.Words) == 0 {
+			fail("no words")
+		}
+		words := strings.FieldsFunc(string(p.line.Line), func(r rune) bool { return r == kBlocked })
+		if !slices.Equal(words, p.line.Words) {
+			fail("words %v do not match line %q", p.line.Words, string(p.line.Line))
+		}
+	case *Memoized:
+		if p.cache == nil {
+			fail("no cache")
+		}
+		if _, ok := p.lines.(*Memoized); ok {
+			fail("lines are Memoized again")
+		}
+		child(p.lines, "lines")
+	}
+}
+
+// validateWords calls fail for each word of words that does not have the length of the first.
+func validateWords(words []string, fail func(format string, args ...any)) {
+	for _, word := range words {
+		if len(word) != len(words[0]) {
+			fail("word %q has %d letters, but %q has %d", word, len(word), words[0], len(words[0]))
+		}
+	}
+}
+
+// nodeName returns the name of the type of p, for the paths of Validate errors.
+func nodeName(p PossibleLines) string {
+	switch p.(type) {
+	case *Impossible:
+		return "Impossible"
+	case *Words:
+		return "Words"
+	case *SortedWords:
+		return "SortedWords"
+	case *TrieWords:
+		return "TrieWords"
+	case *BlockBefore:
+		return "BlockBefore"
+	case *BlockAfter:
+		return "BlockAfter"
+	case *BlockBetween:
+		return "BlockBetween"
+	case *Concat:
+		return "Concat"
+	case *Compound:
+		return "Compound"
+	case *Definite:
+		return "Definite"
+	case *Memoized:
+		return "Memoized"
+	}
+	return fmt.Sprintf("%T", p)
+}
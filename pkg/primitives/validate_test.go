@@ -0,0 +1,83 @@
+package primitives
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	abc := MakeDefinite(ConcreteLine{Line: []rune("abc"), Words: []string{"abc"}})
+	for _, tc := range []struct {
+		name  string
+		lines PossibleLines
+		// want holds a substring of each error wanted, in order.
+		want []string
+	}{
+		{
+			name: "Valid",
+			lines: MemoizedFilter(MakeCompound([]PossibleLines{
+				MakeBlockBetween(MakeWords([]string{"ab", "cd"}, 1, 2), MakeWords([]string{"ef", "gh"}, 2, 2)),
+				MakeBlockBefore(MakeTrieWords([]string{"abcd"}, []string{"efgh"}, 4)),
+				MakeBlockAfter(MakeSortedWords([]string{"abcd", "efgh"}, 1)),
+			}, 5)),
+		},
+		{name: "Impossible", lines: MakeImpossible(3)},
+		{
+			name:  "WordsOfDifferentLengths",
+			lines: &Words{allWords: []string{"abc", "de"}, obscureIdx: 2},
+			want:  []string{`Words: word "de" has 2 letters, but "abc" has 3`},
+		},
+		{
+			name:  "ObscureIndexOutOfRange",
+			lines: &Words{allWords: []string{"abc", "def"}, obscureIdx: 3},
+			want:  []string{"Words: obscure index 3 is out of range for 2 words"},
+		},
+		{
+			name:  "UnsortedWords",
+			lines: &SortedWords{numLetters: 3, preferred: []string{"def", "abc"}},
+			want:  []string{"SortedWords: words are not sorted"},
+		},
+		{
+			name:  "ImpossibleBlockBetween",
+			lines: &BlockBetween{first: abc, second: MakeImpossible(2)},
+			want:  []string{"BlockBetween: second is Impossible"},
+		},
+		{
+			name:  "EmptyBlockBetween",
+			lines: &BlockBetween{first: abc, second: MakeImpossible(0)},
+			want:  []string{"BlockBetween: second is Impossible", "BlockBetween: a line on either side of the block is empty"},
+		},
+		{
+			name:  "CompoundWithImpossible",
+			lines: &Compound{possibilities: []PossibleLines{abc, MakeImpossible(3)}},
+			want:  []string{"Compound: possibility 1 is Impossible"},
+		},
+		{
+			name:  "CompoundOfDifferentLengths",
+			lines: &Compound{possibilities: []PossibleLines{abc, MakeWords([]string{"ab", "cd"}, 2, 2)}},
+			want:  []string{"Compound: possibility 1 has 2 letters, but possibility 0 has 3"},
+		},
+		{
+			name:  "DefiniteWithoutWords",
+			lines: MakeBlockBefore(MakeDefinite(ConcreteLine{Line: []rune("abc")})),
+			want:  []string{"BlockBefore > Definite: no words", `BlockBefore > Definite: words [] do not match line "abc"`},
+		},
+		{
+			name:  "DefiniteWithOtherWords",
+			lines: MakeDefinite(ConcreteLine{Line: []rune("ab`c"), Words: []string{"abc"}}),
+			want:  []string{"Definite: words [abc] do not match line \"ab`c\""},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := Validate(tc.lines)
+			if len(errs) != len(tc.want) {
+				t.Fatalf("Validate() = %v, want %d errors", errs, len(tc.want))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tc.want[i]) {
+					t.Errorf("Validate() error %d = %q, want it to contain %q", i, err, tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package render draws grids for display in a terminal.
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Eyas/xwgen"
+)
+
+// ANSI escape sequences used by Colored.
+const (
+	reset   = "\x1b[0m"
+	reverse = "\x1b[7m"
+	yellow  = "\x1b[33m"
+)
+
+// IsColorTerminal reports whether f is a terminal and the NO_COLOR environment variable is not set,
+// i.e. whether grids written to f should be Colored by default.
+func IsColorTerminal(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Colored writes grid to w with ANSI colors, followed by a legend: blocked cells are drawn as
+// reverse-video blocks, and letters of any entry in obscureEntries are yellow. Other letters are
+// drawn in the terminal's default color.
+func Colored(w io.Writer, grid xwgen.Grid, obscureEntries []string) error {
+	obscure := make(map[string]bool, len(obscureEntries))
+	for _, entry := range obscureEntries {
+		obscure[entry] = true
+	}
+
+	width, height := grid.Size()
+	highlighted := make([][]bool, height)
+	for row := range highlighted {
+		highlighted[row] = make([]bool, width)
+	}
+	// Mark every cell of each obscure entry.
+	for _, entry := range grid.Entries() {
+		if !obscure[entry.Answer] {
+			continue
+		}
+		for _, c := range entryCells(entry) {
+			highlighted[c[0]][c[1]] = true
+		}
+	}
+
+	var b strings.Builder
+	for row := range height {
+		for col := range width {
+			switch {
+			case grid.IsBlocked(row, col):
+				b.WriteString(reverse + " " + reset)
+			case highlighted[row][col]:
+				b.WriteString(yellow + string(grid.Cell(row, col)) + reset)
+			default:
+				b.WriteRune(grid.Cell(row, col))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%s %s blocked  %sa%s obscure entry\n", reverse, reset, yellow, reset)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// entryCells returns the (row, col) cells of entry, from its first letter.
+func entryCells(entry xwgen.Entry) [][2]int {
+	cells := make([][2]int, entry.Length)
+	for i := range cells {
+		cells[i] = [2]int{entry.Row, entry.Col + i}
+		if entry.Direction == xwgen.DirectionVertical {
+			cells[i] = [2]int{entry.Row + i, entry.Col}
+		}
+	}
+	return cells
+}
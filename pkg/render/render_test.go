@@ -0,0 +1,72 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Eyas/xwgen"
+)
+
+func TestColored(t *testing.T) {
+	grid := xwgen.NewGrid([][]rune{
+		[]rune("ab`"),
+		[]rune("cde"),
+		[]rune("`fg"),
+	})
+
+	for _, tc := range []struct {
+		name    string
+		obscure []string
+		want    []string
+	}{
+		{
+			name: "no obscure entries",
+			want: []string{
+				"ab\x1b[7m \x1b[0m",
+				"cde",
+				"\x1b[7m \x1b[0mfg",
+			},
+		},
+		{
+			name:    "obscure across and down entries",
+			obscure: []string{"cde", "bdf"},
+			want: []string{
+				"a\x1b[33mb\x1b[0m\x1b[7m \x1b[0m",
+				"\x1b[33mc\x1b[0m\x1b[33md\x1b[0m\x1b[33me\x1b[0m",
+				"\x1b[7m \x1b[0m\x1b[33mf\x1b[0mg",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Colored(&b, grid, tc.obscure); err != nil {
+				t.Fatalf("Colored() error: %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			if len(lines) != 4 {
+				t.Fatalf("Colored() wrote %d lines, want 3 rows and a legend:\n%q", len(lines), b.String())
+			}
+			for i, want := range tc.want {
+				if lines[i] != want {
+					t.Errorf("row %d = %q, want %q", i, lines[i], want)
+				}
+			}
+			if legend := lines[3]; !strings.Contains(legend, "blocked") || !strings.Contains(legend, "obscure") {
+				t.Errorf("legend = %q, want it to explain blocked cells and obscure entries", legend)
+			}
+		})
+	}
+}
+
+func TestIsColorTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "grid.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsColorTerminal(f) {
+		t.Error("IsColorTerminal() of a regular file = true, want false")
+	}
+}
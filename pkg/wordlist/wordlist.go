@@ -0,0 +1,128 @@
+// Package wordlist reads the word lists that grids are filled from.
+//
+// A word list has one word per line, optionally followed by a tab and a frequency score, e.g.
+//
+//	otter	52.5
+//	aloe
+//
+// Words are case-insensitive, and are returned in lowercase. Blank lines and lines starting with
+// '#' are skipped.
+package wordlist
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Read reads the word list in r. Words longer than maxLength letters are skipped, unless maxLength
+// is 0. scores holds the frequency score of each word that has one.
+//
+// It returns an error if a word has any character other than a letter, or a score is not a number.
+func Read(r io.Reader, maxLength int) (words []string, scores map[string]float64, err error) {
+	return ReadContext(context.Background(), r, 0, maxLength)
+}
+
+// ReadContext is like Read, but also skips words shorter than minLength letters, and stops with
+// ctx's error once ctx is done. Skipped words are not checked, so they cannot cause an error.
+func ReadContext(ctx context.Context, r io.Reader, minLength, maxLength int) (words []string, scores map[string]float64, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		word, score, hasScore := strings.Cut(text, "\t")
+		word = strings.ToLower(strings.TrimSpace(word))
+		if len(word) < minLength || (maxLength > 0 && len(word) > maxLength) {
+			continue
+		}
+		for _, r := range word {
+			if r < 'a' || r > 'z' {
+				return nil, nil, fmt.Errorf("line %d: word %s contains non-lowercase letter %q", line, word, r)
+			}
+		}
+		if hasScore {
+			f, err := strconv.ParseFloat(strings.TrimSpace(score), 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: invalid frequency score for %s: %w", line, word, err)
+			}
+			if scores == nil {
+				scores = make(map[string]float64)
+			}
+			scores[word] = f
+		}
+		words = append(words, word)
+	}
+	return words, scores, scanner.Err()
+}
+
+// LoadWordsFromFile loads the words in the word list at path that fit in a grid with sides of
+// sideLength, or all words if sideLength is 0. The words are returned as preferred words, or as
+// obscure words if obscure is true. Frequency scores are ignored.
+func LoadWordsFromFile(path string, obscure bool, sideLength int) (preferredWords, obscureWords []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	words, _, err := Read(f, sideLength)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if obscure {
+		return nil, words, nil
+	}
+	return words, nil, nil
+}
+
+// LoadExcludedWords loads every word in the word list at path, e.g. to pass as the excluded words
+// of a generator. A file that does not exist yet has no words, so that a list kept with
+// AppendExcludedWords can be loaded before anything is added to it. Frequency scores are ignored.
+func LoadExcludedWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words, _, err := Read(f, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return words, nil
+}
+
+// AppendExcludedWords adds words to the end of the word list at path, one per line, creating the
+// file if it does not exist, e.g. to keep later runs from using the words of the grids generated
+// so far.
+func AppendExcludedWords(path string, words []string) error {
+	var b strings.Builder
+	for _, word := range words {
+		b.WriteString(word)
+		b.WriteByte('\n')
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
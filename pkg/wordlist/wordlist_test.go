@@ -0,0 +1,122 @@
+package wordlist
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	input := "# A comment\r\nOtter\t52.5\r\naloe\r\n\r\n  ends \t 3 \nabracadabra\t1\n"
+	for _, tc := range []struct {
+		maxLength  int
+		wantWords  []string
+		wantScores map[string]float64
+	}{
+		{maxLength: 0, wantWords: []string{"otter", "aloe", "ends", "abracadabra"}, wantScores: map[string]float64{"otter": 52.5, "ends": 3, "abracadabra": 1}},
+		{maxLength: 5, wantWords: []string{"otter", "aloe", "ends"}, wantScores: map[string]float64{"otter": 52.5, "ends": 3}},
+	} {
+		words, scores, err := Read(strings.NewReader(input), tc.maxLength)
+		if err != nil {
+			t.Fatalf("Read(maxLength=%d) error: %v", tc.maxLength, err)
+		}
+		if !slices.Equal(words, tc.wantWords) {
+			t.Errorf("Read(maxLength=%d) words = %v, want %v", tc.maxLength, words, tc.wantWords)
+		}
+		if !maps.Equal(scores, tc.wantScores) {
+			t.Errorf("Read(maxLength=%d) scores = %v, want %v", tc.maxLength, scores, tc.wantScores)
+		}
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"otter\nal0e\n",
+		"otter\tlots\n",
+	} {
+		if _, _, err := Read(strings.NewReader(input), 0); err == nil {
+			t.Errorf("Read(%q) returned no error", input)
+		}
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	if _, _, err := ReadContext(context.Background(), strings.NewReader("a1\nal0e\n"), 4, 0); err == nil {
+		t.Errorf("ReadContext() of an invalid kept word returned no error")
+	}
+	// Words too short to keep, like "a1", are skipped before they are checked.
+	words, _, err := ReadContext(context.Background(), strings.NewReader("a1\notter\naloe\n"), 4, 4)
+	if err != nil {
+		t.Fatalf("ReadContext() error: %v", err)
+	}
+	if want := []string{"aloe"}; !slices.Equal(words, want) {
+		t.Errorf("ReadContext() = %v, want %v", words, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ReadContext(ctx, strings.NewReader("otter\n"), 0, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadContext() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLoadWordsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("aloe\t2\notter\t1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	preferred, obscure, err := LoadWordsFromFile(path, false, 4)
+	if err != nil {
+		t.Fatalf("LoadWordsFromFile() error: %v", err)
+	}
+	if !slices.Equal(preferred, []string{"aloe"}) || obscure != nil {
+		t.Errorf("LoadWordsFromFile() = %v, %v, want [aloe], []", preferred, obscure)
+	}
+
+	preferred, obscure, err = LoadWordsFromFile(path, true, 0)
+	if err != nil {
+		t.Fatalf("LoadWordsFromFile() error: %v", err)
+	}
+	if preferred != nil || !slices.Equal(obscure, []string{"aloe", "otter"}) {
+		t.Errorf("LoadWordsFromFile(obscure) = %v, %v, want [], [aloe otter]", preferred, obscure)
+	}
+
+	if _, _, err := LoadWordsFromFile(filepath.Join(t.TempDir(), "missing.txt"), false, 0); err == nil {
+		t.Error("LoadWordsFromFile() of a missing file returned no error")
+	}
+}
+
+func TestExcludedWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "excluded.txt")
+	words, err := LoadExcludedWords(path)
+	if err != nil || words != nil {
+		t.Fatalf("LoadExcludedWords() of a missing file = %v, %v, want no words", words, err)
+	}
+
+	if err := AppendExcludedWords(path, []string{"aloe", "otter"}); err != nil {
+		t.Fatalf("AppendExcludedWords() error: %v", err)
+	}
+	if err := AppendExcludedWords(path, []string{"abracadabra"}); err != nil {
+		t.Fatalf("AppendExcludedWords() error: %v", err)
+	}
+	words, err = LoadExcludedWords(path)
+	if err != nil {
+		t.Fatalf("LoadExcludedWords() error: %v", err)
+	}
+	if want := []string{"aloe", "otter", "abracadabra"}; !slices.Equal(words, want) {
+		t.Errorf("LoadExcludedWords() = %v, want %v", words, want)
+	}
+
+	if err := os.WriteFile(path, []byte("al0e\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadExcludedWords(path); err == nil {
+		t.Error("LoadExcludedWords() of an invalid file returned no error")
+	}
+}
@@ -0,0 +1,161 @@
+// Package xwserver serves crossword grids over gRPC, streaming each grid to the client as soon as
+// the generator finds it. See xwserver.proto for the service definition.
+package xwserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative xwserver.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Eyas/xwgen"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+const (
+	// DefaultMaxTimeout is the MaxTimeout of servers returned by NewServer.
+	DefaultMaxTimeout = time.Minute
+	// DefaultMaxSize is the MaxSize of servers returned by NewServer.
+	DefaultMaxSize = 21
+)
+
+// Server implements CrosswordService with a new generator for each request.
+type Server struct {
+	UnimplementedCrosswordServiceServer
+
+	// MaxTimeout bounds how long the search of each request runs, even if its client never cancels
+	// it. 0 means no bound.
+	MaxTimeout time.Duration
+	// MaxSize is the width and height of the largest grids that can be requested. 0 means no bound.
+	MaxSize int
+
+	preferred, obscure, excluded []string
+}
+
+// NewServer returns a server that fills grids from the given word lists, unless a request has its
+// own, with a MaxTimeout of DefaultMaxTimeout and a MaxSize of DefaultMaxSize.
+func NewServer(preferred, obscure, excluded []string) *Server {
+	return &Server{
+		MaxTimeout: DefaultMaxTimeout,
+		MaxSize:    DefaultMaxSize,
+		preferred:  preferred,
+		obscure:    obscure,
+		excluded:   excluded,
+	}
+}
+
+// GenerateGrids streams the grids of a generator created from req. It ends once req.Count grids
+// have been sent, the search is exhausted, s.MaxTimeout passes, or the client cancels the stream.
+func (s *Server) GenerateGrids(req *GenerateRequest, stream CrosswordService_GenerateGridsServer) error {
+	opts, err := s.options(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	gen, err := xwgen.CreateGeneratorE(int(req.GetWidth()), opts...)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	if s.MaxTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.MaxTimeout)
+		defer cancel()
+	}
+	sent := int32(0)
+	for grid := range gen.PossibleGrids(ctx) {
+		if err := stream.Send(&GenerateResponse{Grid: GridToProto(grid)}); err != nil {
+			return err
+		}
+		if sent++; req.GetCount() > 0 && sent >= req.GetCount() {
+			return nil
+		}
+	}
+	switch err := gen.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, xwgen.ErrNoGridsPossible):
+		return status.Error(codes.NotFound, err.Error())
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// options returns the options of the generator for req.
+func (s *Server) options(req *GenerateRequest) ([]xwgen.GeneratorOption, error) {
+	if req.GetWidth() < 1 || req.GetHeight() < 0 {
+		return nil, errors.New("the width must be at least 1, and the height at least 0")
+	}
+	height := req.GetHeight()
+	if height == 0 {
+		height = req.GetWidth()
+	}
+	if s.MaxSize > 0 && int(max(req.GetWidth(), height)) > s.MaxSize {
+		return nil, fmt.Errorf("the width and height must be at most %d", s.MaxSize)
+	}
+
+	preferred, obscure, excluded := s.preferred, s.obscure, s.excluded
+	if len(req.GetPreferredWords()) > 0 {
+		preferred, obscure, excluded = req.GetPreferredWords(), req.GetObscureWords(), req.GetExcludedWords()
+	}
+	opts := []xwgen.GeneratorOption{
+		xwgen.WithPreferredWords(preferred),
+		xwgen.WithObscureWords(obscure),
+		xwgen.WithExcludedWords(excluded),
+		xwgen.WithHeight(int(height)),
+		xwgen.WithMaxWordLength(int(max(req.GetWidth(), height))),
+	}
+	if n := req.GetMinWordLength(); n > 0 {
+		opts = append(opts, xwgen.WithMinWordLength(int(n)))
+	}
+	if req.MaxBlocks != nil {
+		opts = append(opts, xwgen.WithMaxBlocks(int(req.GetMaxBlocks())))
+	}
+	for _, name := range strings.Split(req.GetSymmetry(), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts = append(opts, xwgen.WithSymmetry(name))
+		}
+	}
+	if seed := req.GetSeed(); seed != 0 {
+		opts = append(opts, xwgen.WithSeed(seed, seed))
+	}
+	return opts, nil
+}
+
+// GridToProto returns the message for grid, where blocked cells are xwgen.CellBlocked.
+func GridToProto(grid xwgen.Grid) *Grid {
+	g := &Grid{
+		WordsAcross:  grid.WordsAcross(),
+		WordsDown:    grid.WordsDown(),
+		ObscureWords: grid.ObscureWords(),
+	}
+	for y := range grid.Height() {
+		var row strings.Builder
+		for x := range grid.Width() {
+			if r := grid.Get(x, y); r == primitives.Blocked {
+				row.WriteRune(xwgen.CellBlocked)
+			} else {
+				row.WriteRune(r)
+			}
+		}
+		g.Rows = append(g.Rows, row.String())
+	}
+	return g
+}
+
+// GridFromProto returns the grid of a message. Only its cells are kept, so the grid has no words.
+func GridFromProto(g *Grid) xwgen.Grid {
+	rows := make([][]rune, len(g.GetRows()))
+	for y, row := range g.GetRows() {
+		rows[y] = []rune(strings.ReplaceAll(row, string(xwgen.CellBlocked), string(primitives.Blocked)))
+	}
+	return xwgen.NewGrid(rows)
+}
@@ -0,0 +1,140 @@
+package xwserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Eyas/xwgen"
+)
+
+var words = []string{"abc", "def", "ghi", "adg", "beh", "cfi"}
+
+// newClient returns a client of server.
+func newClient(t *testing.T, server *Server) CrosswordServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterCrosswordServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewCrosswordServiceClient(conn)
+}
+
+// receive returns the grids streamed in response to req, and the error that ended the stream.
+func receive(t *testing.T, client CrosswordServiceClient, req *GenerateRequest) ([]*Grid, error) {
+	stream, err := client.GenerateGrids(t.Context(), req)
+	if err != nil {
+		t.Fatalf("GenerateGrids() error: %v", err)
+	}
+	var grids []*Grid
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return grids, nil
+		}
+		if err != nil {
+			return grids, err
+		}
+		grids = append(grids, resp.GetGrid())
+	}
+}
+
+func TestGenerateGrids(t *testing.T) {
+	client := newClient(t, NewServer(words, nil, nil))
+
+	gen, err := xwgen.CreateGeneratorE(3, xwgen.WithPreferredWords(words), xwgen.WithSeed(1, 1), xwgen.WithMaxWordLength(3))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	var want []*Grid
+	for grid := range gen.PossibleGrids(t.Context()) {
+		want = append(want, GridToProto(grid))
+	}
+
+	got, err := receive(t, client, &GenerateRequest{Width: 3, Seed: 1})
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if !slices.EqualFunc(got, want, func(a, b *Grid) bool { return proto.Equal(a, b) }) {
+		t.Errorf("streamed grids %v, want %v", got, want)
+	}
+	for _, g := range got {
+		if repr := GridFromProto(g).Repr(); repr != "abc\ndef\nghi" && repr != "adg\nbeh\ncfi" {
+			t.Errorf("GridFromProto(%v).Repr() = %q", g, repr)
+		}
+	}
+
+	if got, err := receive(t, client, &GenerateRequest{Width: 3, Seed: 1, Count: 1}); err != nil || len(got) != 1 {
+		t.Errorf("GenerateGrids() with a count of 1 streamed %d grids, error %v", len(got), err)
+	}
+	if _, err := receive(t, client, &GenerateRequest{Width: 3, PreferredWords: []string{"abc"}, MaxBlocks: proto.Int32(0)}); status.Code(err) != codes.NotFound {
+		t.Errorf("GenerateGrids() with no possible grids = %v, want NotFound", err)
+	}
+	if _, err := receive(t, client, &GenerateRequest{Width: 0}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GenerateGrids() with a width of 0 = %v, want InvalidArgument", err)
+	}
+	if _, err := receive(t, client, &GenerateRequest{Width: 3, Symmetry: "diagonal"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GenerateGrids() with an unknown symmetry = %v, want InvalidArgument", err)
+	}
+	if _, err := receive(t, client, &GenerateRequest{Width: 3, Height: DefaultMaxSize + 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GenerateGrids() taller than the MaxSize = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGenerateGrids_MaxTimeout(t *testing.T) {
+	server := NewServer(words, nil, nil)
+	server.MaxTimeout = time.Nanosecond
+	client := newClient(t, server)
+	if _, err := receive(t, client, &GenerateRequest{Width: 3, Seed: 1}); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("GenerateGrids() past the MaxTimeout = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestGenerateGrids_Cancel(t *testing.T) {
+	// Every three-letter word of the first six letters, which fill far more grids than are read.
+	var many []string
+	for _, a := range "abcdef" {
+		for _, b := range "abcdef" {
+			for _, c := range "abcdef" {
+				many = append(many, string([]rune{a, b, c}))
+			}
+		}
+	}
+	client := newClient(t, NewServer(many, nil, nil))
+	ctx, cancel := context.WithCancel(t.Context())
+	stream, err := client.GenerateGrids(ctx, &GenerateRequest{Width: 3, Seed: 1})
+	if err != nil {
+		t.Fatalf("GenerateGrids() error: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv() error: %v", err)
+	}
+	cancel()
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if status.Code(err) != codes.Canceled {
+				t.Errorf("Recv() after cancelling = %v, want Canceled", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: xwserver.proto
+
+package xwserver
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GenerateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The width of the grid, and its height, which defaults to the width.
+	Width  int32 `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height int32 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	// The word lists. If preferred_words is empty, the server's own word lists are used.
+	PreferredWords []string `protobuf:"bytes,3,rep,name=preferred_words,json=preferredWords,proto3" json:"preferred_words,omitempty"`
+	ObscureWords   []string `protobuf:"bytes,4,rep,name=obscure_words,json=obscureWords,proto3" json:"obscure_words,omitempty"`
+	ExcludedWords  []string `protobuf:"bytes,5,rep,name=excluded_words,json=excludedWords,proto3" json:"excluded_words,omitempty"`
+	// The minimum word length, which defaults to 3.
+	MinWordLength int32 `protobuf:"varint,6,opt,name=min_word_length,json=minWordLength,proto3" json:"min_word_length,omitempty"`
+	// The maximum number of blocked cells per grid, if set; 0 for word squares.
+	MaxBlocks *int32 `protobuf:"varint,7,opt,name=max_blocks,json=maxBlocks,proto3,oneof" json:"max_blocks,omitempty"`
+	// Comma-separated symmetries the blocked cells must have: "rotational", "vertical", and/or
+	// "horizontal".
+	Symmetry string `protobuf:"bytes,8,opt,name=symmetry,proto3" json:"symmetry,omitempty"`
+	// The random seed, or 0 for a time-based seed.
+	Seed uint64 `protobuf:"varint,9,opt,name=seed,proto3" json:"seed,omitempty"`
+	// The number of grids to generate, or 0 for no limit.
+	Count         int32 `protobuf:"varint,10,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	mi := &file_xwserver_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xwserver_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_xwserver_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GenerateRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetPreferredWords() []string {
+	if x != nil {
+		return x.PreferredWords
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetObscureWords() []string {
+	if x != nil {
+		return x.ObscureWords
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetExcludedWords() []string {
+	if x != nil {
+		return x.ExcludedWords
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetMinWordLength() int32 {
+	if x != nil {
+		return x.MinWordLength
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetMaxBlocks() int32 {
+	if x != nil && x.MaxBlocks != nil {
+		return *x.MaxBlocks
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetSymmetry() string {
+	if x != nil {
+		return x.Symmetry
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetSeed() uint64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type GenerateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grid          *Grid                  `protobuf:"bytes,1,opt,name=grid,proto3" json:"grid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateResponse) Reset() {
+	*x = GenerateResponse{}
+	mi := &file_xwserver_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateResponse) ProtoMessage() {}
+
+func (x *GenerateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xwserver_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateResponse.ProtoReflect.Descriptor instead.
+func (*GenerateResponse) Descriptor() ([]byte, []int) {
+	return file_xwserver_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GenerateResponse) GetGrid() *Grid {
+	if x != nil {
+		return x.Grid
+	}
+	return nil
+}
+
+type Grid struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The rows of the grid from top to bottom, where '#' is a blocked cell.
+	Rows        []string `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	WordsAcross []string `protobuf:"bytes,2,rep,name=words_across,json=wordsAcross,proto3" json:"words_across,omitempty"`
+	WordsDown   []string `protobuf:"bytes,3,rep,name=words_down,json=wordsDown,proto3" json:"words_down,omitempty"`
+	// The words of the grid that are only in the obscure word list.
+	ObscureWords  []string `protobuf:"bytes,4,rep,name=obscure_words,json=obscureWords,proto3" json:"obscure_words,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Grid) Reset() {
+	*x = Grid{}
+	mi := &file_xwserver_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Grid) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Grid) ProtoMessage() {}
+
+func (x *Grid) ProtoReflect() protoreflect.Message {
+	mi := &file_xwserver_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Grid.ProtoReflect.Descriptor instead.
+func (*Grid) Descriptor() ([]byte, []int) {
+	return file_xwserver_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Grid) GetRows() []string {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *Grid) GetWordsAcross() []string {
+	if x != nil {
+		return x.WordsAcross
+	}
+	return nil
+}
+
+func (x *Grid) GetWordsDown() []string {
+	if x != nil {
+		return x.WordsDown
+	}
+	return nil
+}
+
+func (x *Grid) GetObscureWords() []string {
+	if x != nil {
+		return x.ObscureWords
+	}
+	return nil
+}
+
+var File_xwserver_proto protoreflect.FileDescriptor
+
+const file_xwserver_proto_rawDesc = "" +
+	"\n" +
+	"\x0exwserver.proto\x12\bxwserver\"\xd5\x02\n" +
+	"\x0fGenerateRequest\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\x12'\n" +
+	"\x0fpreferred_words\x18\x03 \x03(\tR\x0epreferredWords\x12#\n" +
+	"\robscure_words\x18\x04 \x03(\tR\fobscureWords\x12%\n" +
+	"\x0eexcluded_words\x18\x05 \x03(\tR\rexcludedWords\x12&\n" +
+	"\x0fmin_word_length\x18\x06 \x01(\x05R\rminWordLength\x12\"\n" +
+	"\n" +
+	"max_blocks\x18\a \x01(\x05H\x00R\tmaxBlocks\x88\x01\x01\x12\x1a\n" +
+	"\bsymmetry\x18\b \x01(\tR\bsymmetry\x12\x12\n" +
+	"\x04seed\x18\t \x01(\x04R\x04seed\x12\x14\n" +
+	"\x05count\x18\n" +
+	" \x01(\x05R\x05countB\r\n" +
+	"\v_max_blocks\"6\n" +
+	"\x10GenerateResponse\x12\"\n" +
+	"\x04grid\x18\x01 \x01(\v2\x0e.xwserver.GridR\x04grid\"\x81\x01\n" +
+	"\x04Grid\x12\x12\n" +
+	"\x04rows\x18\x01 \x03(\tR\x04rows\x12!\n" +
+	"\fwords_across\x18\x02 \x03(\tR\vwordsAcross\x12\x1d\n" +
+	"\n" +
+	"words_down\x18\x03 \x03(\tR\twordsDown\x12#\n" +
+	"\robscure_words\x18\x04 \x03(\tR\fobscureWords2\\\n" +
+	"\x10CrosswordService\x12H\n" +
+	"\rGenerateGrids\x12\x19.xwserver.GenerateRequest\x1a\x1a.xwserver.GenerateResponse0\x01B$Z\"github.com/Eyas/xwgen/pkg/xwserverb\x06proto3"
+
+var (
+	file_xwserver_proto_rawDescOnce sync.Once
+	file_xwserver_proto_rawDescData []byte
+)
+
+func file_xwserver_proto_rawDescGZIP() []byte {
+	file_xwserver_proto_rawDescOnce.Do(func() {
+		file_xwserver_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_xwserver_proto_rawDesc), len(file_xwserver_proto_rawDesc)))
+	})
+	return file_xwserver_proto_rawDescData
+}
+
+var file_xwserver_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_xwserver_proto_goTypes = []any{
+	(*GenerateRequest)(nil),  // 0: xwserver.GenerateRequest
+	(*GenerateResponse)(nil), // 1: xwserver.GenerateResponse
+	(*Grid)(nil),             // 2: xwserver.Grid
+}
+var file_xwserver_proto_depIdxs = []int32{
+	2, // 0: xwserver.GenerateResponse.grid:type_name -> xwserver.Grid
+	0, // 1: xwserver.CrosswordService.GenerateGrids:input_type -> xwserver.GenerateRequest
+	1, // 2: xwserver.CrosswordService.GenerateGrids:output_type -> xwserver.GenerateResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_xwserver_proto_init() }
+func file_xwserver_proto_init() {
+	if File_xwserver_proto != nil {
+		return
+	}
+	file_xwserver_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_xwserver_proto_rawDesc), len(file_xwserver_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_xwserver_proto_goTypes,
+		DependencyIndexes: file_xwserver_proto_depIdxs,
+		MessageInfos:      file_xwserver_proto_msgTypes,
+	}.Build()
+	File_xwserver_proto = out.File
+	file_xwserver_proto_goTypes = nil
+	file_xwserver_proto_depIdxs = nil
+}
@@ -0,0 +1,132 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: xwserver.proto
+
+package xwserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CrosswordService_GenerateGrids_FullMethodName = "/xwserver.CrosswordService/GenerateGrids"
+)
+
+// CrosswordServiceClient is the client API for CrosswordService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CrosswordService generates crossword grids.
+type CrosswordServiceClient interface {
+	// GenerateGrids streams each grid as it is found, until the request's count is reached, the
+	// search is exhausted, or the client cancels the stream.
+	GenerateGrids(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerateResponse], error)
+}
+
+type crosswordServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrosswordServiceClient(cc grpc.ClientConnInterface) CrosswordServiceClient {
+	return &crosswordServiceClient{cc}
+}
+
+func (c *crosswordServiceClient) GenerateGrids(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerateResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CrosswordService_ServiceDesc.Streams[0], CrosswordService_GenerateGrids_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GenerateRequest, GenerateResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrosswordService_GenerateGridsClient = grpc.ServerStreamingClient[GenerateResponse]
+
+// CrosswordServiceServer is the server API for CrosswordService service.
+// All implementations must embed UnimplementedCrosswordServiceServer
+// for forward compatibility.
+//
+// CrosswordService generates crossword grids.
+type CrosswordServiceServer interface {
+	// GenerateGrids streams each grid as it is found, until the request's count is reached, the
+	// search is exhausted, or the client cancels the stream.
+	GenerateGrids(*GenerateRequest, grpc.ServerStreamingServer[GenerateResponse]) error
+	mustEmbedUnimplementedCrosswordServiceServer()
+}
+
+// UnimplementedCrosswordServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCrosswordServiceServer struct{}
+
+func (UnimplementedCrosswordServiceServer) GenerateGrids(*GenerateRequest, grpc.ServerStreamingServer[GenerateResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method GenerateGrids not implemented")
+}
+func (UnimplementedCrosswordServiceServer) mustEmbedUnimplementedCrosswordServiceServer() {}
+func (UnimplementedCrosswordServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeCrosswordServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrosswordServiceServer will
+// result in compilation errors.
+type UnsafeCrosswordServiceServer interface {
+	mustEmbedUnimplementedCrosswordServiceServer()
+}
+
+func RegisterCrosswordServiceServer(s grpc.ServiceRegistrar, srv CrosswordServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCrosswordServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CrosswordService_ServiceDesc, srv)
+}
+
+func _CrosswordService_GenerateGrids_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrosswordServiceServer).GenerateGrids(m, &grpc.GenericServerStream[GenerateRequest, GenerateResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrosswordService_GenerateGridsServer = grpc.ServerStreamingServer[GenerateResponse]
+
+// CrosswordService_ServiceDesc is the grpc.ServiceDesc for CrosswordService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CrosswordService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xwserver.CrosswordService",
+	HandlerType: (*CrosswordServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GenerateGrids",
+			Handler:       _CrosswordService_GenerateGrids_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "xwserver.proto",
+}
@@ -0,0 +1,217 @@
+package xwgen
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// Progress tracks a running search so that it can be monitored from another goroutine, e.g. to
+// periodically report on long searches. Pass it to a generator with WithProgress.
+//
+// The zero value is ready to use.
+type Progress struct {
+	nodesExplored atomic.Int64
+	backtracks    atomic.Int64
+	pruned        atomic.Int64
+	depth         atomic.Int64
+
+	best bestPartial
+}
+
+// ProgressSnapshot is the state of a Progress at a point in time.
+type ProgressSnapshot struct {
+	// NodesExplored is the number of points in the search tree visited so far.
+	NodesExplored int64
+	// Backtracks is the number of choices undone without leading to any grid so far.
+	Backtracks int64
+	// Pruned is the number of subtrees cut from the search because they cannot lead to a grid.
+	Pruned int64
+	// Depth is the current number of choices made.
+	Depth int64
+
+	// BestPartial is the partial grid with the most decided cells seen so far, in the same format
+	// accepted by PossibleGridsFrom, i.e. undecided cells are CellUnknown and blocked cells are
+	// CellBlocked. It is nil if no partial grid has been seen yet.
+	BestPartial [][]rune
+	// BestPartialCells is the number of decided cells in BestPartial.
+	BestPartialCells int
+}
+
+// WithProgress reports the progress of searches performed by the generator to p.
+func WithProgress(p *Progress) GeneratorOption {
+	return func(g *Generator) error {
+		g.progress = p
+		return nil
+	}
+}
+
+// progressCallbackInterval is the number of nodes explored between calls to a progress callback.
+const progressCallbackInterval = 1000
+
+// progressCallback counts nodes for a callback passed to WithProgressCallback.
+type progressCallback struct {
+	fn       func(explored, pruned int64)
+	explored atomic.Int64
+	pruned   atomic.Int64
+}
+
+// WithProgressCallback calls fn every 1000 nodes explored by the generator's searches, with the
+// running number of nodes explored and subtrees pruned so far. It is a lighter alternative to
+// WithProgress when all that is needed is a periodic heartbeat.
+//
+// fn is called on the goroutine doing the search, which waits for it to return, so fn should
+// return quickly, e.g. by only recording the counts or sending them without blocking. With
+// WithWorkers, fn may be called concurrently.
+func WithProgressCallback(fn func(explored, pruned int64)) GeneratorOption {
+	return func(g *Generator) error {
+		if fn == nil {
+			return fmt.Errorf("progress callback must not be nil")
+		}
+		g.progressCallback = &progressCallback{fn: fn}
+		return nil
+	}
+}
+
+// nodeExplored counts a node, and calls the callback if it is due.
+func (c *progressCallback) nodeExplored() {
+	if n := c.explored.Add(1); n%progressCallbackInterval == 0 {
+		c.fn(n, c.pruned.Load())
+	}
+}
+
+// Snapshot returns the current state of the search. It is safe to call concurrently with the
+// search.
+func (p *Progress) Snapshot() ProgressSnapshot {
+	s := ProgressSnapshot{
+		NodesExplored: p.nodesExplored.Load(),
+		Backtracks:    p.backtracks.Load(),
+		Pruned:        p.pruned.Load(),
+		Depth:         p.depth.Load(),
+	}
+
+	s.BestPartial, s.BestPartialCells = p.best.get()
+	return s
+}
+
+// bestPartial tracks the partial grid with the most decided cells seen by a search, where a cell
+// is decided once a line through it has a single possibility left. The zero value is ready to use,
+// and it is safe to use concurrently.
+type bestPartial struct {
+	// cells is the number of decided cells in grid. It is read without the lock, so that points
+	// of the search that are not a new best only cost counting their decided lines.
+	cells atomic.Int64
+
+	mu   sync.Mutex
+	grid [][]rune
+}
+
+// observe records the partial grid at state if it has more decided cells than any seen before.
+func (b *bestPartial) observe(state *gridState) {
+	cells := int64(decidedCells(state))
+	if cells <= b.cells.Load() {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Another search may have recorded a better grid since the check above.
+	if cells <= b.cells.Load() {
+		return
+	}
+	b.grid = partialGrid(state)
+	b.cells.Store(cells)
+}
+
+// get returns a copy of the best partial grid and its number of decided cells, or nil and 0 if
+// none has been seen.
+func (b *bestPartial) get() ([][]rune, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.grid == nil {
+		return nil, 0
+	}
+	grid := make([][]rune, len(b.grid))
+	for i, row := range b.grid {
+		grid[i] = slices.Clone(row)
+	}
+	return grid, int(b.cells.Load())
+}
+
+// reset forgets the best partial grid, e.g. at the start of a new search.
+func (b *bestPartial) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.grid = nil
+	b.cells.Store(0)
+}
+
+// decidedCells returns the number of cells of state on a row or column with a single possibility.
+func decidedCells(state *gridState) int {
+	width, height := len(state.down), len(state.across)
+	rows, cols := 0, 0
+	for _, line := range state.across {
+		if line.MaxPossibilities() == 1 {
+			rows++
+		}
+	}
+	for _, line := range state.down {
+		if line.MaxPossibilities() == 1 {
+			cols++
+		}
+	}
+	// Cells where a decided row and a decided column cross are counted once.
+	return rows*width + cols*height - rows*cols
+}
+
+// partialGrid returns the partial grid of the decided cells of state, in the format accepted by
+// PossibleGridsFrom.
+func partialGrid(state *gridState) [][]rune {
+	width, height := len(state.down), len(state.across)
+	partial := make([][]rune, height)
+	for y := range height {
+		partial[y] = make([]rune, width)
+		for x := range width {
+			partial[y][x] = CellUnknown
+		}
+	}
+	for y, line := range state.across {
+		if c := line.FirstOrNull(); c != nil && line.MaxPossibilities() == 1 {
+			for x, r := range c.Line {
+				partial[y][x] = partialCell(r)
+			}
+		}
+	}
+	for x, line := range state.down {
+		if c := line.FirstOrNull(); c != nil && line.MaxPossibilities() == 1 {
+			for y, r := range c.Line {
+				partial[y][x] = partialCell(r)
+			}
+		}
+	}
+	return partial
+}
+
+// partialCell converts a rune in a line to its representation in a partial grid.
+func partialCell(r rune) rune {
+	if r == primitives.Blocked {
+		return CellBlocked
+	}
+	return r
+}
+
+// BestPartial returns the partial grid with the most decided cells seen by the most recent search
+// of the generator, e.g. to diagnose a search that timed out before finding any grid, or to finish
+// it by hand. A cell is decided once its row or column has a single possibility left. The grid is
+// in the format accepted by PossibleGridsFrom, with CellUnknown for undecided cells and
+// CellBlocked for blocked cells, or nil if the search has not got that far.
+//
+// Like Err, it is meant to be called once the search's sequence has finished, and is not
+// meaningful if several searches run concurrently. CountGrids and Improve leave it alone.
+func (g *Generator) BestPartial() [][]rune {
+	grid, _ := g.best.get()
+	return grid
+}
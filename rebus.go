@@ -0,0 +1,212 @@
+package xwgen
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen/internal"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// maxRebusCandidates is the largest number of strings a rebus cell can hold, one per token letter.
+const maxRebusCandidates = 26
+
+// rebusCell is a cell of every grid holding one of several strings. See WithRebusCell.
+type rebusCell struct {
+	row, col   int
+	candidates []string
+}
+
+// rebusToken returns the letter that stands for the i-th candidate of a rebus cell in the lines of
+// the search. Only the two lines through the cell hold it, so any letter will do as long as each
+// candidate has its own.
+func rebusToken(i int) rune {
+	return 'a' + rune(i)
+}
+
+// WithRebusCell makes the cell at the given row and column a rebus cell, which holds one of
+// candidates, e.g. "heart", rather than a single letter, in every grid. The across and down words
+// through the cell are words of the word lists that contain the string there, e.g. "sweetheart"
+// across and "heartache" down, and other cells hold letters as usual.
+//
+// Grid.Rebus returns the string a grid puts in the cell, and Grid.Cell its first letter.
+// Candidates must be at least two letters, and there can be at most 26 of them. At most one rebus
+// cell can be in each row and each column, and CreateGeneratorE returns an error if the cell is
+// outside the grid, if another option pins it or blocks it, or if no words fit it.
+func WithRebusCell(row, col int, candidates ...string) GeneratorOption {
+	return func(g *Generator) error {
+		if len(candidates) == 0 || len(candidates) > maxRebusCandidates {
+			return fmt.Errorf("rebus cell (%d, %d) must have between 1 and %d candidates, got %d", row, col, maxRebusCandidates, len(candidates))
+		}
+		cell := rebusCell{row: row, col: col}
+		for _, s := range candidates {
+			s = strings.ToLower(s)
+			if len(s) < 2 || strings.ContainsFunc(s, func(r rune) bool { return r < 'a' || r > 'z' }) {
+				return fmt.Errorf("rebus cell (%d, %d) candidate %q must be two or more letters", row, col, s)
+			}
+			if slices.Contains(cell.candidates, s) {
+				return fmt.Errorf("rebus cell (%d, %d) candidate %q is repeated", row, col, s)
+			}
+			cell.candidates = append(cell.candidates, s)
+		}
+		g.rebusCells = append(g.rebusCells, cell)
+		return nil
+	}
+}
+
+// validateRebusCells returns an error if a rebus cell is outside g's grids, shares a row or column
+// with another rebus cell, or conflicts with the partial grid, a pinned cell, or the symmetry of
+// the grid.
+func (g *Generator) validateRebusCells() error {
+	if len(g.rebusCells) == 0 {
+		return nil
+	}
+	// fixed holds what each cell is fixed to by the partial grid and the pinned cells.
+	fixed := make(map[[2]int]rune)
+	for y, row := range g.partial {
+		for x, r := range row {
+			if c, ok := cellConstraint(r); ok {
+				fixed[[2]int{y, x}] = c
+			}
+		}
+	}
+	for _, p := range g.pinnedCells {
+		fixed[[2]int{p.row, p.col}] = p.r
+	}
+
+	rows, cols := make(map[int]bool), make(map[int]bool)
+	for _, cell := range g.rebusCells {
+		if cell.row < 0 || cell.col < 0 || cell.row >= g.Height || cell.col >= g.LineLength {
+			return fmt.Errorf("rebus cell (%d, %d) is outside the %dx%d grid", cell.row, cell.col, g.LineLength, g.Height)
+		}
+		if rows[cell.row] || cols[cell.col] {
+			return fmt.Errorf("rebus cell (%d, %d) shares a row or column with another rebus cell", cell.row, cell.col)
+		}
+		rows[cell.row], cols[cell.col] = true, true
+		if r, ok := fixed[[2]int{cell.row, cell.col}]; ok {
+			return fmt.Errorf("cell (%d, %d) cannot be both a rebus cell and %s", cell.row, cell.col, describeCell(r))
+		}
+		for _, sym := range g.symmetries {
+			x, y := sym(cell.col, cell.row, g.LineLength, g.Height)
+			if fixed[[2]int{y, x}] == primitives.Blocked {
+				return fmt.Errorf("cell (%d, %d) cannot be a rebus cell: the symmetry of the grid blocks it, like cell (%d, %d)", cell.row, cell.col, y, x)
+			}
+		}
+	}
+	return nil
+}
+
+// rebusLines returns the lines of every row and column through a rebus cell, with their words in a
+// new random order if shuffle is true.
+func (g *Generator) rebusLines(ctx context.Context, shuffle bool) ([]lineOverride, error) {
+	var lines []lineOverride
+	for _, cell := range g.rebusCells {
+		for _, dir := range []Direction{DirectionHorizontal, DirectionVertical} {
+			lineLength, line, index := g.LineLength, cell.row, cell.col
+			if dir == DirectionVertical {
+				lineLength, line, index = g.Height, cell.col, cell.row
+			}
+			params := g.allPossibleLinesParams(lineLength)
+			params.ShuffleWords = shuffle
+			words := g.rebusWords(cell)
+			if shuffle {
+				g.rand.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+			}
+			l, err := internal.RebusLines(ctx, params, index, words)
+			if err != nil {
+				return nil, err
+			}
+			if g.memoizeFilters {
+				l = primitives.MemoizedFilter(l)
+			}
+			lines = append(lines, lineOverride{dir: dir, index: line, lines: l})
+		}
+	}
+	return lines, nil
+}
+
+// rebusWords returns every word of the word lists that contains a candidate of cell, with the
+// candidate replaced by its token, in the order of the word lists.
+func (g *Generator) rebusWords(cell rebusCell) []internal.RebusWord {
+	preferred, obscure := g.unbannedWords()
+	excluded := make(map[string]bool, len(g.ExcludedWords))
+	for _, word := range g.ExcludedWords {
+		excluded[word] = true
+	}
+
+	var words []internal.RebusWord
+	seen := make(map[internal.RebusWord]bool)
+	for _, list := range [][]string{g.requiredWords, preferred, obscure} {
+		for _, word := range list {
+			if excluded[word] || (g.wordPenalty != nil && math.IsInf(g.wordPenalty(word), 1)) {
+				continue
+			}
+			for i, s := range cell.candidates {
+				for j := 0; j+len(s) <= len(word); j++ {
+					if word[j:j+len(s)] != s {
+						continue
+					}
+					w := internal.RebusWord{Word: word[:j] + string(rebusToken(i)) + word[j+len(s):], Index: j}
+					if seen[w] {
+						continue
+					}
+					seen[w] = true
+					w.Obscure = g.isObscure(word)
+					words = append(words, w)
+				}
+			}
+		}
+	}
+	return words
+}
+
+// expandRebus replaces the token of each rebus cell in rows with the first letter of the string it
+// stands for, and the words through the cell, which wordsAcross and wordsDown hold in the order of
+// the runs of letters of rows, with the words containing the string. It returns the strings of the
+// rebus cells.
+func (g *Generator) expandRebus(rows [][]rune, wordsAcross, wordsDown []string) map[[2]int]string {
+	if len(g.rebusCells) == 0 {
+		return nil
+	}
+	columns := make([][]rune, len(rows[0]))
+	for x := range columns {
+		for _, row := range rows {
+			columns[x] = append(columns[x], row[x])
+		}
+	}
+
+	rebus := make(map[[2]int]string, len(g.rebusCells))
+	for _, cell := range g.rebusCells {
+		s := cell.candidates[rows[cell.row][cell.col]-rebusToken(0)]
+		rebus[[2]int{cell.row, cell.col}] = s
+		expandRun(wordsAcross, rows, cell.row, cell.col, s)
+		expandRun(wordsDown, columns, cell.col, cell.row, s)
+	}
+	// The rows are those of the lines of the search, so they are copied rather than modified.
+	for cell, s := range rebus {
+		rows[cell[0]] = slices.Clone(rows[cell[0]])
+		rows[cell[0]][cell[1]] = rune(s[0])
+	}
+	return rebus
+}
+
+// expandRun replaces the letter at lines[line][index] with s in the word of words through it, where
+// words are the runs of letters of lines in order.
+func expandRun(words []string, lines [][]rune, line, index int, s string) {
+	// The word through the cell is the last of the runs that start at or before it.
+	word, start := -1, 0
+	for l, letters := range lines[:line+1] {
+		for i, r := range letters {
+			if l == line && i > index {
+				break
+			}
+			if r != primitives.Blocked && (i == 0 || letters[i-1] == primitives.Blocked) {
+				word, start = word+1, i
+			}
+		}
+	}
+	words[word] = words[word][:index-start] + s + words[word][index-start+1:]
+}
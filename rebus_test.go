@@ -0,0 +1,84 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRebusCell(t *testing.T) {
+	words := loadTrimmedWords(t)
+	candidates := []string{"st", "er", "in"}
+	gen, err := CreateGeneratorE(4,
+		WithPreferredWords(words),
+		WithSeed(42, 1024),
+		WithRebusCell(1, 2, candidates...),
+	)
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	count := 0
+	for grid := range gen.PossibleGrids(ctx) {
+		count++
+		s := grid.Rebus(1, 2)
+		if !slices.Contains(candidates, s) || grid.Cell(1, 2) != rune(s[0]) {
+			t.Errorf("grid #%d has rebus %q in cell %q, want one of %q:\n%s", count, s, grid.Cell(1, 2), candidates, grid.Repr())
+		}
+		for _, word := range grid.AllWords() {
+			if !slices.Contains(words, word) {
+				t.Errorf("grid #%d has word %q, which is not in the word list:\n%s", count, word, grid.Repr())
+			}
+		}
+		var answers []string
+		for _, e := range grid.Entries() {
+			answers = append(answers, e.Answer)
+			if crosses := e.Row <= 1 && e.Col <= 2 && (e.Direction == DirectionHorizontal && e.Row == 1 && e.Col+e.Length > 2 ||
+				e.Direction == DirectionVertical && e.Col == 2 && e.Row+e.Length > 1); crosses && !strings.Contains(e.Answer, s) {
+				t.Errorf("grid #%d entry %q crosses the rebus cell, but does not contain %q:\n%s", count, e.Answer, s, grid.Repr())
+			}
+		}
+		if got, want := slices.Sorted(slices.Values(answers)), slices.Sorted(slices.Values(grid.AllWords())); !slices.Equal(got, want) {
+			t.Errorf("grid #%d Entries() answers = %q, want the words %q", count, got, want)
+		}
+		if count >= 20 {
+			break
+		}
+	}
+	if count == 0 {
+		t.Errorf("no grids found: %v", gen.Err())
+	}
+}
+
+func TestWithRebusCell_Invalid(t *testing.T) {
+	words := []string{"abc", "def", "ghi", "adg", "beh", "cfi"}
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+		want string
+	}{
+		{name: "NoCandidates", opts: []GeneratorOption{WithRebusCell(0, 0)}, want: "rebus cell (0, 0) must have between 1 and 26 candidates, got 0"},
+		{name: "SingleLetter", opts: []GeneratorOption{WithRebusCell(0, 0, "a")}, want: `rebus cell (0, 0) candidate "a" must be two or more letters`},
+		{name: "Repeated", opts: []GeneratorOption{WithRebusCell(0, 0, "ab", "AB")}, want: `rebus cell (0, 0) candidate "ab" is repeated`},
+		{name: "OutsideGrid", opts: []GeneratorOption{WithRebusCell(3, 0, "ab")}, want: "rebus cell (3, 0) is outside the 3x3 grid"},
+		{name: "SameRow", opts: []GeneratorOption{WithRebusCell(0, 0, "ab"), WithRebusCell(0, 2, "bc")}, want: "rebus cell (0, 2) shares a row or column with another rebus cell"},
+		{name: "Pinned", opts: []GeneratorOption{WithCell(1, 1, 'e'), WithRebusCell(1, 1, "ef")}, want: "cell (1, 1) cannot be both a rebus cell and 'e'"},
+		{
+			name: "BlockedBySymmetry",
+			opts: []GeneratorOption{WithRotationalSymmetry(), WithBlockedCell(0, 0), WithRebusCell(2, 2, "hi")},
+			want: "cell (2, 2) cannot be a rebus cell: the symmetry of the grid blocks it, like cell (0, 0)",
+		},
+		{name: "NoWordFits", opts: []GeneratorOption{WithRebusCell(0, 0, "xy")}, want: "rebus cells are self-contradictory: no words fit row 0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CreateGeneratorE(3, append([]GeneratorOption{WithPreferredWords(words)}, tc.opts...)...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("CreateGeneratorE() error = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
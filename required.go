@@ -0,0 +1,104 @@
+package xwgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// WithRequiredWords only yields grids containing at least one of words, e.g. the theme entries of
+// a themed crossword. Required words are tried before other words of the same length, and need not
+// be in the word lists.
+//
+// CreateGenerator panics if a required word can never fit in a line of the grid.
+func WithRequiredWords(words []string) GeneratorOption {
+	return func(g *Generator) error {
+		for _, word := range words {
+			word = strings.ToLower(word)
+			if word == "" || strings.ContainsFunc(word, func(r rune) bool { return r < 'a' || r > 'z' }) {
+				return fmt.Errorf("required word %q must only contain letters", word)
+			}
+			if !slices.Contains(g.requiredWords, word) {
+				g.requiredWords = append(g.requiredWords, word)
+			}
+		}
+		return nil
+	}
+}
+
+// validateRequiredWords returns an error if any required word is too long or short for the grid.
+func (g *Generator) validateRequiredWords() error {
+	minLength, maxLength := g.minWordLength(), max(g.LineLength, g.Height)
+	if g.MaxWordLength != nil {
+		maxLength = min(maxLength, *g.MaxWordLength)
+	}
+	for _, word := range g.requiredWords {
+		if len(word) > maxLength {
+			return fmt.Errorf("required word %q is longer than %d letters, the longest word that fits a %dx%d grid", word, maxLength, g.LineLength, g.Height)
+		}
+		if len(word) < minLength {
+			return fmt.Errorf("required word %q is shorter than the minimum word length %d", word, minLength)
+		}
+	}
+	return nil
+}
+
+// containsRequiredWord returns true if any of words is a required word, or there are no required
+// words.
+func (g *Generator) containsRequiredWord(words []string) bool {
+	if len(g.requiredWords) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(words, func(word string) bool {
+		return slices.Contains(g.requiredWords, word)
+	})
+}
+
+// canFitRequiredWord returns true if some line of state could still hold a required word, or there
+// are no required words.
+func (g *Generator) canFitRequiredWord(state *gridState) bool {
+	if len(g.requiredWords) == 0 {
+		return true
+	}
+	for _, lines := range [][]primitives.PossibleLines{state.across, state.down} {
+		for _, line := range lines {
+			chars := make([]primitives.CharSet, line.NumLetters())
+			for i := range chars {
+				line.CharsAt(&chars[i], i)
+			}
+			for _, word := range g.requiredWords {
+				if fitsAnywhere(chars, word) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// fitsAnywhere returns true if word can be placed as a whole word somewhere in a line where chars
+// are the possible characters of each cell.
+func fitsAnywhere(chars []primitives.CharSet, word string) bool {
+	for start := 0; start+len(word) <= len(chars); start++ {
+		end := start + len(word)
+		if start > 0 && !chars[start-1].Contains(primitives.Blocked) {
+			continue
+		}
+		if end < len(chars) && !chars[end].Contains(primitives.Blocked) {
+			continue
+		}
+		fits := true
+		for i, r := range word {
+			if !chars[start+i].Contains(r) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return true
+		}
+	}
+	return false
+}
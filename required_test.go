@@ -0,0 +1,66 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPossibleGrids_RequiredWords(t *testing.T) {
+	words := loadWords(t)
+
+	for _, tc := range []struct {
+		name     string
+		size     int
+		required []string
+	}{
+		{name: "one word", size: 4, required: []string{"able"}},
+		{name: "any of several", size: 5, required: []string{"ACID", "adapt"}},
+		{name: "not in word list", size: 4, required: []string{"tare"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			gen := CreateGenerator(tc.size, words, nil, nil, rng, GeneratorParams{}, WithRequiredWords(tc.required))
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			count := 0
+			for grid := range gen.PossibleGrids(ctx) {
+				count++
+				if !slices.ContainsFunc(grid.AllWords(), func(w string) bool { return slices.Contains(gen.requiredWords, w) }) {
+					t.Errorf("grid #%d contains none of %v:\n%s", count, tc.required, grid.Repr())
+				}
+				if count >= 5 {
+					break
+				}
+			}
+			if count == 0 {
+				t.Error("expected at least one grid")
+			}
+		})
+	}
+}
+
+func TestWithRequiredWords_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		required []string
+	}{
+		{name: "longer than grid", required: []string{"abdomen"}},
+		{name: "shorter than minimum", required: []string{"ab"}},
+		{name: "not letters", required: []string{"a-b"}},
+		{name: "empty", required: []string{""}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("CreateGenerator(WithRequiredWords(%q)) did not panic", tc.required)
+				}
+			}()
+			CreateGenerator(5, nil, nil, nil, nil, GeneratorParams{}, WithRequiredWords(tc.required))
+		})
+	}
+}
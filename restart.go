@@ -0,0 +1,185 @@
+package xwgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/Eyas/xwgen/internal"
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// RestartPolicy decides how long a search with WithRestarts keeps going before it starts over.
+type RestartPolicy interface {
+	// Backtracks returns the number of backtracks the nth attempt, counting from 0, may make
+	// without finding a grid before the search abandons it.
+	Backtracks(attempt int) int64
+}
+
+// LubyRestarts restarts after base times the terms of the Luby sequence backtracks: 1, 1, 2, 1, 1,
+// 2, 4, 1, ... Most attempts are short, but every so often one is twice as long as any before it.
+func LubyRestarts(base int64) RestartPolicy {
+	return lubyRestarts{base: base}
+}
+
+type lubyRestarts struct{ base int64 }
+
+func (l lubyRestarts) Backtracks(attempt int) int64 {
+	return saturatingMul(l.base, luby(int64(attempt)+1))
+}
+
+// luby returns the ith term of the Luby sequence, counting from 1.
+func luby(i int64) int64 {
+	for k := 1; ; k++ {
+		if i == 1<<k-1 {
+			return 1 << (k - 1)
+		}
+		if i < 1<<k-1 {
+			return luby(i - (1 << (k - 1)) + 1)
+		}
+	}
+}
+
+// DoublingRestarts restarts after base backtracks, then twice as many each attempt.
+func DoublingRestarts(base int64) RestartPolicy {
+	return doublingRestarts{base: base}
+}
+
+type doublingRestarts struct{ base int64 }
+
+func (d doublingRestarts) Backtracks(attempt int) int64 {
+	if attempt >= 62 {
+		return math.MaxInt64
+	}
+	return saturatingMul(d.base, 1<<attempt)
+}
+
+func saturatingMul(a, b int64) int64 {
+	if a > 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// WithRestarts makes the search start over whenever it backtracks more than policy allows without
+// finding a grid, with the words of each line in a new random order. This helps escape early
+// choices that doom a large part of the search, at the cost of repeating some of it.
+//
+// Grids found before a restart are not yielded again. Since the number of backtracks allowed grows
+// with each attempt, eventually one explores the whole search, so no grids are missed.
+//
+// Restarts cannot be combined with WithWorkers.
+func WithRestarts(policy RestartPolicy) GeneratorOption {
+	return func(g *Generator) error {
+		if policy == nil {
+			return errors.New("restart policy must not be nil")
+		}
+		if policy.Backtracks(0) < 1 {
+			return errors.New("restart policy must allow at least one backtrack")
+		}
+		g.restarts = policy
+		return nil
+	}
+}
+
+// WithGridTimeout gives up on finding the next grid once the search has spent d on it, and starts
+// over with the words of each line in a new random order, as WithRestarts does, rather than ending
+// the search. Each time it does counts as a restart and a grid timeout in the search statistics.
+//
+// The context passed to PossibleGrids still bounds the search as a whole; whichever deadline comes
+// first applies. Unlike WithRestarts, the search may never be exhausted if every attempt times out.
+// A grid timeout can be combined with WithRestarts, but not with WithWorkers,
+// WithIterativeDeepening, or WithBeamSearch.
+func WithGridTimeout(d time.Duration) GeneratorOption {
+	return func(g *Generator) error {
+		if d <= 0 {
+			return fmt.Errorf("grid timeout must be positive, got %v", d)
+		}
+		g.gridTimeout = d
+		return nil
+	}
+}
+
+// searchWithRestarts is like searcher.grids, but starts over from a reshuffled root whenever an
+// attempt backtracks more than g.restarts allows, if set, or spends longer than g.gridTimeout
+// without finding a grid, if set.
+//
+// partial is applied to each new root, as it was to root.
+func (g *Generator) searchWithRestarts(ctx context.Context, root *gridState, partial [][]rune) iter.Seq2[Grid, SearchStats] {
+	return func(yield func(Grid, SearchStats) bool) {
+		// carried accumulates the statistics of the abandoned attempts since the last grid.
+		var carried SearchStats
+		for attempt := 0; ; attempt++ {
+			sr := &searcher{g: g, ctx: ctx, gridTimeout: g.gridTimeout}
+			if g.restarts != nil {
+				sr.maxBacktracks = g.restarts.Backtracks(attempt)
+			}
+			last := time.Now()
+			for grid, stats := range sr.grids(root) {
+				stats.Add(carried)
+				carried = SearchStats{}
+				last = time.Now()
+				if !yield(grid, stats) {
+					return
+				}
+			}
+			if !sr.abandoned && !sr.timedOut {
+				return
+			}
+
+			carried.Add(sr.stats)
+			carried.Elapsed += time.Since(last)
+			carried.Restarts++
+			if s := g.stats; s != nil {
+				atomic.AddInt64(&s.Restarts, 1)
+			}
+			if sr.timedOut {
+				carried.GridTimeouts++
+				if s := g.stats; s != nil {
+					atomic.AddInt64(&s.GridTimeouts, 1)
+				}
+			}
+
+			var err error
+			root, err = g.shuffledState(ctx)
+			if err != nil {
+				return
+			}
+			if partial != nil {
+				applyPartial(root, partial)
+			}
+		}
+	}
+}
+
+// shuffledState is like initialState, but with the words of every line in a new random order.
+func (g *Generator) shuffledState(ctx context.Context) (*gridState, error) {
+	lines := func(lineLength int) (primitives.PossibleLines, error) {
+		params := g.allPossibleLinesParams(lineLength)
+		params.ShuffleWords = true
+		return internal.AllPossibleLines(ctx, params)
+	}
+	acrossLines, err := lines(g.LineLength)
+	if err != nil {
+		return nil, err
+	}
+	downLines := acrossLines
+	if g.Height != g.LineLength {
+		if downLines, err = lines(g.Height); err != nil {
+			return nil, err
+		}
+	}
+	overrides, err := g.lineOverrides(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	gs := g.stateFromLines(acrossLines, downLines, overrides)
+	if err := runAC3(ctx, gs, g.propagationWorkers); err != nil && !errors.Is(err, ErrNoGridsPossible) {
+		return nil, err
+	}
+	return gs, nil
+}
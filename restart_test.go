@@ -0,0 +1,145 @@
+package xwgen
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRestartPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy RestartPolicy
+		want   []int64
+	}{
+		{name: "Luby", policy: LubyRestarts(10), want: []int64{10, 10, 20, 10, 10, 20, 40, 10, 10, 20, 10, 10, 20, 40, 80, 10}},
+		{name: "Doubling", policy: DoublingRestarts(10), want: []int64{10, 20, 40, 80, 160, 320}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []int64
+			for attempt := range tc.want {
+				got = append(got, tc.policy.Backtracks(attempt))
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Backtracks() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if got := DoublingRestarts(10).Backtracks(100); got != math.MaxInt64 {
+		t.Errorf("DoublingRestarts(10).Backtracks(100) = %d, want math.MaxInt64", got)
+	}
+}
+
+func TestWithRestarts(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var subset []string
+	for i, word := range words {
+		if i%3 == 0 {
+			subset = append(subset, word)
+		}
+	}
+
+	search := func(opts ...GeneratorOption) ([]string, Stats) {
+		var stats Stats
+		gen, err := CreateGeneratorE(4, append(opts,
+			WithPreferredWords(subset),
+			WithRand(rand.New(rand.NewPCG(42, 1024))),
+			WithStats(&stats),
+		)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+		defer cancel()
+		var grids []string
+		var restarts int64
+		for grid, s := range gen.PossibleGridsWithStats(ctx) {
+			grids = append(grids, grid.Repr())
+			restarts += s.Restarts
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		if restarts > stats.Restarts {
+			t.Errorf("grids reported %d restarts, but the generator only made %d", restarts, stats.Restarts)
+		}
+		return grids, stats
+	}
+
+	want, _ := search()
+	got, stats := search(WithRestarts(LubyRestarts(2)))
+	if stats.Restarts == 0 {
+		t.Error("the search never restarted")
+	}
+
+	// Every grid is still found, and only once.
+	if len(got) != len(want) {
+		t.Errorf("got %d grids with restarts, want %d", len(got), len(want))
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Error("got different grids with restarts")
+	}
+}
+
+func TestWithGridTimeout(t *testing.T) {
+	words := loadTrimmedWords(t)
+	var stats Stats
+	stalled := false
+	gen, err := CreateGeneratorE(5, WithPreferredWords(words), WithSeed(42, 1024), WithStats(&stats),
+		WithGridTimeout(50*time.Millisecond),
+		// Stall the search once, as a pathological stretch of the search space would.
+		WithProgressCallback(func(int64, int64) {
+			if !stalled {
+				stalled = true
+				time.Sleep(200 * time.Millisecond)
+			}
+		}))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+	var timeouts int64
+	after := 0
+	for _, s := range gen.PossibleGridsWithStats(ctx) {
+		timeouts += s.GridTimeouts
+		if timeouts > 0 {
+			if after++; after == 5 {
+				break
+			}
+		}
+	}
+	if timeouts == 0 || stats.GridTimeouts != timeouts {
+		t.Errorf("grids reported %d grid timeouts, and the generator %d, want the same number, at least 1", timeouts, stats.GridTimeouts)
+	}
+	if after < 5 {
+		t.Errorf("found %d grids after the grid timeout, want 5; error: %v", after, gen.Err())
+	}
+}
+
+func TestWithRestarts_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []GeneratorOption
+	}{
+		{name: "nil policy", opts: []GeneratorOption{WithRestarts(nil)}},
+		{name: "zero backtracks", opts: []GeneratorOption{WithRestarts(DoublingRestarts(0))}},
+		{name: "workers", opts: []GeneratorOption{WithRestarts(LubyRestarts(10)), WithWorkers(2)}},
+		{name: "zero grid timeout", opts: []GeneratorOption{WithGridTimeout(0)}},
+		{name: "grid timeout with workers", opts: []GeneratorOption{WithGridTimeout(time.Second), WithWorkers(2)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CreateGeneratorE(4, tc.opts...); err == nil {
+				t.Error("CreateGeneratorE() succeeded, want an error")
+			}
+		})
+	}
+}
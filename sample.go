@@ -0,0 +1,32 @@
+package xwgen
+
+import (
+	"math/rand/v2"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// WithSampling makes which grids are found first depend on the generator's random source, e.g.
+// WithSeed, rather than mostly on the order of the words.
+//
+// When the search splits the possibilities of a line in two, it samples a line uniformly at random
+// from all of them, and explores first the half that holds it, so each line is tried first about
+// as often as any other. It only changes the order of the search: searched to completion, it finds
+// the same grids. It takes precedence over WithLetterVariety, and searches with it cannot be
+// checkpointed, since the order depends on every sample taken.
+func WithSampling() GeneratorOption {
+	return func(g *Generator) error {
+		g.sampling = true
+		return nil
+	}
+}
+
+// preferSample returns c, the split of lines, with its halves swapped if a line sampled from lines
+// with r is in the remaining possibilities rather than the choice.
+func preferSample(c primitives.ChoiceStep, lines primitives.PossibleLines, r *rand.Rand) primitives.ChoiceStep {
+	line := lines.Sample(r)
+	if line == nil || c.Choice.Contains(line.Line) {
+		return c
+	}
+	return primitives.ChoiceStep{Choice: c.Remaining, Remaining: c.Choice}
+}
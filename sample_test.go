@@ -0,0 +1,57 @@
+package xwgen
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestWithSampling(t *testing.T) {
+	var words []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			words = append(words, word)
+		}
+	}
+	generate := func(seed uint64, limit int, opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(3, append([]GeneratorOption{WithPreferredWords(words), WithSeed(seed, 1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var reprs []string
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if reprs = append(reprs, grid.Repr()); len(reprs) == limit {
+				break
+			}
+		}
+		return reprs
+	}
+
+	// Searched to completion, the same grids are found.
+	all, sampled := generate(42, 0), generate(42, 0, WithSampling())
+	if len(all) == 0 {
+		t.Fatal("got no grids")
+	}
+	set := func(reprs []string) map[string]bool {
+		set := make(map[string]bool)
+		for _, repr := range reprs {
+			set[repr] = true
+		}
+		return set
+	}
+	if got, want := set(sampled), set(all); !maps.Equal(got, want) {
+		t.Errorf("got %d grids with sampling, want the same %d as without", len(got), len(want))
+	}
+
+	// Only the order changes, and it depends on the seed.
+	const first = 20
+	if slices.Equal(all[:first], sampled[:first]) {
+		t.Errorf("the first %d grids are the same with sampling", first)
+	}
+	if again := generate(42, first, WithSampling()); !slices.Equal(again, sampled[:first]) {
+		t.Errorf("the first %d grids with sampling differ with the same seed", first)
+	}
+	if other := generate(7, first, WithSampling()); slices.Equal(other, sampled[:first]) {
+		t.Errorf("the first %d grids with sampling are the same with another seed", first)
+	}
+}
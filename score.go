@@ -0,0 +1,129 @@
+package xwgen
+
+import (
+	"fmt"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// Weights of each component of a GridScore.
+const (
+	scoreWeightDistinctLetter = 1.0
+	scoreWeightObscureWord    = -3.0
+	// A grid of only obscure words loses as much again as for two more obscure words.
+	scoreWeightObscureFraction = -6.0
+	scoreWeightBlock           = -0.5
+)
+
+// GridScore is a measure of the quality of a grid, along with the components it is made of.
+type GridScore struct {
+	// Total is the overall score of the grid. Higher is better.
+	Total float64
+
+	// ObscureWords is the number of obscure words in the grid.
+	ObscureWords int
+	// ObscureFraction is the fraction of the words in the grid that are obscure.
+	ObscureFraction float64
+	// DistinctLetters is the number of distinct letters used in the grid.
+	DistinctLetters int
+	// Blocks is the number of blocked cells in the grid.
+	Blocks int
+}
+
+func (s GridScore) String() string {
+	return fmt.Sprintf("%.1f (obscure words: %d (%.0f%%), distinct letters: %d, blocks: %d)",
+		s.Total, s.ObscureWords, 100*s.ObscureFraction, s.DistinctLetters, s.Blocks)
+}
+
+// Score rates the quality of a grid, preferring grids with fewer obscure words, both in number and
+// as a fraction of all words, more varied letters, and fewer blocked cells.
+func Score(grid Grid) GridScore {
+	var letters primitives.CharSet
+	blocks := 0
+	for y := range grid.Height() {
+		for x := range grid.Width() {
+			if r := grid.Get(x, y); r == primitives.Blocked {
+				blocks++
+			} else {
+				letters.Add(r)
+			}
+		}
+	}
+
+	score := GridScore{
+		ObscureWords:    len(grid.ObscureWords()),
+		ObscureFraction: grid.ObscureWordFraction(),
+		DistinctLetters: letters.Count(),
+		Blocks:          blocks,
+	}
+	score.Total = scoreWeightDistinctLetter*float64(score.DistinctLetters) +
+		scoreWeightObscureWord*float64(score.ObscureWords) +
+		scoreWeightObscureFraction*score.ObscureFraction +
+		scoreWeightBlock*float64(score.Blocks)
+	return score
+}
+
+// WithMaxObscureFraction only yields grids where at most the fraction f of words are obscure, from
+// 0 (no obscure words) to 1 (no limit).
+func WithMaxObscureFraction(f float64) GeneratorOption {
+	return func(g *Generator) error {
+		if f < 0 || f > 1 {
+			return fmt.Errorf("maximum obscure word fraction must be between 0 and 1, got %v", f)
+		}
+		g.maxObscureFraction = &f
+		return nil
+	}
+}
+
+// WithMinPreferredRatio only yields grids where at least the fraction r of words are preferred
+// rather than obscure, from 0 (no limit) to 1 (no obscure words). Each word of a line split by
+// blocks counts separately.
+//
+// Unlike WithMaxObscureFraction, the search prunes any branch whose obscure words already make the
+// ratio out of reach, even if every word not yet decided turns out to be preferred.
+func WithMinPreferredRatio(r float64) GeneratorOption {
+	return func(g *Generator) error {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("minimum preferred word ratio must be between 0 and 1, got %v", r)
+		}
+		g.minPreferredRatio = &r
+		return nil
+	}
+}
+
+// canReachPreferredRatio returns false if no grid completing state can have g's minimum ratio of
+// preferred words.
+func (g *Generator) canReachPreferredRatio(state *gridState) bool {
+	r := g.minPreferredRatio
+	if r == nil {
+		return true
+	}
+
+	// At best, every line has as many words as fit in it, and every word not definitely in it is
+	// preferred.
+	obscure, total := 0, 0
+	for _, lines := range [][]primitives.PossibleLines{state.across, state.down} {
+		for _, line := range lines {
+			words := line.DefiniteWords()
+			if line.MaxPossibilities() == 1 {
+				words = line.FirstOrNull().Words
+				total += len(words)
+			} else {
+				total += g.maxWordsInLine(line.NumLetters())
+			}
+			for _, word := range words {
+				if g.isObscure(word) {
+					obscure++
+				}
+			}
+		}
+	}
+	const epsilon = 1e-9
+	return float64(total-obscure) >= *r*float64(total)-epsilon
+}
+
+// maxWordsInLine returns the largest number of words, each separated by a block, that fit in a line
+// of n cells.
+func (g *Generator) maxWordsInLine(n int) int {
+	return (n + 1) / (g.minWordLength() + 1)
+}
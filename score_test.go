@@ -0,0 +1,158 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func gridFromRows(rows ...string) Grid {
+	grid := make([][]rune, len(rows))
+	for i, row := range rows {
+		grid[i] = []rune(row)
+	}
+	return NewGrid(grid)
+}
+
+func TestScore(t *testing.T) {
+	open := gridFromRows("abc", "def", "ghi")
+	blocked := gridFromRows("abc", "de`", "gh`")
+	obscure := gridFromRows("abc", "def", "ghi")
+	obscure.obscureWords = []string{"abc"}
+
+	for _, tc := range []struct {
+		name string
+		grid Grid
+		want GridScore
+	}{
+		{name: "open", grid: open, want: GridScore{Total: 9, DistinctLetters: 9}},
+		{name: "blocked", grid: blocked, want: GridScore{Total: 6, DistinctLetters: 7, Blocks: 2}},
+		{name: "obscure", grid: obscure, want: GridScore{Total: 5, DistinctLetters: 9, ObscureWords: 1, ObscureFraction: 1.0 / 6}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Score(tc.grid); got != tc.want {
+				t.Errorf("Score() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithMaxObscureFraction(t *testing.T) {
+	// Use every other word, so that every 3x3 grid can be generated quickly.
+	var preferred, obscure []string
+	for i, word := range loadTrimmedWords(t) {
+		switch {
+		case i%2 == 1:
+		case i%8 == 0:
+			obscure = append(obscure, word)
+		default:
+			preferred = append(preferred, word)
+		}
+	}
+
+	countGrids := func(f float64) int {
+		gen, err := CreateGeneratorE(3,
+			WithPreferredWords(preferred),
+			WithObscureWords(obscure),
+			WithRand(rand.New(rand.NewPCG(42, 1024))),
+			WithMaxObscureFraction(f),
+		)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+
+		count := 0
+		for grid := range gen.PossibleGrids(ctx) {
+			count++
+			if got := grid.ObscureWordFraction(); got > f {
+				t.Errorf("grid #%d has obscure word fraction %v, want at most %v:\n%s", count, got, f, grid.Repr())
+			}
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search did not finish: %v", err)
+		}
+		return count
+	}
+
+	all, some, none := countGrids(1), countGrids(0.2), countGrids(0)
+	t.Logf("grids with at most 100%%, 20%%, and 0%% obscure words: %d, %d, %d", all, some, none)
+	if !(all > some && some > none && none > 0) {
+		t.Errorf("got %d, %d, and %d grids, want fewer grids with each lower fraction", all, some, none)
+	}
+}
+
+func TestWithMaxObscureFraction_Invalid(t *testing.T) {
+	for _, f := range []float64{-0.1, 1.5} {
+		if err := WithMaxObscureFraction(f)(&Generator{}); err == nil {
+			t.Errorf("expected an error for fraction %v", f)
+		}
+	}
+}
+
+func TestWithMinPreferredRatio(t *testing.T) {
+	var preferred, obscure []string
+	for i, word := range loadTrimmedWords(t) {
+		switch {
+		case i%2 == 1:
+		case i%8 == 0:
+			obscure = append(obscure, word)
+		default:
+			preferred = append(preferred, word)
+		}
+	}
+
+	search := func(r float64, opt GeneratorOption) (int, Stats) {
+		var stats Stats
+		gen, err := CreateGeneratorE(3,
+			WithPreferredWords(preferred),
+			WithObscureWords(obscure),
+			WithSeed(42, 1024),
+			WithStats(&stats),
+			opt,
+		)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+
+		count := 0
+		for grid := range gen.PossibleGrids(ctx) {
+			count++
+			if got := grid.PreferredRatio(); got < r {
+				t.Errorf("grid #%d has preferred word ratio %v, want at least %v:\n%s", count, got, r, grid.Repr())
+			}
+		}
+		if err := gen.Err(); err != nil {
+			t.Fatalf("search did not finish: %v", err)
+		}
+		return count, stats
+	}
+
+	all, _ := search(0, WithMinPreferredRatio(0))
+	some, pruned := search(0.8, WithMinPreferredRatio(0.8))
+	none, _ := search(1, WithMinPreferredRatio(1))
+	t.Logf("grids with at least 0%%, 80%%, and 100%% preferred words: %d, %d, %d", all, some, none)
+	if !(all > some && some > none && none > 0) {
+		t.Errorf("got %d, %d, and %d grids, want fewer grids with each higher ratio", all, some, none)
+	}
+
+	// Checking only complete grids finds about as many, but explores more of the search. The counts
+	// can differ slightly, since pruning changes the order the rest of the search is explored in.
+	filtered, unpruned := search(0.8, WithMaxObscureFraction(0.2))
+	if diff := filtered - some; diff < -10 || diff > 10 {
+		t.Errorf("got %d grids with WithMinPreferredRatio(0.8), want about the %d with WithMaxObscureFraction(0.2)", some, filtered)
+	}
+	if pruned.NodesExplored >= unpruned.NodesExplored {
+		t.Errorf("explored %d nodes with WithMinPreferredRatio(0.8), want fewer than the %d with WithMaxObscureFraction(0.2)", pruned.NodesExplored, unpruned.NodesExplored)
+	}
+
+	for _, r := range []float64{-0.1, 1.5} {
+		if err := WithMinPreferredRatio(r)(&Generator{}); err == nil {
+			t.Errorf("expected an error for ratio %v", r)
+		}
+	}
+}
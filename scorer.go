@@ -0,0 +1,148 @@
+package xwgen
+
+import "fmt"
+
+// Scorer rates the quality of grids, e.g. to keep the best of many grids.
+type Scorer interface {
+	// Score returns the score of grid. Higher is better.
+	Score(grid Grid) float64
+	// Breakdown returns the components of the score of grid by name, which add up to its score.
+	Breakdown(grid Grid) map[string]float64
+}
+
+// Weights of the components of the reference scorers, on top of those of GridScore.
+const (
+	scoreWeightMeanWordScore = 1.0
+	// A grid of only the rarest letters gains 10 times as much as one of only the most common.
+	scoreWeightLetterPoints = 2.0
+	// Using twice as many letters equally often gains as much as two more distinct letters.
+	scoreWeightLetterEntropy = 2.0
+)
+
+// scoreComponent is a named, weighted component of a score.
+type scoreComponent struct {
+	name  string
+	value float64
+}
+
+// componentScorer implements Scorer from a list of components, adding them up in order so that
+// Score is deterministic.
+type componentScorer func(grid Grid) []scoreComponent
+
+func (f componentScorer) Score(grid Grid) float64 {
+	var total float64
+	for _, c := range f(grid) {
+		total += c.value
+	}
+	return total
+}
+
+func (f componentScorer) Breakdown(grid Grid) map[string]float64 {
+	components := f(grid)
+	breakdown := make(map[string]float64, len(components))
+	for _, c := range components {
+		breakdown[c.name] = c.value
+	}
+	return breakdown
+}
+
+// ClassicScorer returns the default Scorer. It prefers grids with fewer obscure words, both in
+// number and as a fraction of all words, more varied letters, and fewer blocked cells, like
+// Score, as well as a higher mean score of the words in wordScores, e.g. their frequencies.
+// wordScores can be nil.
+func ClassicScorer(wordScores map[string]float64) Scorer {
+	return componentScorer(func(grid Grid) []scoreComponent {
+		return classicComponents(grid, wordScores)
+	})
+}
+
+// ScrabbleScorer returns a Scorer like ClassicScorer, which also prefers grids with rarer letters,
+// by their points in the game of Scrabble: from 1 for letters like 'e' to 10 for 'q' and 'z'.
+func ScrabbleScorer(wordScores map[string]float64) Scorer {
+	return componentScorer(func(grid Grid) []scoreComponent {
+		return append(classicComponents(grid, wordScores), scoreComponent{
+			name:  "letter_points",
+			value: scoreWeightLetterPoints * meanLetterPoints(grid),
+		})
+	})
+}
+
+// VarietyScorer returns a Scorer like ClassicScorer, which also prefers grids that use their
+// letters more evenly, by the entropy of their letters: see Grid.LetterEntropy.
+func VarietyScorer(wordScores map[string]float64) Scorer {
+	return componentScorer(func(grid Grid) []scoreComponent {
+		return append(classicComponents(grid, wordScores), scoreComponent{
+			name:  "letter_entropy",
+			value: scoreWeightLetterEntropy * grid.LetterEntropy(),
+		})
+	})
+}
+
+func classicComponents(grid Grid, wordScores map[string]float64) []scoreComponent {
+	s := Score(grid)
+	return []scoreComponent{
+		{name: "obscure_words", value: scoreWeightObscureWord * float64(s.ObscureWords)},
+		{name: "obscure_fraction", value: scoreWeightObscureFraction * s.ObscureFraction},
+		{name: "distinct_letters", value: scoreWeightDistinctLetter * float64(s.DistinctLetters)},
+		{name: "blocks", value: scoreWeightBlock * float64(s.Blocks)},
+		{name: "mean_word_score", value: scoreWeightMeanWordScore * meanWordScore(grid, wordScores)},
+	}
+}
+
+// meanWordScore returns the mean of the scores of the words of grid, or 0 if it has none.
+func meanWordScore(grid Grid, wordScores map[string]float64) float64 {
+	words := grid.AllWords()
+	if len(words) == 0 || wordScores == nil {
+		return 0
+	}
+	var total float64
+	for _, word := range words {
+		total += wordScores[word]
+	}
+	return total / float64(len(words))
+}
+
+// scrabblePoints holds the points of each letter in Scrabble, from 'a' to 'z'.
+var scrabblePoints = [26]float64{
+	1, 3, 3, 2, 1, 4, 2, 4, 1, 8, 5, 1, 3, 1, 1, 3, 10, 1, 1, 1, 1, 4, 4, 8, 4, 10,
+}
+
+// meanLetterPoints returns the mean Scrabble points of the letters of grid, or 0 if it has none.
+func meanLetterPoints(grid Grid) float64 {
+	var total float64
+	letters := 0
+	for y := range grid.Height() {
+		for x := range grid.Width() {
+			r := grid.Get(x, y)
+			if r < 'a' || r > 'z' {
+				continue
+			}
+			total += scrabblePoints[r-'a']
+			letters++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return total / float64(letters)
+}
+
+// WithScorer sets how the quality of grids is rated, e.g. by xwcli's -rank. See Generator.Scorer.
+func WithScorer(s Scorer) GeneratorOption {
+	return func(g *Generator) error {
+		if s == nil {
+			return fmt.Errorf("scorer must not be nil")
+		}
+		g.scorer = s
+		return nil
+	}
+}
+
+// Scorer returns the Scorer set with WithScorer, or else ClassicScorer with the generator's word
+// frequencies.
+func (g *Generator) Scorer() Scorer {
+	if g.scorer != nil {
+		return g.scorer
+	}
+	return ClassicScorer(g.frequencies)
+}
@@ -0,0 +1,70 @@
+package xwgen
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScorers(t *testing.T) {
+	common := gridFromRows("tee", "eet", "eee")
+	varied := gridFromRows("abc", "def", "ghi")
+	rare := gridFromRows("zzq", "xqz", "qzx")
+	blocked := gridFromRows("abc", "de`", "gh`")
+	obscure := gridFromRows("abc", "def", "ghi")
+	obscure.obscureWords = []string{"abc"}
+
+	for _, tc := range []struct {
+		name   string
+		scorer Scorer
+		// want is the grids from best to worst.
+		want []string
+	}{
+		{name: "classic", scorer: ClassicScorer(nil), want: []string{"varied", "blocked", "obscure", "rare", "common"}},
+		{name: "scrabble", scorer: ScrabbleScorer(nil), want: []string{"rare", "varied", "blocked", "obscure", "common"}},
+		{name: "variety", scorer: VarietyScorer(nil), want: []string{"varied", "blocked", "obscure", "rare", "common"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			grids := map[string]Grid{"common": common, "varied": varied, "rare": rare, "blocked": blocked, "obscure": obscure}
+			for i := range len(tc.want) - 1 {
+				better, worse := tc.want[i], tc.want[i+1]
+				if b, w := tc.scorer.Score(grids[better]), tc.scorer.Score(grids[worse]); b <= w {
+					t.Errorf("Score(%s) = %v, want more than Score(%s) = %v", better, b, worse, w)
+				}
+			}
+			for name, grid := range grids {
+				var total float64
+				for _, v := range tc.scorer.Breakdown(grid) {
+					total += v
+				}
+				if score := tc.scorer.Score(grid); math.Abs(total-score) > 1e-9 {
+					t.Errorf("Breakdown(%s) adds up to %v, want Score() = %v", name, total, score)
+				}
+			}
+		})
+	}
+
+	// The classic scorer agrees with Score, and rewards common words.
+	if got, want := ClassicScorer(nil).Score(obscure), Score(obscure).Total; got != want {
+		t.Errorf("ClassicScorer(nil).Score() = %v, want Score().Total = %v", got, want)
+	}
+	withWords := obscure
+	withWords.wordsAcross = []string{"abc", "def", "ghi"}
+	scores := map[string]float64{"abc": 2, "def": 1}
+	if got, want := ClassicScorer(scores).Breakdown(withWords)["mean_word_score"], 1.0; got != want {
+		t.Errorf("mean word score = %v, want %v", got, want)
+	}
+}
+
+func TestGenerator_Scorer(t *testing.T) {
+	gen, err := CreateGeneratorE(3, WithScorer(ScrabbleScorer(nil)))
+	if err != nil {
+		t.Fatalf("CreateGeneratorE() error: %v", err)
+	}
+	rare := gridFromRows("zzq", "xqz", "qzx")
+	if got, want := gen.Scorer().Score(rare), ScrabbleScorer(nil).Score(rare); got != want {
+		t.Errorf("Scorer().Score() = %v, want the scrabble score %v", got, want)
+	}
+	if _, err := CreateGeneratorE(3, WithScorer(nil)); err == nil {
+		t.Error("CreateGeneratorE(WithScorer(nil)) succeeded, want an error")
+	}
+}
@@ -0,0 +1,142 @@
+package xwgen
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// LineSelector chooses which line the search branches on next.
+type LineSelector interface {
+	// SelectLine returns the direction and index of the line to branch on, given the possible
+	// lines of each row (across) and column (down) of a partially-filled grid. The line must have
+	// more than one possibility. ok is false if every line has at most one possibility, i.e. the
+	// grid is decided.
+	//
+	// r may be used to break ties at random.
+	SelectLine(across, down []primitives.PossibleLines, r *rand.Rand) (dir Direction, index int, ok bool)
+}
+
+// LineSelectorFunc adapts a function to a LineSelector.
+type LineSelectorFunc func(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool)
+
+func (f LineSelectorFunc) SelectLine(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool) {
+	return f(across, down, r)
+}
+
+var (
+	// MostConstrained selects the line with the fewest possibilities, i.e. the minimum remaining
+	// values heuristic. Ties are broken by the number of lines crossing it that are undecided, most
+	// first, so that the choice constrains as much of the grid as possible, and then at random.
+	//
+	// It is the default.
+	MostConstrained LineSelector = LineSelectorFunc(selectMostConstrained)
+
+	// FewestPossibilitiesPerDirection selects the across line and the down line with the fewest
+	// possibilities, breaking ties at random, and then whichever of the two has fewer
+	// possibilities, preferring the down line. It was the default before MostConstrained.
+	FewestPossibilitiesPerDirection LineSelector = LineSelectorFunc(selectFewestPerDirection)
+)
+
+// WithLineSelector sets how the search chooses the line to branch on. The default is
+// MostConstrained.
+func WithLineSelector(s LineSelector) GeneratorOption {
+	return func(g *Generator) error {
+		if s == nil {
+			return fmt.Errorf("line selector must not be nil")
+		}
+		g.lineSelector = s
+		return nil
+	}
+}
+
+// selectLine returns the line to branch on with the generator's line selector.
+func (g *Generator) selectLine(state *gridState) (Direction, int, bool) {
+	s := g.lineSelector
+	if s == nil {
+		s = MostConstrained
+	}
+	return s.SelectLine(state.across, state.down, state.rand)
+}
+
+func selectMostConstrained(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool) {
+	type candidate struct {
+		dir Direction
+		idx int
+	}
+	var least int64
+	var candidates []candidate
+	consider := func(dir Direction, lines []primitives.PossibleLines) {
+		for i, line := range lines {
+			p := line.MaxPossibilities()
+			if p <= 1 || (least != 0 && p > least) {
+				continue
+			}
+			if p < least || least == 0 {
+				least = p
+				candidates = candidates[:0]
+			}
+			candidates = append(candidates, candidate{dir, i})
+		}
+	}
+	consider(DirectionHorizontal, across)
+	consider(DirectionVertical, down)
+
+	if len(candidates) == 0 {
+		return 0, 0, false
+	}
+	if len(candidates) > 1 {
+		most := -1
+		var best []candidate
+		for _, c := range candidates {
+			var n int
+			if c.dir == DirectionHorizontal {
+				n = undecidedCrossings(across[c.idx], down)
+			} else {
+				n = undecidedCrossings(down[c.idx], across)
+			}
+			if n > most {
+				most = n
+				best = best[:0]
+			}
+			if n == most {
+				best = append(best, c)
+			}
+		}
+		candidates = best
+	}
+
+	c := candidates[r.IntN(len(candidates))]
+	return c.dir, c.idx, true
+}
+
+// undecidedCrossings returns the number of crossing lines with more than one possibility that
+// line may have a letter in common with.
+func undecidedCrossings(line primitives.PossibleLines, crossing []primitives.PossibleLines) int {
+	n := 0
+	for i, c := range crossing {
+		if c.MaxPossibilities() > 1 && !line.DefinitelyBlockedAt(i) {
+			n++
+		}
+	}
+	return n
+}
+
+func selectFewestPerDirection(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool) {
+	undecidedDown := getUndecidedIndexWLOG(down, r)
+	undecidedAcross := getUndecidedIndexWLOG(across, r)
+
+	switch {
+	case undecidedDown == nil && undecidedAcross == nil:
+		return 0, 0, false
+	case undecidedAcross == nil:
+		return DirectionVertical, *undecidedDown, true
+	case undecidedDown == nil:
+		return DirectionHorizontal, *undecidedAcross, true
+	case down[*undecidedDown].MaxPossibilities() <= across[*undecidedAcross].MaxPossibilities():
+		return DirectionVertical, *undecidedDown, true
+	default:
+		return DirectionHorizontal, *undecidedAcross, true
+	}
+}
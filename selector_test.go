@@ -0,0 +1,119 @@
+package xwgen
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestSelectMostConstrained(t *testing.T) {
+	words := func(ws ...string) primitives.PossibleLines {
+		return primitives.MakeWords(ws, len(ws), len(ws[0]))
+	}
+	decided := words("abc")
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	// The first column has as few possibilities as the second row, but crosses more undecided
+	// rows.
+	across := []primitives.PossibleLines{words("abc", "abd", "abe"), words("abc", "abd"), words("abc", "abd", "abe")}
+	down := []primitives.PossibleLines{words("abc", "abd"), words("abc", "abd", "abe"), decided}
+	for range 10 {
+		dir, index, ok := MostConstrained.SelectLine(across, down, rng)
+		if !ok || dir != DirectionVertical || index != 0 {
+			t.Fatalf("SelectLine() = %v, %d, %v, want the first column", dir, index, ok)
+		}
+	}
+
+	// Fewer possibilities beat more crossings.
+	across[2] = words("abc", "abd")
+	down[0] = words("abc", "abd", "abe")
+	if dir, index, ok := MostConstrained.SelectLine(across, down, rng); !ok || index == 0 && dir == DirectionVertical {
+		t.Errorf("SelectLine() = %v, %d, %v, want a line with 2 possibilities", dir, index, ok)
+	}
+
+	all := []primitives.PossibleLines{decided, decided, decided}
+	if _, _, ok := MostConstrained.SelectLine(all, all, rng); ok {
+		t.Error("SelectLine() of a decided grid returned ok")
+	}
+}
+
+func TestWithLineSelector(t *testing.T) {
+	words := loadTrimmedWords(t)
+	for _, tc := range []struct {
+		name     string
+		selector LineSelector
+	}{
+		{name: "MostConstrained", selector: MostConstrained},
+		{name: "FewestPossibilitiesPerDirection", selector: FewestPossibilitiesPerDirection},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			counting := LineSelectorFunc(func(across, down []primitives.PossibleLines, r *rand.Rand) (Direction, int, bool) {
+				calls++
+				return tc.selector.SelectLine(across, down, r)
+			})
+			gen, err := CreateGeneratorE(5,
+				WithPreferredWords(words),
+				WithRand(rand.New(rand.NewPCG(42, 1024))),
+				WithLineSelector(counting),
+			)
+			if err != nil {
+				t.Fatalf("CreateGeneratorE() error: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+			count := 0
+			for range gen.PossibleGrids(ctx) {
+				if count++; count >= 5 {
+					break
+				}
+			}
+			if count < 5 {
+				t.Errorf("got %d grids, want 5", count)
+			}
+			if calls == 0 {
+				t.Error("the line selector was never called")
+			}
+		})
+	}
+
+	if err := WithLineSelector(nil)(&Generator{}); err == nil {
+		t.Error("expected an error for a nil line selector")
+	}
+}
+
+// BenchmarkLineSelector measures the time to the first 5x5 grid with each line selector.
+func BenchmarkLineSelector(b *testing.B) {
+	words := loadTrimmedWords(b)
+	b.ReportAllocs()
+
+	for _, tc := range []struct {
+		name     string
+		selector LineSelector
+	}{
+		{name: "MostConstrained", selector: MostConstrained},
+		{name: "FewestPossibilitiesPerDirection", selector: FewestPossibilitiesPerDirection},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			seed := uint64(0)
+			for b.Loop() {
+				seed++
+				gen, err := CreateGeneratorE(5,
+					WithPreferredWords(words),
+					WithRand(rand.New(rand.NewPCG(seed, 1024))),
+					WithLineSelector(tc.selector),
+				)
+				if err != nil {
+					b.Fatalf("CreateGeneratorE() error: %v", err)
+				}
+				for range gen.PossibleGrids(b.Context()) {
+					break
+				}
+			}
+		})
+	}
+}
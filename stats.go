@@ -0,0 +1,91 @@
+package xwgen
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SearchStats describes the work done by the generator to find a grid.
+type SearchStats struct {
+	// ChoiceSteps is the number of choices made, i.e. the number of times a line was narrowed
+	// down to a subset of its possibilities before recursing.
+	ChoiceSteps int64
+	// Backtracks is the number of choices that were undone without leading to any grid.
+	Backtracks int64
+	// DeadEnds is the number of branches that were pruned because they could not lead to a grid.
+	DeadEnds int64
+	// Backjumps is the number of times the search skipped the untried alternatives of a choice,
+	// because the branch that failed did not depend on it.
+	Backjumps int64
+	// Restarts is the number of times the search started over. See WithRestarts.
+	Restarts int64
+	// GridTimeouts is the number of times the search gave up on a grid and started over. See
+	// WithGridTimeout.
+	GridTimeouts int64
+	// PeakFrontier is the largest number of choices that were open at the same time.
+	PeakFrontier int
+	// Elapsed is the wall time spent on the search.
+	Elapsed time.Duration
+}
+
+// Add accumulates other into s, e.g. to summarize the statistics of several grids.
+func (s *SearchStats) Add(other SearchStats) {
+	s.ChoiceSteps += other.ChoiceSteps
+	s.Backtracks += other.Backtracks
+	s.DeadEnds += other.DeadEnds
+	s.Backjumps += other.Backjumps
+	s.Restarts += other.Restarts
+	s.GridTimeouts += other.GridTimeouts
+	s.PeakFrontier = max(s.PeakFrontier, other.PeakFrontier)
+	s.Elapsed += other.Elapsed
+}
+
+func (s SearchStats) String() string {
+	return fmt.Sprintf("choices: %d, backtracks: %d, backjumps: %d, restarts: %d, grid timeouts: %d, dead ends: %d, peak frontier: %d, elapsed: %v",
+		s.ChoiceSteps, s.Backtracks, s.Backjumps, s.Restarts, s.GridTimeouts, s.DeadEnds, s.PeakFrontier, s.Elapsed)
+}
+
+// Stats accumulates statistics across every search performed by a generator, e.g. for profiling.
+// Pass it to a generator with WithStats.
+//
+// The generator updates the fields atomically, so while a search is running they must only be read
+// with the sync/atomic functions. Once the search is done, they can be read directly.
+type Stats struct {
+	// NodesExplored is the number of points in the search tree visited.
+	NodesExplored int64 `json:"nodes_explored"`
+	// NodesPruned is the number of subtrees cut from the search because they cannot lead to a grid.
+	NodesPruned int64 `json:"nodes_pruned"`
+	// GridsFound is the number of distinct grids yielded.
+	GridsFound int64 `json:"grids_found"`
+	// RepeatsSkipped is the number of grids found again and skipped rather than yielded. See
+	// WithDedupeCapacity.
+	RepeatsSkipped int64 `json:"repeats_skipped"`
+	// MaxDepthReached is the largest number of choices made at once.
+	MaxDepthReached int64 `json:"max_depth_reached"`
+	// Restarts is the number of times a search started over. See WithRestarts.
+	Restarts int64 `json:"restarts"`
+	// GridTimeouts is the number of times a search gave up on a grid. See WithGridTimeout.
+	GridTimeouts int64 `json:"grid_timeouts"`
+	// TimeElapsed is the total wall time spent searching, including time spent by the caller
+	// between grids.
+	TimeElapsed time.Duration `json:"time_elapsed_ns"`
+}
+
+// WithStats accumulates statistics of the searches performed by the generator in s.
+func WithStats(s *Stats) GeneratorOption {
+	return func(g *Generator) error {
+		g.stats = s
+		return nil
+	}
+}
+
+// observeDepth records that the search reached depth.
+func (s *Stats) observeDepth(depth int) {
+	for {
+		prev := atomic.LoadInt64(&s.MaxDepthReached)
+		if int64(depth) <= prev || atomic.CompareAndSwapInt64(&s.MaxDepthReached, prev, int64(depth)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package xwgen
+
+import (
+	"fmt"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// symmetry maps a cell of a width x height grid to the cell whose blocked state must match it.
+type symmetry func(x, y, width, height int) (int, int)
+
+// rotationalSymmetry maps each cell to the cell it lands on when the grid is rotated 180 degrees.
+func rotationalSymmetry(x, y, width, height int) (int, int) {
+	return width - 1 - x, height - 1 - y
+}
+
+// verticalAxisSymmetry maps each cell to its mirror image across the vertical center line.
+func verticalAxisSymmetry(x, y, width, height int) (int, int) {
+	return width - 1 - x, y
+}
+
+// horizontalAxisSymmetry maps each cell to its mirror image across the horizontal center line.
+func horizontalAxisSymmetry(x, y, width, height int) (int, int) {
+	return x, height - 1 - y
+}
+
+// Symmetry options compose: a grid must satisfy every symmetry requested, so no option takes
+// precedence over another. Combining both reflective axes implies rotational symmetry, and
+// combining rotational symmetry with either reflective axis implies the other axis as well.
+
+// WithRotationalSymmetry requires the blocked cells of each grid to have 180-degree rotational
+// symmetry, as is standard in American crosswords: if the cell at (row, col) is blocked, so is the
+// cell at (height-1-row, width-1-col).
+func WithRotationalSymmetry() GeneratorOption {
+	return func(g *Generator) error {
+		g.symmetries = append(g.symmetries, rotationalSymmetry)
+		return nil
+	}
+}
+
+// WithReflectiveSymmetry requires the blocked cells of each grid to mirror each other across the
+// given axis, which is either "vertical" (left-right symmetry, where the cell at (row, col) mirrors
+// (row, width-1-col)) or "horizontal" (top-bottom symmetry, where the cell at (row, col) mirrors
+// (height-1-row, col)).
+func WithReflectiveSymmetry(axis string) GeneratorOption {
+	return func(g *Generator) error {
+		switch axis {
+		case "vertical":
+			g.symmetries = append(g.symmetries, verticalAxisSymmetry)
+		case "horizontal":
+			g.symmetries = append(g.symmetries, horizontalAxisSymmetry)
+		default:
+			return fmt.Errorf("unknown reflective symmetry axis %q, expected \"vertical\" or \"horizontal\"", axis)
+		}
+		return nil
+	}
+}
+
+// WithSymmetry requires the blocked cells of each grid to have the named symmetry: "rotational"
+// (see WithRotationalSymmetry), or "vertical" or "horizontal" (see WithReflectiveSymmetry).
+func WithSymmetry(name string) GeneratorOption {
+	switch name {
+	case "rotational":
+		return WithRotationalSymmetry()
+	case "vertical", "horizontal":
+		return WithReflectiveSymmetry(name)
+	}
+	return func(g *Generator) error {
+		return fmt.Errorf("unknown symmetry %q, expected \"rotational\", \"vertical\", or \"horizontal\"", name)
+	}
+}
+
+// letterCharSet is the set of all letters, i.e. every character except primitives.Blocked.
+var letterCharSet = func() primitives.CharSet {
+	var cs primitives.CharSet
+	for r := 'a'; r <= 'z'; r++ {
+		cs.Add(r)
+	}
+	return cs
+}()
+
+// definitelyBlocked returns true if the cell at (x, y) must be blocked.
+func definitelyBlocked(state *gridState, x, y int) bool {
+	return state.across[y].DefinitelyBlockedAt(x) || state.down[x].DefinitelyBlockedAt(y)
+}
+
+// definitelyOpen returns true if the cell at (x, y) cannot be blocked.
+func definitelyOpen(state *gridState, x, y int) bool {
+	var cs primitives.CharSet
+	state.across[y].CharsAt(&cs, x)
+	if !cs.Contains(primitives.Blocked) {
+		return true
+	}
+	cs.Clear()
+	state.down[x].CharsAt(&cs, y)
+	return !cs.Contains(primitives.Blocked)
+}
+
+// enforceSymmetries filters the lines of state in place so that each cell whose blocked state is
+// already decided forces the same state onto the cells it maps to under each symmetry.
+func enforceSymmetries(state *gridState, symmetries []symmetry) {
+	width, height := len(state.down), len(state.across)
+	for _, sym := range symmetries {
+		for y := range height {
+			for x := range width {
+				mx, my := sym(x, y, width, height)
+				if mx == x && my == y {
+					continue
+				}
+				if definitelyBlocked(state, x, y) {
+					state.across[my] = state.across[my].Filter(primitives.Blocked, mx)
+					state.down[mx] = state.down[mx].Filter(primitives.Blocked, my)
+				} else if definitelyOpen(state, x, y) {
+					state.across[my] = state.across[my].FilterAny(&letterCharSet, mx)
+					state.down[mx] = state.down[mx].FilterAny(&letterCharSet, my)
+				}
+			}
+		}
+	}
+}
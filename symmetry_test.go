@@ -0,0 +1,92 @@
+package xwgen
+
+import (
+	"context"
+	"flag"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got against the golden file testdata/golden/name, or updates the file if
+// the -update flag is set.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s (run with -update to regenerate):\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestPossibleGrids_SymmetryGolden(t *testing.T) {
+	words := loadWords(t)
+
+	for _, tc := range []struct {
+		name       string
+		opts       []GeneratorOption
+		symmetries []symmetry
+	}{
+		{name: "none"},
+		{name: "rotational", opts: []GeneratorOption{WithRotationalSymmetry()}, symmetries: []symmetry{rotationalSymmetry}},
+		{name: "vertical", opts: []GeneratorOption{WithReflectiveSymmetry("vertical")}, symmetries: []symmetry{verticalAxisSymmetry}},
+		{name: "horizontal", opts: []GeneratorOption{WithReflectiveSymmetry("horizontal")}, symmetries: []symmetry{horizontalAxisSymmetry}},
+		{
+			name:       "rotational_vertical",
+			opts:       []GeneratorOption{WithRotationalSymmetry(), WithReflectiveSymmetry("vertical")},
+			symmetries: []symmetry{rotationalSymmetry, verticalAxisSymmetry, horizontalAxisSymmetry},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(42, 1024))
+			gen := CreateGenerator(5, words, nil, nil, rng, GeneratorParams{MinWordLength: 3}, tc.opts...)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			var reprs []string
+			for grid := range gen.PossibleGrids(ctx) {
+				for _, sym := range tc.symmetries {
+					for y := range grid.Height() {
+						for x := range grid.Width() {
+							mx, my := sym(x, y, grid.Width(), grid.Height())
+							if (grid.Get(x, y) == '`') != (grid.Get(mx, my) == '`') {
+								t.Errorf("grid is not symmetric at (%d, %d):\n%s", x, y, grid.Repr())
+							}
+						}
+					}
+				}
+				reprs = append(reprs, grid.Repr())
+				if len(reprs) >= 3 {
+					break
+				}
+			}
+
+			checkGolden(t, "symmetry_"+tc.name+".txt", strings.Join(reprs, "\n\n")+"\n")
+		})
+	}
+}
+
+func TestWithReflectiveSymmetry_InvalidAxis(t *testing.T) {
+	if err := WithReflectiveSymmetry("diagonal")(&Generator{}); err == nil {
+		t.Error("expected an error for an unknown axis")
+	}
+}
@@ -0,0 +1,56 @@
+package xwgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// WithConsistencyChecks checks every line of every point the search visits with
+// primitives.Validate, and panics with the errors and the line's tree if one is invalid. Since
+// the lines are only built and filtered by primitives, an invalid line is a bug, which this helps
+// find close to where it was introduced. It also panics if a line the search has decided is not
+// one of the lines its row or column could hold where the search started, since the search only
+// ever narrows lines down. It slows the search down a lot, so it is only meant for debugging.
+func WithConsistencyChecks() GeneratorOption {
+	return func(g *Generator) error {
+		g.consistencyChecks = true
+		return nil
+	}
+}
+
+// checkConsistency panics if any line of state is invalid. See WithConsistencyChecks.
+func checkConsistency(state *gridState) {
+	check := func(dir string, lines []primitives.PossibleLines) {
+		for i, line := range lines {
+			if errs := primitives.Validate(line); len(errs) > 0 {
+				var b strings.Builder
+				fmt.Fprintf(&b, "xwgen: %s line %d at level %d is invalid: %v\n", dir, i, state.level, errors.Join(errs...))
+				b.WriteString(primitives.DebugTree(line, 1))
+				panic(b.String())
+			}
+		}
+	}
+	check("across", state.across)
+	check("down", state.down)
+}
+
+// checkContained panics if a decided line of state is not one of the lines of the same row or
+// column of origin, the point the search started from. See WithConsistencyChecks.
+func checkContained(origin, state *gridState) {
+	check := func(dir string, origins, lines []primitives.PossibleLines) {
+		for i, line := range lines {
+			if line.MaxPossibilities() != 1 {
+				continue
+			}
+			if l := line.FirstOrNull(); l != nil && !origins[i].Contains(l.Line) {
+				panic(fmt.Sprintf("xwgen: %s line %d at level %d is %q, which it could not be at the start of the search\n%s",
+					dir, i, state.level, string(l.Line), primitives.DebugTree(origins[i], 1)))
+			}
+		}
+	}
+	check("across", origin.across, state.across)
+	check("down", origin.down, state.down)
+}
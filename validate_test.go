@@ -0,0 +1,54 @@
+package xwgen
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+func TestWithConsistencyChecks(t *testing.T) {
+	words := loadTrimmedWords(t)
+	search := func(opts ...GeneratorOption) []string {
+		gen, err := CreateGeneratorE(5, append(opts, WithPreferredWords(words), WithSeed(42, 1024), WithMemoizedFilters())...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+		var grids []string
+		for grid := range gen.PossibleGrids(ctx) {
+			if grids = append(grids, grid.Repr()); len(grids) == 5 {
+				break
+			}
+		}
+		return grids
+	}
+
+	// The search panics if any line it visits is invalid.
+	want := search()
+	if got := search(WithConsistencyChecks()); !slices.Equal(got, want) {
+		t.Errorf("WithConsistencyChecks() found grids %q, want %q", got, want)
+	}
+}
+
+func TestCheckContained(t *testing.T) {
+	words := primitives.MakeWords([]string{"abc", "abd", "bcd"}, 3, 3)
+	line := func(s string) primitives.PossibleLines {
+		return primitives.MakeDefinite(primitives.ConcreteLine{Line: []rune(s), Words: []string{s}})
+	}
+	origin := &gridState{across: []primitives.PossibleLines{words}, down: []primitives.PossibleLines{words}}
+
+	// Undecided lines, and decided lines that origin allows, pass.
+	checkContained(origin, origin)
+	checkContained(origin, &gridState{across: []primitives.PossibleLines{line("abd")}, down: []primitives.PossibleLines{words.Filter('b', 0)}})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("checkContained() did not panic for a line origin does not allow")
+		}
+	}()
+	checkContained(origin, &gridState{across: []primitives.PossibleLines{words}, down: []primitives.PossibleLines{line("xyz")}})
+}
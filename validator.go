@@ -0,0 +1,78 @@
+package xwgen
+
+import (
+	"errors"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// LineContext describes the row or column of a line passed to a line validator, and the lines
+// crossing it.
+type LineContext struct {
+	// Direction is DirectionHorizontal for a row, and DirectionVertical for a column. Index is the
+	// index of the row from the top, or the column from the left.
+	Direction Direction
+	Index     int
+	// Crossing holds the possibilities of the lines crossing this one as the search currently
+	// stands, so that the ith cell of the line is cell Index of Crossing[i]. They must not be
+	// modified.
+	Crossing []primitives.PossibleLines
+}
+
+// Allowed returns the characters that the line crossing the ith cell of the line currently allows
+// there, including primitives.Blocked if the cell may be blocked.
+func (c LineContext) Allowed(i int) primitives.CharSet {
+	chars := *primitives.DefaultCharSet()
+	c.Crossing[i].CharsAt(&chars, c.Index)
+	return chars
+}
+
+// WithLineValidator only yields grids whose rows and columns all satisfy valid, e.g. to ban
+// abbreviations unless they are crossed by preferred words. It can be given more than once, in
+// which case every line must satisfy every validator.
+//
+// valid is called for each line the search decides on, and returns false to prune every grid the
+// line is part of. Since the lines crossing a line are narrowed as the search goes on, the same
+// line is validated again at each later point of the search it is part of, with an up to date
+// context; a line may be rejected once its crossing lines are decided even if it was accepted
+// before.
+//
+// valid is called often, and slows the search down by however long it takes, so it should be
+// quick. Since the search cannot tell which choices a rejection depends on, it also backtracks
+// chronologically from the lines it rejects.
+func WithLineValidator(valid func(line primitives.ConcreteLine, ctx LineContext) bool) GeneratorOption {
+	return func(g *Generator) error {
+		if valid == nil {
+			return errors.New("line validator must not be nil")
+		}
+		g.lineValidators = append(g.lineValidators, valid)
+		return nil
+	}
+}
+
+// validLines returns false if a line validator rejects some decided line of state.
+func (g *Generator) validLines(state *gridState) bool {
+	if len(g.lineValidators) == 0 {
+		return true
+	}
+	validate := func(dir Direction, lines, crossing []primitives.PossibleLines) bool {
+		for i, line := range lines {
+			if line.MaxPossibilities() != 1 {
+				continue
+			}
+			concrete := line.FirstOrNull()
+			if concrete == nil {
+				continue
+			}
+			ctx := LineContext{Direction: dir, Index: i, Crossing: crossing}
+			for _, valid := range g.lineValidators {
+				if !valid(*concrete, ctx) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return validate(DirectionHorizontal, state.across, state.down) &&
+		validate(DirectionVertical, state.down, state.across)
+}
@@ -0,0 +1,122 @@
+package xwgen
+
+import (
+	"testing"
+
+	"github.com/Eyas/xwgen/pkg/primitives"
+)
+
+// endsAt returns true if a word of line ends at its ith cell.
+func endsAt(line []rune, i int) bool {
+	return line[i] != primitives.Blocked && (i+1 == len(line) || line[i+1] == primitives.Blocked)
+}
+
+// noPluralCorners rejects lines with a word ending in "s" at the same cell as a decided crossing
+// word that also ends in "s".
+func noPluralCorners(line primitives.ConcreteLine, ctx LineContext) bool {
+	for i := range line.Line {
+		if line.Line[i] != 's' || !endsAt(line.Line, i) {
+			continue
+		}
+		crossing := ctx.Crossing[i]
+		if crossing.MaxPossibilities() != 1 {
+			continue
+		}
+		if c := crossing.FirstOrNull(); c != nil && endsAt(c.Line, ctx.Index) {
+			return false
+		}
+	}
+	return true
+}
+
+// pluralCorners returns the number of cells of grid where an across and a down entry both end in
+// "s".
+func pluralCorners(grid Grid) int {
+	type cell struct{ row, col int }
+	acrossEnds := make(map[cell]bool)
+	n := 0
+	for _, e := range grid.Entries() {
+		if e.Direction == DirectionHorizontal {
+			acrossEnds[cell{e.Row, e.Col + e.Length - 1}] = true
+		}
+	}
+	for _, e := range grid.Entries() {
+		end := cell{e.Row + e.Length - 1, e.Col}
+		if e.Direction == DirectionVertical && acrossEnds[end] && grid.Cell(end.row, end.col) == 's' {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWithLineValidator(t *testing.T) {
+	words := loadTrimmedWords(t)
+	generate := func(opts ...GeneratorOption) []Grid {
+		gen, err := CreateGeneratorE(4, append([]GeneratorOption{WithPreferredWords(words), WithSeed(42, 1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var grids []Grid
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if grids = append(grids, grid); len(grids) >= 50 {
+				break
+			}
+		}
+		return grids
+	}
+
+	withCorners := 0
+	for _, grid := range generate() {
+		if pluralCorners(grid) > 0 {
+			withCorners++
+		}
+	}
+	if withCorners == 0 {
+		t.Fatal("no grid has plural corners without a validator, so the test proves nothing")
+	}
+
+	grids := generate(WithLineValidator(noPluralCorners))
+	if len(grids) == 0 {
+		t.Fatal("got no grids with the validator")
+	}
+	for _, grid := range grids {
+		if pluralCorners(grid) > 0 {
+			t.Errorf("grid has plural corners despite the validator:\n%s", grid.Repr())
+		}
+	}
+	t.Logf("%d of 50 grids had plural corners without the validator", withCorners)
+
+	t.Run("context", func(t *testing.T) {
+		calls := 0
+		check := func(line primitives.ConcreteLine, ctx LineContext) bool {
+			calls++
+			if len(ctx.Crossing) != len(line.Line) {
+				t.Errorf("%d crossing lines for a line of %d cells", len(ctx.Crossing), len(line.Line))
+			}
+			for i, r := range line.Line {
+				if allowed := ctx.Allowed(i); !allowed.Contains(r) {
+					t.Errorf("crossing line %d of %v %d does not allow %q", i, ctx.Direction, ctx.Index, r)
+				}
+			}
+			return true
+		}
+		generate(WithLineValidator(check))
+		if calls == 0 {
+			t.Error("validator was never called")
+		}
+	})
+
+	t.Run("composed", func(t *testing.T) {
+		accept := func(primitives.ConcreteLine, LineContext) bool { return true }
+		reject := func(primitives.ConcreteLine, LineContext) bool { return false }
+		if grids := generate(WithLineValidator(accept), WithLineValidator(reject)); len(grids) != 0 {
+			t.Errorf("got %d grids when a validator rejects every line, want 0", len(grids))
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if _, err := CreateGeneratorE(4, WithLineValidator(nil)); err == nil {
+			t.Error("CreateGeneratorE() with a nil validator succeeded, want an error")
+		}
+	})
+}
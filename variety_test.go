@@ -0,0 +1,60 @@
+package xwgen
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestWithLetterVariety(t *testing.T) {
+	var words []string
+	for i, word := range loadTrimmedWords(t) {
+		if i%2 == 0 {
+			words = append(words, word)
+		}
+	}
+	generate := func(limit int, opts ...GeneratorOption) []Grid {
+		gen, err := CreateGeneratorE(3, append([]GeneratorOption{WithPreferredWords(words), WithSeed(42, 1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("CreateGeneratorE() error: %v", err)
+		}
+		var grids []Grid
+		for grid := range gen.PossibleGrids(t.Context()) {
+			if grids = append(grids, grid); len(grids) == limit {
+				break
+			}
+		}
+		return grids
+	}
+	reprs := func(grids []Grid) map[string]bool {
+		set := make(map[string]bool)
+		for _, grid := range grids {
+			set[grid.Repr()] = true
+		}
+		return set
+	}
+	meanDistinct := func(grids []Grid) float64 {
+		var total int
+		for _, grid := range grids {
+			total += len(grid.LetterHistogram())
+		}
+		return float64(total) / float64(len(grids))
+	}
+
+	// Searched to completion, the same grids are found.
+	all, varied := generate(0), generate(0, WithLetterVariety())
+	if len(all) == 0 {
+		t.Fatal("got no grids")
+	}
+	if got, want := reprs(varied), reprs(all); !maps.Equal(got, want) {
+		t.Errorf("got %d grids with letter variety, want the same %d as without", len(got), len(want))
+	}
+
+	// Only the order changes.
+	const first = 200
+	if slices.EqualFunc(all[:first], varied[:first], func(a, b Grid) bool { return a.Repr() == b.Repr() }) {
+		t.Errorf("the first %d grids are the same with letter variety", first)
+	}
+	t.Logf("first %d grids have %.2f distinct letters on average, or %.2f with letter variety",
+		first, meanDistinct(all[:first]), meanDistinct(varied[:first]))
+}